@@ -2,33 +2,55 @@ package main
 
 import (
 	"context"
+	"flag"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"regexp"
+	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ontair/admin-panel/internal/adapters/primary/api"
 	"github.com/ontair/admin-panel/internal/adapters/primary/middleware"
+	"github.com/ontair/admin-panel/internal/adapters/primary/scheduler"
 	"github.com/ontair/admin-panel/internal/adapters/secondary/cookie"
 	userRepo "github.com/ontair/admin-panel/internal/adapters/secondary/database"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/hasher"
 	"github.com/ontair/admin-panel/internal/adapters/secondary/jwt"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/metrics"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/notifier"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/redis"
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
 	"github.com/ontair/admin-panel/internal/core/ports/service"
 	"github.com/ontair/admin-panel/internal/core/services"
 	"github.com/ontair/admin-panel/internal/infra/config"
 	"github.com/ontair/admin-panel/internal/infra/database"
+	"github.com/ontair/admin-panel/internal/infra/database/migrations"
 	"github.com/ontair/admin-panel/internal/infra/logger"
+	"go.uber.org/zap"
 )
 
 func main() {
+	migrateCmd := flag.String("migrate", "", "run database migrations and exit: up, down or status")
+	migrateSteps := flag.Int("steps", 1, "number of migrations rolled back by -migrate down")
+	flag.Parse()
+
 	// Load configuration
 	cfg, err := config.Load()
 	if err != nil {
 		log.Fatalf("Failed to load config: %v", err)
 	}
 
+	if *migrateCmd != "" {
+		runMigrations(cfg, *migrateCmd, *migrateSteps)
+		return
+	}
+
 	// Initialize logger
 	appLogger, err := logger.NewLogger(cfg)
 	if err != nil {
@@ -38,25 +60,67 @@ func main() {
 
 	appLogger.Info("Starting Admin Panel Server")
 
+	// Initialize password hasher, also used to seed the default admin
+	passwordHasher, err := hasher.New(cfg.Security.HashAlgo, cfg.Security.BcryptCost)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize password hasher")
+	}
+
 	// Initialize database
-	dbService, err := database.NewDatabaseService(cfg)
+	dbService, err := database.NewDatabaseService(cfg, passwordHasher, appLogger)
 	if err != nil {
 		appLogger.Fatal("Failed to initialize database")
 	}
-	defer dbService.Close()
 
 	appLogger.Info("Database connection established")
 
+	// Connect to Redis when state is shared between instances
+	var cacheClient *redis.Client
+	if cfg.Cache.Driver == "redis" {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		cacheClient, err = redis.NewClient(ctx, redis.Config{
+			Addr:     cfg.Cache.Addr,
+			Password: cfg.Cache.Password,
+			DB:       cfg.Cache.DB,
+		})
+		cancel()
+		if err != nil {
+			appLogger.Fatal("Failed to connect to Redis, check cache settings or use cache.driver: memory", zap.String("error", err.Error()))
+		}
+
+		appLogger.Info("Redis connection established")
+	}
+
 	// Initialize dependencies
-	deps := initializeDependencies(cfg, dbService, appLogger)
+	deps := initializeDependencies(cfg, dbService, cacheClient, passwordHasher, appLogger)
 
 	// Setup Gin mode
 	if cfg.IsProduction() {
 		gin.SetMode(gin.ReleaseMode)
 	}
 
+	// Start background jobs, shutdown waits for them before closing connections
+	jobsCtx, stopJobs := context.WithCancel(context.Background())
+	defer stopJobs()
+	var jobs sync.WaitGroup
+
+	deactivationJob := scheduler.NewDeactivationJob(deps.UserService, appLogger, time.Duration(cfg.Users.DeactivationInterval)*time.Second)
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		deactivationJob.Run(jobsCtx)
+	}()
+
+	tokenCleanupJob := scheduler.NewTokenCleanupJob(deps.AuthService, appLogger, time.Duration(cfg.JWT.BlacklistCleanupInterval)*time.Second)
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		tokenCleanupJob.Run(jobsCtx)
+	}()
+
 	// Create router
-	router := setupRouter(deps, cfg, appLogger)
+	var inFlight atomic.Int64
+	router := setupRouter(deps, cfg, appLogger, &inFlight)
 
 	// Create server
 	server := &http.Server{
@@ -75,60 +139,272 @@ func main() {
 		}
 	}()
 
+	// Metrics get their own listener when bound to an internal address
+	var metricsServer *http.Server
+	if cfg.Metrics.Enabled && cfg.Metrics.Addr != "" {
+		metricsMux := http.NewServeMux()
+		metricsMux.Handle("/metrics", deps.Metrics)
+		metricsServer = &http.Server{
+			Addr:        cfg.Metrics.Addr,
+			Handler:     metricsMux,
+			ReadTimeout: time.Duration(cfg.Server.ReadTimeout) * time.Second,
+		}
+
+		go func() {
+			appLogger.Info("Metrics server starting on " + cfg.Metrics.Addr)
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				appLogger.Fatal("Metrics server failed to start", zap.String("error", err.Error()))
+			}
+		}()
+	}
+
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
 
-	appLogger.Info("Server shutting down...")
+	appLogger.Info("Server shutting down...", zap.Int64("in_flight_requests", inFlight.Load()))
+	stopJobs()
 
-	// Graceful shutdown
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Graceful shutdown: drain requests first, connections are closed only afterwards
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
 	defer cancel()
 
 	if err := server.Shutdown(ctx); err != nil {
-		appLogger.Error("Server forced to shutdown")
+		appLogger.Error("Server forced to shutdown", zap.Int64("in_flight_requests", inFlight.Load()))
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(ctx); err != nil {
+			appLogger.Error("Metrics server forced to shutdown")
+		}
 	}
+	jobs.Wait()
+
+	if cacheClient != nil {
+		cacheClient.Close()
+	}
+	dbService.Close()
 
 	appLogger.Info("Server exited")
 }
 
+// runMigrations applies or rolls back migrations separately from server startup
+func runMigrations(cfg *config.Config, command string, steps int) {
+	if command != "up" && command != "down" && command != "status" {
+		log.Fatalf("Unknown -migrate command %q, use up, down or status", command)
+	}
+	if command == "down" && steps < 1 {
+		log.Fatalf("-steps must be at least 1, got %d", steps)
+	}
+
+	appLogger, err := logger.NewLogger(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize logger: %v", err)
+	}
+	defer appLogger.Close()
+
+	passwordHasher, err := hasher.New(cfg.Security.HashAlgo, cfg.Security.BcryptCost)
+	if err != nil {
+		log.Fatalf("Failed to initialize password hasher: %v", err)
+	}
+
+	// Connect only, the command below decides what to migrate
+	cfg.Database.RunMigrations = false
+	dbService, err := database.NewDatabaseService(cfg, passwordHasher, appLogger)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer dbService.Close()
+
+	switch command {
+	case "up":
+		err = dbService.MigrateUp()
+	case "down":
+		err = dbService.MigrateDown(steps)
+	case "status":
+		var statuses []migrations.Status
+		statuses, err = dbService.MigrationStatus()
+		for _, status := range statuses {
+			applied := "pending"
+			if status.AppliedAt != nil {
+				applied = "applied " + status.AppliedAt.Format(time.RFC3339)
+			}
+			log.Printf("%04d_%s: %s", status.Version, status.Name, applied)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Migration %s failed: %v", command, err)
+	}
+}
+
 // initializeDependencies sets up all application dependencies
-func initializeDependencies(cfg *config.Config, dbService *database.DatabaseService, appLogger service.Logger) *Dependencies {
-	// Initialize repositories
-	userRepository := userRepo.NewUserRepository(dbService.GetPool())
+func initializeDependencies(cfg *config.Config, dbService *database.DatabaseService, cacheClient *redis.Client, passwordHasher entities.PasswordHasher, appLogger service.Logger) *Dependencies {
+	// Initialize repositories, every query is bounded by the configured timeout
+	queryTimeout := time.Duration(cfg.Database.QueryTimeout) * time.Second
+	db := userRepo.NewTimeoutDB(dbService.GetPool(), queryTimeout)
+	userRepository := userRepo.NewUserRepository(db)
+	roleRepository := userRepo.NewRoleRepository(db)
+	tokenBlacklist := userRepo.NewTokenBlacklist(db)
+	rateLimitStore := middleware.NewMemoryRateLimitStore()
+	if cacheClient != nil {
+		tokenBlacklist = redis.NewTokenBlacklist(cacheClient)
+		rateLimitStore = redis.NewRateLimitStore(cacheClient)
+	}
+	resetRepository := userRepo.NewPasswordResetRepository(db)
+	verificationRepository := userRepo.NewEmailVerificationRepository(db)
+	auditRepository := userRepo.NewAuditRepository(db)
+	loginAttemptRepository := userRepo.NewLoginAttemptRepository(db)
+	refreshTokenRepository := userRepo.NewRefreshTokenRepository(db)
+	passwordHistoryRepository := userRepo.NewPasswordHistoryRepository(db)
+	txManager := userRepo.NewTxManager(dbService.WithTx, queryTimeout)
 
 	// Initialize external services
-	jwtService := jwt.NewJWTService(cfg)
-	cookieService := cookie.NewCookieService(cfg.Cookie.SameSite, cfg.Cookie.Domain, cfg.Cookie.Secure)
+	jwtService, err := jwt.NewJWTService(cfg)
+	if err != nil {
+		appLogger.Fatal("Failed to initialize JWT service", zap.String("error", err.Error()))
+	}
+	cookieService := cookie.NewCookieService(cfg.Cookie.SameSite, cfg.Cookie.Domain, cfg.Cookie.Secure,
+		time.Duration(cfg.Cookie.AccessExpiry)*time.Minute,
+		time.Duration(cfg.Cookie.RefreshExpiry)*time.Minute,
+	)
+	notifierService := notifier.NewLogNotifier(appLogger)
+
+	// Pool gauges are read on every scrape
+	metricsRegistry := metrics.NewRegistry()
+	metricsRegistry.RegisterGauge("db_connections_acquired", "Database connections currently in use.", func() float64 {
+		return float64(dbService.GetPool().Stat().AcquiredConns())
+	})
+	metricsRegistry.RegisterGauge("db_connections_idle", "Idle database connections in the pool.", func() float64 {
+		return float64(dbService.GetPool().Stat().IdleConns())
+	})
+	metricsRegistry.RegisterGauge("db_connections_total", "Open database connections in the pool.", func() float64 {
+		return float64(dbService.GetPool().Stat().TotalConns())
+	})
+	metricsRegistry.RegisterGauge("db_connections_max", "Maximum size of the database pool.", func() float64 {
+		return float64(dbService.GetPool().Stat().MaxConns())
+	})
+
+	passwordPolicy := entities.PasswordPolicy{
+		MinLength:     cfg.Security.Password.MinLength,
+		RequireUpper:  cfg.Security.Password.RequireUpper,
+		RequireLower:  cfg.Security.Password.RequireLower,
+		RequireDigit:  cfg.Security.Password.RequireDigit,
+		RequireSymbol: cfg.Security.Password.RequireSymbol,
+		RejectCommon:  cfg.Security.Password.RejectCommon,
+	}
+
+	// Patterns are validated on config load
+	rolePatterns := make([]entities.RolePattern, 0, len(cfg.Users.RolePatterns))
+	for _, p := range cfg.Users.RolePatterns {
+		rolePatterns = append(rolePatterns, entities.RolePattern{Pattern: regexp.MustCompile(p.Pattern), Role: entities.Role(p.Role)})
+	}
 
 	// Initialize use cases
-	authService := services.NewAuthService(userRepository, jwtService)
-	userService := services.NewUserService(userRepository)
+	auditService := services.NewAuditService(auditRepository, appLogger)
+	authService := services.NewAuthService(userRepository, jwtService, tokenBlacklist, passwordHasher, notifierService, loginAttemptRepository, refreshTokenRepository, txManager, metricsRegistry, appLogger, services.AuthServiceConfig{
+		RequireApproval:    cfg.Users.RequireApproval,
+		RejectNumericNames: cfg.Users.RejectNumericNames,
+
+		MaxLoginAttempts: cfg.Security.MaxLoginAttempts,
+		LockoutWindow:    time.Duration(cfg.Security.LockoutWindow) * time.Minute,
+
+		AccessExpiry:  time.Duration(cfg.JWT.AccessExpiry) * time.Minute,
+		RefreshExpiry: time.Duration(cfg.JWT.RefreshExpiry) * time.Minute,
+
+		UniqueNames:  cfg.Users.UniqueNames,
+		MaxAdmins:    cfg.Users.MaxAdmins,
+		RolePatterns: rolePatterns,
+
+		RequireVerifiedEmail: cfg.Auth.RequireVerifiedEmail,
+
+		PasswordPolicy: passwordPolicy,
+	})
+	userService := services.NewUserService(userRepository, roleRepository, resetRepository, verificationRepository, passwordHistoryRepository, loginAttemptRepository, txManager, passwordHasher, notifierService, auditService, services.UserServiceConfig{
+		RequireApproval:     cfg.Users.RequireApproval,
+		LockPasswordChanges: cfg.Users.LockPasswordChanges,
+		RejectNumericNames:  cfg.Users.RejectNumericNames,
+		SearchMaxLength:     cfg.Users.SearchMaxLength,
+		SearchWildcards:     cfg.Users.SearchWildcards,
+		CustomRoles:         cfg.Users.CustomRoles,
+
+		LogoutOnPasswordChange:  cfg.Auth.LogoutOnPasswordChange,
+		RejectUnchangedPassword: cfg.Auth.RejectUnchangedPassword,
+
+		HideInactiveFromManagers: cfg.Users.HideInactiveFromManagers,
+
+		RefetchAfterWrite: cfg.Users.RefetchAfterWrite,
+
+		UniqueNames:  cfg.Users.UniqueNames,
+		MaxAdmins:    cfg.Users.MaxAdmins,
+		RolePatterns: rolePatterns,
+
+		PasswordPolicy:  passwordPolicy,
+		PasswordHistory: cfg.Security.Password.History,
+	})
 
 	return &Dependencies{
-		Config:        cfg,
-		Logger:        appLogger,
-		AuthService:   authService,
-		UserService:   userService,
-		JWTService:    jwtService,
-		CookieService: cookieService,
+		Config:         cfg,
+		Logger:         appLogger,
+		Cache:          cacheClient,
+		RateLimitStore: rateLimitStore,
+		AuthService:    authService,
+		UserService:    userService,
+		AuditService:   auditService,
+		JWTService:     jwtService,
+		CookieService:  cookieService,
+		Metrics:        metricsRegistry,
+		Database:       dbService,
 	}
 }
 
 // setupRouter configures Gin router with all routes and middleware
-func setupRouter(deps *Dependencies, cfg *config.Config, appLogger service.Logger) *gin.Engine {
+func setupRouter(deps *Dependencies, cfg *config.Config, appLogger service.Logger, inFlight *atomic.Int64) *gin.Engine {
 	router := gin.New()
 
-	// Middleware
-	router.Use(gin.Logger())
-	router.Use(gin.Recovery())
+	// Client IPs come from X-Forwarded-For only when set by a configured proxy, so rate limits
+	// and login lockout can't be dodged by sending the header directly. Validated by config.
+	var trustedProxies []string
+	if len(cfg.Server.TrustedProxies) > 0 {
+		trustedProxies = cfg.Server.TrustedProxies
+	}
+	if err := router.SetTrustedProxies(trustedProxies); err != nil {
+		appLogger.Fatal("Invalid trusted proxies", zap.String("error", err.Error()))
+	}
 
-	// CORS middleware - handled by Nginx proxy
-	// No CORS headers needed here as Nginx handles them
+	// Middleware
+	router.Use(middleware.InFlight(inFlight))
+	router.Use(middleware.RequestID())
+	router.Use(middleware.AccessLog(appLogger))
+	if cfg.Metrics.Enabled {
+		router.Use(middleware.Metrics(deps.Metrics))
+	}
+	router.Use(middleware.Recovery(appLogger))
+	router.Use(middleware.MaxURILength(cfg.Server.MaxURILength))
+
+	// CORS is usually handled by the Nginx proxy, the built-in one is for setups without it
+	router.Use(middleware.CORS(middleware.CORSConfig{
+		Enabled:          cfg.Server.CORS.Enabled,
+		AllowedOrigins:   cfg.Server.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.Server.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.Server.CORS.AllowedHeaders,
+		ExposedHeaders:   cfg.Server.CORS.ExposedHeaders,
+		AllowCredentials: cfg.Server.CORS.AllowCredentials,
+		MaxAge:           time.Duration(cfg.Server.CORS.MaxAge) * time.Second,
+	}))
 
 	// Health check endpoint
 	router.GET("/health", func(c *gin.Context) {
+		if deps.Cache != nil {
+			if err := deps.Cache.Ping(c.Request.Context()); err != nil {
+				c.JSON(http.StatusServiceUnavailable, gin.H{
+					"status": "degraded",
+					"time":   time.Now(),
+					"cache":  "unreachable",
+				})
+				return
+			}
+		}
+
 		c.JSON(http.StatusOK, gin.H{
 			"status":  "ok",
 			"time":    time.Now(),
@@ -136,18 +412,82 @@ func setupRouter(deps *Dependencies, cfg *config.Config, appLogger service.Logge
 		})
 	})
 
-	// API routes
+	// Liveness probe, only reports that the process serves requests
+	router.GET("/health/live", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"status": "ok",
+			"time":   time.Now(),
+		})
+	})
+
+	// Readiness probe, fails while the database can't be reached
+	router.GET("/health/ready", func(c *gin.Context) {
+		stat := deps.Database.GetPool().Stat()
+		pool := gin.H{
+			"acquired_conns": stat.AcquiredConns(),
+			"idle_conns":     stat.IdleConns(),
+			"total_conns":    stat.TotalConns(),
+			"max_conns":      stat.MaxConns(),
+		}
+
+		if err := deps.Database.Health(); err != nil {
+			appLogger.With(c.Request.Context()).Warn("Readiness check failed", zap.String("error", err.Error()))
+			c.JSON(http.StatusServiceUnavailable, gin.H{
+				"status":   "unavailable",
+				"time":     time.Now(),
+				"database": "unreachable",
+				"pool":     pool,
+			})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{
+			"status":   "ok",
+			"time":     time.Now(),
+			"database": "ok",
+			"pool":     pool,
+		})
+	})
+
+	// Prometheus metrics, unless bound to a separate internal listener
+	if cfg.Metrics.Enabled && cfg.Metrics.Addr == "" {
+		router.GET("/metrics", gin.WrapH(deps.Metrics))
+	}
+
+	// API routes, rate limited per client IP
 	apiGroup := router.Group("/api/v1")
+	apiGroup.Use(middleware.RateLimitWithStore(deps.RateLimitStore, "api", cfg.RateLimit.APIRPS, cfg.RateLimit.APIBurst, cfg.RateLimit.Headers))
 
 	// Initialize handlers
-	authHandler := api.NewAuthHandler(deps.AuthService, appLogger, deps.CookieService, deps.JWTService)
-	userHandler := api.NewUserHandler(deps.UserService, appLogger)
+	authHandler := api.NewAuthHandler(deps.AuthService, deps.UserService, appLogger, deps.CookieService, deps.JWTService, api.AuthHandlerConfig{
+		LockoutWindow: time.Duration(cfg.Security.LockoutWindow) * time.Minute,
+		TokenPreview:  !cfg.IsProduction(),
+		TokenProfile:  cfg.Auth.TokenProfile,
+	})
+	userHandler := api.NewUserHandler(deps.UserService, appLogger, api.UserHandlerConfig{
+		StrictQueryParams: cfg.Server.StrictQueryParams,
+		ExposeEmailTokens: !cfg.IsProduction(),
+	})
+	auditHandler := api.NewAuditHandler(deps.AuditService, appLogger)
 
 	// Init auth middleware
-	authMiddleware := middleware.NewAuthMiddleware(deps.JWTService, appLogger, deps.CookieService, deps.AuthService)
+	authMiddleware := middleware.NewAuthMiddleware(deps.JWTService, appLogger, deps.CookieService, deps.AuthService, middleware.AuthMiddlewareConfig{
+		SlidingExpiration: cfg.JWT.SlidingExpiration,
+		SlidingWindow:     time.Duration(cfg.JWT.SlidingWindow) * time.Minute,
+		AccessExpiry:      time.Duration(cfg.JWT.AccessExpiry) * time.Minute,
+		TokenPrecedence:   cfg.Cookie.TokenPrecedence,
+
+		PasswordChangeRoutes: []string{"/api/v1/users/change-password", "/api/v1/auth/logout"},
+	})
+
+	// Public keys for verifiers of RS256 tokens
+	authHandler.RegisterWellKnownRoutes(router)
 
-	// Register auth routes (login, refresh, logout are public)
-	authHandler.RegisterPublicRoutes(apiGroup)
+	// Register auth routes (login, refresh, logout are public) with a stricter limit
+	public := apiGroup.Group("/")
+	public.Use(middleware.RateLimitWithStore(deps.RateLimitStore, "auth", cfg.RateLimit.AuthRPS, cfg.RateLimit.AuthBurst, cfg.RateLimit.Headers))
+	authHandler.RegisterPublicRoutes(public)
+	userHandler.RegisterPublicRoutes(public) // Password reset
 
 	// Protected routes (require authentication)
 	protected := apiGroup.Group("/")
@@ -164,17 +504,24 @@ func setupRouter(deps *Dependencies, cfg *config.Config, appLogger service.Logge
 	// Admin routes (require admin role)
 	admin := protected.Group("/admin")
 	admin.Use(authMiddleware.RequireAdmin())
-	userHandler.RegisterAdminRoutes(admin) // Admin-specific endpoints (full user list)
+	userHandler.RegisterAdminRoutes(admin)  // Admin-specific endpoints (full user list)
+	authHandler.RegisterAdminRoutes(admin)  // Token signing self-test
+	auditHandler.RegisterAdminRoutes(admin) // Audit log of user changes
 
 	return router
 }
 
 // Dependencies holds all application dependencies
 type Dependencies struct {
-	Config        *config.Config
-	Logger        service.Logger
-	AuthService   service.AuthService
-	UserService   service.UserService
-	JWTService    service.JWTService
-	CookieService service.CookieService
+	Config         *config.Config
+	Logger         service.Logger
+	Cache          *redis.Client // nil unless cache.driver is redis
+	RateLimitStore repository.RateLimitStore
+	AuthService    service.AuthService
+	UserService    service.UserService
+	AuditService   service.AuditService
+	JWTService     service.JWTService
+	CookieService  service.CookieService
+	Metrics        *metrics.Registry
+	Database       *database.DatabaseService
 }
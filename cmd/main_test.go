@@ -0,0 +1,612 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ontair/admin-panel/internal/adapters/primary/middleware"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/cookie"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/hasher"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/jwt"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/metrics"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/notifier"
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/services"
+	"github.com/ontair/admin-panel/internal/infra/config"
+	"github.com/ontair/admin-panel/internal/testutil"
+)
+
+// testApp is the full router wired to in-memory repositories and a real JWT service,
+// with one active account per role
+type testApp struct {
+	router   *gin.Engine
+	store    *testutil.Store
+	accounts map[entities.Role]*entities.User
+	tokens   map[entities.Role]string
+}
+
+// newTestApp builds the router the way main does, minus the database and Redis.
+// Configure funcs adjust the loaded config before anything is wired.
+func newTestApp(t *testing.T, configure ...func(*config.Config)) *testApp {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	cfg, err := config.Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	cfg.RateLimit.APIRPS = 0
+	cfg.RateLimit.AuthRPS = 0
+	cfg.Security.BcryptCost = 4
+	for _, fn := range configure {
+		fn(cfg)
+	}
+
+	appLogger := testutil.NewLogger()
+	store := testutil.NewStore()
+
+	jwtService, err := jwt.NewJWTService(cfg)
+	if err != nil {
+		t.Fatalf("failed to create JWT service: %v", err)
+	}
+	passwordHasher, err := hasher.New("bcrypt", cfg.Security.BcryptCost)
+	if err != nil {
+		t.Fatalf("failed to create hasher: %v", err)
+	}
+	notifierService := notifier.NewLogNotifier(appLogger)
+	metricsRegistry := metrics.NewRegistry()
+	passwordPolicy := entities.PasswordPolicy{MinLength: cfg.Security.Password.MinLength}
+
+	auditService := services.NewAuditService(store.Audit, appLogger)
+	authService := services.NewAuthService(store.Users, jwtService, store.Blacklist, passwordHasher, notifierService, store.LoginAttempts, store.RefreshTokens, store.TxManager(), metricsRegistry, appLogger, services.AuthServiceConfig{
+		AccessExpiry:   time.Duration(cfg.JWT.AccessExpiry) * time.Minute,
+		RefreshExpiry:  time.Duration(cfg.JWT.RefreshExpiry) * time.Minute,
+		PasswordPolicy: passwordPolicy,
+	})
+	userService := services.NewUserService(store.Users, store.Roles, store.PasswordResets, store.EmailVerifications, store.PasswordHistory, store.LoginAttempts, store.TxManager(), passwordHasher, notifierService, auditService, services.UserServiceConfig{
+		PasswordPolicy: passwordPolicy,
+	})
+
+	deps := &Dependencies{
+		Config:         cfg,
+		Logger:         appLogger,
+		RateLimitStore: middleware.NewMemoryRateLimitStore(),
+		AuthService:    authService,
+		UserService:    userService,
+		AuditService:   auditService,
+		JWTService:     jwtService,
+		CookieService:  cookie.NewCookieService(cfg.Cookie.SameSite, cfg.Cookie.Domain, cfg.Cookie.Secure, time.Minute, time.Minute),
+		Metrics:        metricsRegistry,
+	}
+
+	app := &testApp{
+		router:   setupRouter(deps, cfg, appLogger, new(atomic.Int64)),
+		store:    store,
+		accounts: make(map[entities.Role]*entities.User),
+		tokens:   make(map[entities.Role]string),
+	}
+	for _, role := range entities.AllRoles {
+		user := app.createUser(t, "test_"+string(role), role)
+		token, err := jwtService.GenerateAccessToken(user)
+		if err != nil {
+			t.Fatalf("failed to issue token: %v", err)
+		}
+		app.accounts[role] = user
+		app.tokens[role] = token
+	}
+	return app
+}
+
+// createUser stores an active approved user
+func (a *testApp) createUser(t *testing.T, username string, role entities.Role) *entities.User {
+	t.Helper()
+	user := &entities.User{
+		Username:       username,
+		FirstName:      "Test",
+		LastName:       strings.ToUpper(username[:1]) + username[1:],
+		Role:           role,
+		IsActive:       true,
+		ApprovalStatus: entities.ApprovalApproved,
+	}
+	if err := a.store.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("failed to create user %s: %v", username, err)
+	}
+	return user
+}
+
+// do sends request with token of role, an empty role sends it anonymously
+func (a *testApp) do(method, path string, role entities.Role) *httptest.ResponseRecorder {
+	var body *strings.Reader
+	if method == http.MethodPost || method == http.MethodPut {
+		body = strings.NewReader("{}")
+	} else {
+		body = strings.NewReader("")
+	}
+
+	req := httptest.NewRequest(method, path, body)
+	req.Header.Set("Content-Type", "application/json")
+	if role != "" {
+		req.Header.Set("Authorization", "Bearer "+a.tokens[role])
+	}
+
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, req)
+	return w
+}
+
+// access is a row class of the README access matrix
+type access int
+
+const (
+	accessPublic        access = iota // anyone, tokens are not required
+	accessAuthenticated               // any role
+	accessOwnRecord                   // own ID for any role, others by manager¹ and admin (²)
+	accessManager                     // manager and admin
+	accessManagerScoped               // manager and admin, managers only for user and guest targets (¹)
+	accessAdmin                       // admin only
+)
+
+// accessMatrix mirrors the README matrix for every API route.
+// A route missing here fails the test, so new routes have to be classified.
+var accessMatrix = map[string]access{
+	"POST /api/v1/auth/login":                  accessPublic,
+	"POST /api/v1/auth/refresh":                accessPublic,
+	"POST /api/v1/auth/logout":                 accessPublic,
+	"POST /api/v1/auth/reset-password":         accessPublic,
+	"POST /api/v1/auth/reset-password/confirm": accessPublic,
+	"GET /api/v1/auth/verify-email":            accessPublic,
+
+	"GET /api/v1/auth/profile":           accessAuthenticated,
+	"GET /api/v1/users/profile":          accessAuthenticated,
+	"GET /api/v1/users/export-me":        accessAuthenticated,
+	"GET /api/v1/users/security":         accessAuthenticated,
+	"GET /api/v1/users/sessions":         accessAuthenticated,
+	"POST /api/v1/users/verify-email":    accessAuthenticated,
+	"POST /api/v1/users/change-password": accessAuthenticated,
+	"GET /api/v1/users/:id":              accessOwnRecord,
+
+	"GET /api/v1/manager/users/":                     accessManager,
+	"POST /api/v1/manager/users/":                    accessManager,
+	"GET /api/v1/manager/users/stats":                accessManager,
+	"POST /api/v1/manager/auth/register":             accessManager,
+	"GET /api/v1/manager/users/:id":                  accessManagerScoped,
+	"PUT /api/v1/manager/users/:id":                  accessManagerScoped,
+	"GET /api/v1/manager/users/:id/assignable-roles": accessManager, // ¹ empty list instead of 403
+}
+
+// expectedAccess returns the matrix class of route, everything under /admin is admin only
+func expectedAccess(route string) (access, bool) {
+	if class, ok := accessMatrix[route]; ok {
+		return class, true
+	}
+	_, path, _ := strings.Cut(route, " ")
+	if strings.HasPrefix(path, "/api/v1/admin/") {
+		return accessAdmin, true
+	}
+	return 0, false
+}
+
+// allowed tells whether caller with role may reach route on behalf of a target with targetRole.
+// Own is set when the target is the caller.
+func (a access) allowed(role, targetRole entities.Role, own bool) bool {
+	switch a {
+	case accessPublic:
+		return true
+	case accessAuthenticated:
+		return role != ""
+	case accessOwnRecord:
+		if own {
+			return role != ""
+		}
+		return role == entities.RoleAdmin || role == entities.RoleManager && !targetRole.AtLeast(entities.RoleManager)
+	case accessManager:
+		return role.AtLeast(entities.RoleManager)
+	case accessManagerScoped:
+		return role == entities.RoleAdmin || role == entities.RoleManager && !targetRole.AtLeast(entities.RoleManager)
+	default:
+		return role == entities.RoleAdmin
+	}
+}
+
+// callers are anonymous plus every built-in role
+var callers = append([]entities.Role{""}, entities.AllRoles...)
+
+func TestAccessMatrixCoversAllRoutes(t *testing.T) {
+	app := newTestApp(t)
+
+	registered := make(map[string]bool)
+	for _, route := range app.router.Routes() {
+		if !strings.HasPrefix(route.Path, "/api/v1/") {
+			continue
+		}
+		key := route.Method + " " + route.Path
+		registered[key] = true
+		if _, ok := expectedAccess(key); !ok {
+			t.Errorf("route %s is not in the access matrix", key)
+		}
+	}
+	for route := range accessMatrix {
+		if !registered[route] {
+			t.Errorf("matrix lists %s, which is not registered", route)
+		}
+	}
+}
+
+func TestRouteAuthorization(t *testing.T) {
+	for _, route := range newTestApp(t).router.Routes() {
+		key := route.Method + " " + route.Path
+		class, ok := expectedAccess(key)
+		if !ok {
+			continue
+		}
+
+		// Every target role matters for scoped routes, a user target is enough for the rest
+		targets := []entities.Role{entities.RoleUser}
+		if strings.Contains(route.Path, ":id") {
+			targets = entities.AllRoles
+		}
+
+		for _, targetRole := range targets {
+			for _, role := range callers {
+				t.Run(key+"/"+callerName(role)+"->"+string(targetRole), func(t *testing.T) {
+					// A fresh app per call keeps writes of one caller from affecting the next
+					app := newTestApp(t)
+					target := app.createUser(t, "target", targetRole)
+
+					w := app.do(route.Method, app.path(route.Path, target.ID), role)
+					assertAccess(t, w, class, class.allowed(role, targetRole, false))
+				})
+			}
+		}
+
+		if class == accessOwnRecord {
+			for _, role := range entities.AllRoles {
+				t.Run(key+"/"+callerName(role)+"->self", func(t *testing.T) {
+					app := newTestApp(t)
+					w := app.do(route.Method, app.path(route.Path, app.accounts[role].ID), role)
+					assertAccess(t, w, class, class.allowed(role, role, true))
+				})
+			}
+		}
+	}
+}
+
+// path fills route parameters, :id with targetID
+func (a *testApp) path(route string, targetID uint) string {
+	path := strings.ReplaceAll(route, ":session_id", "unknown-session")
+	return strings.ReplaceAll(path, ":id", strconv.FormatUint(uint64(targetID), 10))
+}
+
+// assertAccess checks the response for a denial. Public routes may answer 401 for bad
+// credentials in the request itself, e.g. a missing refresh cookie, only the auth
+// middleware's rejection counts there.
+func assertAccess(t *testing.T, w *httptest.ResponseRecorder, class access, allowed bool) {
+	t.Helper()
+	denied := w.Code == http.StatusUnauthorized || w.Code == http.StatusForbidden
+	if class == accessPublic && w.Code == http.StatusUnauthorized {
+		denied = strings.Contains(w.Body.String(), "Invalid or missing token")
+	}
+	if allowed && denied {
+		t.Errorf("expected access, got %d: %s", w.Code, w.Body.String())
+	}
+	if !allowed && !denied {
+		t.Errorf("expected 401 or 403, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOwnSessionsPaginatedLikeUserList(t *testing.T) {
+	app := newTestApp(t)
+	user := app.accounts[entities.RoleUser]
+	other := app.accounts[entities.RoleManager]
+	for i, userID := range []uint{user.ID, user.ID, user.ID, other.ID} {
+		err := app.store.RefreshTokens.Create(context.Background(), &entities.RefreshToken{
+			UserID:    userID,
+			FamilyID:  "family-" + strconv.Itoa(i),
+			TokenHash: "hash-" + strconv.Itoa(i),
+			ExpiresAt: time.Now().Add(time.Hour),
+		})
+		if err != nil {
+			t.Fatalf("failed to add session: %v", err)
+		}
+	}
+
+	w := app.do(http.MethodGet, "/api/v1/users/sessions?limit=2", entities.RoleUser)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	body := w.Body.String()
+	for _, want := range []string{`"total":3`, `"limit":2`, `"page":1`, `"total_pages":2`, `"has_next":true`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected %s in the first of two pages, got %s", want, body)
+		}
+	}
+	if own := `"user_id":` + strconv.Itoa(int(user.ID)) + `,`; strings.Count(body, own) != 2 || strings.Count(body, `"user_id":`) != 2 {
+		t.Errorf("expected two own sessions, got %s", body)
+	}
+
+	if w := app.do(http.MethodGet, "/api/v1/users/sessions?status=expired", entities.RoleUser); w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"total":0`) {
+		t.Errorf("expected no expired sessions, got %d: %s", w.Code, w.Body.String())
+	}
+	if w := app.do(http.MethodGet, "/api/v1/users/sessions?status=revoked", entities.RoleUser); w.Code != http.StatusBadRequest {
+		t.Errorf("expected 400 for an unknown status, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func callerName(role entities.Role) string {
+	if role == "" {
+		return "anonymous"
+	}
+	return string(role)
+}
+
+func TestAssignableRolesEmptyForManagerOnPrivilegedUsers(t *testing.T) {
+	for _, targetRole := range []entities.Role{entities.RoleManager, entities.RoleAdmin} {
+		app := newTestApp(t)
+		target := app.createUser(t, "target", targetRole)
+
+		w := app.do(http.MethodGet, app.path("/api/v1/manager/users/:id/assignable-roles", target.ID), entities.RoleManager)
+		if w.Code != http.StatusOK || strings.TrimSpace(w.Body.String()) != `{"roles":[]}` {
+			t.Errorf("%s target: expected 200 with no roles, got %d: %s", targetRole, w.Code, w.Body.String())
+		}
+	}
+}
+
+func TestPermissionsCatalogListsBuiltIns(t *testing.T) {
+	app := newTestApp(t)
+
+	w := app.do(http.MethodGet, "/api/v1/admin/permissions", entities.RoleAdmin)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Data []struct {
+			Name        string          `json:"name"`
+			Description string          `json:"description"`
+			Roles       []entities.Role `json:"roles"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+
+	holders := make(map[string][]entities.Role)
+	for _, p := range body.Data {
+		if p.Description == "" {
+			t.Errorf("expected a description for %s", p.Name)
+		}
+		holders[p.Name] = p.Roles
+	}
+	want := map[string][]entities.Role{
+		"profile.read":    entities.AllRoles,
+		"password.change": entities.AllRoles,
+		"users.read":      {entities.RoleAdmin, entities.RoleManager},
+		"users.update":    {entities.RoleAdmin, entities.RoleManager},
+		"users.manage":    {entities.RoleAdmin},
+		"audit.read":      {entities.RoleAdmin},
+	}
+	for name, roles := range want {
+		got, ok := holders[name]
+		if !ok {
+			t.Errorf("expected built-in permission %s", name)
+			continue
+		}
+		if !slices.Equal(got, roles) {
+			t.Errorf("%s: expected roles %v, got %v", name, roles, got)
+		}
+	}
+}
+
+func TestTokenPreviewBlockedInProduction(t *testing.T) {
+	tests := []struct {
+		environment string
+		want        int
+	}{
+		{"development", http.StatusOK},
+		{"production", http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.environment, func(t *testing.T) {
+			app := newTestApp(t, func(cfg *config.Config) { cfg.Server.Environment = tt.environment })
+			path := app.path("/api/v1/admin/users/:id/token-preview", app.accounts[entities.RoleUser].ID)
+
+			w := app.do(http.MethodGet, path, entities.RoleAdmin)
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			// The preview carries decoded claims only, never the signed token
+			if strings.Contains(w.Body.String(), `"token"`) {
+				t.Errorf("expected no signed token in the response: %s", w.Body.String())
+			}
+		})
+	}
+}
+
+// send sends body as JSON with token, an empty token sends it anonymously
+func (a *testApp) send(method, path, token, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	w := httptest.NewRecorder()
+	a.router.ServeHTTP(w, req)
+	return w
+}
+
+// login logs in and returns the access token and whether a password change is required
+func (a *testApp) login(t *testing.T, username, password string) (string, bool) {
+	t.Helper()
+	w := a.send(http.MethodPost, "/api/v1/auth/login", "", `{"username":"`+username+`","password":"`+password+`"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to log in: %d: %s", w.Code, w.Body.String())
+	}
+
+	var response struct {
+		Data struct {
+			MustChangePassword bool `json:"must_change_password"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode login response: %v", err)
+	}
+	for _, c := range w.Result().Cookies() {
+		if c.Name == "access_token" {
+			return c.Value, response.Data.MustChangePassword
+		}
+	}
+	t.Fatalf("expected an access token cookie")
+	return "", false
+}
+
+func TestPasswordChangeRequiredBlocksUntilChanged(t *testing.T) {
+	app := newTestApp(t)
+	passwordHasher, err := hasher.New("bcrypt", 4)
+	if err != nil {
+		t.Fatalf("failed to create hasher: %v", err)
+	}
+	user := &entities.User{Username: "seeded", Role: entities.RoleUser, IsActive: true, ApprovalStatus: entities.ApprovalApproved, MustChangePass: true}
+	if user.Password, err = passwordHasher.Hash("Initial123!"); err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if err := app.store.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	token, mustChange := app.login(t, "seeded", "Initial123!")
+	if !mustChange {
+		t.Errorf("expected login to require a password change")
+	}
+	if w := app.send(http.MethodGet, "/api/v1/users/profile", token, ""); w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), `"must_change_password":true`) {
+		t.Fatalf("expected 403 before the change, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w := app.send(http.MethodPost, "/api/v1/users/change-password", token, `{"current_password":"Initial123!","new_password":"Changed123!"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to change password: %d: %s", w.Code, w.Body.String())
+	}
+
+	token, mustChange = app.login(t, "seeded", "Changed123!")
+	if mustChange {
+		t.Errorf("expected no password change required after changing it")
+	}
+	if w := app.send(http.MethodGet, "/api/v1/users/profile", token, ""); w.Code != http.StatusOK {
+		t.Errorf("expected 200 after the change, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+// Buckets follow X-Forwarded-For only when the peer is a trusted proxy, otherwise a spoofed header would reset the limit
+func TestAuthRateLimitIgnoresSpoofedForwardedFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		proxies []string
+		limited bool
+	}{
+		{"untrusted peer", nil, true},
+		{"trusted proxy", []string{"192.0.2.0/24"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := newTestApp(t, func(cfg *config.Config) {
+				cfg.RateLimit.AuthRPS = 0.001
+				cfg.RateLimit.AuthBurst = 2
+				cfg.Server.TrustedProxies = tt.proxies
+			})
+
+			var last int
+			for i := range 3 {
+				req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", strings.NewReader("{}"))
+				req.Header.Set("Content-Type", "application/json")
+				req.Header.Set("X-Forwarded-For", "198.51.100."+strconv.Itoa(i+1))
+				req.RemoteAddr = "192.0.2.1:1234"
+
+				w := httptest.NewRecorder()
+				app.router.ServeHTTP(w, req)
+				last = w.Code
+			}
+			if limited := last == http.StatusTooManyRequests; limited != tt.limited {
+				t.Errorf("expected limited %v, got status %d", tt.limited, last)
+			}
+		})
+	}
+}
+
+func TestLogoutRevokesRefreshCookie(t *testing.T) {
+	app := newTestApp(t)
+	passwordHasher, err := hasher.New("bcrypt", 4)
+	if err != nil {
+		t.Fatalf("failed to create hasher: %v", err)
+	}
+	user := &entities.User{Username: "leaver", Role: entities.RoleUser, IsActive: true, ApprovalStatus: entities.ApprovalApproved}
+	if user.Password, err = passwordHasher.Hash("Initial123!"); err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	if err := app.store.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	w := app.send(http.MethodPost, "/api/v1/auth/login", "", `{"username":"leaver","password":"Initial123!"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to log in: %d: %s", w.Code, w.Body.String())
+	}
+	cookies := w.Result().Cookies()
+
+	// post sends the login cookies the way a browser would
+	post := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, path, nil)
+		for _, c := range cookies {
+			req.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		app.router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post("/api/v1/auth/logout"); w.Code != http.StatusOK {
+		t.Fatalf("failed to log out: %d: %s", w.Code, w.Body.String())
+	}
+	if w := post("/api/v1/auth/refresh"); w.Code != http.StatusUnauthorized {
+		t.Errorf("expected 401 refreshing after logout, got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestRegisteredUserMustChangePassword(t *testing.T) {
+	app := newTestApp(t)
+
+	w := app.send(http.MethodPost, "/api/v1/manager/auth/register", app.tokens[entities.RoleAdmin], `{"username":"recruit","password":"Initial123!"}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("failed to register: %d: %s", w.Code, w.Body.String())
+	}
+
+	token, mustChange := app.login(t, "recruit", "Initial123!")
+	if !mustChange {
+		t.Errorf("expected login to require a password change")
+	}
+	if w := app.send(http.MethodGet, "/api/v1/users/profile", token, ""); w.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 before the change, got %d: %s", w.Code, w.Body.String())
+	}
+
+	w = app.send(http.MethodPost, "/api/v1/users/change-password", token, `{"current_password":"Initial123!","new_password":"Changed123!"}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("failed to change password: %d: %s", w.Code, w.Body.String())
+	}
+
+	token, mustChange = app.login(t, "recruit", "Changed123!")
+	if mustChange {
+		t.Errorf("expected no password change required after changing it")
+	}
+	if w := app.send(http.MethodGet, "/api/v1/users/profile", token, ""); w.Code != http.StatusOK {
+		t.Errorf("expected 200 after the change, got %d: %s", w.Code, w.Body.String())
+	}
+}
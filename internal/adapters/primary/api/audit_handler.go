@@ -0,0 +1,80 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ontair/admin-panel/internal/core/dto"
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/service"
+	"go.uber.org/zap"
+)
+
+// AuditHandler handles audit log HTTP requests
+type AuditHandler struct {
+	auditService service.AuditService
+	logger       service.Logger
+}
+
+// NewAuditHandler creates new audit handler
+func NewAuditHandler(auditService service.AuditService, logger service.Logger) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+		logger:       logger,
+	}
+}
+
+// RegisterAdminRoutes registers admin-only audit routes
+func (h *AuditHandler) RegisterAdminRoutes(r *gin.RouterGroup) {
+	// List audit entries, filtered by ?actor_id= and ?action=
+	r.GET("/audit-logs", h.ListAuditLogs)
+}
+
+// ListAuditLogs retrieves paginated audit entries (admin only)
+func (h *AuditHandler) ListAuditLogs(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	listReq := &service.ListAuditLogsRequest{
+		Limit:  limit,
+		Offset: offset,
+		Action: entities.AuditAction(c.Query("action")),
+	}
+
+	if actorStr := c.Query("actor_id"); actorStr != "" {
+		actorID, err := strconv.ParseUint(actorStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+			return
+		}
+		id := uint(actorID)
+		listReq.ActorID = &id
+	}
+
+	if targetStr := c.Query("target_id"); targetStr != "" {
+		targetID, err := strconv.ParseUint(targetStr, 10, 32)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+			return
+		}
+		id := uint(targetID)
+		listReq.TargetID = &id
+	}
+
+	response, err := h.auditService.ListAuditLogs(c.Request.Context(), listReq)
+	if err != nil {
+		h.logger.With(c.Request.Context()).Error("List audit logs failed", zap.String("error", err.Error()))
+		serverError(c, err, dto.ErrInternalServer)
+		return
+	}
+
+	c.JSON(http.StatusOK, pageResponse(response, "entries", response.Items))
+}
@@ -1,30 +1,45 @@
 package api
 
 import (
+	"context"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ontair/admin-panel/internal/adapters/primary/middleware"
 	"github.com/ontair/admin-panel/internal/core/dto"
 	"github.com/ontair/admin-panel/internal/core/entities"
 	"github.com/ontair/admin-panel/internal/core/ports/service"
 	"go.uber.org/zap"
 )
 
+// AuthHandlerConfig holds configurable auth handler behavior
+type AuthHandlerConfig struct {
+	LockoutWindow time.Duration // sent as Retry-After when login is locked out
+	TokenPreview  bool          // serve token claim previews, never in production
+	TokenProfile  bool          // serve deprecated token-only profile, 410 Gone otherwise
+}
+
 // AuthHandler handles authentication HTTP requests
 type AuthHandler struct {
 	authService   service.AuthService
+	userService   service.UserService
 	logger        service.Logger
 	cookieService service.CookieService
 	jwtService    service.JWTService
+	config        AuthHandlerConfig
 }
 
 // NewAuthHandler creates new auth handler
-func NewAuthHandler(authService service.AuthService, logger service.Logger, cookieService service.CookieService, jwtService service.JWTService) *AuthHandler {
+func NewAuthHandler(authService service.AuthService, userService service.UserService, logger service.Logger, cookieService service.CookieService, jwtService service.JWTService, config AuthHandlerConfig) *AuthHandler {
 	return &AuthHandler{
 		authService:   authService,
+		userService:   userService,
 		logger:        logger,
 		cookieService: cookieService,
 		jwtService:    jwtService,
+		config:        config,
 	}
 }
 
@@ -38,12 +53,23 @@ func (h *AuthHandler) RegisterPublicRoutes(r *gin.RouterGroup) {
 	}
 }
 
+// RegisterWellKnownRoutes registers public discovery routes outside the API prefix
+func (h *AuthHandler) RegisterWellKnownRoutes(r gin.IRouter) {
+	r.GET("/.well-known/jwks.json", h.JWKS)
+}
+
 // RegisterProtectedRoutes registers protected auth routes (authentication required)
 func (h *AuthHandler) RegisterProtectedRoutes(r *gin.RouterGroup) {
 	auth := r.Group("/auth")
 	{
 		auth.GET("/profile", h.GetProfile)
 	}
+
+	// Own account security overview (any authenticated user)
+	r.GET("/users/security", h.GetSecuritySummary)
+
+	// Own sessions, filtered by ?status= and paginated like the user list
+	r.GET("/users/sessions", h.ListOwnSessions)
 }
 
 // RegisterManagerRoutes registers manager+ auth routes (manager and admin only)
@@ -54,27 +80,52 @@ func (h *AuthHandler) RegisterManagerRoutes(r *gin.RouterGroup) {
 	}
 }
 
+// RegisterAdminRoutes registers admin-only auth routes
+func (h *AuthHandler) RegisterAdminRoutes(r *gin.RouterGroup) {
+	r.GET("/selftest", h.SelfTest)
+
+	// Login attempts over time, ?interval=hour|day|week&days=30
+	r.GET("/stats/logins", h.GetLoginStats)
+
+	// Sessions of a user, ?status=active|expired
+	r.GET("/users/:id/sessions", h.ListSessions)
+	r.DELETE("/users/:id/sessions", h.RevokeAllSessions)
+	r.DELETE("/users/:id/sessions/:session_id", h.RevokeSession)
+
+	// Debugging aid, the route doesn't exist in production
+	if h.config.TokenPreview {
+		r.GET("/users/:id/token-preview", h.PreviewToken)
+	}
+}
+
 // Login handles user login
 func (h *AuthHandler) Login(c *gin.Context) {
 	var loginDTO dto.LoginDTO
 	if err := c.ShouldBindJSON(&loginDTO); err != nil {
-		h.logger.Error("Invalid login request", zap.String("error", err.Error()))
+		h.logger.With(c.Request.Context()).Error("Invalid login request", zap.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
 			"message": "Invalid request data",
 			"details": err.Error(),
+			"fields":  bindErrorFields(err),
 		})
 		return
 	}
+	if !validateRequest(c, &loginDTO) {
+		return
+	}
 
 	// Convert to service request
 	loginReq := &service.LoginRequest{
-		Username: loginDTO.Username,
-		Password: loginDTO.Password,
+		Username:  loginDTO.Username,
+		Password:  loginDTO.Password,
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
 	}
 
 	// Authenticate user
 	response, err := h.authService.Login(c.Request.Context(), loginReq)
+	h.logLoginAttempt(c.Request.Context(), loginReq, err)
 	if err != nil {
 		switch err {
 		case entities.ErrInvalidCredentials:
@@ -98,10 +149,39 @@ func (h *AuthHandler) Login(c *gin.Context) {
 				"message": "Account is deactivated",
 				"details": "Your account has been deactivated. Please contact an administrator.",
 			})
+		case entities.ErrPendingApproval:
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "Forbidden",
+				"message": "Account is pending approval",
+				"details": "Your account is waiting for administrator approval.",
+			})
+		case entities.ErrApprovalRejected:
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "Forbidden",
+				"message": "Account was rejected",
+				"details": "Your account has been rejected. Please contact an administrator.",
+			})
+		case entities.ErrEmailNotVerified:
+			c.JSON(http.StatusForbidden, gin.H{
+				"success": false,
+				"error":   "Forbidden",
+				"message": "Email is not verified",
+				"details": "Please verify your email before logging in.",
+			})
+		case entities.ErrAccountLocked:
+			c.Header("Retry-After", strconv.Itoa(int(h.config.LockoutWindow.Seconds())))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"success": false,
+				"error":   "Too Many Requests",
+				"message": "Too many failed login attempts",
+				"details": "Login is temporarily locked. Please try again later.",
+			})
 		default:
 			// Log only unexpected errors
-			h.logger.Error("Login failed", zap.String("username", loginDTO.Username), zap.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, gin.H{
+			h.logger.With(c.Request.Context()).Error("Login failed", zap.String("username", loginDTO.Username), zap.String("error", err.Error()))
+			serverError(c, err, gin.H{
 				"success": false,
 				"error":   "Internal Server Error",
 				"message": "Login failed",
@@ -115,11 +195,11 @@ func (h *AuthHandler) Login(c *gin.Context) {
 
 	// Convert to DTO (without tokens for security)
 	authResponse := dto.AuthResponseDTO{
-		User:      dto.ToUserDTO(response.User),
+		User:      toUserDTO(c, response.User),
 		ExpiresIn: response.ExpiresIn,
-	}
 
-	h.logger.Info("User logged in successfully", zap.String("username", loginDTO.Username))
+		MustChangePassword: response.MustChangePassword,
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -128,46 +208,83 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	})
 }
 
+// logLoginAttempt emits a structured event for every login attempt
+func (h *AuthHandler) logLoginAttempt(ctx context.Context, req *service.LoginRequest, err error) {
+	fields := []zap.Field{
+		zap.String("event", "login_attempt"),
+		zap.String("username", req.Username),
+		zap.Bool("success", err == nil),
+		zap.String("ip", req.IP),
+		zap.String("user_agent", req.UserAgent),
+	}
+
+	if err != nil {
+		h.logger.With(ctx).Warn("Login failed", append(fields, zap.String("reason", err.Error()))...)
+		return
+	}
+	h.logger.With(ctx).Info("User logged in successfully", fields...)
+}
+
 // Register handles user registration
 func (h *AuthHandler) Register(c *gin.Context) {
 	var registerDTO dto.RegisterDTO
 	if err := c.ShouldBindJSON(&registerDTO); err != nil {
-		h.logger.Error("Invalid registration request", zap.String("error", err.Error()))
+		h.logger.With(c.Request.Context()).Error("Invalid registration request", zap.String("error", err.Error()))
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error":   "Bad Request",
 			"message": "Invalid request data",
 			"details": err.Error(),
+			"fields":  bindErrorFields(err),
 		})
 		return
 	}
+	if !validateRequest(c, &registerDTO) {
+		return
+	}
 
 	// Convert to service request
+	actorRole, _ := middleware.RoleFromContext(c)
 	registerReq := &service.RegisterRequest{
 		Username:  registerDTO.Username,
 		Password:  registerDTO.Password,
 		FirstName: registerDTO.FirstName,
 		LastName:  registerDTO.LastName,
-		Role:      entities.RoleUser, // Default role for registration
+		ActorRole: actorRole,
 	}
 
 	// Register user
 	user, err := h.authService.Register(c.Request.Context(), registerReq)
 	if err != nil {
+		if rejectWeakPassword(c, err, "password") {
+			return
+		}
 		switch err {
 		case entities.ErrUserAlreadyExists:
 			c.JSON(http.StatusConflict, gin.H{
 				"error":   "Conflict",
 				"message": "User already exists",
 			})
-		case entities.ErrInvalidUsername, entities.ErrPasswordTooShort:
+		case entities.ErrDuplicateName:
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Conflict",
+				"message": "Another active user has the same first and last name",
+			})
+		case entities.ErrInvalidUsername, entities.ErrNumericUsername, entities.ErrInvalidRole:
 			c.JSON(http.StatusBadRequest, gin.H{
 				"error":   "Bad Request",
 				"message": err.Error(),
 			})
+		case entities.ErrRoleNotAssignable:
+			c.JSON(http.StatusForbidden, gin.H{
+				"error":   "Forbidden",
+				"message": err.Error(),
+			})
+		case entities.ErrAdminQuotaExceeded:
+			c.JSON(http.StatusForbidden, dto.ErrAdminQuotaExceeded)
 		default:
 			// Log only unexpected errors
-			h.logger.Error("Registration failed", zap.String("username", registerDTO.Username), zap.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, gin.H{
+			h.logger.With(c.Request.Context()).Error("Registration failed", zap.String("username", registerDTO.Username), zap.String("error", err.Error()))
+			serverError(c, err, gin.H{
 				"error":   "Internal Server Error",
 				"message": "Registration failed",
 			})
@@ -175,12 +292,12 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	h.logger.Info("User registered successfully", zap.String("username", user.Username))
+	h.logger.With(c.Request.Context()).Info("User registered successfully", zap.String("username", user.Username))
 
 	c.JSON(http.StatusCreated, gin.H{
 		"success": true,
 		"message": "User registered successfully",
-		"data":    dto.ToUserDTO(user),
+		"data":    toUserDTO(c, user),
 	})
 }
 
@@ -192,16 +309,22 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 		// Fallback to request body
 		var refreshReq dto.JWTResponseDTO
 		if err := c.ShouldBindJSON(&refreshReq); err != nil {
-			h.logger.Error("Invalid refresh token request", zap.String("error", err.Error()))
-			c.JSON(http.StatusBadRequest, gin.H{
-				"error":   "Bad Request",
-				"message": "No refresh token found",
-			})
-			return
+			h.logger.With(c.Request.Context()).Debug("No refresh token in request", zap.String("error", err.Error()))
 		}
 		refreshToken = refreshReq.RefreshToken
 	}
 
+	// Missing token is answered like an invalid one, so clients always redirect to login on 401
+	if refreshToken == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error":   "Unauthorized",
+			"message": "No refresh token found",
+			"details": "Please login again",
+		})
+		return
+	}
+
 	// Convert DTO to service request
 	serviceReq := &service.RefreshTokenRequest{
 		RefreshToken: refreshToken,
@@ -218,6 +341,14 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 				"message": "Invalid or expired refresh token",
 				"details": "Please login again",
 			})
+		case entities.ErrTokenReused:
+			h.logger.With(c.Request.Context()).Warn("Refresh token reuse detected, token family revoked", zap.String("ip", c.ClientIP()))
+			c.JSON(http.StatusUnauthorized, gin.H{
+				"success": false,
+				"error":   "Unauthorized",
+				"message": "Invalid or expired refresh token",
+				"details": "Please login again",
+			})
 		case entities.ErrUserNotFound:
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
@@ -233,8 +364,8 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 				"details": "Your account has been deactivated. Please contact an administrator.",
 			})
 		default:
-			h.logger.Error("Token refresh failed", zap.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, gin.H{
+			h.logger.With(c.Request.Context()).Error("Token refresh failed", zap.String("error", err.Error()))
+			serverError(c, err, gin.H{
 				"success": false,
 				"error":   "Internal Server Error",
 				"message": "Token refresh failed",
@@ -248,7 +379,7 @@ func (h *AuthHandler) RefreshToken(c *gin.Context) {
 
 	// Convert to DTO (without tokens for security)
 	authResponse := dto.AuthResponseDTO{
-		User:      dto.ToUserDTO(response.User),
+		User:      toUserDTO(c, response.User),
 		ExpiresIn: response.ExpiresIn,
 	}
 
@@ -265,8 +396,22 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	// Get token from cookie or header (for backward compatibility)
 	token, err := h.cookieService.GetTokenFromRequest(c)
 	if err != nil {
+		token = ""
+	}
+
+	// Get refresh token from cookie or request body, its session ends with the logout
+	refreshToken, err := h.cookieService.GetRefreshToken(c)
+	if err != nil {
+		var refreshReq dto.JWTResponseDTO
+		if err := c.ShouldBindJSON(&refreshReq); err != nil {
+			h.logger.With(c.Request.Context()).Debug("No refresh token in logout request", zap.String("error", err.Error()))
+		}
+		refreshToken = refreshReq.RefreshToken
+	}
+
+	if token == "" && refreshToken == "" {
 		// No token means user is already logged out
-		h.logger.Info("Logout attempted but no token found - user already logged out")
+		h.logger.With(c.Request.Context()).Info("Logout attempted but no token found - user already logged out")
 		c.JSON(http.StatusOK, gin.H{
 			"success": true,
 			"message": "Already logged out",
@@ -287,11 +432,11 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	}
 
 	// Logout user
-	err = h.authService.Logout(c.Request.Context(), token)
+	err = h.authService.Logout(c.Request.Context(), token, refreshToken)
 	if err != nil {
 		// Log only unexpected errors
-		h.logger.Error("Logout failed", zap.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, gin.H{
+		h.logger.With(c.Request.Context()).Error("Logout failed", zap.String("error", err.Error()))
+		serverError(c, err, gin.H{
 			"error":   "Internal Server Error",
 			"message": "Logout failed",
 		})
@@ -303,9 +448,9 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 
 	// Log successful logout with user info if available
 	if userID != 0 && username != "" {
-		h.logger.Info("User logged out successfully", zap.Uint("userID", userID), zap.String("username", username))
+		h.logger.With(c.Request.Context()).Info("User logged out successfully", zap.Uint("userID", userID), zap.String("username", username))
 	} else {
-		h.logger.Info("User logged out successfully")
+		h.logger.With(c.Request.Context()).Info("User logged out successfully")
 	}
 
 	c.JSON(http.StatusOK, gin.H{
@@ -314,37 +459,302 @@ func (h *AuthHandler) Logout(c *gin.Context) {
 	})
 }
 
+// SelfTest reports whether token signing and parsing work with the current configuration
+func (h *AuthHandler) SelfTest(c *gin.Context) {
+	checks := h.authService.SelfTest(c.Request.Context())
+
+	passed := true
+	for _, check := range checks {
+		if !check.Passed {
+			passed = false
+			h.logger.With(c.Request.Context()).Error("Self-test check failed", zap.String("check", check.Name), zap.String("error", check.Error))
+		}
+	}
+
+	status := http.StatusOK
+	if !passed {
+		status = http.StatusServiceUnavailable
+	}
+
+	c.JSON(status, gin.H{
+		"success": passed,
+		"checks":  checks,
+	})
+}
+
+// GetLoginStats returns login attempt counts bucketed by interval (admin only)
+func (h *AuthHandler) GetLoginStats(c *gin.Context) {
+	days, err := strconv.Atoi(c.DefaultQuery("days", "30"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrInvalidStatsRange)
+		return
+	}
+
+	stats, err := h.authService.GetLoginStats(c.Request.Context(), &service.LoginStatsRequest{
+		Interval: c.DefaultQuery("interval", service.StatsIntervalDay),
+		Days:     days,
+	})
+	if err != nil {
+		switch err {
+		case entities.ErrInvalidStatsRange:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidStatsRange)
+		default:
+			h.logger.With(c.Request.Context()).Error("Get login stats failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    stats,
+	})
+}
+
+// JWKS serves public keys for verifying issued tokens
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	// Short enough for verifiers to pick up rotated keys quickly
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.jwtService.JWKS())
+}
+
+// PreviewToken returns claims of an access token that would be issued to the user (admin, non-production)
+func (h *AuthHandler) PreviewToken(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+		return
+	}
+
+	preview, err := h.authService.PreviewAccessToken(c.Request.Context(), uint(id))
+	if err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		default:
+			h.logger.With(c.Request.Context()).Error("Token preview failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	h.logger.With(c.Request.Context()).Info("Token preview generated", zap.Uint("userID", uint(id)), zap.String("actor", c.GetString("username")))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    preview,
+	})
+}
+
+// ListSessions retrieves paginated sessions of a user (admin only)
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+		return
+	}
+
+	h.listSessions(c, uint(id))
+}
+
+// ListOwnSessions retrieves paginated sessions of the current user
+func (h *AuthHandler) ListOwnSessions(c *gin.Context) {
+	id, ok := middleware.MustCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	h.listSessions(c, id)
+}
+
+// listSessions answers a page of the user's sessions, active ones unless ?status=expired
+func (h *AuthHandler) listSessions(c *gin.Context, userID uint) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	listReq := &service.ListSessionsRequest{
+		UserID: userID,
+		Limit:  limit,
+		Offset: offset,
+	}
+
+	switch c.DefaultQuery("status", "active") {
+	case "active":
+	case "expired":
+		listReq.Expired = true
+	default:
+		c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+		return
+	}
+
+	page, err := h.authService.ListSessions(c.Request.Context(), listReq)
+	if err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		default:
+			h.logger.With(c.Request.Context()).Error("List sessions failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	// Same page format as the user list
+	c.JSON(http.StatusOK, pageResponse(page, "sessions", page.Items))
+}
+
+// RevokeSession ends a session of a user (admin only)
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+		return
+	}
+
+	sessionID := c.Param("session_id")
+	if err := h.authService.RevokeSession(c.Request.Context(), uint(id), sessionID); err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		case entities.ErrSessionNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrSessionNotFound)
+		default:
+			h.logger.With(c.Request.Context()).Error("Revoke session failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	h.logger.With(c.Request.Context()).Info("Session revoked", zap.Uint("userID", uint(id)), zap.String("sessionID", sessionID), zap.String("actor", c.GetString("username")))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Session revoked successfully",
+	})
+}
+
+// RevokeAllSessions ends every session of a user (admin only)
+func (h *AuthHandler) RevokeAllSessions(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+		return
+	}
+
+	revoked, err := h.authService.RevokeAllSessions(c.Request.Context(), uint(id))
+	if err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		default:
+			h.logger.With(c.Request.Context()).Error("Revoke sessions failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	h.logger.With(c.Request.Context()).Info("All sessions revoked", zap.Uint("userID", uint(id)), zap.Int64("count", revoked), zap.String("actor", c.GetString("username")))
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    gin.H{"revoked": revoked},
+	})
+}
+
+// GetSecuritySummary returns the current user's account security overview
+func (h *AuthHandler) GetSecuritySummary(c *gin.Context) {
+	id, ok := middleware.MustCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	summary, err := h.authService.GetSecuritySummary(c.Request.Context(), id)
+	if err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		default:
+			h.logger.With(c.Request.Context()).Error("Security summary failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    summary,
+	})
+}
+
 // GetProfile returns current user profile
 func (h *AuthHandler) GetProfile(c *gin.Context) {
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"error":   "Unauthorized",
-			"message": "User not authenticated",
-		})
+	id, ok := middleware.MustCurrentUser(c)
+	if !ok {
 		return
 	}
 
-	id, ok := userID.(uint)
-	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error":   "Internal Server Error",
-			"message": "Invalid user ID",
+	// Deprecated in favor of the DB-backed /users/profile
+	c.Header("Link", `</api/v1/users/profile>; rel="successor-version"`)
+	if !h.config.TokenProfile {
+		c.JSON(http.StatusGone, gin.H{
+			"success": false,
+			"error":   "Gone",
+			"message": "This endpoint was removed, use GET /api/v1/users/profile",
 		})
 		return
 	}
+	c.Header("Deprecation", "true")
 
-	// For this endpoint, we'll just return the token data
-	// In a real implementation, you might want to fetch fresh user data from database
-	userRole, _ := c.Get("role")
-	username, _ := c.Get("username")
+	// Tracks who still has to migrate
+	h.logger.With(c.Request.Context()).Info("Deprecated endpoint used",
+		zap.String("path", c.FullPath()),
+		zap.Uint("userID", id),
+		zap.String("userAgent", c.Request.UserAgent()),
+	)
+
+	// Load the stored user, token claims miss role changes and deactivation
+	user, err := h.userService.GetCurrentUser(c.Request.Context(), id)
+	if err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, gin.H{
+				"success": false,
+				"error":   "Not Found",
+				"message": "User not found",
+			})
+		default:
+			h.logger.With(c.Request.Context()).Error("Get profile failed", zap.String("error", err.Error()))
+			serverError(c, err, gin.H{
+				"success": false,
+				"error":   "Internal Server Error",
+				"message": "Failed to get user",
+			})
+		}
+		return
+	}
+	if !user.IsActive {
+		c.JSON(http.StatusForbidden, gin.H{
+			"success": false,
+			"error":   "Forbidden",
+			"message": "Account is deactivated",
+			"details": "Your account has been deactivated. Please contact an administrator.",
+		})
+		return
+	}
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data": gin.H{
-			"id":       id,
-			"username": username,
-			"role":     userRole,
-		},
+		"data":    toUserDTO(c, user),
 	})
 }
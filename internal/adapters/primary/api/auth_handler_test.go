@@ -0,0 +1,140 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ontair/admin-panel/internal/adapters/secondary/cookie"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/jwt"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/metrics"
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/services"
+	"github.com/ontair/admin-panel/internal/infra/config"
+	"github.com/ontair/admin-panel/internal/testutil"
+)
+
+// newAuthRouter serves the public auth routes over in-memory repositories
+func newAuthRouter(t *testing.T) *gin.Engine {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	jwtService, err := jwt.NewJWTService(&config.Config{JWT: config.JWTConfig{
+		SecretKey:     "test-access-secret",
+		RefreshSecret: "test-refresh-secret",
+		AccessExpiry:  15,
+		RefreshExpiry: 60,
+		Issuer:        "test-issuer",
+		Audience:      "test-audience",
+		Algorithm:     "HS256",
+	}})
+	if err != nil {
+		t.Fatalf("failed to create JWT service: %v", err)
+	}
+
+	store := testutil.NewStore()
+	logger := testutil.NewLogger()
+	authService := services.NewAuthService(store.Users, jwtService, store.Blacklist, testutil.Hasher{}, &testutil.Notifier{},
+		store.LoginAttempts, store.RefreshTokens, store.TxManager(), metrics.NewRegistry(), logger, services.AuthServiceConfig{
+			AccessExpiry:   15 * time.Minute,
+			RefreshExpiry:  time.Hour,
+			PasswordPolicy: entities.PasswordPolicy{MinLength: 8},
+		})
+	cookieService := cookie.NewCookieService("Lax", "", false, 15*time.Minute, time.Hour)
+
+	router := gin.New()
+	NewAuthHandler(authService, nil, logger, cookieService, jwtService, AuthHandlerConfig{}).RegisterPublicRoutes(router.Group("/api/v1"))
+	return router
+}
+
+func TestRefreshTokenMissingOrInvalidIsUnauthorized(t *testing.T) {
+	router := newAuthRouter(t)
+
+	tests := []struct {
+		name    string
+		body    string
+		cookie  string
+		message string
+	}{
+		{"no body", "", "", "No refresh token found"},
+		{"empty body", "{}", "", "No refresh token found"},
+		{"malformed body", "{", "", "No refresh token found"},
+		{"invalid token in body", `{"refresh_token":"garbage"}`, "", "Invalid or expired refresh token"},
+		{"invalid token in cookie", "", "garbage", "Invalid or expired refresh token"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			if tt.cookie != "" {
+				req.AddCookie(&http.Cookie{Name: "refresh_token", Value: tt.cookie})
+			}
+
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			if w.Code != http.StatusUnauthorized || !strings.Contains(w.Body.String(), tt.message) {
+				t.Errorf("expected 401 %q, got %d: %s", tt.message, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+// newProfileRouter serves the protected auth routes with every request authenticated as a stored user
+func newProfileRouter(t *testing.T, tokenProfile bool) (*gin.Engine, *testutil.Logger) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	store := testutil.NewStore()
+	logger := testutil.NewLogger()
+	user := &entities.User{Username: "alice", Role: entities.RoleUser, IsActive: true, ApprovalStatus: entities.ApprovalApproved}
+	if err := store.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+	userService := services.NewUserService(store.Users, store.Roles, store.PasswordResets, store.EmailVerifications, store.PasswordHistory,
+		store.LoginAttempts, store.TxManager(), testutil.Hasher{}, &testutil.Notifier{}, services.NewAuditService(store.Audit, logger),
+		services.UserServiceConfig{})
+
+	router := gin.New()
+	protected := router.Group("/api/v1", func(c *gin.Context) { c.Set("user_id", user.ID) })
+	NewAuthHandler(nil, userService, logger, nil, nil, AuthHandlerConfig{TokenProfile: tokenProfile}).RegisterProtectedRoutes(protected)
+	return router, logger
+}
+
+func TestTokenProfileDisabledIsGone(t *testing.T) {
+	router, logger := newProfileRouter(t, false)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/auth/profile", nil))
+	if w.Code != http.StatusGone || !strings.Contains(w.Body.String(), "/api/v1/users/profile") {
+		t.Errorf("expected 410 pointing to /users/profile, got %d: %s", w.Code, w.Body.String())
+	}
+	if !strings.Contains(w.Header().Get("Link"), "/api/v1/users/profile") {
+		t.Errorf("expected successor link, got %q", w.Header().Get("Link"))
+	}
+	if slices.Contains(logger.Messages(), "Deprecated endpoint used") {
+		t.Errorf("expected no usage logged for the removed endpoint")
+	}
+}
+
+func TestTokenProfileEnabledLogsDeprecatedUse(t *testing.T) {
+	router, logger := newProfileRouter(t, true)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/auth/profile", nil))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), "alice") {
+		t.Fatalf("expected 200 with the profile, got %d: %s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Deprecation") != "true" {
+		t.Errorf("expected Deprecation header")
+	}
+	if !slices.Contains(logger.Messages(), "Deprecated endpoint used") {
+		t.Errorf("expected deprecated use logged")
+	}
+}
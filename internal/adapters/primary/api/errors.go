@@ -0,0 +1,20 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ontair/admin-panel/internal/core/dto"
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+// serverError answers an unexpected service error with body and 500,
+// or with 503 if a database query timed out and the request may be retried
+func serverError(c *gin.Context, err error, body interface{}) {
+	if errors.Is(err, entities.ErrDatabaseTimeout) {
+		c.JSON(http.StatusServiceUnavailable, dto.ErrDatabaseTimeout)
+		return
+	}
+	c.JSON(http.StatusInternalServerError, body)
+}
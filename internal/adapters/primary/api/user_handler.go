@@ -1,27 +1,50 @@
 package api
 
 import (
+	"encoding/csv"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ontair/admin-panel/internal/adapters/primary/middleware"
 	"github.com/ontair/admin-panel/internal/core/dto"
 	"github.com/ontair/admin-panel/internal/core/entities"
 	"github.com/ontair/admin-panel/internal/core/ports/service"
 	"go.uber.org/zap"
 )
 
+// UserHandlerConfig holds configurable user handler behavior
+type UserHandlerConfig struct {
+	StrictQueryParams bool // reject unrecognized query parameter values with 400
+	ExposeEmailTokens bool // return password reset and email verification tokens in responses until an email adapter exists
+}
+
 // UserHandler handles user management HTTP requests
 type UserHandler struct {
 	userService service.UserService
 	logger      service.Logger
+	config      UserHandlerConfig
 }
 
 // NewUserHandler creates new user handler
-func NewUserHandler(userService service.UserService, logger service.Logger) *UserHandler {
+func NewUserHandler(userService service.UserService, logger service.Logger, config UserHandlerConfig) *UserHandler {
 	return &UserHandler{
 		userService: userService,
 		logger:      logger,
+		config:      config,
+	}
+}
+
+// RegisterPublicRoutes registers password reset routes (no authentication required)
+func (h *UserHandler) RegisterPublicRoutes(r *gin.RouterGroup) {
+	auth := r.Group("/auth")
+	{
+		auth.POST("/reset-password", h.ResetPassword)
+		auth.POST("/reset-password/confirm", h.ConfirmPasswordReset)
+		auth.GET("/verify-email", h.VerifyEmail)
 	}
 }
 
@@ -34,24 +57,67 @@ func (h *UserHandler) RegisterRoutes(r *gin.RouterGroup) {
 
 		// Change password (any authenticated user)
 		users.POST("/change-password", h.ChangePassword)
+
+		// Download own personal data (any authenticated user)
+		users.GET("/export-me", h.ExportMe)
+
+		// Request verification of own email (any authenticated user)
+		users.POST("/verify-email", h.SendOwnVerificationEmail)
+
+		// Get user by ID (own record for any authenticated user, others for manager and admin)
+		users.GET("/:id", h.GetUser)
 	}
 }
 
 // RegisterAdminRoutes registers admin-only user routes
 func (h *UserHandler) RegisterAdminRoutes(r *gin.RouterGroup) {
+	// Permission catalog with the roles holding each (admin only)
+	r.GET("/permissions", h.ListPermissions)
+
 	admin := r.Group("/users")
 	{
 		// List ALL users (admin only) - полный список со всеми ролями
 		admin.GET("/", h.ListAllUsers)
 
+		// Export all users as CSV (admin only)
+		admin.GET("/export", h.ExportUsers)
+
+		// Assign role to many users at once (admin only)
+		admin.POST("/bulk-role", h.BulkAssignRole)
+
+		// Dry-run validation of proposed users before import (admin only)
+		admin.POST("/validate", h.ValidateUsers)
+
+		// Create many users at once (admin only)
+		admin.POST("/import", h.ImportUsers)
+
 		// Delete user (admin only)
 		admin.DELETE("/:id", h.DeleteUser)
 
+		// Restore deleted user (admin only)
+		admin.POST("/:id/restore", h.RestoreUser)
+
 		// Activate user (admin only)
 		admin.POST("/:id/activate", h.ActivateUser)
 
-		// Deactivate user (admin only)
+		// Deactivate user now or at ?at=<RFC 3339 time> (admin only)
 		admin.POST("/:id/deactivate", h.DeactivateUser)
+
+		// Cancel scheduled deactivation (admin only)
+		admin.POST("/:id/cancel-deactivation", h.CancelDeactivation)
+
+		// Effective access including temporary roles (admin only)
+		admin.GET("/:id/permissions", h.GetUserAccess)
+
+		// Grant role for a limited time
+		admin.POST("/:id/grant-temp-role", h.GrantTempRole)
+
+		// Issue email verification token for the user (admin only)
+		admin.POST("/:id/send-verification", h.SendVerificationEmail)
+
+		// Approve or reject pending user (admin only)
+		admin.POST("/:id/approve", h.ApproveUser)
+		admin.POST("/:id/reject", h.RejectUser)
 	}
 }
 
@@ -62,6 +128,9 @@ func (h *UserHandler) RegisterManagerRoutes(r *gin.RouterGroup) {
 		// List users (manager and admin) - manager sees only user/guest, admin sees all
 		manager.GET("/", h.ListUsers)
 
+		// Counts by role and status for dashboards (manager and admin)
+		manager.GET("/stats", h.GetUserStats)
+
 		// Create user (manager and admin)
 		manager.POST("/", h.CreateUser)
 
@@ -70,34 +139,22 @@ func (h *UserHandler) RegisterManagerRoutes(r *gin.RouterGroup) {
 
 		// Update user (manager and admin)
 		manager.PUT("/:id", h.UpdateUser)
+
+		// Roles the current actor may assign to the user (manager and admin)
+		manager.GET("/:id/assignable-roles", h.GetAssignableRoles)
 	}
 }
 
 // GetCurrentUser returns current user profile
 func (h *UserHandler) GetCurrentUser(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"error":   "Unauthorized",
-			"message": "User not authenticated",
-		})
-		return
-	}
-
-	userIDUint, ok := userID.(uint)
+	userID, ok := middleware.MustCurrentUser(c)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"error":   "Internal Server Error",
-			"message": "Invalid user ID format",
-		})
 		return
 	}
 
 	// Get user
-	user, err := h.userService.GetCurrentUser(c.Request.Context(), userIDUint)
+	user, err := h.userService.GetCurrentUser(c.Request.Context(), userID)
 	if err != nil {
 		switch err {
 		case entities.ErrUserNotFound:
@@ -107,8 +164,8 @@ func (h *UserHandler) GetCurrentUser(c *gin.Context) {
 				"message": "User not found",
 			})
 		default:
-			h.logger.Error("Get current user failed", zap.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, gin.H{
+			h.logger.With(c.Request.Context()).Error("Get current user failed", zap.String("error", err.Error()))
+			serverError(c, err, gin.H{
 				"success": false,
 				"error":   "Internal Server Error",
 				"message": "Failed to get user",
@@ -119,47 +176,162 @@ func (h *UserHandler) GetCurrentUser(c *gin.Context) {
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
-		"data":    dto.ToUserDTO(user),
+		"data":    toUserDTO(c, user),
+	})
+}
+
+// ExportMe returns current user's personal data as a downloadable JSON file
+func (h *UserHandler) ExportMe(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, ok := middleware.MustCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	export, err := h.userService.ExportUserData(c.Request.Context(), userID)
+	if err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		default:
+			h.logger.With(c.Request.Context()).Error("Export user data failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	h.logger.With(c.Request.Context()).Info("User data exported", zap.Uint("userID", userID))
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="user-%d-export.json"`, userID))
+	c.IndentedJSON(http.StatusOK, dto.UserExportDTO{
+		ExportedAt:   export.ExportedAt,
+		Profile:      toUserDTO(c, export.Profile),
+		LoginHistory: export.LoginHistory,
+		AuditEntries: export.AuditEntries,
 	})
 }
 
+// userCSVHeader lists columns of the users CSV export
+var userCSVHeader = []string{
+	"id", "username", "first_name", "last_name", "role", "is_active",
+	"approval_status", "last_login", "password_changed_at", "created_at",
+}
+
+// ExportUsers streams all users as CSV (admin only)
+func (h *UserHandler) ExportUsers(c *gin.Context) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="users.csv"`)
+
+	writer := csv.NewWriter(c.Writer)
+	if err := writer.Write(userCSVHeader); err != nil {
+		return
+	}
+
+	exported := 0
+	err := h.userService.ExportUsers(c.Request.Context(), func(users []*entities.User) error {
+		for _, user := range users {
+			record := []string{
+				strconv.FormatUint(uint64(user.ID), 10),
+				csvSafe(user.Username),
+				csvSafe(user.FirstName),
+				csvSafe(user.LastName),
+				string(user.Role),
+				strconv.FormatBool(user.IsActive),
+				string(user.ApprovalStatus),
+				formatCSVTime(user.LastLogin),
+				formatCSVTime(user.PassChangedAt),
+				user.CreatedAt.Format(time.RFC3339),
+			}
+			if err := writer.Write(record); err != nil {
+				return err
+			}
+		}
+		exported += len(users)
+
+		// Send each batch right away
+		writer.Flush()
+		return writer.Error()
+	})
+	if err != nil {
+		// Headers are already sent, the truncated file is all we can return
+		h.logger.With(c.Request.Context()).Error("Export users failed", zap.String("error", err.Error()), zap.Int("exported", exported))
+		return
+	}
+	writer.Flush()
+
+	h.logger.With(c.Request.Context()).Info("Users exported", zap.Int("count", exported), zap.String("actor", c.GetString("username")))
+}
+
+// csvSafe neutralizes values spreadsheets would evaluate as formulas
+func csvSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+// formatCSVTime formats optional time for CSV, empty if not set
+func formatCSVTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
 // CreateUser creates a new user (admin only)
 func (h *UserHandler) CreateUser(c *gin.Context) {
 	var req dto.UserCreateDTO
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	// Convert DTO to service request
+	actorRole, _ := middleware.RoleFromContext(c)
 	createReq := &service.CreateUserRequest{
 		Username:  req.Username,
 		Password:  req.Password,
+		Email:     req.Email,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
 		Role:      entities.Role(req.Role),
 		IsActive:  req.IsActive,
+		ActorRole: actorRole,
 	}
 
 	// Call service
 	user, err := h.userService.CreateUser(c.Request.Context(), createReq)
 	if err != nil {
+		if rejectWeakPassword(c, err, "password") {
+			return
+		}
 		switch err {
 		case entities.ErrUserAlreadyExists:
 			c.JSON(http.StatusConflict, dto.ErrUserAlreadyExists)
-		case entities.ErrInvalidUsername, entities.ErrPasswordTooShort:
+		case entities.ErrDuplicateName:
+			c.JSON(http.StatusConflict, dto.ErrDuplicateName)
+		case entities.ErrAdminQuotaExceeded:
+			c.JSON(http.StatusForbidden, dto.ErrAdminQuotaExceeded)
+		case entities.ErrEmailAlreadyExists:
+			c.JSON(http.StatusConflict, dto.ErrEmailAlreadyExists)
+		case entities.ErrInvalidUsername, entities.ErrInvalidEmail:
 			c.JSON(http.StatusBadRequest, dto.ErrValidationFailed)
+		case entities.ErrNumericUsername:
+			c.JSON(http.StatusBadRequest, dto.ErrNumericUsername)
+		case entities.ErrInvalidRole:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidRole)
+		case entities.ErrRoleNotAssignable:
+			c.JSON(http.StatusForbidden, dto.ErrRoleNotAssignable)
 		default:
-			h.logger.Error("Create user failed", zap.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, dto.ErrInternalServer)
+			h.logger.With(c.Request.Context()).Error("Create user failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
 		}
 		return
 	}
 
-	c.JSON(http.StatusCreated, dto.ToUserDTO(user))
+	c.JSON(http.StatusCreated, toUserDTO(c, user))
 }
 
-// GetUser retrieves user by ID
+// GetUser retrieves user by ID, users below manager may only read their own record
 func (h *UserHandler) GetUser(c *gin.Context) {
 	idStr := c.Param("id")
 	id, err := strconv.ParseUint(idStr, 10, 32)
@@ -168,19 +340,33 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userService.GetUser(c.Request.Context(), uint(id))
+	// Anyone may read their own record, other users need manager+ before the lookup,
+	// so users below manager can't probe which ids exist
+	actorRole, _ := middleware.RoleFromContext(c)
+	currentUserID, _ := middleware.CurrentUserID(c)
+	var user *entities.User
+	switch {
+	case currentUserID == uint(id):
+		user, err = h.userService.GetCurrentUser(c.Request.Context(), uint(id))
+	case !actorRole.AtLeast(entities.RoleManager):
+		err = entities.ErrForbidden
+	default:
+		user, err = h.userService.GetManagedUser(c.Request.Context(), actorRole, uint(id))
+	}
 	if err != nil {
 		switch err {
 		case entities.ErrUserNotFound:
 			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		case entities.ErrForbidden:
+			c.JSON(http.StatusForbidden, dto.ErrInsufficientPrivileges)
 		default:
-			h.logger.Error("Get user failed", zap.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, dto.ErrInternalServer)
+			h.logger.With(c.Request.Context()).Error("Get user failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
 		}
 		return
 	}
 
-	c.JSON(http.StatusOK, dto.ToUserDTO(user))
+	c.JSON(http.StatusOK, toUserDTO(c, user))
 }
 
 // UpdateUser updates user data
@@ -193,18 +379,21 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	}
 
 	var req dto.UserUpdateDTO
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	// Convert DTO to service request
+	actorRole, _ := middleware.RoleFromContext(c)
 	updateReq := &service.UpdateUserRequest{
 		Username:  req.Username,
+		Email:     req.Email,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
 		Role:      (*entities.Role)(req.Role),
 		IsActive:  req.IsActive,
+		Version:   req.Version,
+		ActorRole: actorRole,
 	}
 
 	// Call service
@@ -213,18 +402,105 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 		switch err {
 		case entities.ErrUserNotFound:
 			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		case entities.ErrForbidden:
+			c.JSON(http.StatusForbidden, dto.ErrInsufficientPrivileges)
 		case entities.ErrUserAlreadyExists:
 			c.JSON(http.StatusConflict, dto.ErrUserAlreadyExists)
-		case entities.ErrInvalidUsername:
+		case entities.ErrDuplicateName:
+			c.JSON(http.StatusConflict, dto.ErrDuplicateName)
+		case entities.ErrAdminQuotaExceeded:
+			c.JSON(http.StatusForbidden, dto.ErrAdminQuotaExceeded)
+		case entities.ErrEmailAlreadyExists:
+			c.JSON(http.StatusConflict, dto.ErrEmailAlreadyExists)
+		case entities.ErrInvalidUsername, entities.ErrInvalidEmail:
 			c.JSON(http.StatusBadRequest, dto.ErrValidationFailed)
+		case entities.ErrNumericUsername:
+			c.JSON(http.StatusBadRequest, dto.ErrNumericUsername)
+		case entities.ErrInvalidRole:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidRole)
+		case entities.ErrRoleNotAssignable:
+			c.JSON(http.StatusForbidden, dto.ErrRoleNotAssignable)
+		case entities.ErrConcurrentModification:
+			c.JSON(http.StatusConflict, dto.ErrUserModified)
+		case entities.ErrCannotRemoveLastAdmin:
+			c.JSON(http.StatusConflict, dto.ErrCannotRemoveLastAdmin)
+		case entities.ErrCannotRemoveSelf:
+			c.JSON(http.StatusConflict, dto.ErrCannotRemoveSelf)
+		default:
+			h.logger.With(c.Request.Context()).Error("Update user failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, toUserDTO(c, user))
+}
+
+// GetAssignableRoles returns roles the current actor may assign to the user
+func (h *UserHandler) GetAssignableRoles(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+		return
+	}
+
+	actorRole, _ := middleware.RoleFromContext(c)
+	roles, err := h.userService.GetAssignableRoles(c.Request.Context(), actorRole, uint(id))
+	if err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		default:
+			h.logger.With(c.Request.Context()).Error("Get assignable roles failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"roles": roles})
+}
+
+// GetUserAccess returns effective access of the user including temporary roles (admin only)
+func (h *UserHandler) GetUserAccess(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+		return
+	}
+
+	access, err := h.userService.GetUserAccess(c.Request.Context(), uint(id))
+	if err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
 		default:
-			h.logger.Error("Update user failed", zap.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, dto.ErrInternalServer)
+			h.logger.With(c.Request.Context()).Error("Get user access failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
 		}
 		return
 	}
 
-	c.JSON(http.StatusOK, dto.ToUserDTO(user))
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    access,
+	})
+}
+
+// ListPermissions returns the permission catalog for the RBAC matrix (admin only)
+func (h *UserHandler) ListPermissions(c *gin.Context) {
+	permissions, err := h.userService.ListPermissions(c.Request.Context())
+	if err != nil {
+		h.logger.With(c.Request.Context()).Error("List permissions failed", zap.String("error", err.Error()))
+		serverError(c, err, dto.ErrInternalServer)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"data":    permissions,
+	})
 }
 
 // DeleteUser deletes user by ID (admin only)
@@ -241,9 +517,13 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		switch err {
 		case entities.ErrUserNotFound:
 			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		case entities.ErrCannotRemoveLastAdmin:
+			c.JSON(http.StatusConflict, dto.ErrCannotRemoveLastAdmin)
+		case entities.ErrCannotRemoveSelf:
+			c.JSON(http.StatusConflict, dto.ErrCannotRemoveSelf)
 		default:
-			h.logger.Error("Delete user failed", zap.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, dto.ErrInternalServer)
+			h.logger.With(c.Request.Context()).Error("Delete user failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
 		}
 		return
 	}
@@ -251,6 +531,31 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "User deleted successfully"})
 }
 
+// RestoreUser brings back a deleted user (admin only)
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+		return
+	}
+
+	err = h.userService.RestoreUser(c.Request.Context(), uint(id))
+	if err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		default:
+			h.logger.With(c.Request.Context()).Error("Restore user failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	h.logger.With(c.Request.Context()).Info("User restored", zap.Uint("userID", uint(id)), zap.String("actor", c.GetString("username")))
+	c.JSON(http.StatusOK, gin.H{"message": "User restored successfully"})
+}
+
 // ListUsers retrieves paginated list of users (manager view - only user/guest roles)
 func (h *UserHandler) ListUsers(c *gin.Context) {
 	// Parse query parameters
@@ -270,20 +575,39 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 		offset = 0
 	}
 
-	var isActive *bool
-	if isActiveStr != "" {
-		if isActiveStr == "true" {
-			val := true
-			isActive = &val
-		} else if isActiveStr == "false" {
-			val := false
-			isActive = &val
-		}
+	isActive, ok := h.parseBoolQuery(c, "is_active", isActiveStr)
+	if !ok {
+		return
+	}
+
+	includeInactive, ok := h.parseBoolQuery(c, "include_inactive", c.Query("include_inactive"))
+	if !ok {
+		return
+	}
+
+	neverChangedPassword, ok := h.parseBoolQuery(c, "never_changed_password", c.Query("never_changed_password"))
+	if !ok {
+		return
+	}
+
+	countOnly, ok := h.parseBoolQuery(c, "count_only", c.Query("count_only"))
+	if !ok {
+		return
+	}
+
+	createdFrom, ok := h.parseTimeQuery(c, "created_from")
+	if !ok {
+		return
+	}
+
+	createdTo, ok := h.parseTimeQuery(c, "created_to")
+	if !ok {
+		return
 	}
 
 	// Manager can only see user and guest roles
 	requestedRole := entities.Role(role)
-	if requestedRole != "" && requestedRole != entities.RoleUser && requestedRole != entities.RoleGuest {
+	if requestedRole != "" && !entities.RoleManager.CanManage(requestedRole) {
 		c.JSON(http.StatusForbidden, gin.H{
 			"success": false,
 			"error":   "Forbidden",
@@ -299,28 +623,38 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 		Role:     requestedRole,
 		IsActive: isActive,
 		Search:   search,
+
+		SortBy:    c.Query("sort"),
+		SortOrder: c.Query("order"),
+
+		IncludeInactive: includeInactive,
+
+		NeverChangedPassword: neverChangedPassword,
+
+		CreatedFrom: createdFrom,
+		CreatedTo:   createdTo,
+
+		CountOnly: countOnly != nil && *countOnly,
 	}
 
 	// Call service (manager view - only user/guest roles)
 	response, err := h.userService.ListUsersForManager(c.Request.Context(), listReq)
 	if err != nil {
-		h.logger.Error("List users failed", zap.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, dto.ErrInternalServer)
+		switch err {
+		case entities.ErrSearchTooLong:
+			c.JSON(http.StatusBadRequest, dto.ErrSearchTooLong)
+		case entities.ErrInvalidSort:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidSortParam)
+		case entities.ErrInvalidDateRange:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidDateParam)
+		default:
+			h.logger.With(c.Request.Context()).Error("List users failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
 		return
 	}
 
-	// Convert to DTOs
-	var userDTOs []dto.UserDTO
-	for _, user := range response.Users {
-		userDTOs = append(userDTOs, dto.ToUserDTO(user))
-	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"users":  userDTOs,
-		"total":  response.Total,
-		"limit":  response.Limit,
-		"offset": response.Offset,
-	})
+	h.writeUserList(c, listReq, response)
 }
 
 // ListAllUsers retrieves paginated list of ALL users (admin view - all roles)
@@ -342,15 +676,35 @@ func (h *UserHandler) ListAllUsers(c *gin.Context) {
 		offset = 0
 	}
 
-	var isActive *bool
-	if isActiveStr != "" {
-		if isActiveStr == "true" {
-			val := true
-			isActive = &val
-		} else if isActiveStr == "false" {
-			val := false
-			isActive = &val
-		}
+	isActive, ok := h.parseBoolQuery(c, "is_active", isActiveStr)
+	if !ok {
+		return
+	}
+
+	neverChangedPassword, ok := h.parseBoolQuery(c, "never_changed_password", c.Query("never_changed_password"))
+	if !ok {
+		return
+	}
+
+	countOnly, ok := h.parseBoolQuery(c, "count_only", c.Query("count_only"))
+	if !ok {
+		return
+	}
+
+	createdFrom, ok := h.parseTimeQuery(c, "created_from")
+	if !ok {
+		return
+	}
+
+	createdTo, ok := h.parseTimeQuery(c, "created_to")
+	if !ok {
+		return
+	}
+
+	// Dormant accounts, e.g. no login in 90 days
+	lastLoginBefore, ok := h.parseTimeQuery(c, "last_login_before")
+	if !ok {
+		return
 	}
 
 	// Create service request (admin can see all roles)
@@ -360,50 +714,61 @@ func (h *UserHandler) ListAllUsers(c *gin.Context) {
 		Role:     entities.Role(role),
 		IsActive: isActive,
 		Search:   search,
+
+		SortBy:    c.Query("sort"),
+		SortOrder: c.Query("order"),
+
+		NeverChangedPassword: neverChangedPassword,
+
+		CreatedFrom: createdFrom,
+		CreatedTo:   createdTo,
+
+		LastLoginBefore: lastLoginBefore,
+
+		CountOnly: countOnly != nil && *countOnly,
 	}
 
 	// Call service
 	response, err := h.userService.ListUsers(c.Request.Context(), listReq)
 	if err != nil {
-		h.logger.Error("List all users failed", zap.String("error", err.Error()))
-		c.JSON(http.StatusInternalServerError, dto.ErrInternalServer)
+		switch err {
+		case entities.ErrSearchTooLong:
+			c.JSON(http.StatusBadRequest, dto.ErrSearchTooLong)
+		case entities.ErrInvalidSort:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidSortParam)
+		case entities.ErrInvalidDateRange:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidDateParam)
+		default:
+			h.logger.With(c.Request.Context()).Error("List all users failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
 		return
 	}
 
-	// Convert to DTOs
-	var userDTOs []dto.UserDTO
-	for _, user := range response.Users {
-		userDTOs = append(userDTOs, dto.ToUserDTO(user))
+	h.writeUserList(c, listReq, response)
+}
+
+// GetUserStats returns user counts by role and active status (manager and admin)
+func (h *UserHandler) GetUserStats(c *gin.Context) {
+	actorRole, _ := middleware.RoleFromContext(c)
+	stats, err := h.userService.GetUserStats(c.Request.Context(), actorRole)
+	if err != nil {
+		h.logger.With(c.Request.Context()).Error("Get user stats failed", zap.String("error", err.Error()))
+		serverError(c, err, dto.ErrInternalServer)
+		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"users":  userDTOs,
-		"total":  response.Total,
-		"limit":  response.Limit,
-		"offset": response.Offset,
+		"success": true,
+		"data":    stats,
 	})
 }
 
 // ChangePassword allows user to change their password
 func (h *UserHandler) ChangePassword(c *gin.Context) {
 	// Get user ID from context
-	userID, exists := c.Get("user_id")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{
-			"success": false,
-			"error":   "Unauthorized",
-			"message": "User not authenticated",
-		})
-		return
-	}
-
-	userIDUint, ok := userID.(uint)
+	userID, ok := middleware.MustCurrentUser(c)
 	if !ok {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"success": false,
-			"error":   "Internal Server Error",
-			"message": "Invalid user ID format",
-		})
 		return
 	}
 
@@ -414,9 +779,13 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 			"error":   "Bad Request",
 			"message": "Invalid request data",
 			"details": err.Error(),
+			"fields":  bindErrorFields(err),
 		})
 		return
 	}
+	if !validateRequest(c, &req) {
+		return
+	}
 
 	// Convert DTO to service request
 	changeReq := &service.ChangePasswordRequest{
@@ -425,8 +794,11 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 	}
 
 	// Call service
-	err := h.userService.ChangePassword(c.Request.Context(), userIDUint, changeReq)
+	err := h.userService.ChangePassword(c.Request.Context(), userID, changeReq)
 	if err != nil {
+		if rejectWeakPassword(c, err, "new_password") {
+			return
+		}
 		switch err {
 		case entities.ErrUserNotFound:
 			c.JSON(http.StatusNotFound, gin.H{
@@ -440,15 +812,27 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 				"error":   "Bad Request",
 				"message": "Current password is incorrect",
 			})
-		case entities.ErrPasswordTooShort:
+		case entities.ErrPasswordUnchanged:
+			c.JSON(http.StatusBadRequest, gin.H{
+				"success": false,
+				"error":   "Bad Request",
+				"message": "New password must differ from the current one",
+			})
+		case entities.ErrPasswordReused:
 			c.JSON(http.StatusBadRequest, gin.H{
 				"success": false,
 				"error":   "Bad Request",
-				"message": "New password is too short",
+				"message": "New password must differ from recently used ones",
+			})
+		case entities.ErrUserBusy:
+			c.JSON(http.StatusConflict, gin.H{
+				"success": false,
+				"error":   "Conflict",
+				"message": "Another password change is in progress",
 			})
 		default:
-			h.logger.Error("Change password failed", zap.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, gin.H{
+			h.logger.With(c.Request.Context()).Error("Change password failed", zap.String("error", err.Error()))
+			serverError(c, err, gin.H{
 				"success": false,
 				"error":   "Internal Server Error",
 				"message": "Failed to change password",
@@ -463,6 +847,142 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 	})
 }
 
+// ResetPassword issues a password reset token
+func (h *UserHandler) ResetPassword(c *gin.Context) {
+	var req dto.ResetPasswordDTO
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	token, err := h.userService.ResetPassword(c.Request.Context(), &service.ResetPasswordRequest{
+		Username: req.Username,
+	})
+	if err != nil {
+		h.logger.With(c.Request.Context()).Error("Password reset failed", zap.String("error", err.Error()))
+		serverError(c, err, dto.ErrInternalServer)
+		return
+	}
+
+	// Same response whether or not the user exists
+	response := gin.H{
+		"success": true,
+		"message": "If the user exists, a password reset token has been issued",
+	}
+	if h.config.ExposeEmailTokens && token != "" {
+		response["token"] = token
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// SendOwnVerificationEmail issues a verification token for the current user's email
+func (h *UserHandler) SendOwnVerificationEmail(c *gin.Context) {
+	userID, ok := middleware.MustCurrentUser(c)
+	if !ok {
+		return
+	}
+
+	h.sendVerificationEmail(c, userID)
+}
+
+// SendVerificationEmail issues a verification token for the user's email (admin only)
+func (h *UserHandler) SendVerificationEmail(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+		return
+	}
+
+	h.sendVerificationEmail(c, uint(id))
+}
+
+// sendVerificationEmail issues a verification token for user and writes the response
+func (h *UserHandler) sendVerificationEmail(c *gin.Context, userID uint) {
+	token, err := h.userService.SendVerificationEmail(c.Request.Context(), userID)
+	if err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		case entities.ErrEmailMissing:
+			c.JSON(http.StatusBadRequest, dto.ErrEmailMissing)
+		case entities.ErrEmailVerified:
+			c.JSON(http.StatusConflict, dto.ErrEmailVerified)
+		default:
+			h.logger.With(c.Request.Context()).Error("Send verification email failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	response := gin.H{
+		"success": true,
+		"message": "A verification token has been issued",
+	}
+	if h.config.ExposeEmailTokens {
+		response["token"] = token
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// VerifyEmail marks email verified using a verification token
+func (h *UserHandler) VerifyEmail(c *gin.Context) {
+	err := h.userService.VerifyEmail(c.Request.Context(), c.Query("token"))
+	if err != nil {
+		switch err {
+		case entities.ErrInvalidToken:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidVerifyToken)
+		default:
+			h.logger.With(c.Request.Context()).Error("Email verification failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Email verified successfully",
+	})
+}
+
+// ConfirmPasswordReset sets a new password using a reset token
+func (h *UserHandler) ConfirmPasswordReset(c *gin.Context) {
+	var req dto.ConfirmPasswordResetDTO
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	err := h.userService.ConfirmPasswordReset(c.Request.Context(), &service.ConfirmPasswordResetRequest{
+		Token:       req.Token,
+		NewPassword: req.NewPassword,
+	})
+	if err != nil {
+		if rejectWeakPassword(c, err, "new_password") {
+			return
+		}
+		switch err {
+		case entities.ErrInvalidToken, entities.ErrUserNotFound:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidResetToken)
+		case entities.ErrPasswordUnchanged:
+			c.JSON(http.StatusBadRequest, dto.ErrPasswordUnchanged)
+		case entities.ErrPasswordReused:
+			c.JSON(http.StatusBadRequest, dto.ErrPasswordReused)
+		case entities.ErrUserBusy:
+			c.JSON(http.StatusConflict, dto.ErrConflict)
+		default:
+			h.logger.With(c.Request.Context()).Error("Confirm password reset failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Password has been reset",
+	})
+}
+
 // ActivateUser activates user account (admin only)
 func (h *UserHandler) ActivateUser(c *gin.Context) {
 	idStr := c.Param("id")
@@ -472,19 +992,37 @@ func (h *UserHandler) ActivateUser(c *gin.Context) {
 		return
 	}
 
-	err = h.userService.ActivateUser(c.Request.Context(), uint(id))
+	requireReset, ok := h.parseBoolQuery(c, "require_reset", c.Query("require_reset"))
+	if !ok {
+		return
+	}
+	mustChange := requireReset != nil && *requireReset
+
+	err = h.userService.ActivateUser(c.Request.Context(), uint(id), mustChange)
 	if err != nil {
 		switch err {
 		case entities.ErrUserNotFound:
 			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		case entities.ErrAdminQuotaExceeded:
+			c.JSON(http.StatusForbidden, dto.ErrAdminQuotaExceeded)
 		default:
-			h.logger.Error("Activate user failed", zap.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, dto.ErrInternalServer)
+			h.logger.With(c.Request.Context()).Error("Activate user failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
 		}
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User activated successfully"})
+	if mustChange {
+		h.logger.With(c.Request.Context()).Info("Password change required on next login",
+			zap.Uint("userID", uint(id)),
+			zap.String("actor", c.GetString("username")),
+		)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":              "User activated successfully",
+		"must_change_password": mustChange,
+	})
 }
 
 // DeactivateUser deactivates user account (admin only)
@@ -496,17 +1034,401 @@ func (h *UserHandler) DeactivateUser(c *gin.Context) {
 		return
 	}
 
+	// Deactivate later if a time is given, immediately otherwise
+	if atStr := c.Query("at"); atStr != "" {
+		h.scheduleDeactivation(c, uint(id), atStr)
+		return
+	}
+
 	err = h.userService.DeactivateUser(c.Request.Context(), uint(id))
 	if err != nil {
 		switch err {
 		case entities.ErrUserNotFound:
 			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		case entities.ErrCannotRemoveLastAdmin:
+			c.JSON(http.StatusConflict, dto.ErrCannotRemoveLastAdmin)
+		case entities.ErrCannotRemoveSelf:
+			c.JSON(http.StatusConflict, dto.ErrCannotRemoveSelf)
 		default:
-			h.logger.Error("Deactivate user failed", zap.String("error", err.Error()))
-			c.JSON(http.StatusInternalServerError, dto.ErrInternalServer)
+			h.logger.With(c.Request.Context()).Error("Deactivate user failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
 		}
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "User deactivated successfully"})
 }
+
+// CancelDeactivation cancels scheduled deactivation (admin only)
+func (h *UserHandler) CancelDeactivation(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+		return
+	}
+
+	err = h.userService.CancelScheduledDeactivation(c.Request.Context(), uint(id))
+	if err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		case entities.ErrNotScheduled:
+			c.JSON(http.StatusConflict, dto.ErrNotScheduled)
+		default:
+			h.logger.With(c.Request.Context()).Error("Cancel deactivation failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scheduled deactivation cancelled"})
+}
+
+func (h *UserHandler) scheduleDeactivation(c *gin.Context, id uint, atStr string) {
+	at, err := time.Parse(time.RFC3339, atStr)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrInvalidSchedule)
+		return
+	}
+
+	err = h.userService.ScheduleDeactivation(c.Request.Context(), id, at)
+	if err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		case entities.ErrInvalidSchedule:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidSchedule)
+		case entities.ErrCannotRemoveLastAdmin:
+			c.JSON(http.StatusConflict, dto.ErrCannotRemoveLastAdmin)
+		case entities.ErrCannotRemoveSelf:
+			c.JSON(http.StatusConflict, dto.ErrCannotRemoveSelf)
+		default:
+			h.logger.With(c.Request.Context()).Error("Schedule deactivation failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":       "User deactivation scheduled",
+		"deactivate_at": at,
+	})
+}
+
+// BulkAssignRole assigns a role to many users at once (admin only)
+func (h *UserHandler) BulkAssignRole(c *gin.Context) {
+	var req dto.BulkRoleDTO
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	actorRole, _ := middleware.RoleFromContext(c)
+	bulkReq := &service.BulkRoleRequest{
+		IDs:       req.IDs,
+		Role:      entities.Role(req.Role),
+		ActorRole: actorRole,
+	}
+
+	results, err := h.userService.BulkAssignRole(c.Request.Context(), bulkReq)
+	if err != nil {
+		switch err {
+		case entities.ErrInvalidRole:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidRole)
+		case entities.ErrInvalidBulkSize:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidBulkSize)
+		default:
+			h.logger.With(c.Request.Context()).Error("Bulk role assignment failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	for _, result := range results {
+		if result.Success {
+			h.logger.With(c.Request.Context()).Info("User role changed",
+				zap.Uint("userID", result.ID),
+				zap.String("role", req.Role),
+				zap.String("actor", c.GetString("username")),
+			)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// ValidateUsers checks proposed users against create policy without creating them (admin only)
+func (h *UserHandler) ValidateUsers(c *gin.Context) {
+	var req []dto.UserCreateDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.NewBindError(bindErrorFields(err)))
+		return
+	}
+
+	results, err := h.userService.ValidateUsers(c.Request.Context(), toCreateUserRequests(c, req))
+	if err != nil {
+		switch err {
+		case entities.ErrInvalidBulkSize:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidBulkSize)
+		default:
+			h.logger.With(c.Request.Context()).Error("Validate users failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"results": results})
+}
+
+// ImportUsers creates many users at once, ?atomic=true creates none if any row fails (admin only)
+func (h *UserHandler) ImportUsers(c *gin.Context) {
+	atomic, ok := h.parseBoolQuery(c, "atomic", c.Query("atomic"))
+	if !ok {
+		return
+	}
+
+	var req []dto.UserCreateDTO
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.NewBindError(bindErrorFields(err)))
+		return
+	}
+
+	report, err := h.userService.BulkCreate(c.Request.Context(), toCreateUserRequests(c, req), atomic != nil && *atomic)
+	if err != nil {
+		switch err {
+		case entities.ErrInvalidBulkSize:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidBulkSize)
+		case entities.ErrUserAlreadyExists:
+			// Created concurrently after validation, the whole batch was rolled back
+			c.JSON(http.StatusConflict, dto.ErrUserAlreadyExists)
+		case entities.ErrEmailAlreadyExists:
+			c.JSON(http.StatusConflict, dto.ErrEmailAlreadyExists)
+		default:
+			h.logger.With(c.Request.Context()).Error("Import users failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	h.logger.With(c.Request.Context()).Info("Users imported",
+		zap.Int("created", report.Created),
+		zap.Int("failed", report.Failed),
+		zap.Bool("rolledBack", report.RolledBack),
+		zap.String("actor", c.GetString("username")),
+	)
+
+	c.JSON(http.StatusOK, report)
+}
+
+// toCreateUserRequests converts proposed users of validate and import requests
+func toCreateUserRequests(c *gin.Context, users []dto.UserCreateDTO) []*service.CreateUserRequest {
+	actorRole, _ := middleware.RoleFromContext(c)
+	reqs := make([]*service.CreateUserRequest, 0, len(users))
+	for _, user := range users {
+		reqs = append(reqs, &service.CreateUserRequest{
+			Username:  user.Username,
+			Password:  user.Password,
+			Email:     user.Email,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Role:      entities.Role(user.Role),
+			IsActive:  user.IsActive,
+			ActorRole: actorRole,
+		})
+	}
+	return reqs
+}
+
+// GrantTempRole grants a role to user until the given time (admin only)
+func (h *UserHandler) GrantTempRole(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+		return
+	}
+
+	var req dto.TempRoleDTO
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	actorRole, _ := middleware.RoleFromContext(c)
+	grantReq := &service.TempRoleRequest{
+		Role:      entities.Role(req.Role),
+		Until:     req.ExpiresAt,
+		ActorRole: actorRole,
+	}
+
+	user, err := h.userService.GrantTempRole(c.Request.Context(), uint(id), grantReq)
+	if err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		case entities.ErrInvalidRole:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidRole)
+		case entities.ErrInvalidTempRole:
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidTempRole)
+		case entities.ErrForbidden:
+			c.JSON(http.StatusForbidden, dto.ErrInsufficientPrivileges)
+		case entities.ErrAdminQuotaExceeded:
+			c.JSON(http.StatusForbidden, dto.ErrAdminQuotaExceeded)
+		default:
+			h.logger.With(c.Request.Context()).Error("Grant temporary role failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	h.logger.With(c.Request.Context()).Info("Temporary role granted",
+		zap.Uint("userID", user.ID),
+		zap.String("role", req.Role),
+		zap.Time("expires_at", req.ExpiresAt),
+		zap.String("actor", c.GetString("username")),
+	)
+
+	c.JSON(http.StatusOK, gin.H{"user": toUserDTO(c, user)})
+}
+
+// ApproveUser approves pending user account (admin only)
+func (h *UserHandler) ApproveUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+		return
+	}
+
+	err = h.userService.ApproveUser(c.Request.Context(), uint(id))
+	if err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		case entities.ErrNotPendingApproval:
+			c.JSON(http.StatusConflict, dto.ErrNotPendingApproval)
+		default:
+			h.logger.With(c.Request.Context()).Error("Approve user failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User approved successfully"})
+}
+
+// RejectUser rejects pending user account (admin only)
+func (h *UserHandler) RejectUser(c *gin.Context) {
+	idStr := c.Param("id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, dto.ErrBadRequest)
+		return
+	}
+
+	err = h.userService.RejectUser(c.Request.Context(), uint(id))
+	if err != nil {
+		switch err {
+		case entities.ErrUserNotFound:
+			c.JSON(http.StatusNotFound, dto.ErrUserNotFound)
+		case entities.ErrNotPendingApproval:
+			c.JSON(http.StatusConflict, dto.ErrNotPendingApproval)
+		default:
+			h.logger.With(c.Request.Context()).Error("Reject user failed", zap.String("error", err.Error()))
+			serverError(c, err, dto.ErrInternalServer)
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User rejected successfully"})
+}
+
+// writeUserList writes a page of users with pagination metadata, or just the total for count_only
+func (h *UserHandler) writeUserList(c *gin.Context, req *service.ListUsersRequest, page *service.Page[*entities.User]) {
+	// Dashboards only need the number of matches
+	if req.CountOnly {
+		c.JSON(http.StatusOK, gin.H{"total": page.Total})
+		return
+	}
+
+	// Convert to DTOs, an empty page is rendered as [] rather than null
+	userDTOs := make([]dto.UserDTO, 0, len(page.Items))
+	for _, user := range page.Items {
+		userDTOs = append(userDTOs, toUserDTO(c, user))
+	}
+
+	c.JSON(http.StatusOK, pageResponse(page, "users", userDTOs))
+}
+
+// pageResponse renders items with page metadata, items are keyed by name of the listed resource
+func pageResponse[T any](page *service.Page[T], key string, items interface{}) gin.H {
+	return gin.H{
+		key:           items,
+		"total":       page.Total,
+		"limit":       page.Limit,
+		"offset":      page.Offset,
+		"page":        page.Page,
+		"total_pages": page.TotalPages,
+		"has_next":    page.HasNext,
+	}
+}
+
+// parseBoolQuery parses an optional boolean query parameter.
+// Empty value yields nil. Unrecognized values are ignored, or answered
+// with 400 in strict mode, in which case ok is false and the request is done.
+func (h *UserHandler) parseBoolQuery(c *gin.Context, name, value string) (result *bool, ok bool) {
+	if value == "" {
+		return nil, true
+	}
+
+	parsed, err := parseBool(value)
+	if err != nil {
+		if h.config.StrictQueryParams {
+			h.logger.With(c.Request.Context()).Debug("Invalid boolean query parameter", zap.String("name", name), zap.String("value", value))
+			c.JSON(http.StatusBadRequest, dto.ErrInvalidBoolParam)
+			return nil, false
+		}
+		return nil, true
+	}
+
+	return &parsed, true
+}
+
+// parseTimeQuery parses an optional RFC 3339 query parameter.
+// Empty value yields nil. Malformed values are answered with 400,
+// in which case ok is false and the request is done.
+func (h *UserHandler) parseTimeQuery(c *gin.Context, name string) (result *time.Time, ok bool) {
+	value := c.Query(name)
+	if value == "" {
+		return nil, true
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		h.logger.With(c.Request.Context()).Debug("Invalid time query parameter", zap.String("name", name), zap.String("value", value))
+		c.JSON(http.StatusBadRequest, dto.ErrInvalidDateParam)
+		return nil, false
+	}
+
+	return &parsed, true
+}
+
+// toUserDTO converts user to DTO, with roles serialized as objects for ?expand=role
+func toUserDTO(c *gin.Context, user *entities.User) dto.UserDTO {
+	userDTO := dto.ToUserDTO(user)
+	if c.Query("expand") == "role" {
+		return userDTO.WithExpandedRoles()
+	}
+	return userDTO
+}
+
+// parseBool accepts true/false, 1/0 and yes/no case-insensitively
+func parseBool(value string) (bool, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "1", "yes":
+		return true, nil
+	case "false", "0", "no":
+		return false, nil
+	default:
+		return false, fmt.Errorf("invalid boolean value %q", value)
+	}
+}
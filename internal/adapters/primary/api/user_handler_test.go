@@ -0,0 +1,108 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/services"
+	"github.com/ontair/admin-panel/internal/testutil"
+)
+
+func TestParseBool(t *testing.T) {
+	accepted := map[string]bool{
+		"true": true, "TRUE": true, "1": true, "yes": true, " Yes ": true,
+		"false": false, "False": false, "0": false, "no": false, "NO": false,
+	}
+	for value, want := range accepted {
+		got, err := parseBool(value)
+		if err != nil || got != want {
+			t.Errorf("parseBool(%q) = %v, %v, want %v", value, got, err, want)
+		}
+	}
+
+	for _, value := range []string{"", "2", "on", "off", "y", "truthy"} {
+		if _, err := parseBool(value); err == nil {
+			t.Errorf("parseBool(%q) accepted", value)
+		}
+	}
+}
+
+func TestParseBoolQueryStrictMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name   string
+		strict bool
+		value  string
+		want   *bool
+		ok     bool
+	}{
+		{"empty", true, "", nil, true},
+		{"accepted form", true, "1", boolPtr(true), true},
+		{"invalid in strict mode", true, "maybe", nil, false},
+		{"invalid in lenient mode", false, "maybe", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewUserHandler(nil, testutil.NewLogger(), UserHandlerConfig{StrictQueryParams: tt.strict})
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/users", nil)
+
+			got, ok := h.parseBoolQuery(c, "is_active", tt.value)
+			if ok != tt.ok {
+				t.Fatalf("expected ok %v, got %v", tt.ok, ok)
+			}
+			if (got == nil) != (tt.want == nil) || got != nil && *got != *tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+			if !ok && w.Code != http.StatusBadRequest {
+				t.Errorf("expected 400 for rejected value, got %d", w.Code)
+			}
+		})
+	}
+}
+
+func boolPtr(value bool) *bool {
+	return &value
+}
+
+// timeoutUserRepository fails every lookup by id like a query past its deadline
+type timeoutUserRepository struct {
+	*testutil.UserRepository
+}
+
+func (r timeoutUserRepository) GetByID(ctx context.Context, id uint) (*entities.User, error) {
+	return nil, fmt.Errorf("%w: %w", entities.ErrDatabaseTimeout, context.DeadlineExceeded)
+}
+
+func TestGetUserTimeoutIsServiceUnavailable(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := testutil.NewStore()
+	logger := testutil.NewLogger()
+	userService := services.NewUserService(timeoutUserRepository{store.Users}, store.Roles, store.PasswordResets, store.EmailVerifications,
+		store.PasswordHistory, store.LoginAttempts, store.TxManager(), testutil.Hasher{}, &testutil.Notifier{},
+		services.NewAuditService(store.Audit, logger), services.UserServiceConfig{})
+
+	router := gin.New()
+	protected := router.Group("/api/v1", func(c *gin.Context) {
+		c.Set("user_id", uint(1))
+		c.Set("role", entities.RoleAdmin)
+	})
+	NewUserHandler(userService, logger, UserHandlerConfig{}).RegisterRoutes(protected)
+
+	for _, id := range []string{"1", "2"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/users/"+id, nil))
+		if w.Code != http.StatusServiceUnavailable {
+			t.Errorf("user %s: expected 503, got %d: %s", id, w.Code, w.Body.String())
+		}
+	}
+}
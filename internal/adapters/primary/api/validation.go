@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/ontair/admin-panel/internal/core/dto"
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+// requestValidator checks DTO fields against their validate tags.
+// Failed fields are reported by their JSON names.
+var requestValidator = newRequestValidator()
+
+func newRequestValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// bindJSON binds the request body into req and validates it. A malformed body is
+// answered 400 and failed rules 422, in which case ok is false and the request is done.
+func bindJSON(c *gin.Context, req interface{}) (ok bool) {
+	if err := c.ShouldBindJSON(req); err != nil {
+		c.JSON(http.StatusBadRequest, dto.NewBindError(bindErrorFields(err)))
+		return false
+	}
+	return validateRequest(c, req)
+}
+
+// bindErrorFields names the fields of a body that decoded with wrong JSON types.
+// Syntax errors cannot be pinned to a field and yield an empty map.
+func bindErrorFields(err error) map[string]string {
+	fields := map[string]string{}
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		fields[typeErr.Field] = "must be of type " + jsonTypeName(typeErr.Type)
+	}
+	return fields
+}
+
+// jsonTypeName describes a Go type the way API clients see it in JSON
+func jsonTypeName(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Pointer:
+		return jsonTypeName(t.Elem())
+	default:
+		return "object"
+	}
+}
+
+// fieldMessage turns a failed rule into a message suitable for showing next to a form field
+func fieldMessage(fieldErr validator.FieldError) string {
+	unit := "characters"
+	if kind := fieldErr.Kind(); kind == reflect.Slice || kind == reflect.Array || kind == reflect.Map {
+		unit = "items"
+	}
+
+	switch fieldErr.Tag() {
+	case "required":
+		return "is required"
+	case "min":
+		return fmt.Sprintf("must be at least %s %s", fieldErr.Param(), unit)
+	case "max":
+		return fmt.Sprintf("must be at most %s %s", fieldErr.Param(), unit)
+	case "email":
+		return "must be a valid email address"
+	default:
+		return fmt.Sprintf("failed %s validation", fieldErr.Tag())
+	}
+}
+
+// rejectWeakPassword answers 400 with the unmet requirement if err is a password policy
+// failure for field, in which case it returns true and the request is done
+func rejectWeakPassword(c *gin.Context, err error, field string) bool {
+	var weak *entities.WeakPasswordError
+	if !errors.As(err, &weak) {
+		return false
+	}
+	c.JSON(http.StatusBadRequest, dto.NewWeakPasswordError(field, weak.Requirement))
+	return true
+}
+
+// validateRequest validates a bound request DTO. On failure it answers 422 listing
+// every failed field and rule, in which case ok is false and the request is done.
+func validateRequest(c *gin.Context, req interface{}) (ok bool) {
+	err := requestValidator.Struct(req)
+	if err == nil {
+		return true
+	}
+
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		c.JSON(http.StatusBadRequest, dto.NewBindError(map[string]string{}))
+		return false
+	}
+
+	errs := make([]dto.FieldError, 0, len(fieldErrs))
+	fields := make(map[string]string, len(fieldErrs))
+	for _, fieldErr := range fieldErrs {
+		errs = append(errs, dto.FieldError{
+			Field: fieldErr.Field(),
+			Rule:  fieldErr.Tag(),
+			Param: fieldErr.Param(),
+		})
+		fields[fieldErr.Field()] = fieldMessage(fieldErr)
+	}
+
+	c.JSON(http.StatusUnprocessableEntity, dto.NewValidationError(errs, fields))
+	return false
+}
@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ontair/admin-panel/internal/core/ports/service"
+	"go.uber.org/zap"
+)
+
+// accessLogSkipPaths are polled by probes and would drown out real traffic
+var accessLogSkipPaths = map[string]bool{
+	"/health":       true,
+	"/health/live":  true,
+	"/health/ready": true,
+}
+
+// AccessLog middleware that writes one structured line per request, replacing gin.Logger().
+// Server errors are logged at error level, everything else at info.
+func AccessLog(logger service.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if accessLogSkipPaths[c.Request.URL.Path] {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		fields := []zap.Field{
+			zap.String("method", c.Request.Method),
+			zap.String("path", c.Request.URL.Path),
+			zap.Int("status", status),
+			zap.Duration("latency", time.Since(start)),
+			zap.String("client_ip", c.ClientIP()),
+			zap.Int("size", c.Writer.Size()),
+		}
+
+		// RequestID runs before this middleware, so the logger picks the ID up from the context
+		log := logger.With(c.Request.Context())
+		if status >= http.StatusInternalServerError {
+			log.Error("Request handled", fields...)
+			return
+		}
+		log.Info("Request handled", fields...)
+	}
+}
@@ -1,41 +1,63 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/ontair/admin-panel/internal/core/dto"
 	"github.com/ontair/admin-panel/internal/core/entities"
 	"github.com/ontair/admin-panel/internal/core/ports/service"
 	"go.uber.org/zap"
 )
 
+// Token sources for AuthMiddlewareConfig.TokenPrecedence
+const (
+	TokenPrecedenceHeader = "header"
+	TokenPrecedenceCookie = "cookie"
+)
+
+// AuthMiddlewareConfig holds configurable authentication middleware behavior
+type AuthMiddlewareConfig struct {
+	SlidingExpiration bool          // re-issue access token on activity
+	SlidingWindow     time.Duration // remaining lifetime below which the token is re-issued
+	AccessExpiry      time.Duration // lifetime of a re-issued access token
+	TokenPrecedence   string        // token source that wins when header and cookie both carry one
+
+	PasswordChangeRoutes []string // full route paths still reachable while a password change is required
+}
+
 // AuthMiddleware handles authentication
 type AuthMiddleware struct {
 	jwtService    service.JWTService
 	logger        service.Logger
 	cookieService service.CookieService
 	authService   service.AuthService
+	config        AuthMiddlewareConfig
 }
 
 // NewAuthMiddleware creates new auth middleware
-func NewAuthMiddleware(jwtService service.JWTService, logger service.Logger, cookieService service.CookieService, authService service.AuthService) *AuthMiddleware {
+func NewAuthMiddleware(jwtService service.JWTService, logger service.Logger, cookieService service.CookieService, authService service.AuthService, config AuthMiddlewareConfig) *AuthMiddleware {
 	return &AuthMiddleware{
 		jwtService:    jwtService,
 		logger:        logger,
 		cookieService: cookieService,
 		authService:   authService,
+		config:        config,
 	}
 }
 
 // RequireAuth middleware that requires authentication
 func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		m.logger.Info("RequireAuth middleware called")
+		m.logger.With(c.Request.Context()).Info("RequireAuth middleware called")
 		token, err := m.extractToken(c)
 		if err != nil {
-			m.logger.Info("Failed to extract token", zap.String("error", err.Error()))
+			m.logger.With(c.Request.Context()).Info("Failed to extract token", zap.String("error", err.Error()))
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
 				"error":   "Unauthorized",
@@ -50,16 +72,19 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		parsedToken, err := m.jwtService.ParseAccessToken(token)
 		if err != nil {
 			// Check if token is expired and try to refresh
-			if m.isTokenExpiredError(err) {
-				m.logger.Info("Access token expired, attempting refresh")
-				if m.attemptTokenRefresh(c) {
+			if errors.Is(err, entities.ErrTokenExpired) {
+				m.logger.With(c.Request.Context()).Info("Access token expired, attempting refresh")
+				if user, ok := m.attemptTokenRefresh(c); ok {
 					// Token refresh successful, continue with the request
+					if m.blockPendingPasswordChange(c, user) {
+						return
+					}
 					c.Next()
 					return
 				}
 			}
 
-			m.logger.Info("Invalid token", zap.String("error", err.Error()))
+			m.logger.With(c.Request.Context()).Info("Invalid token", zap.String("error", err.Error()))
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
 				"error":   "Unauthorized",
@@ -70,10 +95,39 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			return
 		}
 
+		// Load the user once for all per-request checks, rejecting tokens revoked on logout
+		user, err := m.authService.GetTokenUser(c.Request.Context(), parsedToken)
+		if err != nil {
+			switch err {
+			case entities.ErrInvalidToken:
+				m.logger.With(c.Request.Context()).Info("Revoked token used", zap.String("path", c.Request.URL.Path))
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"success": false,
+					"error":   "Unauthorized",
+					"message": "Invalid token",
+					"details": "Token has been revoked",
+				})
+				c.Abort()
+			case entities.ErrUserNotFound:
+				m.logger.With(c.Request.Context()).Info("Token of unknown user used", zap.String("path", c.Request.URL.Path))
+				c.JSON(http.StatusUnauthorized, gin.H{
+					"success": false,
+					"error":   "Unauthorized",
+					"message": "Invalid token",
+					"details": "User no longer exists",
+				})
+				c.Abort()
+			default:
+				m.logger.With(c.Request.Context()).Error("Failed to load token user", zap.String("error", err.Error()))
+				abortServerError(c, err)
+			}
+			return
+		}
+
 		// Extract user info from token
 		userInfo, err := m.jwtService.ExtractUserFromToken(parsedToken)
 		if err != nil {
-			m.logger.Error("Failed to extract user from token", zap.String("error", err.Error()))
+			m.logger.With(c.Request.Context()).Error("Failed to extract user from token", zap.String("error", err.Error()))
 			c.JSON(http.StatusUnauthorized, gin.H{
 				"success": false,
 				"error":   "Unauthorized",
@@ -87,44 +141,73 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		// Set user info in context
 		c.Set("user_id", userInfo.UserID)
 		c.Set("username", userInfo.Username)
-		c.Set("role", userInfo.Role) // Keep as string for consistency
+		c.Set("role", userInfo.Role)
 		c.Set("user_info", userInfo)
+		applyTempRole(c, user)
+		m.setActor(c, userInfo)
+
+		if m.blockPendingPasswordChange(c, user) {
+			return
+		}
+
+		// Extend session on activity if enabled
+		if m.config.SlidingExpiration {
+			m.slideAccessToken(c, parsedToken, user)
+		}
 
-		m.logger.Info("User authenticated successfully", zap.String("username", userInfo.Username), zap.String("role", userInfo.Role))
+		m.logger.With(c.Request.Context()).Info("User authenticated successfully", zap.String("username", userInfo.Username), zap.String("role", string(userInfo.Role)))
 		c.Next()
 	}
 }
 
 // RequireRole middleware that requires specific role
 func (m *AuthMiddleware) RequireRole(role entities.Role) gin.HandlerFunc {
+	return m.RequireRoles(role)
+}
+
+// RequireRoles middleware that requires any of the given roles
+func (m *AuthMiddleware) RequireRoles(roles ...entities.Role) gin.HandlerFunc {
+	names := make([]string, len(roles))
+	for i, role := range roles {
+		names[i] = string(role)
+	}
+
 	return func(c *gin.Context) {
-		userRole, exists := c.Get("role")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"error":   "Unauthorized",
-				"message": "User role not found",
-			})
-			c.Abort()
+		userRole, ok := m.roleFromContext(c)
+		if !ok {
 			return
 		}
 
-		userRoleStr, ok := userRole.(string)
+		for _, role := range roles {
+			if userRole == role {
+				c.Next()
+				return
+			}
+		}
+
+		c.JSON(http.StatusForbidden, gin.H{
+			"success":        false,
+			"error":          "Forbidden",
+			"message":        fmt.Sprintf("Required role: %s", strings.Join(names, " or ")),
+			"accepted_roles": names,
+		})
+		c.Abort()
+	}
+}
+
+// RequireMinRole middleware that requires the given role or a higher one
+func (m *AuthMiddleware) RequireMinRole(role entities.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userRole, ok := m.roleFromContext(c)
 		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"error":   "Internal Server Error",
-				"message": "Invalid user role format",
-			})
-			c.Abort()
 			return
 		}
 
-		if entities.Role(userRoleStr) != role {
+		if !userRole.AtLeast(role) {
 			c.JSON(http.StatusForbidden, gin.H{
 				"success": false,
 				"error":   "Forbidden",
-				"message": fmt.Sprintf("Required role: %s", role),
+				"message": fmt.Sprintf("Required role: %s or higher", role),
 			})
 			c.Abort()
 			return
@@ -141,76 +224,112 @@ func (m *AuthMiddleware) RequireAdmin() gin.HandlerFunc {
 
 // RequireManagerOrHigher middleware that requires manager or admin role
 func (m *AuthMiddleware) RequireManagerOrHigher() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		userRole, exists := c.Get("role")
-		if !exists {
-			c.JSON(http.StatusUnauthorized, gin.H{
-				"success": false,
-				"error":   "Unauthorized",
-				"message": "User role not found",
-			})
-			c.Abort()
-			return
-		}
+	return m.RequireRoles(entities.RoleManager, entities.RoleAdmin)
+}
 
-		userRoleStr, ok := userRole.(string)
-		if !ok {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"success": false,
-				"error":   "Internal Server Error",
-				"message": "Invalid user role format",
-			})
-			c.Abort()
-			return
-		}
+// Helper methods
 
-		role := entities.Role(userRoleStr)
-		if role != entities.RoleAdmin && role != entities.RoleManager {
-			c.JSON(http.StatusForbidden, gin.H{
-				"success": false,
-				"error":   "Forbidden",
-				"message": "Required role: manager or admin",
-			})
-			c.Abort()
-			return
-		}
+// CurrentUserID returns id of the user authenticated by RequireAuth
+func CurrentUserID(c *gin.Context) (uint, bool) {
+	value, ok := c.Get("user_id")
+	if !ok {
+		return 0, false
+	}
+	userID, ok := value.(uint)
+	return userID, ok
+}
 
-		c.Next()
+// MustCurrentUser returns id of the authenticated user, answering 401 and aborting the request if there is none
+func MustCurrentUser(c *gin.Context) (uint, bool) {
+	userID, ok := CurrentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error":   "Unauthorized",
+			"message": "User not authenticated",
+		})
+		c.Abort()
+		return 0, false
 	}
+	return userID, true
 }
 
-// Helper methods
+// RoleFromContext returns role set by RequireAuth, including an active temporary grant
+func RoleFromContext(c *gin.Context) (entities.Role, bool) {
+	role, ok := c.Get("role")
+	if !ok {
+		return "", false
+	}
+	userRole, ok := role.(entities.Role)
+	return userRole, ok
+}
+
+// roleFromContext returns role set by RequireAuth, aborting the request if it is missing
+func (m *AuthMiddleware) roleFromContext(c *gin.Context) (entities.Role, bool) {
+	role, ok := RoleFromContext(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{
+			"success": false,
+			"error":   "Unauthorized",
+			"message": "User role not found",
+		})
+		c.Abort()
+		return "", false
+	}
+	return role, true
+}
 
 func (m *AuthMiddleware) extractToken(c *gin.Context) (string, error) {
-	// Try to get token from Authorization header
+	// Get token from Authorization header
+	var headerToken string
 	authHeader := c.GetHeader("Authorization")
 	if authHeader != "" {
 		parts := strings.SplitN(authHeader, " ", 2)
 		if len(parts) == 2 && parts[0] == "Bearer" {
-			return parts[1], nil
+			headerToken = parts[1]
 		}
 	}
 
-	// Try to get token from cookie
-	token, err := m.cookieService.GetTokenFromRequest(c)
-	if err == nil && token != "" {
-		return token, nil
+	// Get token from cookie
+	cookieToken, _ := m.cookieService.GetAccessToken(c)
+
+	// Header wins unless cookie precedence is configured
+	first, second := headerToken, cookieToken
+	if m.tokenPrecedence() == TokenPrecedenceCookie {
+		first, second = cookieToken, headerToken
+	}
+
+	if headerToken != "" && cookieToken != "" && headerToken != cookieToken {
+		m.logger.With(c.Request.Context()).Debug("Authorization header and cookie carry different tokens",
+			zap.String("precedence", m.tokenPrecedence()),
+			zap.String("path", c.Request.URL.Path),
+		)
+	}
+
+	if first != "" {
+		return first, nil
+	}
+	if second != "" {
+		return second, nil
 	}
 
 	return "", fmt.Errorf("no token found")
 }
 
-func (m *AuthMiddleware) isTokenExpiredError(err error) bool {
-	// Check if error indicates token expiration
-	return err != nil && (err.Error() == "token is expired" || err.Error() == "Token is expired")
+func (m *AuthMiddleware) tokenPrecedence() string {
+	if m.config.TokenPrecedence == TokenPrecedenceCookie {
+		return TokenPrecedenceCookie
+	}
+	return TokenPrecedenceHeader
 }
 
-func (m *AuthMiddleware) attemptTokenRefresh(c *gin.Context) bool {
+// attemptTokenRefresh refreshes tokens from the refresh cookie and returns the refreshed user
+func (m *AuthMiddleware) attemptTokenRefresh(c *gin.Context) (*entities.User, bool) {
 	// Try to get refresh token from cookie
 	refreshToken, err := m.cookieService.GetRefreshToken(c)
 	if err != nil {
-		m.logger.Info("Failed to get refresh token", zap.String("error", err.Error()))
-		return false
+		m.logger.With(c.Request.Context()).Info("Failed to get refresh token", zap.String("error", err.Error()))
+		return nil, false
 	}
 
 	// Attempt to refresh token
@@ -220,8 +339,8 @@ func (m *AuthMiddleware) attemptTokenRefresh(c *gin.Context) bool {
 
 	response, err := m.authService.RefreshToken(c.Request.Context(), refreshReq)
 	if err != nil {
-		m.logger.Info("Token refresh failed", zap.String("error", err.Error()))
-		return false
+		m.logger.With(c.Request.Context()).Info("Token refresh failed", zap.String("error", err.Error()))
+		return nil, false
 	}
 
 	// Set new cookies
@@ -230,23 +349,130 @@ func (m *AuthMiddleware) attemptTokenRefresh(c *gin.Context) bool {
 	// Parse new access token to get user info
 	parsedToken, err := m.jwtService.ParseAccessToken(response.AccessToken)
 	if err != nil {
-		m.logger.Info("New token parsing failed", zap.String("error", err.Error()))
-		return false
+		m.logger.With(c.Request.Context()).Info("New token parsing failed", zap.String("error", err.Error()))
+		return nil, false
 	}
 
 	// Extract user info from new token
 	userInfo, err := m.jwtService.ExtractUserFromToken(parsedToken)
 	if err != nil {
-		m.logger.Info("Failed to extract user from new token", zap.String("error", err.Error()))
-		return false
+		m.logger.With(c.Request.Context()).Info("Failed to extract user from new token", zap.String("error", err.Error()))
+		return nil, false
 	}
 
 	// Set user information in context
 	c.Set("user_id", userInfo.UserID)
 	c.Set("username", userInfo.Username)
-	c.Set("role", userInfo.Role) // Keep as string for consistency
+	c.Set("role", userInfo.Role)
 	c.Set("user_info", userInfo)
+	applyTempRole(c, response.User)
+	m.setActor(c, userInfo)
+
+	m.logger.With(c.Request.Context()).Info("Token refreshed successfully", zap.String("username", userInfo.Username))
+	return response.User, true
+}
+
+// applyTempRole replaces the role from the token with an active temporary grant, if any
+func applyTempRole(c *gin.Context, user *entities.User) {
+	// Expired grants are ignored, so the token role applies again without re-login
+	if user.HasActiveTempRole() {
+		c.Set("role", *user.TempRole)
+	}
+}
+
+// blockPendingPasswordChange answers 403 when the authenticated user has to change
+// password first and the route is not one of PasswordChangeRoutes.
+func (m *AuthMiddleware) blockPendingPasswordChange(c *gin.Context, user *entities.User) bool {
+	for _, route := range m.config.PasswordChangeRoutes {
+		if c.FullPath() == route {
+			return false
+		}
+	}
+
+	if !user.MustChangePass {
+		return false
+	}
 
-	m.logger.Info("Token refreshed successfully", zap.String("username", userInfo.Username))
+	c.JSON(http.StatusForbidden, gin.H{
+		"success":              false,
+		"error":                "Forbidden",
+		"message":              "Password change required",
+		"details":              "Change your password before using other endpoints",
+		"must_change_password": true,
+	})
+	c.Abort()
 	return true
 }
+
+// abortServerError answers 503 if the database timed out and 500 otherwise
+func abortServerError(c *gin.Context, err error) {
+	if errors.Is(err, entities.ErrDatabaseTimeout) {
+		c.AbortWithStatusJSON(http.StatusServiceUnavailable, dto.ErrDatabaseTimeout)
+		return
+	}
+	c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrInternalServer)
+}
+
+// setActor makes the authenticated user available to services through the request context
+func (m *AuthMiddleware) setActor(c *gin.Context, userInfo *service.UserInfo) {
+	role, _ := RoleFromContext(c)
+	ctx := entities.ContextWithActor(c.Request.Context(), entities.Actor{
+		ID:       userInfo.UserID,
+		Username: userInfo.Username,
+		Role:     role,
+	})
+	c.Request = c.Request.WithContext(ctx)
+}
+
+// slideAccessToken re-issues an access token about to expire for a session that is still valid.
+// The new token is built from the stored user, so deactivation and role changes apply.
+func (m *AuthMiddleware) slideAccessToken(c *gin.Context, accessToken *jwt.Token, user *entities.User) {
+	accessExp, err := accessToken.Claims.GetExpirationTime()
+	if err != nil || accessExp == nil {
+		return
+	}
+
+	// Only re-issue when the token is about to expire
+	now := time.Now()
+	if accessExp.Sub(now) > m.config.SlidingWindow {
+		return
+	}
+
+	// Deactivated users keep their current token at most until it expires
+	if !user.IsActive || user.IsDeactivationDue() {
+		return
+	}
+
+	// The refresh token bounds the absolute session lifetime
+	refreshToken, err := m.cookieService.GetRefreshToken(c)
+	if err != nil {
+		return
+	}
+
+	// Revoked and rotated sessions must not be extended
+	session, err := m.authService.GetRefreshSession(c.Request.Context(), refreshToken)
+	if err != nil {
+		m.logger.With(c.Request.Context()).Debug("Access token not extended", zap.String("error", err.Error()))
+		return
+	}
+	if session.UserID != user.ID {
+		return
+	}
+
+	expiresAt := now.Add(m.config.AccessExpiry)
+	if expiresAt.After(session.ExpiresAt) {
+		expiresAt = session.ExpiresAt
+	}
+	if !expiresAt.After(accessExp.Time) {
+		return
+	}
+
+	newToken, err := m.jwtService.GenerateAccessTokenWithExpiry(user, expiresAt)
+	if err != nil {
+		m.logger.With(c.Request.Context()).Error("Failed to extend access token", zap.String("error", err.Error()))
+		return
+	}
+
+	m.cookieService.SetAccessCookie(c, newToken)
+	m.logger.With(c.Request.Context()).Debug("Access token extended", zap.String("username", user.Username))
+}
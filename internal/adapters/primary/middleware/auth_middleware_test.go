@@ -0,0 +1,385 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/cookie"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/jwt"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/metrics"
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/service"
+	"github.com/ontair/admin-panel/internal/core/services"
+	"github.com/ontair/admin-panel/internal/infra/config"
+	"github.com/ontair/admin-panel/internal/testutil"
+)
+
+// authFixture is the auth middleware over a real JWT service and in-memory repositories
+type authFixture struct {
+	middleware  *AuthMiddleware
+	jwtService  service.JWTService
+	authService service.AuthService
+	store       *testutil.Store
+	logger      *testutil.Logger
+}
+
+func newAuthFixture(t *testing.T, middlewareConfig AuthMiddlewareConfig) *authFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+
+	jwtService, err := jwt.NewJWTService(&config.Config{JWT: config.JWTConfig{
+		SecretKey:     "test-access-secret",
+		RefreshSecret: "test-refresh-secret",
+		AccessExpiry:  15,
+		RefreshExpiry: 60,
+		Issuer:        "test-issuer",
+		Audience:      "test-audience",
+		Algorithm:     "HS256",
+	}})
+	if err != nil {
+		t.Fatalf("failed to create JWT service: %v", err)
+	}
+	if middlewareConfig.AccessExpiry == 0 {
+		middlewareConfig.AccessExpiry = 15 * time.Minute
+	}
+
+	store := testutil.NewStore()
+	logger := testutil.NewLogger()
+	authService := services.NewAuthService(store.Users, jwtService, store.Blacklist, testutil.Hasher{}, &testutil.Notifier{},
+		store.LoginAttempts, store.RefreshTokens, store.TxManager(), metrics.NewRegistry(), logger, services.AuthServiceConfig{
+			AccessExpiry:   15 * time.Minute,
+			RefreshExpiry:  time.Hour,
+			PasswordPolicy: entities.PasswordPolicy{MinLength: 8},
+		})
+	cookieService := cookie.NewCookieService("Lax", "", false, 15*time.Minute, time.Hour)
+
+	return &authFixture{
+		middleware:  NewAuthMiddleware(jwtService, logger, cookieService, authService, middlewareConfig),
+		jwtService:  jwtService,
+		authService: authService,
+		store:       store,
+		logger:      logger,
+	}
+}
+
+// addUser stores an active approved user
+func (f *authFixture) addUser(t *testing.T, username string, role entities.Role) *entities.User {
+	t.Helper()
+	user := &entities.User{
+		Username:       username,
+		Role:           role,
+		IsActive:       true,
+		ApprovalStatus: entities.ApprovalApproved,
+	}
+	if err := f.store.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("failed to add user %s: %v", username, err)
+	}
+	return user
+}
+
+// login sets a password for user and logs in, returning the stored session's tokens
+func (f *authFixture) login(t *testing.T, user *entities.User) *service.LoginResponse {
+	t.Helper()
+	password, _ := testutil.Hasher{}.Hash("Secret123!")
+	if err := f.store.Users.UpdatePasswordLocked(context.Background(), user.ID, func(u *entities.User) error {
+		u.Password = password
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to set password: %v", err)
+	}
+
+	session, err := f.authService.Login(context.Background(), &service.LoginRequest{Username: user.Username, Password: "Secret123!"})
+	if err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+	return session
+}
+
+// serve sends req through handlers ending in an empty 200 response
+func serve(req *http.Request, handlers ...gin.HandlerFunc) *httptest.ResponseRecorder {
+	router := gin.New()
+	handlers = append(handlers, func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/test", handlers...)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// responseCookie returns the cookie named name set by the response
+func responseCookie(w *httptest.ResponseRecorder, name string) *http.Cookie {
+	for _, c := range w.Result().Cookies() {
+		if c.Name == name {
+			return c
+		}
+	}
+	return nil
+}
+
+func TestSlidingExpirationReissuesTokenNearExpiry(t *testing.T) {
+	f := newAuthFixture(t, AuthMiddlewareConfig{SlidingExpiration: true, SlidingWindow: 5 * time.Minute})
+	user := f.addUser(t, "alice", entities.RoleUser)
+	refreshToken := f.login(t, user).RefreshToken
+
+	tests := []struct {
+		name     string
+		expiry   time.Duration
+		refresh  bool
+		reissued bool
+	}{
+		{"near expiry", 2 * time.Minute, true, true},
+		{"fresh token", 14 * time.Minute, true, false},
+		{"near expiry without refresh cookie", 2 * time.Minute, false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			accessToken, err := f.jwtService.GenerateAccessTokenWithExpiry(user, time.Now().Add(tt.expiry))
+			if err != nil {
+				t.Fatalf("failed to issue access token: %v", err)
+			}
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.AddCookie(&http.Cookie{Name: "access_token", Value: accessToken})
+			if tt.refresh {
+				req.AddCookie(&http.Cookie{Name: "refresh_token", Value: refreshToken})
+			}
+
+			w := serve(req, f.middleware.RequireAuth())
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+
+			issued := responseCookie(w, "access_token")
+			if !tt.reissued {
+				if issued != nil {
+					t.Errorf("expected no new access cookie, got one")
+				}
+				return
+			}
+			if issued == nil || issued.Value == accessToken {
+				t.Fatalf("expected a new access cookie")
+			}
+
+			parsed, err := f.jwtService.ParseAccessToken(issued.Value)
+			if err != nil {
+				t.Fatalf("failed to parse re-issued token: %v", err)
+			}
+			exp, _ := parsed.Claims.GetExpirationTime()
+			if remaining := time.Until(exp.Time); remaining < 14*time.Minute {
+				t.Errorf("expected re-issued token to last the access expiry, remains %v", remaining)
+			}
+		})
+	}
+}
+
+// Sliding must respect revocation and deactivation, otherwise a session that can't refresh would live on
+func TestSlidingExpirationNotExtended(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(f *authFixture, user *entities.User)
+	}{
+		{"revoked session", func(f *authFixture, user *entities.User) {
+			if _, err := f.authService.RevokeAllSessions(context.Background(), user.ID); err != nil {
+				t.Fatalf("failed to revoke sessions: %v", err)
+			}
+		}},
+		{"inactive user", func(f *authFixture, user *entities.User) {
+			if err := f.store.Users.SetActive(context.Background(), user.ID, false); err != nil {
+				t.Fatalf("failed to deactivate user: %v", err)
+			}
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAuthFixture(t, AuthMiddlewareConfig{SlidingExpiration: true, SlidingWindow: 5 * time.Minute})
+			user := f.addUser(t, "alice", entities.RoleUser)
+			refreshToken := f.login(t, user).RefreshToken
+			accessToken, _ := f.jwtService.GenerateAccessTokenWithExpiry(user, time.Now().Add(2*time.Minute))
+			tt.setup(f, user)
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.AddCookie(&http.Cookie{Name: "access_token", Value: accessToken})
+			req.AddCookie(&http.Cookie{Name: "refresh_token", Value: refreshToken})
+
+			w := serve(req, f.middleware.RequireAuth())
+			if responseCookie(w, "access_token") != nil {
+				t.Errorf("expected no new access cookie")
+			}
+		})
+	}
+}
+
+func TestSlidingExpirationDisabled(t *testing.T) {
+	f := newAuthFixture(t, AuthMiddlewareConfig{SlidingWindow: 5 * time.Minute})
+	user := f.addUser(t, "alice", entities.RoleUser)
+
+	accessToken, _ := f.jwtService.GenerateAccessTokenWithExpiry(user, time.Now().Add(time.Minute))
+	refreshToken, _ := f.jwtService.GenerateRefreshToken(user)
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: accessToken})
+	req.AddCookie(&http.Cookie{Name: "refresh_token", Value: refreshToken})
+
+	w := serve(req, f.middleware.RequireAuth())
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if responseCookie(w, "access_token") != nil {
+		t.Errorf("expected no new access cookie with sliding expiration disabled")
+	}
+}
+
+func TestConflictingHeaderAndCookieTokens(t *testing.T) {
+	tests := []struct {
+		precedence string
+		want       string
+	}{
+		{"", "header_user"},
+		{TokenPrecedenceHeader, "header_user"},
+		{TokenPrecedenceCookie, "cookie_user"},
+	}
+
+	for _, tt := range tests {
+		t.Run("precedence "+tt.precedence, func(t *testing.T) {
+			f := newAuthFixture(t, AuthMiddlewareConfig{TokenPrecedence: tt.precedence})
+			headerToken, _ := f.jwtService.GenerateAccessToken(f.addUser(t, "header_user", entities.RoleUser))
+			cookieToken, _ := f.jwtService.GenerateAccessToken(f.addUser(t, "cookie_user", entities.RoleUser))
+
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+headerToken)
+			req.AddCookie(&http.Cookie{Name: "access_token", Value: cookieToken})
+
+			var username string
+			w := serve(req, f.middleware.RequireAuth(), func(c *gin.Context) { username = c.GetString("username") })
+			if w.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+			if username != tt.want {
+				t.Errorf("expected %s authenticated, got %s", tt.want, username)
+			}
+			if !slices.Contains(f.logger.Messages(), "Authorization header and cookie carry different tokens") {
+				t.Errorf("expected the conflict to be logged")
+			}
+		})
+	}
+}
+
+func TestMatchingHeaderAndCookieTokensNotLogged(t *testing.T) {
+	f := newAuthFixture(t, AuthMiddlewareConfig{})
+	token, _ := f.jwtService.GenerateAccessToken(f.addUser(t, "alice", entities.RoleUser))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.AddCookie(&http.Cookie{Name: "access_token", Value: token})
+
+	if w := serve(req, f.middleware.RequireAuth()); w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+	}
+	if slices.Contains(f.logger.Messages(), "Authorization header and cookie carry different tokens") {
+		t.Errorf("expected no conflict logged for equal tokens")
+	}
+}
+
+func TestTempRoleGrantsAccessUntilExpiry(t *testing.T) {
+	tests := []struct {
+		name  string
+		until time.Duration
+		want  int
+	}{
+		{"active grant", time.Hour, http.StatusOK},
+		{"expired grant", -time.Minute, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newAuthFixture(t, AuthMiddlewareConfig{})
+			manager := f.addUser(t, "manager", entities.RoleManager)
+			admin, until := entities.RoleAdmin, time.Now().Add(tt.until)
+			if err := f.store.Users.SetTempRole(context.Background(), manager.ID, &admin, &until); err != nil {
+				t.Fatalf("failed to grant temp role: %v", err)
+			}
+
+			// The token still carries the assigned role
+			token, _ := f.jwtService.GenerateAccessToken(manager)
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			if w := serve(req, f.middleware.RequireAuth(), f.middleware.RequireAdmin()); w.Code != tt.want {
+				t.Errorf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+		})
+	}
+}
+
+// Only expired tokens are refreshed, other validation failures answer 401 straight away
+func TestRefreshOnlyExpiredTokens(t *testing.T) {
+	f := newAuthFixture(t, AuthMiddlewareConfig{})
+	user := f.addUser(t, "alice", entities.RoleUser)
+	expired, _ := f.jwtService.GenerateAccessTokenWithExpiry(user, time.Now().Add(-time.Minute))
+
+	tests := []struct {
+		name  string
+		token string
+		want  int
+	}{
+		{"expired", expired, http.StatusOK},
+		{"malformed", "not.a.token", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A stored session, so the refresh itself would succeed
+			session := f.login(t, user)
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.AddCookie(&http.Cookie{Name: "access_token", Value: tt.token})
+			req.AddCookie(&http.Cookie{Name: "refresh_token", Value: session.RefreshToken})
+
+			w := serve(req, f.middleware.RequireAuth())
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			if refreshed := responseCookie(w, "access_token") != nil; refreshed != (tt.want == http.StatusOK) {
+				t.Errorf("expected refreshed %v, got %v", tt.want == http.StatusOK, refreshed)
+			}
+		})
+	}
+}
+
+func TestRequireManagerOrHigher(t *testing.T) {
+	tests := []struct {
+		role entities.Role
+		want int
+	}{
+		{entities.RoleAdmin, http.StatusOK},
+		{entities.RoleManager, http.StatusOK},
+		{entities.RoleUser, http.StatusForbidden},
+		{entities.RoleGuest, http.StatusForbidden},
+		{"auditor", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			f := newAuthFixture(t, AuthMiddlewareConfig{})
+			user := f.addUser(t, "caller", tt.role)
+			token, _ := f.jwtService.GenerateAccessToken(user)
+			req := httptest.NewRequest(http.MethodGet, "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+token)
+
+			w := serve(req, f.middleware.RequireAuth(), f.middleware.RequireManagerOrHigher())
+			if w.Code != tt.want {
+				t.Fatalf("expected %d, got %d: %s", tt.want, w.Code, w.Body.String())
+			}
+			if tt.want == http.StatusForbidden && !strings.Contains(w.Body.String(), `"accepted_roles":["manager","admin"]`) {
+				t.Errorf("expected the accepted roles listed, got %s", w.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,85 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig holds cross-origin settings for browsers calling the API directly
+type CORSConfig struct {
+	Enabled          bool          // answer CORS, otherwise the middleware does nothing (e.g. behind Nginx)
+	AllowedOrigins   []string      // exact origins such as https://admin.example.com, "*" allows any
+	AllowedMethods   []string      // methods allowed in preflight responses
+	AllowedHeaders   []string      // request headers allowed in preflight responses
+	ExposedHeaders   []string      // response headers readable by the browser
+	AllowCredentials bool          // let browsers send cookies, never combined with "*"
+	MaxAge           time.Duration // how long browsers may cache a preflight response
+}
+
+// CORS middleware that adds CORS headers for allowed origins and answers preflight requests.
+// Preflights from other origins get 403, other requests from them pass without CORS headers
+// so the browser blocks reading the response.
+func CORS(config CORSConfig) gin.HandlerFunc {
+	if !config.Enabled {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	anyOrigin := slices.Contains(config.AllowedOrigins, "*")
+	methods := strings.Join(config.AllowedMethods, ", ")
+	headers := strings.Join(config.AllowedHeaders, ", ")
+	exposed := strings.Join(config.ExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(config.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		// Responses differ per origin, caches must not mix them up
+		c.Writer.Header().Add("Vary", "Origin")
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		allowed := anyOrigin || slices.Contains(config.AllowedOrigins, origin)
+		if !allowed {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		if config.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+			c.Header("Access-Control-Allow-Methods", methods)
+			if headers != "" {
+				c.Header("Access-Control-Allow-Headers", headers)
+			}
+			if config.MaxAge > 0 {
+				c.Header("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if exposed != "" {
+			c.Header("Access-Control-Expose-Headers", exposed)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,18 @@
+package middleware
+
+import (
+	"sync/atomic"
+
+	"github.com/gin-gonic/gin"
+)
+
+// InFlight middleware that keeps count of requests being handled, e.g. to report
+// how many are still draining on shutdown
+func InFlight(count *atomic.Int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		count.Add(1)
+		defer count.Add(-1)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,25 @@
+package middleware
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ontair/admin-panel/internal/core/ports/service"
+)
+
+// unmatchedRoute labels requests that hit no route, so stray paths can't blow up label cardinality
+const unmatchedRoute = "unmatched"
+
+// Metrics middleware that records count and latency of every request by route template, method and status
+func Metrics(metrics service.Metrics) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = unmatchedRoute
+		}
+		metrics.ObserveRequest(route, c.Request.Method, c.Writer.Status(), time.Since(start))
+	}
+}
@@ -0,0 +1,140 @@
+package middleware
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+)
+
+// RateLimit middleware that limits requests per client IP with an in-memory token bucket.
+// Non-positive rps disables the limit.
+func RateLimit(rps float64, burst int) gin.HandlerFunc {
+	return RateLimitWithStore(NewMemoryRateLimitStore(), "default", rps, burst, true)
+}
+
+// RateLimitWithStore is RateLimit backed by the given store.
+// Scope separates buckets of different limits sharing one store,
+// headers adds X-RateLimit-* headers to every limited response.
+func RateLimitWithStore(store repository.RateLimitStore, scope string, rps float64, burst int, headers bool) gin.HandlerFunc {
+	if rps <= 0 {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+	if burst < 1 {
+		burst = 1
+	}
+
+	return func(c *gin.Context) {
+		result, err := store.Allow(c.Request.Context(), scope+":"+c.ClientIP(), rps, burst)
+		if err != nil {
+			// An unavailable store must not take the API down
+			c.Next()
+			return
+		}
+
+		if headers {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(burst))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(result.Remaining))
+			c.Header("X-RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+		}
+
+		if !result.Allowed {
+			c.Header("Retry-After", strconv.Itoa(max(1, ceilSeconds(result.RetryAfter))))
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"success": false,
+				"error":   "Too Many Requests",
+				"message": "Rate limit exceeded, please slow down",
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// ceilSeconds rounds duration up to whole seconds
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// bucketSweepInterval is how often full buckets are dropped from memory
+const bucketSweepInterval = time.Minute
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+	rate   float64
+	burst  float64
+}
+
+// refill adds tokens accumulated since the last update
+func (b *tokenBucket) refill(now time.Time) {
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+}
+
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	buckets   map[string]*tokenBucket
+	lastSweep time.Time
+}
+
+// NewMemoryRateLimitStore creates a rate limit store local to this instance
+func NewMemoryRateLimitStore() repository.RateLimitStore {
+	return &memoryRateLimitStore{
+		buckets:   make(map[string]*tokenBucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token from the bucket for key
+func (s *memoryRateLimitStore) Allow(ctx context.Context, key string, rate float64, burst int) (repository.RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.sweep(now)
+
+	bucket, ok := s.buckets[key]
+	if !ok {
+		bucket = &tokenBucket{tokens: float64(burst), last: now}
+		s.buckets[key] = bucket
+	}
+	bucket.rate = rate
+	bucket.burst = float64(burst)
+	bucket.refill(now)
+
+	result := repository.RateLimitResult{Allowed: bucket.tokens >= 1}
+	if result.Allowed {
+		bucket.tokens--
+	} else {
+		result.RetryAfter = time.Duration((1 - bucket.tokens) / rate * float64(time.Second))
+	}
+	result.Remaining = int(bucket.tokens)
+	result.Reset = time.Duration((bucket.burst - bucket.tokens) / rate * float64(time.Second))
+
+	return result, nil
+}
+
+// sweep drops buckets that have refilled completely, they behave like new ones
+func (s *memoryRateLimitStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < bucketSweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, bucket := range s.buckets {
+		bucket.refill(now)
+		if bucket.tokens >= bucket.burst {
+			delete(s.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// requestFrom returns a request of the client at addr
+func requestFrom(addr string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	req.RemoteAddr = addr
+	return req
+}
+
+func TestRateLimitHeadersDecrementPerClient(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	// Slow enough that no token is refilled during the test
+	limit := RateLimitWithStore(NewMemoryRateLimitStore(), "test", 0.001, 3, true)
+
+	for _, want := range []string{"2", "1", "0"} {
+		w := serve(requestFrom("192.0.2.1:1234"), limit)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		if got := w.Header().Get("X-RateLimit-Remaining"); got != want {
+			t.Errorf("expected %s remaining, got %s", want, got)
+		}
+		if got := w.Header().Get("X-RateLimit-Limit"); got != "3" {
+			t.Errorf("expected limit 3, got %s", got)
+		}
+		if w.Header().Get("X-RateLimit-Reset") == "" {
+			t.Errorf("expected reset header")
+		}
+	}
+
+	w := serve(requestFrom("192.0.2.1:1234"), limit)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("X-RateLimit-Remaining") != "0" || w.Header().Get("Retry-After") == "" {
+		t.Errorf("expected 429 with 0 remaining and Retry-After, got %d with %v", w.Code, w.Header())
+	}
+
+	// Another client has its own bucket
+	if got := serve(requestFrom("192.0.2.2:1234"), limit).Header().Get("X-RateLimit-Remaining"); got != "2" {
+		t.Errorf("expected 2 remaining for another client, got %s", got)
+	}
+}
+
+func TestRateLimitHeadersDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limit := RateLimitWithStore(NewMemoryRateLimitStore(), "test", 0.001, 3, false)
+
+	w := serve(requestFrom("192.0.2.1:1234"), limit)
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", w.Code)
+	}
+	if w.Header().Get("X-RateLimit-Remaining") != "" {
+		t.Errorf("expected no rate limit headers, got %v", w.Header())
+	}
+}
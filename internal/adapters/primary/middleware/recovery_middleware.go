@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"syscall"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ontair/admin-panel/internal/core/dto"
+	"github.com/ontair/admin-panel/internal/core/ports/service"
+	"go.uber.org/zap"
+)
+
+// Recovery middleware that turns a panic into a logged stack trace and a 500 JSON response,
+// replacing gin.Recovery(). Must run after RequestID so the log line carries the request ID.
+func Recovery(logger service.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			recovered := recover()
+			if recovered == nil {
+				return
+			}
+
+			log := logger.With(c.Request.Context())
+
+			// The client is gone, there is nobody to answer
+			if err, ok := recovered.(error); ok && (errors.Is(err, syscall.EPIPE) || errors.Is(err, syscall.ECONNRESET)) {
+				log.Warn("Connection closed by client", zap.String("path", c.Request.URL.Path), zap.String("error", err.Error()))
+				c.Abort()
+				return
+			}
+
+			log.Error("Panic recovered",
+				zap.String("method", c.Request.Method),
+				zap.String("path", c.Request.URL.Path),
+				zap.String("panic", fmt.Sprint(recovered)),
+				zap.String("stack", string(debug.Stack())),
+			)
+
+			// Headers already sent can't be replaced
+			if c.Writer.Written() {
+				c.Abort()
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusInternalServerError, dto.ErrInternalServer)
+		}()
+
+		c.Next()
+	}
+}
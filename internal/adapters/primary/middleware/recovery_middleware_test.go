@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"syscall"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ontair/admin-panel/internal/core/dto"
+	"github.com/ontair/admin-panel/internal/testutil"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRecoveryAnswersPanicWithInternalServerError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := testutil.NewLogger()
+
+	w := serve(httptest.NewRequest(http.MethodGet, "/test", nil), Recovery(logger), func(c *gin.Context) {
+		panic("boom")
+	})
+	if w.Code != http.StatusInternalServerError {
+		t.Fatalf("expected 500, got %d", w.Code)
+	}
+	want, _ := json.Marshal(dto.ErrInternalServer)
+	if strings.TrimSpace(w.Body.String()) != string(want) {
+		t.Errorf("expected %s, got %s", want, w.Body.String())
+	}
+
+	entries := logger.Entries()
+	if len(entries) != 1 || entries[0].Level != zapcore.ErrorLevel || entries[0].Message != "Panic recovered" {
+		t.Fatalf("expected one error entry, got %v", entries)
+	}
+	if entries[0].Fields["panic"] != "boom" || !strings.Contains(fmt.Sprint(entries[0].Fields["stack"]), "goroutine") {
+		t.Errorf("expected panic value and stack logged, got %v", entries[0].Fields)
+	}
+}
+
+func TestRecoveryClientGoneNotLoggedAsError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := testutil.NewLogger()
+
+	serve(httptest.NewRequest(http.MethodGet, "/test", nil), Recovery(logger), func(c *gin.Context) {
+		panic(fmt.Errorf("write: %w", syscall.EPIPE))
+	})
+
+	entries := logger.Entries()
+	if len(entries) != 1 || entries[0].Level != zapcore.WarnLevel {
+		t.Errorf("expected one warning, got %v", entries)
+	}
+}
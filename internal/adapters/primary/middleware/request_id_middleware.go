@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+// RequestIDHeader carries the request ID in both directions
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client supplied IDs so they can't bloat every log line
+const maxRequestIDLength = 128
+
+// RequestID middleware that tags each request with an ID taken from X-Request-ID or freshly generated.
+// The ID is echoed in the response header and stored in the request context for logging.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if requestID == "" || len(requestID) > maxRequestIDLength {
+			requestID = newRequestID()
+		}
+
+		c.Set("request_id", requestID)
+		c.Header(RequestIDHeader, requestID)
+		c.Request = c.Request.WithContext(entities.ContextWithRequestID(c.Request.Context(), requestID))
+
+		c.Next()
+	}
+}
+
+// newRequestID generates a random version 4 UUID
+func newRequestID() string {
+	var b [16]byte
+	// crypto/rand.Read never fails on supported platforms
+	_, _ = rand.Read(b[:])
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,32 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxURILength middleware that rejects requests whose path and query exceed maxLength bytes.
+// Non-positive maxLength disables the check.
+func MaxURILength(maxLength int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// RequestURI is only set for server requests
+		uri := c.Request.RequestURI
+		if uri == "" {
+			uri = c.Request.URL.RequestURI()
+		}
+
+		if maxLength > 0 && len(uri) > maxLength {
+			c.JSON(http.StatusRequestURITooLong, gin.H{
+				"success": false,
+				"error":   "URI Too Long",
+				"message": fmt.Sprintf("Request URI must not exceed %d characters", maxLength),
+			})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestMaxURILength(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name      string
+		maxLength int
+		query     string
+		want      int
+	}{
+		{"short query", 64, "search=alice", http.StatusOK},
+		{"at the limit", 64, "search=" + strings.Repeat("a", 64-len("/test?search=")), http.StatusOK},
+		{"over-long query", 64, "search=" + strings.Repeat("a", 100), http.StatusRequestURITooLong},
+		{"repeated parameter", 64, strings.Repeat("role=user&", 10), http.StatusRequestURITooLong},
+		{"disabled", 0, "search=" + strings.Repeat("a", 10000), http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/test?"+tt.query, nil)
+			if w := serve(req, MaxURILength(tt.maxLength)); w.Code != tt.want {
+				t.Errorf("expected %d, got %d", tt.want, w.Code)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/ontair/admin-panel/internal/core/ports/service"
+	"go.uber.org/zap"
+)
+
+// DeactivationJob periodically applies scheduled user deactivations and expires temporary roles
+type DeactivationJob struct {
+	userService service.UserService
+	logger      service.Logger
+	interval    time.Duration
+}
+
+// NewDeactivationJob creates new deactivation job
+func NewDeactivationJob(userService service.UserService, logger service.Logger, interval time.Duration) *DeactivationJob {
+	return &DeactivationJob{
+		userService: userService,
+		logger:      logger,
+		interval:    interval,
+	}
+}
+
+// Run applies due deactivations every interval until context is cancelled
+func (j *DeactivationJob) Run(ctx context.Context) {
+	// Non-positive interval disables the job, deactivation is still enforced at login
+	if j.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.runOnce(ctx)
+		}
+	}
+}
+
+func (j *DeactivationJob) runOnce(ctx context.Context) {
+	count, err := j.userService.ApplyScheduledDeactivations(ctx)
+	if err != nil {
+		j.logger.Error("Scheduled deactivation failed", zap.String("error", err.Error()))
+		return
+	}
+
+	if count > 0 {
+		j.logger.Info("Scheduled deactivations applied", zap.Int64("count", count))
+	}
+
+	users, err := j.userService.ExpireTempRoles(ctx)
+	if err != nil {
+		j.logger.Error("Temporary role expiry failed", zap.String("error", err.Error()))
+		return
+	}
+
+	for _, user := range users {
+		j.logger.Info("Temporary role expired",
+			zap.Uint("userID", user.ID),
+			zap.String("role", string(user.Role)),
+		)
+	}
+}
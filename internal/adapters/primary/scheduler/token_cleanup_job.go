@@ -0,0 +1,57 @@
+package scheduler
+
+import (
+	"context"
+	"time"
+
+	"github.com/ontair/admin-panel/internal/core/ports/service"
+	"go.uber.org/zap"
+)
+
+// TokenCleanupJob periodically drops expired entries from the token blacklist
+type TokenCleanupJob struct {
+	authService service.AuthService
+	logger      service.Logger
+	interval    time.Duration
+}
+
+// NewTokenCleanupJob creates new token cleanup job
+func NewTokenCleanupJob(authService service.AuthService, logger service.Logger, interval time.Duration) *TokenCleanupJob {
+	return &TokenCleanupJob{
+		authService: authService,
+		logger:      logger,
+		interval:    interval,
+	}
+}
+
+// Run purges expired revocations every interval until context is cancelled
+func (j *TokenCleanupJob) Run(ctx context.Context) {
+	// Non-positive interval disables the job, the blacklist then only grows
+	if j.interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			j.runOnce(ctx)
+		}
+	}
+}
+
+func (j *TokenCleanupJob) runOnce(ctx context.Context) {
+	count, err := j.authService.PurgeExpiredRevocations(ctx)
+	if err != nil {
+		j.logger.Error("Token blacklist cleanup failed", zap.String("error", err.Error()))
+		return
+	}
+
+	if count > 0 {
+		j.logger.Info("Expired blacklist entries removed", zap.Int64("count", count))
+	}
+}
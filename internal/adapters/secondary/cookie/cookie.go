@@ -3,6 +3,7 @@ package cookie
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ontair/admin-panel/internal/core/ports/service"
@@ -16,10 +17,12 @@ type CookieService struct {
 	secure           bool
 	httpOnly         bool
 	sameSite         http.SameSite
+	accessMaxAge     int // seconds
+	refreshMaxAge    int // seconds
 }
 
-// NewCookieService creates new cookie service
-func NewCookieService(sameSite string, domain string, secure bool) service.CookieService {
+// NewCookieService creates new cookie service, cookies live for the given expiries
+func NewCookieService(sameSite string, domain string, secure bool, accessExpiry, refreshExpiry time.Duration) service.CookieService {
 	sameSiteMode := http.SameSiteLaxMode // Default
 	
 	switch sameSite {
@@ -38,6 +41,8 @@ func NewCookieService(sameSite string, domain string, secure bool) service.Cooki
 		secure:           secure,
 		httpOnly:         true,
 		sameSite:         sameSiteMode,
+		accessMaxAge:     int(accessExpiry.Seconds()),
+		refreshMaxAge:    int(refreshExpiry.Seconds()),
 	}
 }
 
@@ -50,7 +55,7 @@ func (s *CookieService) SetAuthCookies(c *gin.Context, accessToken, refreshToken
 	c.SetCookie(
 		s.accessTokenName,
 		accessToken,
-		15*60, // 15 minutes
+		s.accessMaxAge,
 		"/",
 		s.domain,
 		s.secure,
@@ -61,7 +66,22 @@ func (s *CookieService) SetAuthCookies(c *gin.Context, accessToken, refreshToken
 	c.SetCookie(
 		s.refreshTokenName,
 		refreshToken,
-		7*24*60*60, // 7 days
+		s.refreshMaxAge,
+		"/",
+		s.domain,
+		s.secure,
+		s.httpOnly,
+	)
+}
+
+// SetAccessCookie sets only the access token cookie
+func (s *CookieService) SetAccessCookie(c *gin.Context, accessToken string) {
+	c.SetSameSite(s.sameSite)
+
+	c.SetCookie(
+		s.accessTokenName,
+		accessToken,
+		s.accessMaxAge,
 		"/",
 		s.domain,
 		s.secure,
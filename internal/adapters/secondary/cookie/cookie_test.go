@@ -0,0 +1,55 @@
+package cookie
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cookiesSetBy runs set on a fresh context and returns the cookies of the response by name
+func cookiesSetBy(set func(c *gin.Context)) map[string]*http.Cookie {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	set(c)
+
+	cookies := make(map[string]*http.Cookie)
+	for _, cookie := range w.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	return cookies
+}
+
+func TestCookieMaxAgeFollowsConfiguredExpiry(t *testing.T) {
+	s := NewCookieService("Strict", "", true, 10*time.Minute, 2*time.Hour)
+
+	cookies := cookiesSetBy(func(c *gin.Context) { s.SetAuthCookies(c, "access", "refresh") })
+	if got := cookies["access_token"].MaxAge; got != 600 {
+		t.Errorf("expected access cookie max-age 600, got %d", got)
+	}
+	if got := cookies["refresh_token"].MaxAge; got != 7200 {
+		t.Errorf("expected refresh cookie max-age 7200, got %d", got)
+	}
+
+	cookies = cookiesSetBy(func(c *gin.Context) { s.SetAccessCookie(c, "access") })
+	if got := cookies["access_token"].MaxAge; got != 600 {
+		t.Errorf("expected re-issued access cookie max-age 600, got %d", got)
+	}
+	if _, ok := cookies["refresh_token"]; ok {
+		t.Errorf("expected refresh cookie untouched")
+	}
+}
+
+func TestClearAuthCookiesExpiresBoth(t *testing.T) {
+	s := NewCookieService("Lax", "", false, 10*time.Minute, 2*time.Hour)
+
+	cookies := cookiesSetBy(s.ClearAuthCookies)
+	for _, name := range []string{"access_token", "refresh_token"} {
+		if cookie, ok := cookies[name]; !ok || cookie.MaxAge >= 0 {
+			t.Errorf("expected %s expired, got %v", name, cookie)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+)
+
+// AuditRepository implements AuditRepository interface using pgx
+type AuditRepository struct {
+	db DB
+}
+
+// NewAuditRepository creates new audit repository
+func NewAuditRepository(db DB) repository.AuditRepository {
+	return &AuditRepository{
+		db: db,
+	}
+}
+
+// Record stores a new audit entry
+func (r *AuditRepository) Record(ctx context.Context, entry *entities.AuditEntry) error {
+	query := `
+		INSERT INTO audit_logs (actor_id, action, target_id, details, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id, created_at`
+
+	details := entry.Details
+	if details == nil {
+		details = map[string]interface{}{}
+	}
+
+	err := r.db.QueryRow(ctx, query, entry.ActorID, string(entry.Action), entry.TargetID, details).
+		Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
+
+// List retrieves a page of entries matching filter, newest first, and the total number of matches
+func (r *AuditRepository) List(ctx context.Context, filter repository.AuditFilter) ([]*entities.AuditEntry, int64, error) {
+	var conditions []string
+	var args []interface{}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if filter.ActorID != nil {
+		conditions = append(conditions, "actor_id = "+addArg(*filter.ActorID))
+	}
+	if filter.TargetID != nil {
+		conditions = append(conditions, "target_id = "+addArg(*filter.TargetID))
+	}
+	if filter.Action != "" {
+		conditions = append(conditions, "action = "+addArg(string(filter.Action)))
+	}
+
+	where := ""
+	if len(conditions) > 0 {
+		where = " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var total int64
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM audit_logs`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count audit entries: %w", err)
+	}
+
+	query := `
+		SELECT id, actor_id, action, target_id, details, created_at
+		FROM audit_logs` + where + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ` + addArg(filter.Limit) + ` OFFSET ` + addArg(filter.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*entities.AuditEntry{}
+	for rows.Next() {
+		var entry entities.AuditEntry
+		err := rows.Scan(
+			&entry.ID,
+			&entry.ActorID,
+			&entry.Action,
+			&entry.TargetID,
+			&entry.Details,
+			&entry.CreatedAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan audit entry: %w", err)
+		}
+		entries = append(entries, &entry)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return entries, total, nil
+}
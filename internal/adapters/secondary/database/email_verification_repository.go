@@ -0,0 +1,80 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// EmailVerificationRepository implements EmailVerificationRepository interface using pgx
+type EmailVerificationRepository struct {
+	db DB
+}
+
+// NewEmailVerificationRepository creates new email verification repository
+func NewEmailVerificationRepository(db DB) repository.EmailVerificationRepository {
+	return &EmailVerificationRepository{
+		db: db,
+	}
+}
+
+// Create stores a new verification token
+func (r *EmailVerificationRepository) Create(ctx context.Context, token *entities.EmailVerificationToken) error {
+	query := `
+		INSERT INTO email_verification_tokens (user_id, email, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, NOW())
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(ctx, query, token.UserID, token.Email, token.TokenHash, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create verification token: %w", err)
+	}
+	return nil
+}
+
+// GetByHash retrieves verification token by its hash
+func (r *EmailVerificationRepository) GetByHash(ctx context.Context, tokenHash string) (*entities.EmailVerificationToken, error) {
+	query := `
+		SELECT id, user_id, email, token_hash, expires_at, used_at, created_at
+		FROM email_verification_tokens WHERE token_hash = $1`
+
+	var token entities.EmailVerificationToken
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Email,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entities.ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to get verification token: %w", err)
+	}
+	return &token, nil
+}
+
+// MarkUsed consumes verification token, fails with ErrInvalidToken if it was already used
+func (r *EmailVerificationRepository) MarkUsed(ctx context.Context, id uint) error {
+	query := `UPDATE email_verification_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`
+
+	cmdTag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to consume verification token: %w", err)
+	}
+
+	// Another request consumed it first
+	if cmdTag.RowsAffected() == 0 {
+		return entities.ErrInvalidToken
+	}
+
+	return nil
+}
@@ -0,0 +1,143 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+)
+
+// LoginAttemptRepository implements LoginAttemptRepository interface using pgx
+type LoginAttemptRepository struct {
+	db DB
+}
+
+// NewLoginAttemptRepository creates new login attempt repository
+func NewLoginAttemptRepository(db DB) repository.LoginAttemptRepository {
+	return &LoginAttemptRepository{
+		db: db,
+	}
+}
+
+// Create stores a login attempt
+func (r *LoginAttemptRepository) Create(ctx context.Context, attempt *entities.LoginAttempt) error {
+	query := `
+		INSERT INTO login_attempts (username, user_id, success, ip, user_agent, created_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(ctx, query,
+		attempt.Username,
+		attempt.UserID,
+		attempt.Success,
+		attempt.IP,
+		attempt.UserAgent,
+	).Scan(&attempt.ID, &attempt.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to record login attempt: %w", err)
+	}
+	return nil
+}
+
+// CountFailuresSince counts failed attempts for username made after since and after its last success
+func (r *LoginAttemptRepository) CountFailuresSince(ctx context.Context, username string, since time.Time) (int64, error) {
+	return r.countFailures(ctx, "username", username, since)
+}
+
+// CountIPFailuresSince counts failed attempts from ip made after since and after its last success
+func (r *LoginAttemptRepository) CountIPFailuresSince(ctx context.Context, ip string, since time.Time) (int64, error) {
+	return r.countFailures(ctx, "ip", ip, since)
+}
+
+// ListByUser retrieves attempts of the user, newest first: successful ones by user ID
+// and failed ones by any of the logins the user can be identified with
+func (r *LoginAttemptRepository) ListByUser(ctx context.Context, userID uint, logins []string) ([]*entities.LoginAttempt, error) {
+	query := `
+		SELECT id, username, user_id, success, COALESCE(ip, ''), COALESCE(user_agent, ''), created_at
+		FROM login_attempts
+		WHERE user_id = $1 OR (user_id IS NULL AND username = ANY($2))
+		ORDER BY created_at DESC, id DESC`
+
+	rows, err := r.db.Query(ctx, query, userID, logins)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list login attempts: %w", err)
+	}
+	defer rows.Close()
+
+	attempts := []*entities.LoginAttempt{}
+	for rows.Next() {
+		var attempt entities.LoginAttempt
+		err := rows.Scan(
+			&attempt.ID,
+			&attempt.Username,
+			&attempt.UserID,
+			&attempt.Success,
+			&attempt.IP,
+			&attempt.UserAgent,
+			&attempt.CreatedAt,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan login attempt: %w", err)
+		}
+		attempts = append(attempts, &attempt)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return attempts, nil
+}
+
+// CountByInterval counts attempts made since since, grouped by date_trunc of interval in UTC.
+// Intervals without attempts are left out.
+func (r *LoginAttemptRepository) CountByInterval(ctx context.Context, interval string, since time.Time) ([]repository.LoginCount, error) {
+	query := `
+		SELECT date_trunc($1, created_at AT TIME ZONE 'UTC') AS bucket,
+			COUNT(*) FILTER (WHERE success),
+			COUNT(*) FILTER (WHERE NOT success)
+		FROM login_attempts
+		WHERE created_at >= $2
+		GROUP BY bucket
+		ORDER BY bucket`
+
+	rows, err := r.db.Query(ctx, query, interval, since)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count login attempts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []repository.LoginCount
+	for rows.Next() {
+		var count repository.LoginCount
+		if err := rows.Scan(&count.Bucket, &count.Successful, &count.Failed); err != nil {
+			return nil, fmt.Errorf("failed to scan login count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return counts, nil
+}
+
+// countFailures counts failures matching column, a successful login resets the count.
+// column is never user input.
+func (r *LoginAttemptRepository) countFailures(ctx context.Context, column, value string, since time.Time) (int64, error) {
+	query := fmt.Sprintf(`
+		SELECT COUNT(*) FROM login_attempts
+		WHERE %[1]s = $1 AND success = false AND created_at > $2
+		  AND created_at > COALESCE(
+			(SELECT MAX(created_at) FROM login_attempts WHERE %[1]s = $1 AND success = true),
+			'-infinity')`, column)
+
+	var count int64
+	if err := r.db.QueryRow(ctx, query, value, since).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count login failures: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+)
+
+// PasswordHistoryRepository implements PasswordHistoryRepository interface using pgx
+type PasswordHistoryRepository struct {
+	db DB
+}
+
+// NewPasswordHistoryRepository creates new password history repository
+func NewPasswordHistoryRepository(db DB) repository.PasswordHistoryRepository {
+	return &PasswordHistoryRepository{
+		db: db,
+	}
+}
+
+// Recent returns up to limit hashes of the user's previous passwords, newest first
+func (r *PasswordHistoryRepository) Recent(ctx context.Context, userID uint, limit int) ([]string, error) {
+	query := `
+		SELECT password_hash FROM password_history
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2`
+
+	rows, err := r.db.Query(ctx, query, userID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get password history: %w", err)
+	}
+	defer rows.Close()
+
+	hashes := []string{}
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, fmt.Errorf("failed to scan password history: %w", err)
+		}
+		hashes = append(hashes, hash)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get password history: %w", err)
+	}
+
+	return hashes, nil
+}
+
+// Add stores a replaced password hash and drops all but the newest keep entries
+func (r *PasswordHistoryRepository) Add(ctx context.Context, userID uint, passwordHash string, keep int) error {
+	query := `
+		INSERT INTO password_history (user_id, password_hash, created_at)
+		VALUES ($1, $2, NOW())`
+	if _, err := r.db.Exec(ctx, query, userID, passwordHash); err != nil {
+		return fmt.Errorf("failed to add password history: %w", err)
+	}
+
+	query = `
+		DELETE FROM password_history
+		WHERE user_id = $1 AND id NOT IN (
+			SELECT id FROM password_history
+			WHERE user_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		)`
+	if _, err := r.db.Exec(ctx, query, userID, keep); err != nil {
+		return fmt.Errorf("failed to trim password history: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,79 @@
+package database
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// PasswordResetRepository implements PasswordResetRepository interface using pgx
+type PasswordResetRepository struct {
+	db DB
+}
+
+// NewPasswordResetRepository creates new password reset repository
+func NewPasswordResetRepository(db DB) repository.PasswordResetRepository {
+	return &PasswordResetRepository{
+		db: db,
+	}
+}
+
+// Create stores a new reset token
+func (r *PasswordResetRepository) Create(ctx context.Context, token *entities.PasswordResetToken) error {
+	query := `
+		INSERT INTO password_reset_tokens (user_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, NOW())
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(ctx, query, token.UserID, token.TokenHash, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create reset token: %w", err)
+	}
+	return nil
+}
+
+// GetByHash retrieves reset token by its hash
+func (r *PasswordResetRepository) GetByHash(ctx context.Context, tokenHash string) (*entities.PasswordResetToken, error) {
+	query := `
+		SELECT id, user_id, token_hash, expires_at, used_at, created_at
+		FROM password_reset_tokens WHERE token_hash = $1`
+
+	var token entities.PasswordResetToken
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.TokenHash,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entities.ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to get reset token: %w", err)
+	}
+	return &token, nil
+}
+
+// MarkUsed consumes reset token, fails with ErrInvalidToken if it was already used
+func (r *PasswordResetRepository) MarkUsed(ctx context.Context, id uint) error {
+	query := `UPDATE password_reset_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL`
+
+	cmdTag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to consume reset token: %w", err)
+	}
+
+	// Another request consumed it first
+	if cmdTag.RowsAffected() == 0 {
+		return entities.ErrInvalidToken
+	}
+
+	return nil
+}
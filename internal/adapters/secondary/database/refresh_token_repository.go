@@ -0,0 +1,195 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// RefreshTokenRepository implements RefreshTokenRepository interface using pgx
+type RefreshTokenRepository struct {
+	db DB
+}
+
+// NewRefreshTokenRepository creates new refresh token repository
+func NewRefreshTokenRepository(db DB) repository.RefreshTokenRepository {
+	return &RefreshTokenRepository{
+		db: db,
+	}
+}
+
+// Create stores a newly issued refresh token
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *entities.RefreshToken) error {
+	query := `
+		INSERT INTO refresh_tokens (user_id, family_id, token_hash, user_agent, ip, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		RETURNING id, created_at`
+
+	err := r.db.QueryRow(ctx, query, token.UserID, token.FamilyID, token.TokenHash, token.UserAgent, token.IP, token.ExpiresAt).
+		Scan(&token.ID, &token.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+	return nil
+}
+
+// GetByHash retrieves refresh token by its hash, fails with ErrInvalidToken if unknown
+func (r *RefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*entities.RefreshToken, error) {
+	query := `
+		SELECT id, user_id, family_id, token_hash, COALESCE(user_agent, ''), COALESCE(ip, ''),
+			expires_at, used_at, revoked_at, created_at
+		FROM refresh_tokens WHERE token_hash = $1`
+
+	var token entities.RefreshToken
+	err := r.db.QueryRow(ctx, query, tokenHash).Scan(
+		&token.ID,
+		&token.UserID,
+		&token.FamilyID,
+		&token.TokenHash,
+		&token.UserAgent,
+		&token.IP,
+		&token.ExpiresAt,
+		&token.UsedAt,
+		&token.RevokedAt,
+		&token.CreatedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entities.ErrInvalidToken
+		}
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	return &token, nil
+}
+
+// MarkUsed rotates refresh token out, fails with ErrInvalidToken if it was already used or revoked
+func (r *RefreshTokenRepository) MarkUsed(ctx context.Context, id uint) error {
+	query := `UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1 AND used_at IS NULL AND revoked_at IS NULL`
+
+	cmdTag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+
+	// Another request rotated it first
+	if cmdTag.RowsAffected() == 0 {
+		return entities.ErrInvalidToken
+	}
+
+	return nil
+}
+
+// RevokeFamily revokes all tokens of the family
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE family_id = $1 AND revoked_at IS NULL`
+
+	if _, err := r.db.Exec(ctx, query, familyID); err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+	return nil
+}
+
+// ListSessions retrieves not revoked sessions of a user and their total count.
+// The one token of a family that is neither used nor revoked represents the session.
+func (r *RefreshTokenRepository) ListSessions(ctx context.Context, filter repository.SessionFilter) ([]*entities.Session, int64, error) {
+	conditions := []string{"t.user_id = $1", "t.used_at IS NULL", "t.revoked_at IS NULL"}
+	if filter.Expired {
+		conditions = append(conditions, "t.expires_at <= NOW()")
+	} else {
+		conditions = append(conditions, "t.expires_at > NOW()")
+	}
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	var total int64
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM refresh_tokens t`+where, filter.UserID).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count sessions: %w", err)
+	}
+
+	query := `
+		SELECT t.family_id, t.user_id, COALESCE(t.user_agent, ''), COALESCE(t.ip, ''),
+			(SELECT MIN(f.created_at) FROM refresh_tokens f WHERE f.family_id = t.family_id),
+			t.created_at, t.expires_at
+		FROM refresh_tokens t` + where + `
+		ORDER BY t.created_at DESC, t.id DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := r.db.Query(ctx, query, filter.UserID, filter.Limit, filter.Offset)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	defer rows.Close()
+
+	sessions := []*entities.Session{}
+	for rows.Next() {
+		var session entities.Session
+		err := rows.Scan(
+			&session.ID,
+			&session.UserID,
+			&session.UserAgent,
+			&session.IP,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+		)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan session: %w", err)
+		}
+		sessions = append(sessions, &session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	return sessions, total, nil
+}
+
+// CountActiveSessions counts sessions of a user whose refresh token is still usable
+func (r *RefreshTokenRepository) CountActiveSessions(ctx context.Context, userID uint) (int64, error) {
+	query := `
+		SELECT COUNT(*) FROM refresh_tokens
+		WHERE user_id = $1 AND used_at IS NULL AND revoked_at IS NULL AND expires_at > NOW()`
+
+	var count int64
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count sessions: %w", err)
+	}
+	return count, nil
+}
+
+// RevokeSession revokes family of the user, fails with ErrSessionNotFound if it has no tokens left to revoke
+func (r *RefreshTokenRepository) RevokeSession(ctx context.Context, userID uint, familyID string) error {
+	query := `UPDATE refresh_tokens SET revoked_at = NOW() WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL`
+
+	cmdTag, err := r.db.Exec(ctx, query, userID, familyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return entities.ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// RevokeAllSessions revokes every token of the user and returns the number of sessions ended
+func (r *RefreshTokenRepository) RevokeAllSessions(ctx context.Context, userID uint) (int64, error) {
+	query := `
+		WITH revoked AS (
+			UPDATE refresh_tokens SET revoked_at = NOW()
+			WHERE user_id = $1 AND revoked_at IS NULL
+			RETURNING used_at
+		)
+		SELECT COUNT(*) FROM revoked WHERE used_at IS NULL`
+
+	var count int64
+	if err := r.db.QueryRow(ctx, query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+	return count, nil
+}
@@ -0,0 +1,108 @@
+package database
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+)
+
+// RoleRepository implements RoleRepository interface using pgx
+type RoleRepository struct {
+	db DB
+}
+
+// NewRoleRepository creates new role repository
+func NewRoleRepository(db DB) repository.RoleRepository {
+	return &RoleRepository{
+		db: db,
+	}
+}
+
+// List retrieves all roles, built-in and custom
+func (r *RoleRepository) List(ctx context.Context) ([]entities.Role, error) {
+	query := `SELECT name FROM roles ORDER BY is_builtin DESC, name`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+	defer rows.Close()
+
+	var roles []entities.Role
+	for rows.Next() {
+		var role entities.Role
+		if err := rows.Scan(&role); err != nil {
+			return nil, fmt.Errorf("failed to scan role: %w", err)
+		}
+		roles = append(roles, role)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return roles, nil
+}
+
+// Exists checks if role is defined
+func (r *RoleRepository) Exists(ctx context.Context, role entities.Role) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM roles WHERE name = $1)`
+
+	var exists bool
+	if err := r.db.QueryRow(ctx, query, string(role)).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check role: %w", err)
+	}
+	return exists, nil
+}
+
+// ListPermissions retrieves the permission catalog with the roles granted each permission
+func (r *RoleRepository) ListPermissions(ctx context.Context) ([]entities.Permission, error) {
+	query := `
+		SELECT p.name, p.description, rp.role
+		FROM permissions p
+		LEFT JOIN role_permissions rp ON rp.permission = p.name
+		ORDER BY p.created_at, p.name, rp.role`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+	defer rows.Close()
+
+	var permissions []entities.Permission
+	for rows.Next() {
+		var (
+			permission entities.Permission
+			role       *entities.Role
+		)
+		if err := rows.Scan(&permission.Name, &permission.Description, &role); err != nil {
+			return nil, fmt.Errorf("failed to scan permission: %w", err)
+		}
+
+		// One row per granted role, a permission granted to no role has a single row without one
+		if n := len(permissions); n == 0 || permissions[n-1].Name != permission.Name {
+			permission.Roles = []entities.Role{}
+			permissions = append(permissions, permission)
+		}
+		if role != nil {
+			last := &permissions[len(permissions)-1]
+			last.Roles = append(last.Roles, *role)
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	// Built-in roles from highest to lowest privilege, custom ones after them by name
+	for _, p := range permissions {
+		slices.SortStableFunc(p.Roles, func(a, b entities.Role) int {
+			return cmp.Compare(b.Level(), a.Level())
+		})
+	}
+	return permissions, nil
+}
@@ -0,0 +1,60 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/pashagolub/pgxmock/v4"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+func TestRoleRepositoryListIncludesCustomRoles(t *testing.T) {
+	mock := newMockPool(t)
+	mock.ExpectQuery(`SELECT name FROM roles ORDER BY is_builtin DESC, name`).
+		WillReturnRows(pgxmock.NewRows([]string{"name"}).AddRow("admin").AddRow("manager").AddRow("user").AddRow("guest").AddRow("auditor"))
+
+	roles, err := NewRoleRepository(mock).List(context.Background())
+	if err != nil {
+		t.Fatalf("failed to list roles: %v", err)
+	}
+	want := append(append([]entities.Role{}, entities.AllRoles...), "auditor")
+	if fmt.Sprint(roles) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, roles)
+	}
+}
+
+func TestRoleRepositoryExists(t *testing.T) {
+	mock := newMockPool(t)
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM roles WHERE name = \$1\)`).WithArgs("auditor").
+		WillReturnRows(pgxmock.NewRows([]string{"exists"}).AddRow(true))
+
+	exists, err := NewRoleRepository(mock).Exists(context.Background(), "auditor")
+	if err != nil || !exists {
+		t.Errorf("expected custom role to exist, got %v, %v", exists, err)
+	}
+}
+
+func TestRoleRepositoryListPermissionsGroupsRoles(t *testing.T) {
+	admin, manager, auditor := entities.RoleAdmin, entities.RoleManager, entities.Role("auditor")
+	mock := newMockPool(t)
+	mock.ExpectQuery(`SELECT p.name, p.description, rp.role\s+FROM permissions p\s+LEFT JOIN role_permissions rp`).
+		WillReturnRows(pgxmock.NewRows([]string{"name", "description", "role"}).
+			AddRow("users.read", "List users", &admin).
+			AddRow("users.read", "List users", &auditor).
+			AddRow("users.read", "List users", &manager).
+			AddRow("reports.export", "Export reports", (*entities.Role)(nil)))
+
+	permissions, err := NewRoleRepository(mock).ListPermissions(context.Background())
+	if err != nil {
+		t.Fatalf("failed to list permissions: %v", err)
+	}
+	want := []entities.Permission{
+		{Name: "users.read", Description: "List users", Roles: []entities.Role{entities.RoleAdmin, entities.RoleManager, "auditor"}},
+		{Name: "reports.export", Description: "Export reports", Roles: []entities.Role{}},
+	}
+	if fmt.Sprint(permissions) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, permissions)
+	}
+}
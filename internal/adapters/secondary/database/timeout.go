@@ -0,0 +1,147 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// timeoutDB bounds every query by a deadline, so a slow query can't hold
+// a connection forever when the caller's context has none.
+// Transactions started by Begin keep bounding each of their queries.
+type timeoutDB struct {
+	db      DB
+	timeout time.Duration
+}
+
+// NewTimeoutDB wraps db so each query is cancelled after timeout and fails
+// with ErrDatabaseTimeout. A zero timeout returns db unchanged.
+func NewTimeoutDB(db DB, timeout time.Duration) DB {
+	if timeout <= 0 {
+		return db
+	}
+	return &timeoutDB{
+		db:      db,
+		timeout: timeout,
+	}
+}
+
+// Exec runs sql with the query deadline
+func (t *timeoutDB) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	tag, err := t.db.Exec(ctx, sql, args...)
+	return tag, timeoutError(err)
+}
+
+// Query runs sql with the query deadline, which lasts until the rows are closed
+func (t *timeoutDB) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+
+	rows, err := t.db.Query(ctx, sql, args...)
+	if err != nil {
+		cancel()
+		return nil, timeoutError(err)
+	}
+	return &timeoutRows{Rows: rows, cancel: cancel}, nil
+}
+
+// QueryRow runs sql with the query deadline, which lasts until the row is scanned
+func (t *timeoutDB) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	return &timeoutRow{row: t.db.QueryRow(ctx, sql, args...), cancel: cancel}
+}
+
+// Begin starts a transaction, the transaction itself outlives the call
+// so only its queries get the deadline
+func (t *timeoutDB) Begin(ctx context.Context) (pgx.Tx, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+
+	tx, err := t.db.Begin(ctx)
+	if err != nil {
+		return nil, timeoutError(err)
+	}
+	return &timeoutTx{Tx: tx, db: &timeoutDB{db: tx, timeout: t.timeout}}, nil
+}
+
+// timeoutTx is a transaction whose queries and commit are bounded like timeoutDB
+type timeoutTx struct {
+	pgx.Tx
+	db *timeoutDB
+}
+
+func (t *timeoutTx) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+	return t.db.Exec(ctx, sql, args...)
+}
+
+func (t *timeoutTx) Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error) {
+	return t.db.Query(ctx, sql, args...)
+}
+
+func (t *timeoutTx) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+	return t.db.QueryRow(ctx, sql, args...)
+}
+
+// Begin starts a savepoint whose queries are bounded too
+func (t *timeoutTx) Begin(ctx context.Context) (pgx.Tx, error) {
+	return t.db.Begin(ctx)
+}
+
+func (t *timeoutTx) Commit(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, t.db.timeout)
+	defer cancel()
+
+	return timeoutError(t.Tx.Commit(ctx))
+}
+
+// timeoutRows releases the query deadline when the rows are closed
+type timeoutRows struct {
+	pgx.Rows
+	cancel context.CancelFunc
+}
+
+func (r *timeoutRows) Next() bool {
+	if r.Rows.Next() {
+		return true
+	}
+	// Rows close themselves after the last one
+	r.cancel()
+	return false
+}
+
+func (r *timeoutRows) Close() {
+	r.Rows.Close()
+	r.cancel()
+}
+
+func (r *timeoutRows) Err() error {
+	return timeoutError(r.Rows.Err())
+}
+
+// timeoutRow releases the query deadline once the row is scanned
+type timeoutRow struct {
+	row    pgx.Row
+	cancel context.CancelFunc
+}
+
+func (r *timeoutRow) Scan(dest ...interface{}) error {
+	defer r.cancel()
+	return timeoutError(r.row.Scan(dest...))
+}
+
+// timeoutError marks errors caused by an exceeded deadline with ErrDatabaseTimeout,
+// keeping the original error in the chain
+func timeoutError(err error) error {
+	if err != nil && errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", entities.ErrDatabaseTimeout, err)
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+)
+
+// TokenBlacklist implements TokenBlacklist interface using pgx
+type TokenBlacklist struct {
+	db DB
+}
+
+// NewTokenBlacklist creates new token blacklist
+func NewTokenBlacklist(db DB) repository.TokenBlacklist {
+	return &TokenBlacklist{
+		db: db,
+	}
+}
+
+// Add revokes token with given jti until its expiry
+func (b *TokenBlacklist) Add(ctx context.Context, jti string, exp time.Time) error {
+	query := `
+		INSERT INTO token_blacklist (jti, expires_at) VALUES ($1, $2)
+		ON CONFLICT (jti) DO NOTHING`
+
+	if _, err := b.db.Exec(ctx, query, jti, exp); err != nil {
+		return fmt.Errorf("failed to blacklist token: %w", err)
+	}
+	return nil
+}
+
+// IsBlacklisted checks if token with given jti was revoked.
+// Lookup failures count as revoked so a database outage can't revive a token.
+func (b *TokenBlacklist) IsBlacklisted(ctx context.Context, jti string) bool {
+	query := `SELECT EXISTS (SELECT 1 FROM token_blacklist WHERE jti = $1)`
+
+	var exists bool
+	if err := b.db.QueryRow(ctx, query, jti).Scan(&exists); err != nil {
+		return true
+	}
+	return exists
+}
+
+// DeleteExpired drops entries for tokens that have expired anyway
+func (b *TokenBlacklist) DeleteExpired(ctx context.Context) (int64, error) {
+	query := `DELETE FROM token_blacklist WHERE expires_at <= NOW()`
+
+	cmdTag, err := b.db.Exec(ctx, query)
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired blacklist entries: %w", err)
+	}
+	return cmdTag.RowsAffected(), nil
+}
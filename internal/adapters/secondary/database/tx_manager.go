@@ -0,0 +1,51 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// DB is implemented by both *pgxpool.Pool and pgx.Tx, so repositories
+// run the same queries inside and outside of a transaction.
+// Begin on a pgx.Tx starts a savepoint.
+type DB interface {
+	Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...interface{}) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row
+	Begin(ctx context.Context) (pgx.Tx, error)
+}
+
+// TxFunc runs fn in a transaction, committing if fn returns nil
+type TxFunc func(ctx context.Context, fn func(tx pgx.Tx) error) error
+
+// TxManager implements TxManager interface on top of a transaction runner
+type TxManager struct {
+	withTx       TxFunc
+	queryTimeout time.Duration
+}
+
+// NewTxManager creates new transaction manager, queryTimeout bounds each query like NewTimeoutDB
+func NewTxManager(withTx TxFunc, queryTimeout time.Duration) repository.TxManager {
+	return &TxManager{
+		withTx:       withTx,
+		queryTimeout: queryTimeout,
+	}
+}
+
+// WithTx runs fn with repositories bound to one transaction
+func (m *TxManager) WithTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	return m.withTx(ctx, func(tx pgx.Tx) error {
+		db := NewTimeoutDB(tx, m.queryTimeout)
+		return fn(repository.Repositories{
+			Users:           &UserRepository{db: db},
+			RefreshTokens:   &RefreshTokenRepository{db: db},
+			Audit:           &AuditRepository{db: db},
+			PasswordHistory: &PasswordHistoryRepository{db: db},
+		})
+	})
+}
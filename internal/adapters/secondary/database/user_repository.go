@@ -2,23 +2,76 @@ package database
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/ontair/admin-panel/internal/core/entities"
 	"github.com/ontair/admin-panel/internal/core/ports/repository"
 
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/pgconn"
 )
 
+// Postgres error codes handled by the repository
+const (
+	pgLockNotAvailable = "55P03" // returned by NOWAIT locks
+	pgUniqueViolation  = "23505"
+)
+
+// emailUniqueIndex is the index enforcing unique emails
+const emailUniqueIndex = "idx_users_email"
+
+// userColumns lists the users table columns in the order expected by scanUser
+const userColumns = `id, username, email, email_verified, password, first_name, last_name, role, is_active,
+			   approval_status, last_login, deactivate_at, temp_role, temp_role_until,
+			   tokens_revoked_at, must_change_password, password_changed_at, deleted_at, version, created_at, updated_at`
+
+// rowScanner is implemented by both pgx.Row and pgx.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanUser scans a single users row selected with userColumns
+func scanUser(row rowScanner) (*entities.User, error) {
+	var user entities.User
+	err := row.Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.EmailVerified,
+		&user.Password,
+		&user.FirstName,
+		&user.LastName,
+		&user.Role,
+		&user.IsActive,
+		&user.ApprovalStatus,
+		&user.LastLogin,
+		&user.DeactivateAt,
+		&user.TempRole,
+		&user.TempRoleUntil,
+		&user.TokensRevoked,
+		&user.MustChangePass,
+		&user.PassChangedAt,
+		&user.DeletedAt,
+		&user.Version,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
+
 // UserRepository implements UserRepository interface using pgx
 type UserRepository struct {
-	db *pgxpool.Pool
+	db DB
 }
 
 // NewUserRepository creates new user repository
-func NewUserRepository(db *pgxpool.Pool) repository.UserRepository {
+func NewUserRepository(db DB) repository.UserRepository {
 	return &UserRepository{
 		db: db,
 	}
@@ -27,9 +80,13 @@ func NewUserRepository(db *pgxpool.Pool) repository.UserRepository {
 // Create creates a new user
 func (r *UserRepository) Create(ctx context.Context, user *entities.User) error {
 	query := `
-		INSERT INTO users (username, password, first_name, last_name, role, is_active, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())
-		RETURNING id, created_at, updated_at`
+		INSERT INTO users (username, password, first_name, last_name, role, is_active, approval_status, email, must_change_password, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW())
+		RETURNING id, version, created_at, updated_at`
+
+	if user.ApprovalStatus == "" {
+		user.ApprovalStatus = entities.ApprovalApproved
+	}
 
 	err := r.db.QueryRow(ctx, query,
 		user.Username,
@@ -38,34 +95,42 @@ func (r *UserRepository) Create(ctx context.Context, user *entities.User) error
 		user.LastName,
 		string(user.Role),
 		user.IsActive,
-	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+		string(user.ApprovalStatus),
+		user.Email,
+		user.MustChangePass,
+	).Scan(&user.ID, &user.Version, &user.CreatedAt, &user.UpdatedAt)
 
 	if err != nil {
+		// Soft-deleted users keep their username and email reserved
+		if err := uniqueViolation(err); err != nil {
+			return err
+		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 	return nil
 }
 
+// uniqueViolation maps a violated username or email constraint to its domain error.
+// The service checks availability beforehand, this catches concurrent writes.
+// Returns nil for any other error.
+func uniqueViolation(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) || pgErr.Code != pgUniqueViolation {
+		return nil
+	}
+	if pgErr.ConstraintName == emailUniqueIndex {
+		return entities.ErrEmailAlreadyExists
+	}
+	return entities.ErrUserAlreadyExists
+}
+
 // GetByID retrieves user by ID
 func (r *UserRepository) GetByID(ctx context.Context, id uint) (*entities.User, error) {
 	query := `
-		SELECT id, username, password, first_name, last_name, role, is_active, 
-			   last_login, created_at, updated_at
-		FROM users WHERE id = $1`
+		SELECT ` + userColumns + `
+		FROM users WHERE id = $1 AND deleted_at IS NULL`
 
-	var user entities.User
-	err := r.db.QueryRow(ctx, query, id).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Password,
-		&user.FirstName,
-		&user.LastName,
-		&user.Role,
-		&user.IsActive,
-		&user.LastLogin,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	user, err := scanUser(r.db.QueryRow(ctx, query, id))
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -73,29 +138,16 @@ func (r *UserRepository) GetByID(ctx context.Context, id uint) (*entities.User,
 		}
 		return nil, fmt.Errorf("failed to get user by id: %w", err)
 	}
-	return &user, nil
+	return user, nil
 }
 
 // GetByUsername retrieves user by username
 func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*entities.User, error) {
 	query := `
-		SELECT id, username, password, first_name, last_name, role, is_active, 
-			   last_login, created_at, updated_at
-		FROM users WHERE username = $1`
+		SELECT ` + userColumns + `
+		FROM users WHERE username = $1 AND deleted_at IS NULL`
 
-	var user entities.User
-	err := r.db.QueryRow(ctx, query, username).Scan(
-		&user.ID,
-		&user.Username,
-		&user.Password,
-		&user.FirstName,
-		&user.LastName,
-		&user.Role,
-		&user.IsActive,
-		&user.LastLogin,
-		&user.CreatedAt,
-		&user.UpdatedAt,
-	)
+	user, err := scanUser(r.db.QueryRow(ctx, query, username))
 
 	if err != nil {
 		if err == pgx.ErrNoRows {
@@ -103,7 +155,24 @@ func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*e
 		}
 		return nil, fmt.Errorf("failed to get user by username: %w", err)
 	}
-	return &user, nil
+	return user, nil
+}
+
+// GetByEmail retrieves user by email, case-insensitively
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*entities.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users WHERE LOWER(email) = LOWER($1) AND deleted_at IS NULL`
+
+	user, err := scanUser(r.db.QueryRow(ctx, query, email))
+
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, entities.ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to get user by email: %w", err)
+	}
+	return user, nil
 }
 
 // Update updates user data
@@ -112,10 +181,12 @@ func (r *UserRepository) Update(ctx context.Context, user *entities.User) error
 		UPDATE users SET 
 			username = $2, password = $3, first_name = $4, 
 			last_name = $5, role = $6, is_active = $7, last_login = $8,
-			updated_at = NOW()
-		WHERE id = $1`
+			approval_status = $9, must_change_password = $10, password_changed_at = $11, email = $12,
+			email_verified = $13, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND version = $14
+		RETURNING version`
 
-	cmdTag, err := r.db.Exec(ctx, query,
+	err := r.db.QueryRow(ctx, query,
 		user.ID,
 		user.Username,
 		user.Password,
@@ -124,12 +195,81 @@ func (r *UserRepository) Update(ctx context.Context, user *entities.User) error
 		string(user.Role),
 		user.IsActive,
 		user.LastLogin,
-	)
+		string(user.ApprovalStatus),
+		user.MustChangePass,
+		user.PassChangedAt,
+		user.Email,
+		user.EmailVerified,
+		user.Version,
+	).Scan(&user.Version)
 
 	if err != nil {
+		if err == pgx.ErrNoRows {
+			return r.versionMismatch(ctx, user.ID)
+		}
+		if err := uniqueViolation(err); err != nil {
+			return err
+		}
 		return fmt.Errorf("failed to update user: %w", err)
 	}
 
+	return nil
+}
+
+// versionMismatch tells a stale version apart from a missing user after an update matched no row
+func (r *UserRepository) versionMismatch(ctx context.Context, id uint) error {
+	var exists bool
+	err := r.db.QueryRow(ctx, `SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)`, id).Scan(&exists)
+	if err != nil {
+		return fmt.Errorf("failed to check user: %w", err)
+	}
+	if !exists {
+		return entities.ErrUserNotFound
+	}
+	return entities.ErrConcurrentModification
+}
+
+// UpdateRole updates only user's role
+func (r *UserRepository) UpdateRole(ctx context.Context, id uint, role entities.Role) error {
+	query := `UPDATE users SET role = $2, version = version + 1, updated_at = NOW() WHERE id = $1`
+
+	cmdTag, err := r.db.Exec(ctx, query, id, string(role))
+	if err != nil {
+		return fmt.Errorf("failed to update user role: %w", err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return entities.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetActive updates only user's active status
+func (r *UserRepository) SetActive(ctx context.Context, id uint, active bool) error {
+	query := `UPDATE users SET is_active = $2, version = version + 1, updated_at = NOW() WHERE id = $1`
+
+	cmdTag, err := r.db.Exec(ctx, query, id, active)
+	if err != nil {
+		return fmt.Errorf("failed to update user active status: %w", err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return entities.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// SetMustChangePassword sets whether user has to change password on next login
+func (r *UserRepository) SetMustChangePassword(ctx context.Context, id uint, required bool) error {
+	query := `UPDATE users SET must_change_password = $2, version = version + 1, updated_at = NOW() WHERE id = $1`
+
+	cmdTag, err := r.db.Exec(ctx, query, id, required)
+	if err != nil {
+		return fmt.Errorf("failed to update password change requirement: %w", err)
+	}
+
 	if cmdTag.RowsAffected() == 0 {
 		return entities.ErrUserNotFound
 	}
@@ -137,9 +277,160 @@ func (r *UserRepository) Update(ctx context.Context, user *entities.User) error
 	return nil
 }
 
-// Delete deletes user by ID
+// SetDeactivateAt schedules user deactivation, nil cancels it
+func (r *UserRepository) SetDeactivateAt(ctx context.Context, id uint, at *time.Time) error {
+	query := `UPDATE users SET deactivate_at = $2, version = version + 1, updated_at = NOW() WHERE id = $1`
+
+	cmdTag, err := r.db.Exec(ctx, query, id, at)
+	if err != nil {
+		return fmt.Errorf("failed to schedule deactivation: %w", err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return entities.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// LockDueDeactivations locks and returns users whose scheduled deactivation time has passed
+func (r *UserRepository) LockDueDeactivations(ctx context.Context) ([]*entities.User, error) {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users
+		WHERE deactivate_at IS NOT NULL AND deactivate_at <= NOW() AND deleted_at IS NULL
+		ORDER BY id
+		FOR UPDATE`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock due deactivations: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*entities.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return users, nil
+}
+
+// SetTempRole grants a temporary role until the given time, nil role revokes it
+func (r *UserRepository) SetTempRole(ctx context.Context, id uint, role *entities.Role, until *time.Time) error {
+	query := `UPDATE users SET temp_role = $2, temp_role_until = $3, version = version + 1, updated_at = NOW() WHERE id = $1`
+
+	cmdTag, err := r.db.Exec(ctx, query, id, role, until)
+	if err != nil {
+		return fmt.Errorf("failed to set temporary role: %w", err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return entities.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// RevokeTokens invalidates all tokens issued to the user so far
+func (r *UserRepository) RevokeTokens(ctx context.Context, id uint) error {
+	query := `UPDATE users SET tokens_revoked_at = NOW(), version = version + 1, updated_at = NOW() WHERE id = $1`
+
+	cmdTag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to revoke tokens: %w", err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return entities.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// ClearExpiredTempRoles removes temporary roles that have expired and returns the affected users
+func (r *UserRepository) ClearExpiredTempRoles(ctx context.Context) ([]*entities.User, error) {
+	query := `
+		UPDATE users SET temp_role = NULL, temp_role_until = NULL, version = version + 1, updated_at = NOW()
+		WHERE temp_role_until IS NOT NULL AND temp_role_until <= NOW()
+		RETURNING ` + userColumns
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to clear expired temporary roles: %w", err)
+	}
+	defer rows.Close()
+
+	var users []*entities.User
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return users, nil
+}
+
+// UpdatePasswordLocked locks user row, applies change and saves the new password
+func (r *UserRepository) UpdatePasswordLocked(ctx context.Context, id uint, change func(user *entities.User) error) error {
+	tx, err := r.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+		SELECT ` + userColumns + `
+		FROM users WHERE id = $1 AND deleted_at IS NULL
+		FOR UPDATE NOWAIT`
+
+	user, err := scanUser(tx.QueryRow(ctx, query, id))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return entities.ErrUserNotFound
+		}
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == pgLockNotAvailable {
+			return entities.ErrUserBusy
+		}
+		return fmt.Errorf("failed to lock user: %w", err)
+	}
+
+	if err := change(user); err != nil {
+		return err
+	}
+
+	query = `
+		UPDATE users SET password = $2, must_change_password = $3, password_changed_at = $4, version = version + 1, updated_at = NOW()
+		WHERE id = $1`
+	if _, err := tx.Exec(ctx, query, id, user.Password, user.MustChangePass, user.PassChangedAt); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	return nil
+}
+
+// Delete soft-deletes user by ID, the row is kept but hidden from reads
 func (r *UserRepository) Delete(ctx context.Context, id uint) error {
-	query := `DELETE FROM users WHERE id = $1`
+	query := `UPDATE users SET deleted_at = NOW(), version = version + 1, updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
 
 	cmdTag, err := r.db.Exec(ctx, query, id)
 	if err != nil {
@@ -153,12 +444,43 @@ func (r *UserRepository) Delete(ctx context.Context, id uint) error {
 	return nil
 }
 
+// HardDelete removes user row permanently, including soft-deleted users
+func (r *UserRepository) HardDelete(ctx context.Context, id uint) error {
+	query := `DELETE FROM users WHERE id = $1`
+
+	cmdTag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to hard delete user: %w", err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return entities.ErrUserNotFound
+	}
+
+	return nil
+}
+
+// Restore brings back a soft-deleted user
+func (r *UserRepository) Restore(ctx context.Context, id uint) error {
+	query := `UPDATE users SET deleted_at = NULL, version = version + 1, updated_at = NOW() WHERE id = $1 AND deleted_at IS NOT NULL`
+
+	cmdTag, err := r.db.Exec(ctx, query, id)
+	if err != nil {
+		return fmt.Errorf("failed to restore user: %w", err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return entities.ErrUserNotFound
+	}
+
+	return nil
+}
+
 // List retrieves list of users with pagination
 func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*entities.User, error) {
 	query := `
-		SELECT id, username, password, first_name, last_name, role, is_active, 
-			   last_login, created_at, updated_at
-		FROM users 
+		SELECT ` + userColumns + `
+		FROM users WHERE deleted_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT $1 OFFSET $2`
 
@@ -170,23 +492,11 @@ func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*entiti
 
 	var users []*entities.User
 	for rows.Next() {
-		var user entities.User
-		err := rows.Scan(
-			&user.ID,
-			&user.Username,
-			&user.Password,
-			&user.FirstName,
-			&user.LastName,
-			&user.Role,
-			&user.IsActive,
-			&user.LastLogin,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
-		users = append(users, &user)
+		users = append(users, user)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -196,6 +506,169 @@ func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*entiti
 	return users, nil
 }
 
+// Iterate calls fn with batches of all users ordered by ID.
+// Keyset pagination keeps batches stable while rows are inserted or deleted.
+func (r *UserRepository) Iterate(ctx context.Context, batchSize int, fn func(users []*entities.User) error) error {
+	query := `
+		SELECT ` + userColumns + `
+		FROM users WHERE deleted_at IS NULL AND id > $1
+		ORDER BY id
+		LIMIT $2`
+
+	var lastID uint
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		rows, err := r.db.Query(ctx, query, lastID, batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to iterate users: %w", err)
+		}
+
+		users := make([]*entities.User, 0, batchSize)
+		for rows.Next() {
+			user, err := scanUser(rows)
+			if err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan user: %w", err)
+			}
+			users = append(users, user)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating rows: %w", err)
+		}
+
+		if len(users) == 0 {
+			return nil
+		}
+		if err := fn(users); err != nil {
+			return err
+		}
+		if len(users) < batchSize {
+			return nil
+		}
+		lastID = users[len(users)-1].ID
+	}
+}
+
+// Search retrieves a page of users matching filter and the total number of matches
+func (r *UserRepository) Search(ctx context.Context, filter repository.UserFilter) ([]*entities.User, int64, error) {
+	conditions := []string{"deleted_at IS NULL"}
+	var args []interface{}
+	addArg := func(value interface{}) string {
+		args = append(args, value)
+		return fmt.Sprintf("$%d", len(args))
+	}
+
+	if len(filter.Roles) > 0 {
+		roles := make([]string, len(filter.Roles))
+		for i, role := range filter.Roles {
+			roles[i] = string(role)
+		}
+		conditions = append(conditions, "role = ANY("+addArg(roles)+")")
+	}
+	if filter.IsActive != nil {
+		conditions = append(conditions, "is_active = "+addArg(*filter.IsActive))
+	}
+	if filter.NeverChangedPassword != nil {
+		neverChanged := "(password_changed_at IS NULL OR password_changed_at = created_at)"
+		if !*filter.NeverChangedPassword {
+			neverChanged = "NOT " + neverChanged
+		}
+		conditions = append(conditions, neverChanged)
+	}
+	if filter.CreatedFrom != nil {
+		conditions = append(conditions, "created_at >= "+addArg(*filter.CreatedFrom))
+	}
+	if filter.CreatedTo != nil {
+		conditions = append(conditions, "created_at <= "+addArg(*filter.CreatedTo))
+	}
+	if filter.LastLoginBefore != nil {
+		conditions = append(conditions, "(last_login IS NULL OR last_login < "+addArg(*filter.LastLoginBefore)+")")
+	}
+	if filter.Search != "" {
+		pattern := addArg("%" + likePattern(filter.Search, filter.Wildcards) + "%")
+		conditions = append(conditions, fmt.Sprintf(
+			"(username ILIKE %[1]s OR email ILIKE %[1]s OR first_name ILIKE %[1]s OR last_name ILIKE %[1]s)", pattern))
+	}
+
+	where := " WHERE " + strings.Join(conditions, " AND ")
+
+	// Total is counted with the same conditions, so it stays correct past the last page
+	var total int64
+	if err := r.db.QueryRow(ctx, `SELECT COUNT(*) FROM users`+where, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+	}
+	if filter.CountOnly {
+		return []*entities.User{}, total, nil
+	}
+
+	// Without explicit sort, rank exact username or email matches first, then their prefixes, then other matches
+	orderBy := "created_at DESC"
+	if filter.SortBy != "" {
+		column, ok := userSortColumns[filter.SortBy]
+		if !ok {
+			return nil, 0, entities.ErrInvalidSort
+		}
+		orderBy = column + " ASC NULLS FIRST, id ASC"
+		if filter.SortDesc {
+			orderBy = column + " DESC NULLS LAST, id DESC"
+		}
+	} else if filter.Search != "" {
+		orderBy = fmt.Sprintf(`CASE
+			WHEN LOWER(username) = LOWER(%[1]s) OR LOWER(email) = LOWER(%[1]s) THEN 0
+			WHEN username ILIKE %[2]s OR email ILIKE %[2]s THEN 1
+			ELSE 2
+		END, `, addArg(filter.Search), addArg(likePattern(filter.Search, filter.Wildcards)+"%")) + orderBy
+	}
+
+	query := `
+		SELECT ` + userColumns + `
+		FROM users` + where + `
+		ORDER BY ` + orderBy + `
+		LIMIT ` + addArg(filter.Limit) + ` OFFSET ` + addArg(filter.Offset)
+
+	rows, err := r.db.Query(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to search users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*entities.User{}
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("error iterating rows: %w", err)
+	}
+
+	return users, total, nil
+}
+
+// userSortColumns maps repository.UserSortFields to columns, keeping ORDER BY out of user input
+var userSortColumns = map[string]string{
+	"username":   "username",
+	"created_at": "created_at",
+	"last_login": "last_login",
+	"role":       "role",
+}
+
+// likePattern escapes LIKE special characters in search, keeping % and _ as wildcards if requested
+func likePattern(search string, wildcards bool) string {
+	replacements := []string{`\`, `\\`}
+	if !wildcards {
+		replacements = append(replacements, "%", `\%`, "_", `\_`)
+	}
+	return strings.NewReplacer(replacements...).Replace(search)
+}
+
 // GetByRoles retrieves users by multiple roles
 func (r *UserRepository) GetByRoles(ctx context.Context, roles []entities.Role) ([]*entities.User, error) {
 	if len(roles) == 0 {
@@ -211,9 +684,8 @@ func (r *UserRepository) GetByRoles(ctx context.Context, roles []entities.Role)
 	}
 
 	query := fmt.Sprintf(`
-		SELECT id, username, password, first_name, last_name, role, is_active, 
-			   last_login, created_at, updated_at
-		FROM users WHERE role IN (%s)
+		SELECT `+userColumns+`
+		FROM users WHERE role IN (%s) AND deleted_at IS NULL
 		ORDER BY created_at DESC`, strings.Join(placeholders, ","))
 
 	rows, err := r.db.Query(ctx, query, args...)
@@ -224,23 +696,11 @@ func (r *UserRepository) GetByRoles(ctx context.Context, roles []entities.Role)
 
 	var users []*entities.User
 	for rows.Next() {
-		var user entities.User
-		err := rows.Scan(
-			&user.ID,
-			&user.Username,
-			&user.Password,
-			&user.FirstName,
-			&user.LastName,
-			&user.Role,
-			&user.IsActive,
-			&user.LastLogin,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
-		users = append(users, &user)
+		users = append(users, user)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -252,7 +712,7 @@ func (r *UserRepository) GetByRoles(ctx context.Context, roles []entities.Role)
 
 // Count returns total number of users
 func (r *UserRepository) Count(ctx context.Context) (int64, error) {
-	query := `SELECT COUNT(*) FROM users`
+	query := `SELECT COUNT(*) FROM users WHERE deleted_at IS NULL`
 
 	var count int64
 	err := r.db.QueryRow(ctx, query).Scan(&count)
@@ -262,12 +722,102 @@ func (r *UserRepository) Count(ctx context.Context) (int64, error) {
 	return count, nil
 }
 
+// SetEmailVerified marks email of the user verified, fails with ErrInvalidToken
+// if the user's email is no longer the verified one
+func (r *UserRepository) SetEmailVerified(ctx context.Context, id uint, email string) error {
+	query := `
+		UPDATE users SET email_verified = true, version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND LOWER(email) = LOWER($2) AND deleted_at IS NULL`
+
+	cmdTag, err := r.db.Exec(ctx, query, id, email)
+	if err != nil {
+		return fmt.Errorf("failed to verify email: %w", err)
+	}
+
+	if cmdTag.RowsAffected() == 0 {
+		return entities.ErrInvalidToken
+	}
+
+	return nil
+}
+
+// CountByRole counts active users with the role, unexpired temporary grants of it included
+func (r *UserRepository) CountByRole(ctx context.Context, role entities.Role) (int64, error) {
+	query := `
+		SELECT COUNT(*) FROM users
+		WHERE (role = $1 OR (temp_role = $1 AND temp_role_until > NOW()))
+		  AND is_active = true AND deleted_at IS NULL`
+
+	var count int64
+	if err := r.db.QueryRow(ctx, query, string(role)).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users by role: %w", err)
+	}
+	return count, nil
+}
+
+// CountByRoleAndStatus counts users grouped by role and active status
+func (r *UserRepository) CountByRoleAndStatus(ctx context.Context) ([]repository.UserCount, error) {
+	query := `
+		SELECT role, is_active, COUNT(*) FROM users
+		WHERE deleted_at IS NULL
+		GROUP BY role, is_active
+		ORDER BY role, is_active`
+
+	rows, err := r.db.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count users by role and status: %w", err)
+	}
+	defer rows.Close()
+
+	counts := []repository.UserCount{}
+	for rows.Next() {
+		var count repository.UserCount
+		if err := rows.Scan(&count.Role, &count.IsActive, &count.Count); err != nil {
+			return nil, fmt.Errorf("failed to scan user count: %w", err)
+		}
+		counts = append(counts, count)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to count users by role and status: %w", err)
+	}
+
+	return counts, nil
+}
+
+// LockActiveAdmins locks active admin rows until the transaction ends and counts them
+func (r *UserRepository) LockActiveAdmins(ctx context.Context) (int64, error) {
+	query := `
+		SELECT COUNT(*) FROM (
+			SELECT id FROM users WHERE role = 'admin' AND is_active = true AND deleted_at IS NULL
+			FOR UPDATE
+		) AS admins`
+
+	var count int64
+	if err := r.db.QueryRow(ctx, query).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to lock admins: %w", err)
+	}
+	return count, nil
+}
+
+// CountActiveByName counts active users with the given first and last name, ignoring case and excludeID
+func (r *UserRepository) CountActiveByName(ctx context.Context, firstName, lastName string, excludeID uint) (int64, error) {
+	query := `
+		SELECT COUNT(*) FROM users
+		WHERE deleted_at IS NULL AND is_active = true AND id <> $3
+		  AND LOWER(TRIM(first_name)) = LOWER(TRIM($1)) AND LOWER(TRIM(last_name)) = LOWER(TRIM($2))`
+
+	var count int64
+	if err := r.db.QueryRow(ctx, query, firstName, lastName, excludeID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count users by name: %w", err)
+	}
+	return count, nil
+}
+
 // GetByRole retrieves users by role
 func (r *UserRepository) GetByRole(ctx context.Context, role entities.Role) ([]*entities.User, error) {
 	query := `
-		SELECT id, username, password, first_name, last_name, role, is_active, 
-			   last_login, created_at, updated_at
-		FROM users WHERE role = $1
+		SELECT ` + userColumns + `
+		FROM users WHERE role = $1 AND deleted_at IS NULL
 		ORDER BY created_at DESC`
 
 	rows, err := r.db.Query(ctx, query, string(role))
@@ -278,23 +828,11 @@ func (r *UserRepository) GetByRole(ctx context.Context, role entities.Role) ([]*
 
 	var users []*entities.User
 	for rows.Next() {
-		var user entities.User
-		err := rows.Scan(
-			&user.ID,
-			&user.Username,
-			&user.Password,
-			&user.FirstName,
-			&user.LastName,
-			&user.Role,
-			&user.IsActive,
-			&user.LastLogin,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-		)
+		user, err := scanUser(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan user: %w", err)
 		}
-		users = append(users, &user)
+		users = append(users, user)
 	}
 
 	if err := rows.Err(); err != nil {
@@ -306,7 +844,7 @@ func (r *UserRepository) GetByRole(ctx context.Context, role entities.Role) ([]*
 
 // UpdateLastLogin updates user's last login timestamp
 func (r *UserRepository) UpdateLastLogin(ctx context.Context, userID uint) error {
-	query := `UPDATE users SET last_login = NOW() WHERE id = $1`
+	query := `UPDATE users SET last_login = NOW(), version = version + 1 WHERE id = $1`
 
 	cmdTag, err := r.db.Exec(ctx, query, userID)
 	if err != nil {
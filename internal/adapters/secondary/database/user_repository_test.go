@@ -0,0 +1,358 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/pashagolub/pgxmock/v4"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+)
+
+// newMockPool returns a pool expecting statements in order, checked at the end of the test
+func newMockPool(t *testing.T) pgxmock.PgxPoolIface {
+	t.Helper()
+	mock, err := pgxmock.NewPool()
+	if err != nil {
+		t.Fatalf("failed to create mock pool: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Errorf("unmet expectations: %v", err)
+		}
+		mock.Close()
+	})
+	return mock
+}
+
+// userRows returns users as rows selected with userColumns
+func userRows(users ...*entities.User) *pgxmock.Rows {
+	columns := strings.Split(userColumns, ",")
+	for i := range columns {
+		columns[i] = strings.TrimSpace(columns[i])
+	}
+
+	rows := pgxmock.NewRows(columns)
+	for _, u := range users {
+		rows.AddRow(u.ID, u.Username, u.Email, u.EmailVerified, u.Password, u.FirstName, u.LastName, u.Role, u.IsActive,
+			u.ApprovalStatus, u.LastLogin, u.DeactivateAt, u.TempRole, u.TempRoleUntil,
+			u.TokensRevoked, u.MustChangePass, u.PassChangedAt, u.DeletedAt, u.Version, u.CreatedAt, u.UpdatedAt)
+	}
+	return rows
+}
+
+// The targeted updaters must write their own column only, so the statements are matched exactly
+func TestUserRepositoryUpdateRoleWritesOnlyRole(t *testing.T) {
+	mock := newMockPool(t)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET role = $2, version = version + 1, updated_at = NOW() WHERE id = $1`)).
+		WithArgs(uint(7), "manager").
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	if err := NewUserRepository(mock).UpdateRole(context.Background(), 7, entities.RoleManager); err != nil {
+		t.Fatalf("failed to update role: %v", err)
+	}
+}
+
+func TestUserRepositorySetActiveWritesOnlyActive(t *testing.T) {
+	mock := newMockPool(t)
+	mock.ExpectExec(regexp.QuoteMeta(`UPDATE users SET is_active = $2, version = version + 1, updated_at = NOW() WHERE id = $1`)).
+		WithArgs(uint(7), false).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+
+	if err := NewUserRepository(mock).SetActive(context.Background(), 7, false); err != nil {
+		t.Fatalf("failed to set active: %v", err)
+	}
+}
+
+func TestUserRepositoryTargetedUpdatesUnknownUser(t *testing.T) {
+	mock := newMockPool(t)
+	repo := NewUserRepository(mock)
+	mock.ExpectExec(`UPDATE users SET role`).WithArgs(uint(42), "user").WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+	mock.ExpectExec(`UPDATE users SET is_active`).WithArgs(uint(42), true).WillReturnResult(pgxmock.NewResult("UPDATE", 0))
+
+	if err := repo.UpdateRole(context.Background(), 42, entities.RoleUser); err != entities.ErrUserNotFound {
+		t.Errorf("expected ErrUserNotFound from UpdateRole, got %v", err)
+	}
+	if err := repo.SetActive(context.Background(), 42, true); err != entities.ErrUserNotFound {
+		t.Errorf("expected ErrUserNotFound from SetActive, got %v", err)
+	}
+}
+
+func TestUserRepositoryUpdatePasswordLockedSavesChange(t *testing.T) {
+	mock := newMockPool(t)
+	now := time.Now()
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FOR UPDATE NOWAIT`).WithArgs(uint(7)).
+		WillReturnRows(userRows(&entities.User{ID: 7, Username: "alice", Password: "old", Version: 3, CreatedAt: now, UpdatedAt: now}))
+	mock.ExpectExec(`UPDATE users SET password = \$2`).WithArgs(uint(7), "new", false, &now).
+		WillReturnResult(pgxmock.NewResult("UPDATE", 1))
+	mock.ExpectCommit()
+
+	err := NewUserRepository(mock).UpdatePasswordLocked(context.Background(), 7, func(user *entities.User) error {
+		user.Password = "new"
+		user.PassChangedAt = &now
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to update password: %v", err)
+	}
+}
+
+func TestUserRepositoryUpdatePasswordLockedBusyRow(t *testing.T) {
+	mock := newMockPool(t)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FOR UPDATE NOWAIT`).WithArgs(uint(7)).WillReturnError(&pgconn.PgError{Code: pgLockNotAvailable})
+	mock.ExpectRollback()
+
+	called := false
+	err := NewUserRepository(mock).UpdatePasswordLocked(context.Background(), 7, func(user *entities.User) error {
+		called = true
+		return nil
+	})
+	if err != entities.ErrUserBusy {
+		t.Errorf("expected ErrUserBusy, got %v", err)
+	}
+	if called {
+		t.Errorf("expected change not to run on a locked row")
+	}
+}
+
+func TestUserRepositoryUpdatePasswordLockedRejectedChange(t *testing.T) {
+	mock := newMockPool(t)
+	rejected := errors.New("rejected")
+	mock.ExpectBegin()
+	mock.ExpectQuery(`FOR UPDATE NOWAIT`).WithArgs(uint(7)).
+		WillReturnRows(userRows(&entities.User{ID: 7, Username: "alice", Password: "old"}))
+	mock.ExpectRollback()
+
+	err := NewUserRepository(mock).UpdatePasswordLocked(context.Background(), 7, func(user *entities.User) error {
+		return rejected
+	})
+	if err != rejected {
+		t.Errorf("expected the change error, got %v", err)
+	}
+}
+
+func TestLikePattern(t *testing.T) {
+	tests := []struct {
+		search    string
+		wildcards bool
+		want      string
+	}{
+		{"alice", false, "alice"},
+		{"50%", false, `50\%`},
+		{"a_b", false, `a\_b`},
+		{`back\slash`, false, `back\\slash`},
+		{"%%%", false, `\%\%\%`},
+		{"50%", true, "50%"},
+		{`a_b\`, true, `a_b\\`},
+	}
+
+	for _, tt := range tests {
+		if got := likePattern(tt.search, tt.wildcards); got != tt.want {
+			t.Errorf("likePattern(%q, %v) = %q, want %q", tt.search, tt.wildcards, got, tt.want)
+		}
+	}
+}
+
+func TestUserRepositorySearchMatchesPercentLiterally(t *testing.T) {
+	mock := newMockPool(t)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users WHERE deleted_at IS NULL AND \(username ILIKE \$1`).
+		WithArgs(`%50\%%`).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(1)))
+	mock.ExpectQuery(`ORDER BY`).
+		WithArgs(`%50\%%`, "50%", `50\%%`, 20, 0).
+		WillReturnRows(userRows(&entities.User{ID: 1, Username: "discount50%"}))
+
+	users, total, err := NewUserRepository(mock).Search(context.Background(), repository.UserFilter{Search: "50%", Limit: 20})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if total != 1 || len(users) != 1 || users[0].Username != "discount50%" {
+		t.Errorf("unexpected result: total %d, users %v", total, users)
+	}
+}
+
+// The count and the page come from the same conditions, so total is the number of
+// active users, not all users, whatever page is requested
+func TestUserRepositorySearchCountsActiveFilter(t *testing.T) {
+	mock := newMockPool(t)
+	active := true
+	mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users WHERE deleted_at IS NULL AND is_active = $1`)).
+		WithArgs(true).
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(3)))
+	mock.ExpectQuery(`WHERE deleted_at IS NULL AND is_active = \$1\s+ORDER BY created_at DESC\s+LIMIT \$2 OFFSET \$3`).
+		WithArgs(true, 2, 2).
+		WillReturnRows(userRows(&entities.User{ID: 3, Username: "carol", IsActive: true}))
+
+	users, total, err := NewUserRepository(mock).Search(context.Background(), repository.UserFilter{IsActive: &active, Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if total != 3 || len(users) != 1 {
+		t.Errorf("expected total 3 with 1 user on the last page, got %d with %d", total, len(users))
+	}
+}
+
+// Ranking happens in SQL, so the test pins the ORDER BY: exact username or email
+// matches first, then prefixes, then other substring matches
+func TestUserRepositorySearchRanksExactMatchesFirst(t *testing.T) {
+	mock := newMockPool(t)
+	mock.ExpectQuery(`SELECT COUNT\(\*\) FROM users WHERE deleted_at IS NULL AND \(username ILIKE \$1 OR email ILIKE \$1 OR first_name ILIKE \$1 OR last_name ILIKE \$1\)`).
+		WithArgs("%ann%").
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(3)))
+	mock.ExpectQuery(`ORDER BY CASE\s+WHEN LOWER\(username\) = LOWER\(\$2\) OR LOWER\(email\) = LOWER\(\$2\) THEN 0\s+`+
+		`WHEN username ILIKE \$3 OR email ILIKE \$3 THEN 1\s+ELSE 2\s+END, created_at DESC`).
+		WithArgs("%ann%", "ann", "ann%", 20, 0).
+		WillReturnRows(userRows(
+			&entities.User{ID: 1, Username: "ann"},
+			&entities.User{ID: 2, Username: "anna"},
+			&entities.User{ID: 3, Username: "joann"},
+		))
+
+	users, _, err := NewUserRepository(mock).Search(context.Background(), repository.UserFilter{Search: "ann", Limit: 20})
+	if err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+	if len(users) != 3 || users[0].Username != "ann" {
+		t.Errorf("expected exact match first, got %v", users)
+	}
+}
+
+func TestUserRepositorySearchExplicitSortSkipsRanking(t *testing.T) {
+	mock := newMockPool(t)
+	mock.ExpectQuery(`SELECT COUNT`).WithArgs("%ann%").
+		WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(0)))
+	mock.ExpectQuery(`ORDER BY username ASC NULLS FIRST, id ASC\s+LIMIT \$2 OFFSET \$3`).
+		WithArgs("%ann%", 20, 0).
+		WillReturnRows(userRows())
+
+	if _, _, err := NewUserRepository(mock).Search(context.Background(), repository.UserFilter{Search: "ann", SortBy: "username", Limit: 20}); err != nil {
+		t.Fatalf("failed to search: %v", err)
+	}
+}
+
+func TestUserRepositorySearchNeverChangedPassword(t *testing.T) {
+	tests := []struct {
+		never bool
+		want  string
+	}{
+		{true, `WHERE deleted_at IS NULL AND is_active = $1 AND (password_changed_at IS NULL OR password_changed_at = created_at)`},
+		{false, `WHERE deleted_at IS NULL AND is_active = $1 AND NOT (password_changed_at IS NULL OR password_changed_at = created_at)`},
+	}
+
+	for _, tt := range tests {
+		mock := newMockPool(t)
+		mock.ExpectQuery(regexp.QuoteMeta(`SELECT COUNT(*) FROM users ` + tt.want)).WithArgs(true).
+			WillReturnRows(pgxmock.NewRows([]string{"count"}).AddRow(int64(0)))
+		mock.ExpectQuery(regexp.QuoteMeta(tt.want)).WithArgs(true, 20, 0).
+			WillReturnRows(userRows())
+
+		active, never := true, tt.never
+		filter := repository.UserFilter{IsActive: &active, NeverChangedPassword: &never, Limit: 20}
+		if _, _, err := NewUserRepository(mock).Search(context.Background(), filter); err != nil {
+			t.Fatalf("never changed %v: failed to search: %v", tt.never, err)
+		}
+	}
+}
+
+// usersWithIDs returns users numbered by ids
+func usersWithIDs(ids ...uint) []*entities.User {
+	users := make([]*entities.User, len(ids))
+	for i, id := range ids {
+		users[i] = &entities.User{ID: id, Username: fmt.Sprintf("user%d", id)}
+	}
+	return users
+}
+
+func TestUserRepositoryIterateBatchesPastOneBatch(t *testing.T) {
+	mock := newMockPool(t)
+	// Each batch continues after the last ID of the previous one
+	mock.ExpectQuery(`id > \$1\s+ORDER BY id\s+LIMIT \$2`).WithArgs(uint(0), 2).WillReturnRows(userRows(usersWithIDs(1, 2)...))
+	mock.ExpectQuery(`id > \$1`).WithArgs(uint(2), 2).WillReturnRows(userRows(usersWithIDs(3, 4)...))
+	mock.ExpectQuery(`id > \$1`).WithArgs(uint(4), 2).WillReturnRows(userRows(usersWithIDs(5)...))
+
+	var batches [][]uint
+	err := NewUserRepository(mock).Iterate(context.Background(), 2, func(users []*entities.User) error {
+		var ids []uint
+		for _, u := range users {
+			ids = append(ids, u.ID)
+		}
+		batches = append(batches, ids)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("failed to iterate: %v", err)
+	}
+	if fmt.Sprint(batches) != "[[1 2] [3 4] [5]]" {
+		t.Errorf("unexpected batches %v", batches)
+	}
+}
+
+func TestUserRepositoryIterateStopsWhenCancelled(t *testing.T) {
+	mock := newMockPool(t)
+	mock.ExpectQuery(`id > \$1`).WithArgs(uint(0), 2).WillReturnRows(userRows(usersWithIDs(1, 2)...))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	calls := 0
+	err := NewUserRepository(mock).Iterate(ctx, 2, func(users []*entities.User) error {
+		calls++
+		cancel()
+		return nil
+	})
+	if !errors.Is(err, context.Canceled) || calls != 1 {
+		t.Errorf("expected cancellation after one batch, got %v after %d", err, calls)
+	}
+}
+
+// anyArgs matches n arguments of any value
+func anyArgs(n int) []interface{} {
+	args := make([]interface{}, n)
+	for i := range args {
+		args[i] = pgxmock.AnyArg()
+	}
+	return args
+}
+
+func TestUserRepositoryUniqueViolations(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"username", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: "users_username_key"}, entities.ErrUserAlreadyExists},
+		{"email", &pgconn.PgError{Code: pgUniqueViolation, ConstraintName: emailUniqueIndex}, entities.ErrEmailAlreadyExists},
+		{"other constraint", &pgconn.PgError{Code: "23503"}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newMockPool(t)
+			repo := NewUserRepository(mock)
+			mock.ExpectQuery(`INSERT INTO users`).WithArgs(anyArgs(9)...).WillReturnError(tt.err)
+			mock.ExpectQuery(`UPDATE users SET`).WithArgs(anyArgs(14)...).WillReturnError(tt.err)
+
+			user := &entities.User{ID: 7, Username: "alice", Role: entities.RoleUser, Version: 1}
+			createErr := repo.Create(context.Background(), user)
+			updateErr := repo.Update(context.Background(), user)
+
+			for op, err := range map[string]error{"create": createErr, "update": updateErr} {
+				if tt.want != nil && err != tt.want {
+					t.Errorf("%s: expected %v, got %v", op, tt.want, err)
+				}
+				// Other errors stay wrapped for the caller to log
+				if tt.want == nil && (err == nil || errors.Is(err, entities.ErrUserAlreadyExists) || !errors.Is(err, tt.err)) {
+					t.Errorf("%s: expected the original error wrapped, got %v", op, err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,68 @@
+package hasher
+
+import (
+	"fmt"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+// Supported hashing algorithms for New
+const (
+	AlgoBcrypt   = "bcrypt"
+	AlgoArgon2id = "argon2id"
+)
+
+// algorithm is a single hashing scheme that recognizes its own encoded values
+type algorithm interface {
+	Hash(password string) (string, error)
+	Verify(password, encoded string) bool
+	Matches(encoded string) bool
+}
+
+// Hasher implements entities.PasswordHasher, hashing with the configured
+// algorithm and verifying with whichever algorithm produced the hash
+type Hasher struct {
+	primary    algorithm
+	algorithms []algorithm
+}
+
+// New creates new password hasher for the given algorithm
+func New(algo string, bcryptCost int) (entities.PasswordHasher, error) {
+	bcryptHasher := NewBcryptHasher(bcryptCost)
+	argon2idHasher := NewArgon2idHasher()
+
+	var primary algorithm
+	switch algo {
+	case AlgoBcrypt, "":
+		primary = bcryptHasher
+	case AlgoArgon2id:
+		primary = argon2idHasher
+	default:
+		return nil, fmt.Errorf("unsupported hash algorithm: %s", algo)
+	}
+
+	return &Hasher{
+		primary:    primary,
+		algorithms: []algorithm{bcryptHasher, argon2idHasher},
+	}, nil
+}
+
+// Hash encodes password with the configured algorithm
+func (h *Hasher) Hash(password string) (string, error) {
+	return h.primary.Hash(password)
+}
+
+// Verify checks password against an encoded hash of any supported algorithm
+func (h *Hasher) Verify(password, encoded string) bool {
+	for _, algo := range h.algorithms {
+		if algo.Matches(encoded) {
+			return algo.Verify(password, encoded)
+		}
+	}
+	return false
+}
+
+// NeedsRehash checks if encoded hash was produced by a different algorithm than the configured one
+func (h *Hasher) NeedsRehash(encoded string) bool {
+	return !h.primary.Matches(encoded)
+}
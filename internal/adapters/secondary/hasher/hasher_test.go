@@ -0,0 +1,41 @@
+package hasher
+
+import (
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestBcryptHasherUsesConfiguredCost(t *testing.T) {
+	h, err := New(AlgoBcrypt, bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to create hasher: %v", err)
+	}
+
+	hashed, err := h.Hash("Secret123!")
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+	if cost, err := bcrypt.Cost([]byte(hashed)); err != nil || cost != bcrypt.MinCost {
+		t.Errorf("expected cost %d, got %d, %v", bcrypt.MinCost, cost, err)
+	}
+}
+
+func TestBcryptHashesOfOtherCostsStillVerify(t *testing.T) {
+	// The cost is encoded in the hash, so changing the configured cost keeps old hashes working
+	old, err := bcrypt.GenerateFromPassword([]byte("Secret123!"), bcrypt.MinCost+1)
+	if err != nil {
+		t.Fatalf("failed to hash: %v", err)
+	}
+
+	h, _ := New(AlgoBcrypt, bcrypt.MinCost)
+	if !h.Verify("Secret123!", string(old)) {
+		t.Errorf("expected hash of another cost to verify")
+	}
+	if h.Verify("wrong", string(old)) {
+		t.Errorf("expected wrong password to be rejected")
+	}
+	if h.NeedsRehash(string(old)) {
+		t.Errorf("expected bcrypt hash of another cost not to need rehashing")
+	}
+}
@@ -1,7 +1,16 @@
 package jwt
 
 import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"math/big"
+	"os"
 	"time"
 
 	"github.com/ontair/admin-panel/internal/core/entities"
@@ -16,6 +25,38 @@ type JWTService struct {
 	config        *config.Config
 	accessClaims  map[string]interface{}
 	refreshClaims map[string]interface{}
+
+	method      jwt.SigningMethod
+	accessKeys  *keyRing
+	refreshKeys *keyRing
+}
+
+// keyRing holds the signing key and all keys accepted when verifying one token type
+type keyRing struct {
+	sign   interface{}
+	keyIDs []string               // the current key first, then keys rotated out
+	verify map[string]interface{} // verification keys by kid
+}
+
+// newKeyRing creates ring signing with key identified by keyID
+func newKeyRing(sign interface{}, keyID string, verify interface{}) *keyRing {
+	ring := &keyRing{sign: sign, verify: make(map[string]interface{})}
+	ring.add(keyID, verify)
+	return ring
+}
+
+// add registers a verification key, duplicates are ignored
+func (r *keyRing) add(keyID string, verify interface{}) {
+	if _, ok := r.verify[keyID]; ok {
+		return
+	}
+	r.keyIDs = append(r.keyIDs, keyID)
+	r.verify[keyID] = verify
+}
+
+// currentKeyID returns kid of the signing key
+func (r *keyRing) currentKeyID() string {
+	return r.keyIDs[0]
 }
 
 // Claims represent JWT claims
@@ -27,9 +68,9 @@ type Claims struct {
 	Type     string `json:"type"` // "access" or "refresh"
 }
 
-// NewJWTService creates new JWT service
-func NewJWTService(config *config.Config) *JWTService {
-	return &JWTService{
+// NewJWTService creates new JWT service signing with the configured algorithm
+func NewJWTService(config *config.Config) (*JWTService, error) {
+	s := &JWTService{
 		config: config,
 		accessClaims: map[string]interface{}{
 			"type": "access",
@@ -38,17 +79,143 @@ func NewJWTService(config *config.Config) *JWTService {
 			"type": "refresh",
 		},
 	}
+
+	switch config.JWT.Algorithm {
+	case "", "HS256":
+		s.method = jwt.SigningMethodHS256
+		s.accessKeys = newHMACKeyRing(config.JWT.SecretKey, config.JWT.PreviousSecretKeys)
+		s.refreshKeys = newHMACKeyRing(config.JWT.RefreshSecret, config.JWT.PreviousRefreshSecrets)
+	case "RS256":
+		privateKey, publicKey, err := loadRSAKeys(config.JWT.PrivateKeyFile, config.JWT.PublicKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		ring := newKeyRing(privateKey, rsaKeyID(publicKey), publicKey)
+
+		// Keys of previous signing pairs still verify tokens issued before rotation
+		for _, file := range config.JWT.PreviousPublicKeyFiles {
+			previousKey, err := loadRSAPublicKey(file)
+			if err != nil {
+				return nil, err
+			}
+			ring.add(rsaKeyID(previousKey), previousKey)
+		}
+
+		// Both token types share the key pair, the type claim keeps them apart
+		s.method = jwt.SigningMethodRS256
+		s.accessKeys = ring
+		s.refreshKeys = ring
+	default:
+		return nil, fmt.Errorf("unsupported JWT algorithm %q", config.JWT.Algorithm)
+	}
+
+	return s, nil
+}
+
+// loadRSAKeys reads PEM keys, the public key is derived from the private one if no file is given
+func loadRSAKeys(privateKeyFile, publicKeyFile string) (*rsa.PrivateKey, *rsa.PublicKey, error) {
+	privatePEM, err := os.ReadFile(privateKeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read JWT private key: %w", err)
+	}
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privatePEM)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse JWT private key: %w", err)
+	}
+
+	if publicKeyFile == "" {
+		return privateKey, &privateKey.PublicKey, nil
+	}
+
+	publicKey, err := loadRSAPublicKey(publicKeyFile)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !publicKey.Equal(&privateKey.PublicKey) {
+		return nil, nil, fmt.Errorf("JWT public key does not match the private key")
+	}
+
+	return privateKey, publicKey, nil
+}
+
+// loadRSAPublicKey reads a PEM public key
+func loadRSAPublicKey(file string) (*rsa.PublicKey, error) {
+	publicPEM, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT public key: %w", err)
+	}
+	publicKey, err := jwt.ParseRSAPublicKeyFromPEM(publicPEM)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse JWT public key %s: %w", file, err)
+	}
+	return publicKey, nil
+}
+
+// newHMACKeyRing creates ring signing with secret and accepting previous secrets
+func newHMACKeyRing(secret string, previousSecrets []string) *keyRing {
+	ring := newKeyRing([]byte(secret), hmacKeyID(secret), []byte(secret))
+	for _, previous := range previousSecrets {
+		ring.add(hmacKeyID(previous), []byte(previous))
+	}
+	return ring
+}
+
+// hmacKeyID derives kid from secret. Tokens already allow offline guessing of
+// the secret, so the truncated hash gives nothing more away.
+func hmacKeyID(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return base64.RawURLEncoding.EncodeToString(sum[:8])
+}
+
+// rsaKeyID returns the RFC 7638 thumbprint of the key, stable across restarts
+func rsaKeyID(publicKey *rsa.PublicKey) string {
+	// Members in lexicographic order without whitespace
+	thumbprint, _ := json.Marshal(struct {
+		E   string `json:"e"`
+		Kty string `json:"kty"`
+		N   string `json:"n"`
+	}{
+		E:   encodeExponent(publicKey.E),
+		Kty: "RSA",
+		N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+	})
+
+	sum := sha256.Sum256(thumbprint)
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// encodeExponent encodes RSA exponent as base64url of its big-endian bytes
+func encodeExponent(e int) string {
+	return base64.RawURLEncoding.EncodeToString(big.NewInt(int64(e)).Bytes())
+}
+
+// signToken signs claims with the current key and sets kid so verifiers can pick the key
+func (s *JWTService) signToken(claims Claims, keys *keyRing) (string, error) {
+	token := jwt.NewWithClaims(s.method, claims)
+	token.Header["kid"] = keys.currentKeyID()
+	return token.SignedString(keys.sign)
 }
 
 // GenerateAccessToken generates access token for user
 func (s *JWTService) GenerateAccessToken(user *entities.User) (string, error) {
+	return s.GenerateAccessTokenWithExpiry(user, time.Now().Add(time.Duration(s.config.JWT.AccessExpiry)*time.Minute))
+}
+
+// GenerateAccessTokenWithExpiry generates access token for user expiring at the given time
+func (s *JWTService) GenerateAccessTokenWithExpiry(user *entities.User, expiresAt time.Time) (string, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
 	now := time.Now()
 	claims := Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    "github.com/ontair/admin-panel",
+			ID:        tokenID,
+			Issuer:    s.config.JWT.Issuer,
 			Subject:   fmt.Sprintf("%d", user.ID),
-			Audience:  []string{"admin-panel-users"},
-			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(s.config.JWT.AccessExpiry) * time.Minute)),
+			Audience:  []string{s.config.JWT.Audience},
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			NotBefore: jwt.NewNumericDate(now),
 			IssuedAt:  jwt.NewNumericDate(now),
 		},
@@ -58,18 +225,23 @@ func (s *JWTService) GenerateAccessToken(user *entities.User) (string, error) {
 		Type:     "access",
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.config.JWT.SecretKey))
+	return s.signToken(claims, s.accessKeys)
 }
 
 // GenerateRefreshToken generates refresh token for user
 func (s *JWTService) GenerateRefreshToken(user *entities.User) (string, error) {
+	tokenID, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
 	now := time.Now()
 	claims := Claims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			Issuer:    "github.com/ontair/admin-panel",
+			ID:        tokenID,
+			Issuer:    s.config.JWT.Issuer,
 			Subject:   fmt.Sprintf("%d", user.ID),
-			Audience:  []string{"admin-panel-users"},
+			Audience:  []string{s.config.JWT.Audience},
 			ExpiresAt: jwt.NewNumericDate(now.Add(time.Duration(s.config.JWT.RefreshExpiry) * time.Minute)),
 			NotBefore: jwt.NewNumericDate(now),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -80,46 +252,90 @@ func (s *JWTService) GenerateRefreshToken(user *entities.User) (string, error) {
 		Type:     "refresh",
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.config.JWT.RefreshSecret))
+	return s.signToken(claims, s.refreshKeys)
 }
 
 // ParseAccessToken parses and validates access token
 func (s *JWTService) ParseAccessToken(tokenString string) (*jwt.Token, error) {
-	return s.parseToken(tokenString, s.config.JWT.SecretKey, "access")
+	return s.parseToken(tokenString, s.accessKeys, "access")
 }
 
 // ParseRefreshToken parses and validates refresh token
 func (s *JWTService) ParseRefreshToken(tokenString string) (*jwt.Token, error) {
-	return s.parseToken(tokenString, s.config.JWT.RefreshSecret, "refresh")
+	return s.parseToken(tokenString, s.refreshKeys, "refresh")
 }
 
-// parseToken parses token with specified secret and type
-func (s *JWTService) parseToken(tokenString, secret, expectedType string) (*jwt.Token, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		// Validate signing method
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+// newTokenID generates a random jti so individual tokens can be revoked
+func newTokenID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate token id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// parseToken parses token with specified keys and type.
+// Tokens with kid are verified by that key only, tokens without it are tried
+// against the current key first and then against keys rotated out.
+// Issuer and audience must match the configured ones so tokens of other services are rejected.
+// Failures are reported as ErrTokenExpired or ErrInvalidToken.
+func (s *JWTService) parseToken(tokenString string, keys *keyRing, expectedType string) (*jwt.Token, error) {
+	parse := func(defaultKeyID string) (*jwt.Token, error) {
+		return jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+			// Validate signing method, only the configured one is accepted
+			if token.Method.Alg() != s.method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+			}
+
+			keyID, ok := token.Header["kid"].(string)
+			if !ok {
+				keyID = defaultKeyID
+			}
+			key, ok := keys.verify[keyID]
+			if !ok {
+				return nil, fmt.Errorf("unknown key id: %s", keyID)
+			}
+			return key, nil
+		},
+			jwt.WithValidMethods([]string{s.method.Alg()}),
+			jwt.WithIssuer(s.config.JWT.Issuer),
+			jwt.WithAudience(s.config.JWT.Audience),
+		)
+	}
+
+	token, err := parse(keys.currentKeyID())
+	for _, keyID := range keys.keyIDs[1:] {
+		if !errors.Is(err, jwt.ErrTokenSignatureInvalid) || token == nil || token.Header["kid"] != nil {
+			break
 		}
-		return []byte(secret), nil
-	})
+		token, err = parse(keyID)
+	}
 
 	if err != nil {
-		return nil, err
+		return nil, tokenError(err)
 	}
 
 	// Validate token type
 	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
 		if tokenType, ok := claims["type"].(string); !ok || tokenType != expectedType {
-			return nil, fmt.Errorf("invalid token type: expected %s, got %s", expectedType, tokenType)
+			return nil, fmt.Errorf("%w: expected type %s, got %s", entities.ErrInvalidToken, expectedType, tokenType)
 		}
 	} else {
-		return nil, fmt.Errorf("invalid token")
+		return nil, entities.ErrInvalidToken
 	}
 
 	return token, nil
 }
 
+// tokenError maps jwt library errors to ErrTokenExpired, which callers may refresh,
+// or ErrInvalidToken, keeping the original error in the chain
+func tokenError(err error) error {
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return fmt.Errorf("%w: %w", entities.ErrTokenExpired, err)
+	}
+	return fmt.Errorf("%w: %w", entities.ErrInvalidToken, err)
+}
+
 // ExtractUserFromToken extracts user information from token
 func (s *JWTService) ExtractUserFromToken(token *jwt.Token) (*service.UserInfo, error) {
 	claims, ok := token.Claims.(jwt.MapClaims)
@@ -145,10 +361,31 @@ func (s *JWTService) ExtractUserFromToken(token *jwt.Token) (*service.UserInfo,
 	return &service.UserInfo{
 		UserID:   uint(userIDFloat),
 		Username: username,
-		Role:     role,
+		Role:     entities.Role(role),
 	}, nil
 }
 
+// JWKS returns public verification keys, the current signing key first, empty for HMAC algorithms
+func (s *JWTService) JWKS() service.JWKSet {
+	set := service.JWKSet{Keys: []service.JWK{}}
+	for _, keyID := range s.accessKeys.keyIDs {
+		// HMAC secrets are never published
+		publicKey, ok := s.accessKeys.verify[keyID].(*rsa.PublicKey)
+		if !ok {
+			continue
+		}
+		set.Keys = append(set.Keys, service.JWK{
+			Kid: keyID,
+			Kty: "RSA",
+			Use: "sig",
+			Alg: s.method.Alg(),
+			N:   base64.RawURLEncoding.EncodeToString(publicKey.N.Bytes()),
+			E:   encodeExponent(publicKey.E),
+		})
+	}
+	return set
+}
+
 // GetAccessTokenExpiry returns access token expiry in minutes
 func (s *JWTService) GetAccessTokenExpiry() int {
 	return s.config.JWT.AccessExpiry
@@ -223,7 +460,7 @@ func (info *TokenUserInfo) IsAdmin() bool {
 
 // IsManagerOrHigher checks if user has manager privileges or higher
 func (info *TokenUserInfo) IsManagerOrHigher() bool {
-	return info.Role == entities.RoleAdmin || info.Role == entities.RoleManager
+	return info.Role.AtLeast(entities.RoleManager)
 }
 
 // HasRole checks if user has specific role
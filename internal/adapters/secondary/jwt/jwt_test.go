@@ -0,0 +1,250 @@
+package jwt
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/infra/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// testJWTConfig returns an HS256 configuration
+func testJWTConfig() config.JWTConfig {
+	return config.JWTConfig{
+		SecretKey:     "access-secret",
+		RefreshSecret: "refresh-secret",
+		AccessExpiry:  15,
+		RefreshExpiry: 60,
+		Issuer:        "test-issuer",
+		Audience:      "test-audience",
+		Algorithm:     "HS256",
+	}
+}
+
+// newTestService creates the service for jwtConfig
+func newTestService(t *testing.T, jwtConfig config.JWTConfig) *JWTService {
+	t.Helper()
+	s, err := NewJWTService(&config.Config{JWT: jwtConfig})
+	if err != nil {
+		t.Fatalf("failed to create JWT service: %v", err)
+	}
+	return s
+}
+
+var testUser = &entities.User{ID: 7, Username: "alice", Role: entities.RoleUser}
+
+func TestRotatedSecretStillVerifies(t *testing.T) {
+	before := newTestService(t, testJWTConfig())
+	accessToken, _ := before.GenerateAccessToken(testUser)
+	refreshToken, _ := before.GenerateRefreshToken(testUser)
+
+	rotatedConfig := testJWTConfig()
+	rotatedConfig.SecretKey, rotatedConfig.PreviousSecretKeys = "new-access-secret", []string{"access-secret"}
+	rotatedConfig.RefreshSecret, rotatedConfig.PreviousRefreshSecrets = "new-refresh-secret", []string{"refresh-secret"}
+	rotated := newTestService(t, rotatedConfig)
+
+	if _, err := rotated.ParseAccessToken(accessToken); err != nil {
+		t.Errorf("expected access token of the old secret to verify, got %v", err)
+	}
+	if _, err := rotated.ParseRefreshToken(refreshToken); err != nil {
+		t.Errorf("expected refresh token of the old secret to verify, got %v", err)
+	}
+
+	// Signing always uses the current secret
+	newToken, _ := rotated.GenerateAccessToken(testUser)
+	parsed, err := rotated.ParseAccessToken(newToken)
+	if err != nil {
+		t.Fatalf("failed to parse new token: %v", err)
+	}
+	if parsed.Header["kid"] != hmacKeyID("new-access-secret") {
+		t.Errorf("expected kid of the current secret, got %v", parsed.Header["kid"])
+	}
+	if _, err := before.ParseAccessToken(newToken); err == nil {
+		t.Errorf("expected token of the new secret rejected by the old service")
+	}
+}
+
+func TestDroppedSecretNoLongerVerifies(t *testing.T) {
+	accessToken, _ := newTestService(t, testJWTConfig()).GenerateAccessToken(testUser)
+
+	rotatedConfig := testJWTConfig()
+	rotatedConfig.SecretKey = "new-access-secret"
+
+	if _, err := newTestService(t, rotatedConfig).ParseAccessToken(accessToken); !errors.Is(err, entities.ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+// Tokens issued before kid was set are tried against every key
+func TestRotatedSecretVerifiesTokenWithoutKeyID(t *testing.T) {
+	claims := jwt.MapClaims{
+		"iss":  "test-issuer",
+		"aud":  "test-audience",
+		"exp":  time.Now().Add(time.Minute).Unix(),
+		"type": "access",
+	}
+	legacyToken, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("access-secret"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+
+	rotatedConfig := testJWTConfig()
+	rotatedConfig.SecretKey, rotatedConfig.PreviousSecretKeys = "new-access-secret", []string{"older-secret", "access-secret"}
+
+	if _, err := newTestService(t, rotatedConfig).ParseAccessToken(legacyToken); err != nil {
+		t.Errorf("expected token without kid to verify by a previous secret, got %v", err)
+	}
+}
+
+// writeRSAKey writes a new private key and its public key as PEM files and returns their paths
+func writeRSAKey(t *testing.T, dir, name string) (string, string) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	publicDER, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	if err != nil {
+		t.Fatalf("failed to marshal public key: %v", err)
+	}
+
+	privateFile, publicFile := filepath.Join(dir, name+".pem"), filepath.Join(dir, name+".pub.pem")
+	privatePEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicPEM := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicDER})
+	if err := os.WriteFile(privateFile, privatePEM, 0o600); err != nil {
+		t.Fatalf("failed to write private key: %v", err)
+	}
+	if err := os.WriteFile(publicFile, publicPEM, 0o600); err != nil {
+		t.Fatalf("failed to write public key: %v", err)
+	}
+	return privateFile, publicFile
+}
+
+func TestRotatedRSAKeyStillVerifies(t *testing.T) {
+	dir := t.TempDir()
+	oldPrivate, oldPublic := writeRSAKey(t, dir, "old")
+	newPrivate, _ := writeRSAKey(t, dir, "new")
+
+	oldConfig := testJWTConfig()
+	oldConfig.Algorithm, oldConfig.PrivateKeyFile = "RS256", oldPrivate
+	accessToken, _ := newTestService(t, oldConfig).GenerateAccessToken(testUser)
+
+	rotatedConfig := oldConfig
+	rotatedConfig.PrivateKeyFile, rotatedConfig.PreviousPublicKeyFiles = newPrivate, []string{oldPublic}
+	rotated := newTestService(t, rotatedConfig)
+
+	if _, err := rotated.ParseAccessToken(accessToken); err != nil {
+		t.Errorf("expected token of the old key pair to verify, got %v", err)
+	}
+	if keys := rotated.JWKS().Keys; len(keys) != 2 {
+		t.Errorf("expected both keys published, got %d", len(keys))
+	}
+}
+
+func TestTokensCarryConfiguredIssuerAndAudience(t *testing.T) {
+	s := newTestService(t, testJWTConfig())
+	token, _ := s.GenerateAccessToken(testUser)
+
+	parsed, err := s.ParseAccessToken(token)
+	if err != nil {
+		t.Fatalf("failed to parse token: %v", err)
+	}
+	issuer, _ := parsed.Claims.GetIssuer()
+	audience, _ := parsed.Claims.GetAudience()
+	if issuer != "test-issuer" || len(audience) != 1 || audience[0] != "test-audience" {
+		t.Errorf("expected configured issuer and audience, got %q %v", issuer, audience)
+	}
+}
+
+func TestMismatchedIssuerOrAudienceRejected(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(*config.JWTConfig)
+	}{
+		{"issuer", func(c *config.JWTConfig) { c.Issuer = "other-service" }},
+		{"audience", func(c *config.JWTConfig) { c.Audience = "other-audience" }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Same secrets, so only the claims differ
+			otherConfig := testJWTConfig()
+			tt.modify(&otherConfig)
+			other := newTestService(t, otherConfig)
+			accessToken, _ := other.GenerateAccessToken(testUser)
+			refreshToken, _ := other.GenerateRefreshToken(testUser)
+
+			s := newTestService(t, testJWTConfig())
+			if _, err := s.ParseAccessToken(accessToken); !errors.Is(err, entities.ErrInvalidToken) {
+				t.Errorf("expected access token rejected, got %v", err)
+			}
+			if _, err := s.ParseRefreshToken(refreshToken); !errors.Is(err, entities.ErrInvalidToken) {
+				t.Errorf("expected refresh token rejected, got %v", err)
+			}
+		})
+	}
+}
+
+// signedAccessToken signs an access token for testUser with the test secret, adjusted by modify
+func signedAccessToken(t *testing.T, modify func(jwt.MapClaims)) string {
+	t.Helper()
+	claims := jwt.MapClaims{
+		"iss":      "test-issuer",
+		"aud":      "test-audience",
+		"exp":      time.Now().Add(time.Minute).Unix(),
+		"type":     "access",
+		"user_id":  testUser.ID,
+		"username": testUser.Username,
+		"role":     string(testUser.Role),
+	}
+	modify(claims)
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("access-secret"))
+	if err != nil {
+		t.Fatalf("failed to sign token: %v", err)
+	}
+	return token
+}
+
+func TestParseTokenErrors(t *testing.T) {
+	s := newTestService(t, testJWTConfig())
+
+	tests := []struct {
+		name    string
+		token   string
+		want    error
+		expired bool
+	}{
+		{"expired", signedAccessToken(t, func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Minute).Unix() }), entities.ErrTokenExpired, true},
+		{"not yet valid", signedAccessToken(t, func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(time.Hour).Unix() }), entities.ErrInvalidToken, false},
+		{"malformed", "not.a.token", entities.ErrInvalidToken, false},
+		{"empty", "", entities.ErrInvalidToken, false},
+		{"wrong type", signedAccessToken(t, func(c jwt.MapClaims) { c["type"] = "refresh" }), entities.ErrInvalidToken, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.ParseAccessToken(tt.token)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, err)
+			}
+			// Only expired tokens may be refreshed
+			if errors.Is(err, entities.ErrTokenExpired) != tt.expired {
+				t.Errorf("expected expired %v, got %v", tt.expired, err)
+			}
+		})
+	}
+
+	if _, err := s.ParseAccessToken(signedAccessToken(t, func(jwt.MapClaims) {})); err != nil {
+		t.Errorf("expected a valid token accepted, got %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/ontair/admin-panel/internal/core/ports/service"
+)
+
+// Registry implements port.Metrics interface with client_golang collectors
+// and serves them through promhttp
+type Registry struct {
+	registry *prometheus.Registry
+	handler  http.Handler
+
+	requests  *prometheus.CounterVec
+	latencies *prometheus.HistogramVec
+	logins    *prometheus.CounterVec
+	refreshes *prometheus.CounterVec
+}
+
+// NewRegistry creates metrics registry with its own collectors, so several
+// registries don't clash on the global default one
+func NewRegistry() *Registry {
+	r := &Registry{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_requests_total",
+			Help: "Handled HTTP requests by route, method and status.",
+		}, []string{"method", "route", "status"}),
+		latencies: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "HTTP request latency by route, method and status.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"method", "route", "status"}),
+		logins: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_login_attempts_total",
+			Help: "Login attempts by result.",
+		}, []string{"result"}),
+		refreshes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_token_refreshes_total",
+			Help: "Refresh token exchanges by result.",
+		}, []string{"result"}),
+	}
+
+	r.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		r.requests,
+		r.latencies,
+		r.logins,
+		r.refreshes,
+	)
+	r.handler = promhttp.HandlerFor(r.registry, promhttp.HandlerOpts{Registry: r.registry})
+	return r
+}
+
+var _ service.Metrics = (*Registry)(nil)
+
+// RegisterGauge adds a gauge whose value is read on every scrape, e.g. from pool stats.
+// It must be called at startup, before the registry is served.
+func (r *Registry) RegisterGauge(name, help string, read func() float64) {
+	r.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Name: name,
+		Help: help,
+	}, read))
+}
+
+// ObserveRequest records a handled HTTP request
+func (r *Registry) ObserveRequest(route, method string, status int, duration time.Duration) {
+	code := strconv.Itoa(status)
+	r.requests.WithLabelValues(method, route, code).Inc()
+	r.latencies.WithLabelValues(method, route, code).Observe(duration.Seconds())
+}
+
+// LoginAttempt counts a login by outcome
+func (r *Registry) LoginAttempt(success bool) {
+	r.logins.WithLabelValues(result(success)).Inc()
+}
+
+// TokenRefresh counts a refresh token exchange by outcome
+func (r *Registry) TokenRefresh(success bool) {
+	r.refreshes.WithLabelValues(result(success)).Inc()
+}
+
+// ServeHTTP writes all metrics in the Prometheus exposition format
+func (r *Registry) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.handler.ServeHTTP(w, req)
+}
+
+func result(success bool) string {
+	if success {
+		return "success"
+	}
+	return "failure"
+}
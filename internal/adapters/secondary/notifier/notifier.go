@@ -0,0 +1,30 @@
+package notifier
+
+import (
+	"context"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/service"
+	"go.uber.org/zap"
+)
+
+// LogNotifier implements port.Notifier interface by writing notifications to the application log
+type LogNotifier struct {
+	logger service.Logger
+}
+
+// NewLogNotifier creates new log-based notifier
+func NewLogNotifier(logger service.Logger) service.Notifier {
+	return &LogNotifier{
+		logger: logger,
+	}
+}
+
+// NotifyPendingApproval informs admins that a user account awaits approval
+func (n *LogNotifier) NotifyPendingApproval(ctx context.Context, user *entities.User) {
+	n.logger.Info("User account is pending admin approval",
+		zap.Uint("userID", user.ID),
+		zap.String("username", user.Username),
+		zap.String("role", string(user.Role)),
+	)
+}
@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Config holds Redis connection settings
+type Config struct {
+	Addr     string
+	Password string
+	DB       int
+}
+
+// Client wraps go-redis client shared by Redis-backed stores
+type Client struct {
+	rdb *goredis.Client
+}
+
+// NewClient creates client and checks the server is reachable
+func NewClient(ctx context.Context, config Config) (*Client, error) {
+	client := &Client{
+		rdb: goredis.NewClient(&goredis.Options{
+			Addr:     config.Addr,
+			Password: config.Password,
+			DB:       config.DB,
+		}),
+	}
+	if err := client.Ping(ctx); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("redis at %s is unreachable: %w", config.Addr, err)
+	}
+	return client, nil
+}
+
+// Ping checks the connection to Redis
+func (c *Client) Ping(ctx context.Context) error {
+	return c.rdb.Ping(ctx).Err()
+}
+
+// Close closes all pooled connections
+func (c *Client) Close() {
+	c.rdb.Close()
+}
@@ -0,0 +1,73 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+)
+
+const rateLimitPrefix = "rate_limit:"
+
+// tokenBucketScript refills and takes a token atomically.
+// Returns {allowed, whole tokens left, milliseconds until the next token, milliseconds until full}.
+const tokenBucketScript = `
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+
+local bucket = redis.call('HMGET', KEYS[1], 'tokens', 'ts')
+local tokens = tonumber(bucket[1]) or burst
+local ts = tonumber(bucket[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) / 1000 * rate)
+
+local allowed, wait = 0, 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+else
+	wait = math.ceil((1 - tokens) / rate * 1000)
+end
+
+local reset = math.ceil((burst - tokens) / rate * 1000)
+redis.call('HMSET', KEYS[1], 'tokens', tostring(tokens), 'ts', now)
+redis.call('PEXPIRE', KEYS[1], math.ceil(burst / rate * 1000))
+return {allowed, math.floor(tokens), wait, reset}`
+
+var tokenBucket = goredis.NewScript(tokenBucketScript)
+
+// RateLimitStore implements RateLimitStore interface shared by all instances
+type RateLimitStore struct {
+	client *Client
+}
+
+// NewRateLimitStore creates new rate limit store
+func NewRateLimitStore(client *Client) repository.RateLimitStore {
+	return &RateLimitStore{
+		client: client,
+	}
+}
+
+// Allow takes a token from the bucket for key
+func (s *RateLimitStore) Allow(ctx context.Context, key string, rate float64, burst int) (repository.RateLimitResult, error) {
+	values, err := tokenBucket.Run(ctx, s.client.rdb, []string{rateLimitPrefix + key},
+		rate, burst, time.Now().UnixMilli(),
+	).Int64Slice()
+	if err != nil {
+		return repository.RateLimitResult{}, fmt.Errorf("failed to check rate limit: %w", err)
+	}
+	if len(values) != 4 {
+		return repository.RateLimitResult{}, fmt.Errorf("unexpected rate limit reply: %v", values)
+	}
+	allowed, remaining, wait, reset := values[0], values[1], values[2], values[3]
+
+	return repository.RateLimitResult{
+		Allowed:    allowed == 1,
+		Remaining:  int(remaining),
+		RetryAfter: time.Duration(wait) * time.Millisecond,
+		Reset:      time.Duration(reset) * time.Millisecond,
+	}, nil
+}
@@ -0,0 +1,52 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+)
+
+const blacklistPrefix = "token_blacklist:"
+
+// TokenBlacklist implements TokenBlacklist interface using Redis keys that expire with the token
+type TokenBlacklist struct {
+	client *Client
+}
+
+// NewTokenBlacklist creates new token blacklist
+func NewTokenBlacklist(client *Client) repository.TokenBlacklist {
+	return &TokenBlacklist{
+		client: client,
+	}
+}
+
+// Add revokes token with given jti until its expiry
+func (b *TokenBlacklist) Add(ctx context.Context, jti string, exp time.Time) error {
+	ttl := time.Until(exp)
+	if ttl <= 0 {
+		// Expired tokens are rejected anyway
+		return nil
+	}
+
+	if err := b.client.rdb.Set(ctx, blacklistPrefix+jti, "1", ttl).Err(); err != nil {
+		return fmt.Errorf("failed to blacklist token: %w", err)
+	}
+	return nil
+}
+
+// IsBlacklisted checks if token with given jti was revoked.
+// Lookup failures count as revoked so a Redis outage can't revive a token.
+func (b *TokenBlacklist) IsBlacklisted(ctx context.Context, jti string) bool {
+	count, err := b.client.rdb.Exists(ctx, blacklistPrefix+jti).Result()
+	if err != nil {
+		return true
+	}
+	return count > 0
+}
+
+// DeleteExpired is a no-op, Redis expires entries itself
+func (b *TokenBlacklist) DeleteExpired(ctx context.Context) (int64, error) {
+	return 0, nil
+}
@@ -25,12 +25,68 @@ func NewAPIError(code int, message string, details string) *APIError {
 	}
 }
 
+// FieldError describes a request field that failed a validation rule
+type FieldError struct {
+	Field string `json:"field"`           // JSON name of the field
+	Rule  string `json:"rule"`            // failed validate tag, e.g. required or min
+	Param string `json:"param,omitempty"` // rule parameter, e.g. 8 for min=8
+}
+
+// ValidationErrorResponse represents 400/422 response for a request body that could not be accepted.
+// Fields maps each offending field's JSON name to a human readable message.
+type ValidationErrorResponse struct {
+	*APIError
+	Errors []FieldError      `json:"errors,omitempty"`
+	Fields map[string]string `json:"fields"`
+}
+
+// NewValidationError creates 422 response for failed fields
+func NewValidationError(errs []FieldError, fields map[string]string) *ValidationErrorResponse {
+	return &ValidationErrorResponse{
+		APIError: NewAPIError(http.StatusUnprocessableEntity, "Validation Failed", "One or more fields are invalid"),
+		Errors:   errs,
+		Fields:   fields,
+	}
+}
+
+// NewWeakPasswordError creates 400 response naming the password policy requirement field misses
+func NewWeakPasswordError(field, requirement string) *ValidationErrorResponse {
+	return &ValidationErrorResponse{
+		APIError: NewAPIError(http.StatusBadRequest, "Password is too weak", "Password "+requirement),
+		Fields:   map[string]string{field: requirement},
+	}
+}
+
+// NewBindError creates 400 response for a malformed request body.
+// Fields is empty when the body is not valid JSON at all.
+func NewBindError(fields map[string]string) *ValidationErrorResponse {
+	return &ValidationErrorResponse{
+		APIError: NewAPIError(http.StatusBadRequest, "Bad Request", ""),
+		Fields:   fields,
+	}
+}
+
 // Common API errors
 var (
 	// HTTP 400
 	ErrBadRequest         = NewAPIError(http.StatusBadRequest, "Bad Request", "")
 	ErrValidationFailed   = NewAPIError(http.StatusBadRequest, "Validation Failed", "")
 	ErrInvalidCredentials = NewAPIError(http.StatusBadRequest, "Invalid credentials", "")
+	ErrNumericUsername    = NewAPIError(http.StatusBadRequest, "Username must not be purely numeric", "")
+	ErrSearchTooLong      = NewAPIError(http.StatusBadRequest, "Search query is too long", "")
+	ErrInvalidRole        = NewAPIError(http.StatusBadRequest, "Invalid role", "")
+	ErrInvalidBulkSize    = NewAPIError(http.StatusBadRequest, "Invalid number of users", "Between 1 and 100 users can be processed at once")
+	ErrInvalidSchedule    = NewAPIError(http.StatusBadRequest, "Invalid schedule", "Scheduled time must be an RFC 3339 timestamp in the future")
+	ErrInvalidBoolParam   = NewAPIError(http.StatusBadRequest, "Invalid query parameter", "Boolean parameters accept true/false, 1/0 or yes/no")
+	ErrInvalidResetToken  = NewAPIError(http.StatusBadRequest, "Invalid reset token", "Token is unknown, expired or already used")
+	ErrInvalidVerifyToken = NewAPIError(http.StatusBadRequest, "Invalid verification token", "Token is unknown, expired, already used or was sent to a previous email")
+	ErrEmailMissing       = NewAPIError(http.StatusBadRequest, "No email", "User has no email to verify")
+	ErrPasswordUnchanged  = NewAPIError(http.StatusBadRequest, "Password unchanged", "New password must differ from the current one")
+	ErrPasswordReused     = NewAPIError(http.StatusBadRequest, "Password reused", "New password must differ from recently used ones")
+	ErrInvalidTempRole    = NewAPIError(http.StatusBadRequest, "Invalid temporary role", "Role must differ from the current one and expire in the future")
+	ErrInvalidSortParam   = NewAPIError(http.StatusBadRequest, "Invalid sort parameter", "Sort by username, created_at, last_login or role in asc or desc order")
+	ErrInvalidDateParam   = NewAPIError(http.StatusBadRequest, "Invalid date parameter", "Dates must be RFC 3339 timestamps and created_from must not be after created_to")
+	ErrInvalidStatsRange  = NewAPIError(http.StatusBadRequest, "Invalid stats range", "interval must be hour, day or week; days from 1 to 366, at most 31 for hour")
 
 	// HTTP 401
 	ErrUnauthorized = NewAPIError(http.StatusUnauthorized, "Unauthorized", "")
@@ -40,14 +96,25 @@ var (
 	// HTTP 403
 	ErrForbidden              = NewAPIError(http.StatusForbidden, "Forbidden", "")
 	ErrInsufficientPrivileges = NewAPIError(http.StatusForbidden, "Insufficient privileges", "")
+	ErrAdminQuotaExceeded     = NewAPIError(http.StatusForbidden, "Admin quota exceeded", "Maximum number of active admins reached")
+	ErrRoleNotAssignable      = NewAPIError(http.StatusForbidden, "Role not assignable", "Managers may only assign user and guest roles, manager and admin are assigned by admins")
 
 	// HTTP 404
-	ErrNotFound     = NewAPIError(http.StatusNotFound, "Not Found", "")
-	ErrUserNotFound = NewAPIError(http.StatusNotFound, "User not found", "")
+	ErrNotFound        = NewAPIError(http.StatusNotFound, "Not Found", "")
+	ErrUserNotFound    = NewAPIError(http.StatusNotFound, "User not found", "")
+	ErrSessionNotFound = NewAPIError(http.StatusNotFound, "Session not found", "")
 
 	// HTTP 409
-	ErrConflict          = NewAPIError(http.StatusConflict, "Conflict", "")
-	ErrUserAlreadyExists = NewAPIError(http.StatusConflict, "User already exists", "")
+	ErrConflict              = NewAPIError(http.StatusConflict, "Conflict", "")
+	ErrUserModified          = NewAPIError(http.StatusConflict, "User was modified", "User changed since it was read, reload it and retry")
+	ErrUserAlreadyExists     = NewAPIError(http.StatusConflict, "User already exists", "")
+	ErrEmailAlreadyExists    = NewAPIError(http.StatusConflict, "Email already in use", "")
+	ErrEmailVerified         = NewAPIError(http.StatusConflict, "Email already verified", "")
+	ErrDuplicateName         = NewAPIError(http.StatusConflict, "Duplicate name", "Another active user has the same first and last name")
+	ErrNotPendingApproval    = NewAPIError(http.StatusConflict, "User is not pending approval", "")
+	ErrNotScheduled          = NewAPIError(http.StatusConflict, "No deactivation is scheduled", "")
+	ErrCannotRemoveLastAdmin = NewAPIError(http.StatusConflict, "Cannot remove the last admin", "At least one active admin must remain")
+	ErrCannotRemoveSelf      = NewAPIError(http.StatusConflict, "Cannot remove own account", "Admins can't delete, deactivate or demote themselves")
 
 	// HTTP 422
 	ErrUnprocessableEntity = NewAPIError(http.StatusUnprocessableEntity, "Unprocessable Entity", "")
@@ -55,4 +122,7 @@ var (
 	// HTTP 500
 	ErrInternalServer = NewAPIError(http.StatusInternalServerError, "Internal Server Error", "")
 	ErrDatabaseError  = NewAPIError(http.StatusInternalServerError, "Database Error", "")
+
+	// HTTP 503
+	ErrDatabaseTimeout = NewAPIError(http.StatusServiceUnavailable, "Database timeout", "Database did not answer in time, retry later")
 )
@@ -1,6 +1,7 @@
 package dto
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/ontair/admin-panel/internal/core/entities"
@@ -8,21 +9,91 @@ import (
 
 // UserDTO represents user data transfer object
 type UserDTO struct {
-	ID        uint       `json:"id"`
-	Username  string     `json:"username"`
-	FirstName string     `json:"first_name"`
-	LastName  string     `json:"last_name"`
-	Role      string     `json:"role"`
-	IsActive  bool       `json:"is_active"`
-	LastLogin *time.Time `json:"last_login"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	ID             uint       `json:"id"`
+	Username       string     `json:"username"`
+	Email          *string    `json:"email"`
+	EmailVerified  bool       `json:"email_verified"`
+	FirstName      string     `json:"first_name"`
+	LastName       string     `json:"last_name"`
+	Role           string     `json:"role"`
+	IsActive       bool       `json:"is_active"`
+	ApprovalStatus string     `json:"approval_status"`
+	LastLogin      *time.Time `json:"last_login"`
+	DeactivateAt   *time.Time `json:"deactivate_at,omitempty"`
+	TempRole       *string    `json:"temp_role,omitempty"`
+	TempRoleUntil  *time.Time `json:"temp_role_until,omitempty"`
+	MustChangePass bool       `json:"must_change_password"`
+	PassChangedAt  *time.Time `json:"password_changed_at"`
+	Version        int        `json:"version"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	expandRoles bool // serialize roles as RoleDTO objects
+}
+
+// RoleDTO represents role with display metadata
+type RoleDTO struct {
+	Name  string `json:"name"`
+	Label string `json:"label"`
+	Level int    `json:"level"` // -1 for custom roles
+}
+
+// roleLabels holds display names of built-in roles
+var roleLabels = map[entities.Role]string{
+	entities.RoleAdmin:   "Administrator",
+	entities.RoleManager: "Manager",
+	entities.RoleUser:    "User",
+	entities.RoleGuest:   "Guest",
+}
+
+// ToRoleDTO converts role to RoleDTO, custom roles are labeled with their name
+func ToRoleDTO(role entities.Role) RoleDTO {
+	label, ok := roleLabels[role]
+	if !ok {
+		label = string(role)
+	}
+
+	return RoleDTO{
+		Name:  string(role),
+		Label: label,
+		Level: role.Level(),
+	}
+}
+
+// WithExpandedRoles returns copy of the DTO that serializes role and temp_role as RoleDTO objects
+func (d UserDTO) WithExpandedRoles() UserDTO {
+	d.expandRoles = true
+	return d
+}
+
+// MarshalJSON serializes roles as bare strings unless expanded
+func (d UserDTO) MarshalJSON() ([]byte, error) {
+	type plainUserDTO UserDTO
+	if !d.expandRoles {
+		return json.Marshal(plainUserDTO(d))
+	}
+
+	expanded := struct {
+		plainUserDTO
+		Role     RoleDTO  `json:"role"`
+		TempRole *RoleDTO `json:"temp_role,omitempty"`
+	}{
+		plainUserDTO: plainUserDTO(d),
+		Role:         ToRoleDTO(entities.Role(d.Role)),
+	}
+	if d.TempRole != nil {
+		tempRole := ToRoleDTO(entities.Role(*d.TempRole))
+		expanded.TempRole = &tempRole
+	}
+
+	return json.Marshal(expanded)
 }
 
 // UserCreateDTO represents user creation DTO
 type UserCreateDTO struct {
 	Username  string `json:"username" validate:"required,min=3"`
 	Password  string `json:"password" validate:"required,min=8"`
+	Email     string `json:"email"`
 	FirstName string `json:"first_name"`
 	LastName  string `json:"last_name"`
 	Role      string `json:"role"`
@@ -31,16 +102,30 @@ type UserCreateDTO struct {
 
 // UserUpdateDTO represents user update DTO
 type UserUpdateDTO struct {
-	Username  *string `json:"username"`
+	Username  *string `json:"username" validate:"omitempty,min=3"`
+	Email     *string `json:"email"`
 	FirstName *string `json:"first_name"`
 	LastName  *string `json:"last_name"`
 	Role      *string `json:"role"`
 	IsActive  *bool   `json:"is_active"`
+	Version   *int    `json:"version" validate:"omitempty,min=1"` // version from the last read, 409 if the user changed since
+}
+
+// BulkRoleDTO represents bulk role assignment DTO
+type BulkRoleDTO struct {
+	IDs  []uint `json:"ids" validate:"required"`
+	Role string `json:"role" validate:"required"`
+}
+
+// TempRoleDTO represents temporary role grant DTO
+type TempRoleDTO struct {
+	Role      string    `json:"role" validate:"required"`
+	ExpiresAt time.Time `json:"expires_at" validate:"required"`
 }
 
 // LoginDTO represents login DTO
 type LoginDTO struct {
-	Username string `json:"username" validate:"required"`
+	Username string `json:"username" validate:"required"` // username or email
 	Password string `json:"password" validate:"required"`
 }
 
@@ -63,6 +148,12 @@ type ResetPasswordDTO struct {
 	Username string `json:"username" validate:"required"`
 }
 
+// ConfirmPasswordResetDTO represents password reset confirmation DTO
+type ConfirmPasswordResetDTO struct {
+	Token       string `json:"token" validate:"required"`
+	NewPassword string `json:"new_password" validate:"required,min=8"`
+}
+
 // JWTResponseDTO represents JWT response DTO
 type JWTResponseDTO struct {
 	AccessToken  string  `json:"access_token"`
@@ -75,19 +166,45 @@ type JWTResponseDTO struct {
 type AuthResponseDTO struct {
 	User      UserDTO `json:"user"`
 	ExpiresIn int     `json:"expires_in"`
+
+	MustChangePassword bool `json:"must_change_password"` // client should go straight to password change
+}
+
+// UserExportDTO represents personal data export DTO
+type UserExportDTO struct {
+	ExportedAt   time.Time                `json:"exported_at"`
+	Profile      UserDTO                  `json:"profile"`
+	LoginHistory []*entities.LoginAttempt `json:"login_history"`
+	AuditEntries []*entities.AuditEntry   `json:"audit_entries"`
 }
 
 // ToUserDTO converts domain user entity to DTO
 func ToUserDTO(user *entities.User) UserDTO {
-	return UserDTO{
-		ID:        user.ID,
-		Username:  user.Username,
-		FirstName: user.FirstName,
-		LastName:  user.LastName,
-		Role:      string(user.Role),
-		IsActive:  user.IsActive,
-		LastLogin: user.LastLogin,
-		CreatedAt: user.CreatedAt,
-		UpdatedAt: user.UpdatedAt,
+	userDTO := UserDTO{
+		ID:             user.ID,
+		Username:       user.Username,
+		Email:          user.Email,
+		EmailVerified:  user.EmailVerified,
+		FirstName:      user.FirstName,
+		LastName:       user.LastName,
+		Role:           string(user.Role),
+		IsActive:       user.IsActive,
+		ApprovalStatus: string(user.ApprovalStatus),
+		LastLogin:      user.LastLogin,
+		DeactivateAt:   user.DeactivateAt,
+		MustChangePass: user.MustChangePass,
+		PassChangedAt:  user.PassChangedAt,
+		Version:        user.Version,
+		CreatedAt:      user.CreatedAt,
+		UpdatedAt:      user.UpdatedAt,
+	}
+
+	// Expired grants are not shown even if not cleaned up yet
+	if user.HasActiveTempRole() {
+		tempRole := string(*user.TempRole)
+		userDTO.TempRole = &tempRole
+		userDTO.TempRoleUntil = user.TempRoleUntil
 	}
+
+	return userDTO
 }
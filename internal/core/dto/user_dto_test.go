@@ -0,0 +1,60 @@
+package dto
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+// decode marshals userDTO and decodes it into a generic map
+func decode(t *testing.T, userDTO UserDTO) map[string]interface{} {
+	t.Helper()
+	body, err := json.Marshal(userDTO)
+	if err != nil {
+		t.Fatalf("failed to marshal: %v", err)
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(body, &fields); err != nil {
+		t.Fatalf("failed to unmarshal %s: %v", body, err)
+	}
+	return fields
+}
+
+func TestUserDTORolesAreStringsByDefault(t *testing.T) {
+	fields := decode(t, ToUserDTO(&entities.User{ID: 1, Username: "alice", Role: entities.RoleManager}))
+
+	if fields["role"] != "manager" {
+		t.Errorf("expected bare role string, got %v", fields["role"])
+	}
+	if _, ok := fields["temp_role"]; ok {
+		t.Errorf("expected no temp_role without a grant, got %v", fields["temp_role"])
+	}
+}
+
+func TestUserDTOExpandedRoles(t *testing.T) {
+	admin := entities.RoleAdmin
+	until := time.Now().Add(time.Hour)
+	user := &entities.User{ID: 1, Username: "alice", Role: entities.RoleManager, TempRole: &admin, TempRoleUntil: &until}
+
+	fields := decode(t, ToUserDTO(user).WithExpandedRoles())
+
+	role, ok := fields["role"].(map[string]interface{})
+	if !ok || role["name"] != "manager" || role["label"] != "Manager" || role["level"] != float64(2) {
+		t.Errorf("expected expanded manager role, got %v", fields["role"])
+	}
+	tempRole, ok := fields["temp_role"].(map[string]interface{})
+	if !ok || tempRole["name"] != "admin" || tempRole["label"] != "Administrator" || tempRole["level"] != float64(3) {
+		t.Errorf("expected expanded admin temp role, got %v", fields["temp_role"])
+	}
+	if fields["username"] != "alice" {
+		t.Errorf("expected other fields kept, got %v", fields)
+	}
+}
+
+func TestToRoleDTOCustomRole(t *testing.T) {
+	if got := ToRoleDTO("auditor"); got != (RoleDTO{Name: "auditor", Label: "auditor", Level: -1}) {
+		t.Errorf("expected custom role labeled by name at level -1, got %+v", got)
+	}
+}
@@ -0,0 +1,31 @@
+package entities
+
+import "time"
+
+// AuditAction identifies an audited change to user accounts
+type AuditAction string
+
+const (
+	AuditUserCreate             AuditAction = "user.create"
+	AuditUserUpdate             AuditAction = "user.update"
+	AuditUserDelete             AuditAction = "user.delete"
+	AuditUserRestore            AuditAction = "user.restore"
+	AuditUserActivate           AuditAction = "user.activate"
+	AuditUserDeactivate         AuditAction = "user.deactivate"
+	AuditUserScheduleDeactivate AuditAction = "user.schedule_deactivation"
+	AuditUserCancelDeactivate   AuditAction = "user.cancel_deactivation"
+	AuditUserGrantTempRole      AuditAction = "user.grant_temp_role"
+	AuditUserBulkRole           AuditAction = "user.bulk_role"
+	AuditUserApprove            AuditAction = "user.approve"
+	AuditUserReject             AuditAction = "user.reject"
+)
+
+// AuditEntry records who performed an action on which user
+type AuditEntry struct {
+	ID        uint                   `json:"id"`
+	ActorID   *uint                  `json:"actor_id"` // nil for actions without an authenticated actor
+	Action    AuditAction            `json:"action"`
+	TargetID  *uint                  `json:"target_id"` // nil for actions on many users
+	Details   map[string]interface{} `json:"details"`
+	CreatedAt time.Time              `json:"created_at"`
+}
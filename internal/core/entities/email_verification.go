@@ -0,0 +1,20 @@
+package entities
+
+import "time"
+
+// EmailVerificationToken represents a pending email ownership check.
+// Only the SHA-256 hash of the token is stored.
+type EmailVerificationToken struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id"`
+	Email     string     `json:"email"` // address the token was sent to
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IsUsable checks if token has neither been used nor expired
+func (t *EmailVerificationToken) IsUsable() bool {
+	return t.UsedAt == nil && t.ExpiresAt.After(time.Now())
+}
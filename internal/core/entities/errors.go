@@ -4,17 +4,49 @@ import "errors"
 
 // Domain errors
 var (
-	ErrUserNotFound       = errors.New("user not found")
-	ErrUserAlreadyExists  = errors.New("user already exists")
-	ErrInvalidCredentials = errors.New("invalid credentials")
-	ErrInvalidUsername    = errors.New("invalid username")
-	ErrPasswordTooShort   = errors.New("password too short")
-	ErrUnauthorized       = errors.New("unauthorized")
-	ErrForbidden          = errors.New("forbidden")
-	ErrInvalidToken       = errors.New("invalid token")
-	ErrTokenExpired       = errors.New("token expired")
-	ErrInvalidRole        = errors.New("invalid role")
-	ErrSessionNotFound    = errors.New("session not found")
-	ErrSessionExpired     = errors.New("session expired")
-	ErrUserDeactivated    = errors.New("user account is deactivated")
+	ErrUserNotFound          = errors.New("user not found")
+	ErrUserAlreadyExists     = errors.New("user already exists")
+	ErrInvalidCredentials    = errors.New("invalid credentials")
+	ErrInvalidUsername       = errors.New("invalid username")
+	ErrInvalidEmail          = errors.New("invalid email")
+	ErrEmailAlreadyExists    = errors.New("email is already in use")
+	ErrEmailMissing          = errors.New("user has no email")
+	ErrEmailNotVerified      = errors.New("email is not verified")
+	ErrEmailVerified         = errors.New("email is already verified")
+	ErrPasswordTooShort      = errors.New("password too short")
+	ErrPasswordUnchanged     = errors.New("new password must differ from the current one")
+	ErrWeakPassword          = errors.New("password does not meet the password policy")
+	ErrPasswordReused        = errors.New("new password must differ from recently used ones")
+	ErrUnauthorized          = errors.New("unauthorized")
+	ErrForbidden             = errors.New("forbidden")
+	ErrInvalidToken          = errors.New("invalid token")
+	ErrTokenExpired          = errors.New("token expired")
+	ErrTokenReused           = errors.New("refresh token was already used")
+	ErrInvalidRole           = errors.New("invalid role")
+	ErrRoleNotAssignable     = errors.New("role may not be assigned by the current user")
+	ErrSessionNotFound       = errors.New("session not found")
+	ErrSessionExpired        = errors.New("session expired")
+	ErrUserDeactivated       = errors.New("user account is deactivated")
+	ErrPendingApproval       = errors.New("user account is pending approval")
+	ErrApprovalRejected      = errors.New("user account was rejected")
+	ErrNotPendingApproval    = errors.New("user account is not pending approval")
+	ErrUserBusy              = errors.New("user record is locked by another operation")
+	ErrNumericUsername       = errors.New("username must not be purely numeric")
+	ErrSearchTooLong         = errors.New("search query is too long")
+	ErrCannotRemoveLastAdmin = errors.New("cannot remove the last admin")
+	ErrCannotRemoveSelf      = errors.New("cannot delete, deactivate or demote your own account")
+	ErrAdminQuotaExceeded    = errors.New("maximum number of active admins reached")
+	ErrInvalidBulkSize       = errors.New("invalid number of users in bulk request")
+	ErrInvalidSchedule       = errors.New("scheduled time must be in the future")
+	ErrNotScheduled          = errors.New("no deactivation is scheduled")
+	ErrInvalidTempRole       = errors.New("temporary role must differ from the current role and expire in the future")
+	ErrInvalidSort           = errors.New("invalid sort field or order")
+	ErrInvalidDateRange      = errors.New("start of date range is after its end")
+	ErrInvalidStatsRange     = errors.New("invalid stats interval or number of days")
+	ErrAccountLocked         = errors.New("too many failed login attempts")
+	ErrDuplicateName         = errors.New("another active user has the same first and last name")
+	ErrDatabaseTimeout       = errors.New("database query timed out")
+
+	// ErrConcurrentModification means the user changed since the caller read it
+	ErrConcurrentModification = errors.New("user was modified by another request")
 )
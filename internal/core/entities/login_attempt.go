@@ -0,0 +1,14 @@
+package entities
+
+import "time"
+
+// LoginAttempt records a single login attempt for intrusion detection
+type LoginAttempt struct {
+	ID        uint      `json:"id"`
+	Username  string    `json:"username"`
+	UserID    *uint     `json:"user_id"` // set for successful attempts
+	Success   bool      `json:"success"`
+	IP        string    `json:"ip"`
+	UserAgent string    `json:"user_agent"`
+	CreatedAt time.Time `json:"created_at"`
+}
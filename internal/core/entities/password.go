@@ -0,0 +1,12 @@
+package entities
+
+// PasswordHasher hashes and verifies user passwords.
+// Encoded hashes carry an algorithm prefix so several schemes can coexist.
+type PasswordHasher interface {
+	// Hash encodes password with the configured algorithm
+	Hash(password string) (string, error)
+	// Verify checks password against an encoded hash of any supported algorithm
+	Verify(password, encoded string) bool
+	// NeedsRehash checks if encoded hash was produced by a different algorithm than the configured one
+	NeedsRehash(encoded string) bool
+}
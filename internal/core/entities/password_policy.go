@@ -0,0 +1,116 @@
+package entities
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// PasswordPolicy lists the requirements a new password must meet
+type PasswordPolicy struct {
+	MinLength     int  // characters, not bytes
+	RequireUpper  bool // at least one upper case letter
+	RequireLower  bool // at least one lower case letter
+	RequireDigit  bool // at least one digit
+	RequireSymbol bool // at least one character that is neither letter nor digit
+	RejectCommon  bool // reject passwords from commonPasswords
+}
+
+// DefaultPasswordPolicy is the length-only rule that applied before the policy was configurable
+var DefaultPasswordPolicy = PasswordPolicy{MinLength: 8}
+
+// WeakPasswordError reports the first requirement a password fails.
+// It matches ErrWeakPassword with errors.Is.
+type WeakPasswordError struct {
+	Requirement string // human readable, e.g. "must contain a digit"
+}
+
+// Error implements error interface
+func (e *WeakPasswordError) Error() string {
+	return "password " + e.Requirement
+}
+
+// Is makes errors.Is(err, ErrWeakPassword) true for every policy failure
+func (e *WeakPasswordError) Is(target error) bool {
+	return target == ErrWeakPassword
+}
+
+// Check returns a *WeakPasswordError naming the first unmet requirement, or nil
+func (p PasswordPolicy) Check(password string) error {
+	if utf8.RuneCountInString(password) < p.MinLength {
+		return &WeakPasswordError{Requirement: fmt.Sprintf("must be at least %d characters", p.MinLength)}
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsLetter(r):
+			symbol = true
+		}
+	}
+
+	switch {
+	case p.RequireUpper && !upper:
+		return &WeakPasswordError{Requirement: "must contain an upper case letter"}
+	case p.RequireLower && !lower:
+		return &WeakPasswordError{Requirement: "must contain a lower case letter"}
+	case p.RequireDigit && !digit:
+		return &WeakPasswordError{Requirement: "must contain a digit"}
+	case p.RequireSymbol && !symbol:
+		return &WeakPasswordError{Requirement: "must contain a symbol"}
+	case p.RejectCommon && commonPasswords[strings.ToLower(password)]:
+		return &WeakPasswordError{Requirement: "is too common"}
+	}
+	return nil
+}
+
+// commonPasswords are frequent leaked passwords long enough to pass a length check, lower case
+var commonPasswords = map[string]bool{
+	"password":      true,
+	"password1":     true,
+	"password12":    true,
+	"password123":   true,
+	"passw0rd":      true,
+	"p@ssw0rd":      true,
+	"12345678":      true,
+	"123456789":     true,
+	"1234567890":    true,
+	"87654321":      true,
+	"11111111":      true,
+	"00000000":      true,
+	"qwertyui":      true,
+	"qwerty123":     true,
+	"qwertyuiop":    true,
+	"1q2w3e4r":      true,
+	"1qaz2wsx":      true,
+	"zaq12wsx":      true,
+	"abcd1234":      true,
+	"abc12345":      true,
+	"iloveyou":      true,
+	"sunshine":      true,
+	"princess":      true,
+	"football":      true,
+	"baseball":      true,
+	"welcome1":      true,
+	"welcome123":    true,
+	"letmein1":      true,
+	"trustno1":      true,
+	"superman":      true,
+	"starwars":      true,
+	"admin123":      true,
+	"admin1234":     true,
+	"administrator": true,
+	"changeme":      true,
+	"changeme123":   true,
+	"secret123":     true,
+	"whatever":      true,
+	"computer":      true,
+	"internet":      true,
+}
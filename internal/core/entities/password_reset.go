@@ -0,0 +1,19 @@
+package entities
+
+import "time"
+
+// PasswordResetToken represents a pending password reset request.
+// Only the SHA-256 hash of the token is stored.
+type PasswordResetToken struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id"`
+	TokenHash string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// IsUsable checks if token has neither been used nor expired
+func (t *PasswordResetToken) IsUsable() bool {
+	return t.UsedAt == nil && t.ExpiresAt.After(time.Now())
+}
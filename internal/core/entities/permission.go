@@ -0,0 +1,41 @@
+package entities
+
+import "slices"
+
+// Permission is an action the API authorizes, with the roles granted it in role_permissions
+type Permission struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Roles       []Role `json:"roles"` // built-in roles from highest to lowest privilege, then custom ones
+}
+
+// HeldBy checks if role was granted the permission
+func (p Permission) HeldBy(role Role) bool {
+	return slices.Contains(p.Roles, role)
+}
+
+// BuiltInPermissions are seeded into the permissions and role_permissions tables, one per
+// row group of the README access matrix. Each is granted to a built-in role and every role
+// above it, as the route middlewares enforce.
+var BuiltInPermissions = []Permission{
+	{Name: "profile.read", Description: "View own profile, security summary, sessions and data export", Roles: rolesFrom(RoleGuest)},
+	{Name: "password.change", Description: "Change own password", Roles: rolesFrom(RoleGuest)},
+	{Name: "users.read", Description: "List users and view user statistics", Roles: rolesFrom(RoleManager)},
+	{Name: "users.create", Description: "Create and register users with roles below own", Roles: rolesFrom(RoleManager)},
+	{Name: "users.update", Description: "Update users with roles below own", Roles: rolesFrom(RoleManager)},
+	{Name: "users.manage", Description: "Delete, restore, activate, deactivate, approve and import users", Roles: rolesFrom(RoleAdmin)},
+	{Name: "roles.assign", Description: "Assign roles in bulk and grant temporary roles", Roles: rolesFrom(RoleAdmin)},
+	{Name: "sessions.manage", Description: "List and revoke sessions of any user", Roles: rolesFrom(RoleAdmin)},
+	{Name: "audit.read", Description: "Read the audit log and login statistics", Roles: rolesFrom(RoleAdmin)},
+}
+
+// rolesFrom returns the built-in roles at least min, from highest to lowest privilege
+func rolesFrom(min Role) []Role {
+	roles := []Role{}
+	for _, role := range AllRoles {
+		if role.AtLeast(min) {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
@@ -0,0 +1,34 @@
+package entities
+
+import "time"
+
+// RefreshToken represents an issued refresh token. Tokens rotated from one login
+// share a family, only the SHA-256 hash of the token is stored.
+type RefreshToken struct {
+	ID        uint       `json:"id"`
+	UserID    uint       `json:"user_id"`
+	FamilyID  string     `json:"family_id"`
+	TokenHash string     `json:"-"`
+	UserAgent string     `json:"user_agent"` // of the login that started the family
+	IP        string     `json:"ip"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// Session represents a login, described by the newest refresh token of its family
+type Session struct {
+	ID         string    `json:"id"` // refresh token family
+	UserID     uint      `json:"user_id"`
+	UserAgent  string    `json:"user_agent"`
+	IP         string    `json:"ip"`
+	CreatedAt  time.Time `json:"created_at"`   // login time
+	LastUsedAt time.Time `json:"last_used_at"` // last login or refresh
+	ExpiresAt  time.Time `json:"expires_at"`
+}
+
+// IsUsable checks if token was neither rotated, revoked nor expired
+func (t *RefreshToken) IsUsable() bool {
+	return t.UsedAt == nil && t.RevokedAt == nil && t.ExpiresAt.After(time.Now())
+}
@@ -0,0 +1,24 @@
+package entities
+
+import "regexp"
+
+// RolePattern assigns Role to new users whose username matches Pattern
+type RolePattern struct {
+	Pattern *regexp.Regexp
+	Role    Role
+}
+
+// ResolveRole returns the role for a new user: the explicit role if given,
+// otherwise the role of the first pattern matching username.
+// Empty result means the caller's default role applies.
+func ResolveRole(patterns []RolePattern, username string, role Role) Role {
+	if role != "" {
+		return role
+	}
+	for _, p := range patterns {
+		if p.Pattern.MatchString(username) {
+			return p.Role
+		}
+	}
+	return ""
+}
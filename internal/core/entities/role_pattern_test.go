@@ -0,0 +1,30 @@
+package entities
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestResolveRolePrecedence(t *testing.T) {
+	patterns := []RolePattern{
+		{Pattern: regexp.MustCompile(`^svc-`), Role: RoleGuest},
+		{Pattern: regexp.MustCompile(`^svc-ops-`), Role: RoleManager},
+	}
+
+	tests := []struct {
+		username string
+		role     Role
+		want     Role
+	}{
+		{"svc-backup", "", RoleGuest},
+		{"svc-ops-deploy", "", RoleGuest}, // first match wins
+		{"svc-backup", RoleUser, RoleUser},
+		{"alice", "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := ResolveRole(patterns, tt.username, tt.role); got != tt.want {
+			t.Errorf("ResolveRole(%q, %q) = %q, want %q", tt.username, tt.role, got, tt.want)
+		}
+	}
+}
@@ -1,23 +1,34 @@
 package entities
 
 import (
+	"net/mail"
+	"strings"
 	"time"
-
-	"golang.org/x/crypto/bcrypt"
 )
 
 // User represents a user entity in the domain
 type User struct {
-	ID        uint       `json:"id" gorm:"primary_key"`
-	Username  string     `json:"username" gorm:"unique;not null"`
-	Password  string     `json:"-" gorm:"not null"` // Hidden in JSON
-	FirstName string     `json:"first_name"`
-	LastName  string     `json:"last_name"`
-	Role      Role       `json:"role" gorm:"type:varchar(20);default:'user'"`
-	IsActive  bool       `json:"is_active" gorm:"default:true"`
-	LastLogin *time.Time `json:"last_login"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	ID             uint           `json:"id" gorm:"primary_key"`
+	Username       string         `json:"username" gorm:"unique;not null"`
+	Email          *string        `json:"email"`             // optional, unique case-insensitively, also accepted as login
+	EmailVerified  bool           `json:"email_verified"`    // reset whenever the email changes
+	Password       string         `json:"-" gorm:"not null"` // Hidden in JSON
+	FirstName      string         `json:"first_name"`
+	LastName       string         `json:"last_name"`
+	Role           Role           `json:"role" gorm:"type:varchar(20);default:'user'"`
+	IsActive       bool           `json:"is_active" gorm:"default:true"`
+	ApprovalStatus ApprovalStatus `json:"approval_status" gorm:"type:varchar(20);default:'approved'"`
+	LastLogin      *time.Time     `json:"last_login"`
+	DeactivateAt   *time.Time     `json:"deactivate_at"`
+	TempRole       *Role          `json:"temp_role"`
+	TempRoleUntil  *time.Time     `json:"temp_role_until"`
+	TokensRevoked  *time.Time     `json:"-"` // tokens issued before this moment are rejected
+	MustChangePass bool           `json:"must_change_password"`
+	PassChangedAt  *time.Time     `json:"password_changed_at"` // nil until the initial password is changed
+	DeletedAt      *time.Time     `json:"-"`                   // soft-deleted users are hidden from all reads
+	Version        int            `json:"version"`             // incremented on every update, guards against lost writes
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
 }
 
 // Role represents user roles
@@ -30,6 +41,74 @@ const (
 	RoleGuest   Role = "guest"
 )
 
+// AllRoles lists all known roles from highest to lowest privilege.
+// The order defines the role hierarchy used by Level.
+var AllRoles = []Role{RoleAdmin, RoleManager, RoleUser, RoleGuest}
+
+// AssignableRoles returns roles that a user with this role may grant to others
+func (r Role) AssignableRoles() []Role {
+	if r == RoleAdmin {
+		return append([]Role{}, AllRoles...)
+	}
+
+	// Managers and above grant roles strictly below their own
+	roles := []Role{}
+	if !r.AtLeast(RoleManager) {
+		return roles
+	}
+	for _, role := range AllRoles {
+		if role.Level() < r.Level() {
+			roles = append(roles, role)
+		}
+	}
+	return roles
+}
+
+// Level returns role position in the hierarchy: guest=0, user=1, manager=2, admin=3.
+// Unknown and custom roles return -1.
+func (r Role) Level() int {
+	for i, role := range AllRoles {
+		if role == r {
+			return len(AllRoles) - 1 - i
+		}
+	}
+	return -1
+}
+
+// AtLeast checks if role is valid and the other role or higher in the hierarchy
+func (r Role) AtLeast(other Role) bool {
+	return r.IsValid() && r.Level() >= other.Level()
+}
+
+// IsValid checks if role is one of the built-in roles
+func (r Role) IsValid() bool {
+	return r.Level() >= 0
+}
+
+// CanManage checks if a user with this role may manage users with target role
+func (r Role) CanManage(target Role) bool {
+	// Admins manage everyone, including users with custom roles
+	if r == RoleAdmin {
+		return true
+	}
+
+	for _, role := range r.AssignableRoles() {
+		if role == target {
+			return true
+		}
+	}
+	return false
+}
+
+// ApprovalStatus represents the admin approval state of an account
+type ApprovalStatus string
+
+const (
+	ApprovalPending  ApprovalStatus = "pending"
+	ApprovalApproved ApprovalStatus = "approved"
+	ApprovalRejected ApprovalStatus = "rejected"
+)
+
 // HasRole checks if user has specific role
 func (u *User) HasRole(role Role) bool {
 	return u.Role == role
@@ -42,23 +121,90 @@ func (u *User) IsAdmin() bool {
 
 // IsManagerOrHigher checks if user has manager or admin role
 func (u *User) IsManagerOrHigher() bool {
-	return u.Role == RoleAdmin || u.Role == RoleManager
+	return u.Role.AtLeast(RoleManager)
 }
 
 // SetPassword hashes the password
-func (u *User) SetPassword(password string) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+func (u *User) SetPassword(password string, hasher PasswordHasher) error {
+	hashedPassword, err := hasher.Hash(password)
 	if err != nil {
 		return err
 	}
-	u.Password = string(hashedPassword)
+	u.Password = hashedPassword
+	u.MustChangePass = false
 	return nil
 }
 
 // VerifyPassword verifies the password
-func (u *User) VerifyPassword(password string) bool {
-	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
-	return err == nil
+func (u *User) VerifyPassword(password string, hasher PasswordHasher) bool {
+	return hasher.Verify(password, u.Password)
+}
+
+// RehashPassword re-hashes a verified password if it is stored with an outdated algorithm.
+// Returns true if the hash changed and needs to be saved.
+func (u *User) RehashPassword(password string, hasher PasswordHasher) (bool, error) {
+	if !hasher.NeedsRehash(u.Password) {
+		return false, nil
+	}
+
+	hashedPassword, err := hasher.Hash(password)
+	if err != nil {
+		return false, err
+	}
+	u.Password = hashedPassword
+	return true, nil
+}
+
+// IsPendingApproval checks if user is waiting for admin approval
+func (u *User) IsPendingApproval() bool {
+	return u.ApprovalStatus == ApprovalPending
+}
+
+// MarkPendingApproval deactivates user until an admin approves the account
+func (u *User) MarkPendingApproval() {
+	u.ApprovalStatus = ApprovalPending
+	u.IsActive = false
+}
+
+// Approve marks user as approved and activates the account
+func (u *User) Approve() {
+	u.ApprovalStatus = ApprovalApproved
+	u.IsActive = true
+}
+
+// Reject marks user as rejected and keeps the account inactive
+func (u *User) Reject() {
+	u.ApprovalStatus = ApprovalRejected
+	u.IsActive = false
+}
+
+// IsDeactivationDue checks if a scheduled deactivation time has passed
+func (u *User) IsDeactivationDue() bool {
+	return u.DeactivateAt != nil && !u.DeactivateAt.After(time.Now())
+}
+
+// HasActiveTempRole checks if user holds a temporary role that has not expired yet
+func (u *User) HasActiveTempRole() bool {
+	return u.TempRole != nil && u.TempRoleUntil != nil && u.TempRoleUntil.After(time.Now())
+}
+
+// EffectiveRole returns the active temporary role if any, otherwise the assigned role
+func (u *User) EffectiveRole() Role {
+	if u.HasActiveTempRole() {
+		return *u.TempRole
+	}
+	return u.Role
+}
+
+// IsTokenRevoked checks if a token issued at given time was revoked for all sessions.
+// Token timestamps have second precision, so the revocation time is truncated too.
+func (u *User) IsTokenRevoked(issuedAt time.Time) bool {
+	return u.TokensRevoked != nil && issuedAt.Before(u.TokensRevoked.Truncate(time.Second))
+}
+
+// IsDeleted checks if user was soft-deleted
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt != nil
 }
 
 // UpdateLastLogin updates the last login time
@@ -67,11 +213,43 @@ func (u *User) UpdateLastLogin() {
 	u.LastLogin = &now
 }
 
+// IsNumericUsername checks if username consists of digits only and could be mistaken for an ID
+func IsNumericUsername(username string) bool {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return false
+	}
+	for _, r := range username {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// NormalizeEmail trims email, an empty one means no email
+func NormalizeEmail(email string) *string {
+	email = strings.TrimSpace(email)
+	if email == "" {
+		return nil
+	}
+	return &email
+}
+
+// IsValidEmail checks if email is a bare address like user@example.com
+func IsValidEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
+
 // Validate validates user data
 func (u *User) Validate() error {
 	if u.Username == "" {
 		return ErrInvalidUsername
 	}
+	if u.Email != nil && !IsValidEmail(*u.Email) {
+		return ErrInvalidEmail
+	}
 	if len(u.Password) < 8 {
 		return ErrPasswordTooShort
 	}
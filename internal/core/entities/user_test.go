@@ -0,0 +1,57 @@
+package entities
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEffectiveRoleWithTempRole(t *testing.T) {
+	admin := RoleAdmin
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name  string
+		user  User
+		want  Role
+		grant bool
+	}{
+		{"no grant", User{Role: RoleManager}, RoleManager, false},
+		{"active grant", User{Role: RoleManager, TempRole: &admin, TempRoleUntil: &future}, RoleAdmin, true},
+		{"expired grant", User{Role: RoleManager, TempRole: &admin, TempRoleUntil: &past}, RoleManager, false},
+		{"grant without expiry", User{Role: RoleManager, TempRole: &admin}, RoleManager, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.user.EffectiveRole(); got != tt.want {
+				t.Errorf("expected effective role %s, got %s", tt.want, got)
+			}
+			if got := tt.user.HasActiveTempRole(); got != tt.grant {
+				t.Errorf("expected active grant %v, got %v", tt.grant, got)
+			}
+		})
+	}
+}
+
+// Built-in permissions are granted along the role hierarchy, so the README
+// matrix and the seeded catalog rely on exactly these built-in roles and levels
+func TestBuiltInRoleCatalog(t *testing.T) {
+	want := map[Role]int{RoleGuest: 0, RoleUser: 1, RoleManager: 2, RoleAdmin: 3}
+	if len(AllRoles) != len(want) {
+		t.Fatalf("expected %d built-in roles, got %v", len(want), AllRoles)
+	}
+	for _, role := range AllRoles {
+		level, ok := want[role]
+		if !ok {
+			t.Errorf("unexpected built-in role %s", role)
+			continue
+		}
+		if role.Level() != level || !role.IsValid() {
+			t.Errorf("expected %s at level %d, got %d", role, level, role.Level())
+		}
+	}
+	if Role("auditor").IsValid() {
+		t.Errorf("expected custom role not to be built-in")
+	}
+}
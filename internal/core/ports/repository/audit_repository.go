@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+// AuditFilter holds optional criteria for AuditRepository.List, zero values match all entries
+type AuditFilter struct {
+	ActorID  *uint
+	TargetID *uint
+	Action   entities.AuditAction
+	Limit    int
+	Offset   int
+}
+
+// AuditRepository defines the interface for audit log operations
+type AuditRepository interface {
+	// Record stores a new audit entry
+	Record(ctx context.Context, entry *entities.AuditEntry) error
+	// List retrieves a page of entries matching filter, newest first, and the total number of matches
+	List(ctx context.Context, filter AuditFilter) ([]*entities.AuditEntry, int64, error)
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+// EmailVerificationRepository defines the interface for email verification token operations
+type EmailVerificationRepository interface {
+	// Create stores a new verification token
+	Create(ctx context.Context, token *entities.EmailVerificationToken) error
+	// GetByHash retrieves verification token by its hash
+	GetByHash(ctx context.Context, tokenHash string) (*entities.EmailVerificationToken, error)
+	// MarkUsed consumes verification token, fails with ErrInvalidToken if it was already used
+	MarkUsed(ctx context.Context, id uint) error
+}
@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+// LoginCount holds the number of login attempts made in the interval starting at Bucket
+type LoginCount struct {
+	Bucket     time.Time // UTC
+	Successful int64
+	Failed     int64
+}
+
+// LoginAttemptRepository defines the interface for login attempt storage
+type LoginAttemptRepository interface {
+	// Create stores a login attempt
+	Create(ctx context.Context, attempt *entities.LoginAttempt) error
+	// CountFailuresSince counts failed attempts for username made after since and after its last success
+	CountFailuresSince(ctx context.Context, username string, since time.Time) (int64, error)
+	// CountIPFailuresSince counts failed attempts from ip made after since and after its last success
+	CountIPFailuresSince(ctx context.Context, ip string, since time.Time) (int64, error)
+	// ListByUser retrieves attempts of the user, newest first: successful ones by user ID
+	// and failed ones by any of the logins the user can be identified with
+	ListByUser(ctx context.Context, userID uint, logins []string) ([]*entities.LoginAttempt, error)
+	// CountByInterval counts attempts made since since, grouped by date_trunc of interval in UTC.
+	// Intervals without attempts are left out.
+	CountByInterval(ctx context.Context, interval string, since time.Time) ([]LoginCount, error)
+}
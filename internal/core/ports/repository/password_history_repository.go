@@ -0,0 +1,11 @@
+package repository
+
+import "context"
+
+// PasswordHistoryRepository defines the interface for previously used password hashes
+type PasswordHistoryRepository interface {
+	// Recent returns up to limit hashes of the user's previous passwords, newest first
+	Recent(ctx context.Context, userID uint, limit int) ([]string, error)
+	// Add stores a replaced password hash and drops all but the newest keep entries
+	Add(ctx context.Context, userID uint, passwordHash string, keep int) error
+}
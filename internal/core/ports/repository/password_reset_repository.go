@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+// PasswordResetRepository defines the interface for password reset token operations
+type PasswordResetRepository interface {
+	// Create stores a new reset token
+	Create(ctx context.Context, token *entities.PasswordResetToken) error
+	// GetByHash retrieves reset token by its hash
+	GetByHash(ctx context.Context, tokenHash string) (*entities.PasswordResetToken, error)
+	// MarkUsed consumes reset token, fails with ErrInvalidToken if it was already used
+	MarkUsed(ctx context.Context, id uint) error
+}
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// RateLimitResult describes bucket state after a request
+type RateLimitResult struct {
+	Allowed    bool
+	Remaining  int           // whole tokens left in the bucket
+	RetryAfter time.Duration // wait until the next token when not allowed
+	Reset      time.Duration // wait until the bucket is full again
+}
+
+// RateLimitStore keeps token buckets for rate limiting
+type RateLimitStore interface {
+	// Allow takes a token from the bucket for key, refilled at rate per second up to burst
+	Allow(ctx context.Context, key string, rate float64, burst int) (RateLimitResult, error)
+}
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+// SessionFilter represents filters and pagination for listing a user's sessions
+type SessionFilter struct {
+	UserID  uint
+	Expired bool // list sessions whose refresh token expired instead of active ones
+	Limit   int
+	Offset  int
+}
+
+// RefreshTokenRepository defines the interface for issued refresh token operations
+type RefreshTokenRepository interface {
+	// Create stores a newly issued refresh token
+	Create(ctx context.Context, token *entities.RefreshToken) error
+	// GetByHash retrieves refresh token by its hash, fails with ErrInvalidToken if unknown
+	GetByHash(ctx context.Context, tokenHash string) (*entities.RefreshToken, error)
+	// MarkUsed rotates refresh token out, fails with ErrInvalidToken if it was already used or revoked
+	MarkUsed(ctx context.Context, id uint) error
+	// RevokeFamily revokes all tokens of the family
+	RevokeFamily(ctx context.Context, familyID string) error
+	// ListSessions retrieves not revoked sessions of a user and their total count
+	ListSessions(ctx context.Context, filter SessionFilter) ([]*entities.Session, int64, error)
+	// CountActiveSessions counts sessions of a user whose refresh token is still usable
+	CountActiveSessions(ctx context.Context, userID uint) (int64, error)
+	// RevokeSession revokes family of the user, fails with ErrSessionNotFound if it has no tokens left to revoke
+	RevokeSession(ctx context.Context, userID uint, familyID string) error
+	// RevokeAllSessions revokes every token of the user and returns the number of sessions ended
+	RevokeAllSessions(ctx context.Context, userID uint) (int64, error)
+}
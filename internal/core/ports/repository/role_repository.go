@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+// RoleRepository defines the interface for role data operations
+type RoleRepository interface {
+	// List retrieves all roles, built-in and custom
+	List(ctx context.Context) ([]entities.Role, error)
+	// Exists checks if role is defined
+	Exists(ctx context.Context, role entities.Role) (bool, error)
+	// ListPermissions retrieves the permission catalog with the roles granted each permission
+	ListPermissions(ctx context.Context) ([]entities.Permission, error)
+}
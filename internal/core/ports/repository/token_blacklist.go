@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+// TokenBlacklist defines the interface for revoked token storage
+type TokenBlacklist interface {
+	// Add revokes token with given jti until its expiry
+	Add(ctx context.Context, jti string, exp time.Time) error
+	// IsBlacklisted checks if token with given jti was revoked
+	IsBlacklisted(ctx context.Context, jti string) bool
+	// DeleteExpired drops entries for tokens that have expired anyway
+	DeleteExpired(ctx context.Context) (int64, error)
+}
@@ -0,0 +1,18 @@
+package repository
+
+import "context"
+
+// Repositories groups repositories bound to one transaction
+type Repositories struct {
+	Users           UserRepository
+	RefreshTokens   RefreshTokenRepository
+	Audit           AuditRepository
+	PasswordHistory PasswordHistoryRepository
+}
+
+// TxManager defines the interface for running work atomically
+type TxManager interface {
+	// WithTx runs fn with transaction-scoped repositories.
+	// The transaction is committed if fn returns nil and rolled back otherwise.
+	WithTx(ctx context.Context, fn func(repos Repositories) error) error
+}
@@ -2,10 +2,42 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/ontair/admin-panel/internal/core/entities"
 )
 
+// UserFilter holds optional criteria for UserRepository.Search, zero values match all users
+type UserFilter struct {
+	Roles     []entities.Role // users having any of the roles
+	IsActive  *bool           // users with this active status
+	Search    string          // case-insensitive substring of username, first or last name, exact username matches first
+	Wildcards bool            // treat % and _ in Search as wildcards instead of literals
+	SortBy    string          // one of UserSortFields, empty keeps the default order
+	SortDesc  bool
+	Limit     int
+	Offset    int
+
+	NeverChangedPassword *bool // users still on (true) or past (false) their initial password
+
+	CreatedFrom *time.Time // users created at or after
+	CreatedTo   *time.Time // users created at or before
+
+	LastLoginBefore *time.Time // users whose last login is earlier, users who never logged in match too
+
+	CountOnly bool // only count matches, Search returns no users
+}
+
+// UserCount is the number of users with one role and active status
+type UserCount struct {
+	Role     entities.Role
+	IsActive bool
+	Count    int64
+}
+
+// UserSortFields lists fields users can be sorted by
+var UserSortFields = []string{"username", "created_at", "last_login", "role"}
+
 // UserRepository defines the interface for user data operations
 type UserRepository interface {
 	// Create creates a new user
@@ -14,14 +46,55 @@ type UserRepository interface {
 	GetByID(ctx context.Context, id uint) (*entities.User, error)
 	// GetByUsername retrieves user by username
 	GetByUsername(ctx context.Context, username string) (*entities.User, error)
-	// Update updates user data
+	// GetByEmail retrieves user by email, case-insensitively
+	GetByEmail(ctx context.Context, email string) (*entities.User, error)
+	// SetEmailVerified marks email of the user verified, fails with ErrInvalidToken if the user's email changed
+	SetEmailVerified(ctx context.Context, id uint, email string) error
+	// CountByRole counts active users with the role, unexpired temporary grants of it included
+	CountByRole(ctx context.Context, role entities.Role) (int64, error)
+	// CountByRoleAndStatus counts users grouped by role and active status
+	CountByRoleAndStatus(ctx context.Context) ([]UserCount, error)
+	// LockActiveAdmins locks active admin rows until the transaction ends and counts them.
+	// Use it through TxManager, outside a transaction the locks are released at once.
+	LockActiveAdmins(ctx context.Context) (int64, error)
+	// Update updates user data if user.Version still matches the stored row and increments it,
+	// fails with ErrConcurrentModification otherwise
 	Update(ctx context.Context, user *entities.User) error
-	// Delete deletes user by ID
+	// UpdateRole updates only user's role
+	UpdateRole(ctx context.Context, id uint, role entities.Role) error
+	// SetActive updates only user's active status
+	SetActive(ctx context.Context, id uint, active bool) error
+	// SetMustChangePassword sets whether user has to change password on next login
+	SetMustChangePassword(ctx context.Context, id uint, required bool) error
+	// SetDeactivateAt schedules user deactivation, nil cancels it
+	SetDeactivateAt(ctx context.Context, id uint, at *time.Time) error
+	// SetTempRole grants a temporary role until the given time, nil role revokes it
+	SetTempRole(ctx context.Context, id uint, role *entities.Role, until *time.Time) error
+	// RevokeTokens invalidates all tokens issued to the user so far
+	RevokeTokens(ctx context.Context, id uint) error
+	// ClearExpiredTempRoles removes temporary roles that have expired and returns the affected users
+	ClearExpiredTempRoles(ctx context.Context) ([]*entities.User, error)
+	// LockDueDeactivations locks and returns users whose scheduled deactivation time has passed.
+	// Use it through TxManager like LockActiveAdmins.
+	LockDueDeactivations(ctx context.Context) ([]*entities.User, error)
+	// UpdatePasswordLocked locks user row, applies change and saves the new password
+	UpdatePasswordLocked(ctx context.Context, id uint, change func(user *entities.User) error) error
+	// Delete soft-deletes user by ID
 	Delete(ctx context.Context, id uint) error
+	// HardDelete removes user permanently, including soft-deleted users
+	HardDelete(ctx context.Context, id uint) error
+	// Restore brings back a soft-deleted user, fails with ErrUserNotFound if user is not deleted
+	Restore(ctx context.Context, id uint) error
 	// List retrieves list of users with pagination
 	List(ctx context.Context, limit, offset int) ([]*entities.User, error)
+	// Iterate calls fn with batches of all users ordered by ID, stopping at the first error
+	Iterate(ctx context.Context, batchSize int, fn func(users []*entities.User) error) error
+	// CountActiveByName counts active users with the given first and last name, ignoring case and excludeID
+	CountActiveByName(ctx context.Context, firstName, lastName string, excludeID uint) (int64, error)
 	// Count returns total number of users
 	Count(ctx context.Context) (int64, error)
+	// Search retrieves a page of users matching filter and the total number of matches
+	Search(ctx context.Context, filter UserFilter) ([]*entities.User, int64, error)
 	// GetByRole retrieves users by role
 	GetByRole(ctx context.Context, role entities.Role) ([]*entities.User, error)
 	// GetByRoles retrieves users by multiple roles
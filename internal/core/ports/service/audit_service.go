@@ -0,0 +1,25 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+// ListAuditLogsRequest represents audit log listing request with filters and pagination
+type ListAuditLogsRequest struct {
+	Limit    int                  `query:"limit"`
+	Offset   int                  `query:"offset"`
+	ActorID  *uint                `query:"actor_id"`
+	TargetID *uint                `query:"target_id"`
+	Action   entities.AuditAction `query:"action"`
+}
+
+// AuditService defines the interface for audit log operations
+type AuditService interface {
+	// Record stores entry on behalf of the actor from ctx. Failures are logged, never returned,
+	// so auditing can't block the audited operation.
+	Record(ctx context.Context, entry *entities.AuditEntry)
+	// ListAuditLogs retrieves paginated audit entries (admin only)
+	ListAuditLogs(ctx context.Context, req *ListAuditLogsRequest) (*Page[*entities.AuditEntry], error)
+}
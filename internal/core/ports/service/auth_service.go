@@ -2,14 +2,18 @@ package service
 
 import (
 	"context"
+	"time"
 
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/ontair/admin-panel/internal/core/entities"
 )
 
 // LoginRequest represents login request data
 type LoginRequest struct {
-	Username string `json:"username" validate:"required"`
-	Password string `json:"password" validate:"required"`
+	Username  string `json:"username" validate:"required"` // username or email
+	Password  string `json:"password" validate:"required"`
+	IP        string `json:"-"` // client address, recorded with the attempt
+	UserAgent string `json:"-"`
 }
 
 // LoginResponse represents login response data
@@ -18,6 +22,8 @@ type LoginResponse struct {
 	RefreshToken string         `json:"refresh_token"`
 	User         *entities.User `json:"user"`
 	ExpiresIn    int            `json:"expires_in"`
+
+	MustChangePassword bool `json:"must_change_password"` // only password change and logout are allowed until it is cleared
 }
 
 // RegisterRequest represents registration request data
@@ -27,6 +33,7 @@ type RegisterRequest struct {
 	FirstName string        `json:"first_name"`
 	LastName  string        `json:"last_name"`
 	Role      entities.Role `json:"role"`
+	ActorRole entities.Role `json:"-"` // role of the user performing the registration
 }
 
 // RefreshTokenRequest represents refresh token request
@@ -34,6 +41,63 @@ type RefreshTokenRequest struct {
 	RefreshToken string `json:"refresh_token" validate:"required"`
 }
 
+// SelfTestCheck represents the result of a single self-test check
+type SelfTestCheck struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// TokenPreview holds the decoded parts of an access token issued for debugging
+type TokenPreview struct {
+	Header map[string]interface{} `json:"header"`
+	Claims map[string]interface{} `json:"claims"`
+}
+
+// ListSessionsRequest represents session listing request with filter and pagination
+type ListSessionsRequest struct {
+	UserID  uint `json:"-"`
+	Expired bool `query:"expired"` // list expired sessions instead of active ones
+	Limit   int  `query:"limit"`
+	Offset  int  `query:"offset"`
+}
+
+// SecuritySummary represents an overview of the user's account security
+type SecuritySummary struct {
+	PasswordAgeDays      int        `json:"password_age_days"` // since last change, or account creation if never changed
+	TwoFactorEnabled     bool       `json:"two_factor_enabled"`
+	ActiveSessions       int64      `json:"active_sessions"`
+	LastLogin            *time.Time `json:"last_login"`
+	FailedAttemptsRecent int64      `json:"failed_attempts_recent"` // failed logins within the last 24 hours
+}
+
+// Intervals of LoginStatsRequest
+const (
+	StatsIntervalHour = "hour"
+	StatsIntervalDay  = "day"
+	StatsIntervalWeek = "week"
+)
+
+// LoginStatsRequest represents request for login counts over the last Days days
+type LoginStatsRequest struct {
+	Interval string `query:"interval"`
+	Days     int    `query:"days"`
+}
+
+// LoginStatsBucket holds login counts of the interval starting at Start
+type LoginStatsBucket struct {
+	Start      time.Time `json:"start"`
+	Successful int64     `json:"successful"`
+	Failed     int64     `json:"failed"`
+}
+
+// LoginStats represents login counts bucketed by interval, oldest first, empty intervals included
+type LoginStats struct {
+	Interval string             `json:"interval"`
+	From     time.Time          `json:"from"`
+	Buckets  []LoginStatsBucket `json:"buckets"`
+}
+
 // AuthService defines authentication service interface
 type AuthService interface {
 	// Login authenticates user and returns tokens
@@ -42,8 +106,36 @@ type AuthService interface {
 	Register(ctx context.Context, req *RegisterRequest) (*entities.User, error)
 	// RefreshToken generates new access token using refresh token
 	RefreshToken(ctx context.Context, req *RefreshTokenRequest) (*LoginResponse, error)
-	// Logout invalidates user session
-	Logout(ctx context.Context, token string) error
+	// GetRefreshSession returns the stored record of a refresh token, failing with ErrInvalidToken
+	// if it is unknown, rotated, revoked or expired
+	GetRefreshSession(ctx context.Context, refreshToken string) (*entities.RefreshToken, error)
+	// Logout invalidates user session of the access and refresh token, either may be empty
+	Logout(ctx context.Context, token, refreshToken string) error
 	// ValidateToken validates JWT token
 	ValidateToken(ctx context.Context, token string) (*entities.User, error)
+	// IsTokenRevoked checks if a parsed token was blacklisted on logout
+	IsTokenRevoked(ctx context.Context, token *jwt.Token) bool
+	// PurgeExpiredRevocations drops blacklist entries for tokens that have expired
+	PurgeExpiredRevocations(ctx context.Context) (int64, error)
+	// GetTokenUser loads the user a parsed token was issued to, failing with ErrInvalidToken
+	// if the token was revoked
+	GetTokenUser(ctx context.Context, token *jwt.Token) (*entities.User, error)
+	// RecordLoginAttempt stores the outcome of a login attempt
+	RecordLoginAttempt(ctx context.Context, attempt *entities.LoginAttempt) error
+	// CountRecentFailures counts failed login attempts for username within window
+	CountRecentFailures(ctx context.Context, username string, window time.Duration) (int64, error)
+	// SelfTest verifies token signing and parsing round-trips
+	SelfTest(ctx context.Context) []SelfTestCheck
+	// PreviewAccessToken decodes an access token generated for user without issuing it
+	PreviewAccessToken(ctx context.Context, userID uint) (*TokenPreview, error)
+	// ListSessions retrieves paginated sessions of a user
+	ListSessions(ctx context.Context, req *ListSessionsRequest) (*Page[*entities.Session], error)
+	// RevokeSession ends a session of the user so its refresh token stops working
+	RevokeSession(ctx context.Context, userID uint, sessionID string) error
+	// RevokeAllSessions ends every session of the user and returns how many were ended
+	RevokeAllSessions(ctx context.Context, userID uint) (int64, error)
+	// GetSecuritySummary returns an overview of the user's account security
+	GetSecuritySummary(ctx context.Context, userID uint) (*SecuritySummary, error)
+	// GetLoginStats counts login attempts over time (admin only)
+	GetLoginStats(ctx context.Context, req *LoginStatsRequest) (*LoginStats, error)
 }
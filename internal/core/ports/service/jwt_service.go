@@ -1,6 +1,8 @@
 package service
 
 import (
+	"time"
+
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/ontair/admin-panel/internal/core/entities"
 )
@@ -8,18 +10,35 @@ import (
 // JWTService defines the interface for JWT operations
 type JWTService interface {
 	GenerateAccessToken(user *entities.User) (string, error)
+	GenerateAccessTokenWithExpiry(user *entities.User, expiresAt time.Time) (string, error)
 	GenerateRefreshToken(user *entities.User) (string, error)
 	ParseAccessToken(tokenString string) (*jwt.Token, error)
 	ParseRefreshToken(tokenString string) (*jwt.Token, error)
 	ExtractUserFromToken(token *jwt.Token) (*UserInfo, error)
 	ValidateToken(tokenString string) (*Claims, error)
+	JWKS() JWKSet
+}
+
+// JWK represents a public key in JSON Web Key format
+type JWK struct {
+	Kid string `json:"kid"`
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// JWKSet represents a JSON Web Key Set
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
 }
 
 // UserInfo contains user information extracted from JWT
 type UserInfo struct {
 	UserID   uint
 	Username string
-	Role     string
+	Role     entities.Role
 }
 
 // Claims represents JWT claims
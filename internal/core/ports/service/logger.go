@@ -1,6 +1,10 @@
 package service
 
-import "go.uber.org/zap"
+import (
+	"context"
+
+	"go.uber.org/zap"
+)
 
 // Logger defines the interface for logging operations
 type Logger interface {
@@ -9,5 +13,7 @@ type Logger interface {
 	Warn(msg string, fields ...zap.Field)
 	Error(msg string, fields ...zap.Field)
 	Fatal(msg string, fields ...zap.Field)
+	// With returns a logger that tags every line with the request ID carried by ctx
+	With(ctx context.Context) Logger
 	Close() error
 }
@@ -0,0 +1,13 @@
+package service
+
+import "time"
+
+// Metrics defines the interface for recording operational metrics
+type Metrics interface {
+	// ObserveRequest records a handled HTTP request by route template, method and status
+	ObserveRequest(route, method string, status int, duration time.Duration)
+	// LoginAttempt counts a login by outcome
+	LoginAttempt(success bool)
+	// TokenRefresh counts a refresh token exchange by outcome
+	TokenRefresh(success bool)
+}
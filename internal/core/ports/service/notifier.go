@@ -0,0 +1,13 @@
+package service
+
+import (
+	"context"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+// Notifier defines the interface for delivering notifications to administrators
+type Notifier interface {
+	// NotifyPendingApproval informs admins that a user account awaits approval
+	NotifyPendingApproval(ctx context.Context, user *entities.User)
+}
@@ -0,0 +1,31 @@
+package service
+
+// Page represents one page of a paginated listing
+type Page[T any] struct {
+	Items  []T   `json:"items"`
+	Total  int64 `json:"total"`
+	Limit  int   `json:"limit"`
+	Offset int   `json:"offset"`
+
+	Page       int  `json:"page"`        // 1-based page for offset, may exceed TotalPages
+	TotalPages int  `json:"total_pages"` // number of pages for Total with current Limit
+	HasNext    bool `json:"has_next"`    // more items exist after this page
+}
+
+// NewPage builds a page of items out of total matches, limit must be positive.
+// Paging past the end is not an error: items is empty and Page exceeds TotalPages.
+func NewPage[T any](items []T, total int64, limit, offset int) *Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+
+	return &Page[T]{
+		Items:      items,
+		Total:      total,
+		Limit:      limit,
+		Offset:     offset,
+		Page:       offset/limit + 1,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+		HasNext:    int64(offset+len(items)) < total,
+	}
+}
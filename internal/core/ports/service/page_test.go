@@ -0,0 +1,37 @@
+package service
+
+import "testing"
+
+func TestNewPage(t *testing.T) {
+	tests := []struct {
+		name       string
+		items      int
+		total      int64
+		limit      int
+		offset     int
+		page       int
+		totalPages int
+		hasNext    bool
+	}{
+		{"first page", 10, 25, 10, 0, 1, 3, true},
+		{"last page", 5, 25, 10, 20, 3, 3, false},
+		{"past the last page", 0, 25, 10, 40, 5, 3, false},
+		{"no matches", 0, 0, 10, 0, 1, 0, false},
+		{"no matches past the end", 0, 0, 10, 30, 4, 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := NewPage(make([]int, tt.items), tt.total, tt.limit, tt.offset)
+			if page.Page != tt.page || page.TotalPages != tt.totalPages || page.HasNext != tt.hasNext {
+				t.Errorf("expected page %d of %d, has next %v, got %+v", tt.page, tt.totalPages, tt.hasNext, page)
+			}
+		})
+	}
+}
+
+func TestNewPageNilItems(t *testing.T) {
+	if page := NewPage[int](nil, 0, 10, 0); page.Items == nil {
+		t.Errorf("expected empty items, got nil")
+	}
+}
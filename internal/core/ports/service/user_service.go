@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/ontair/admin-panel/internal/core/entities"
 )
@@ -10,19 +11,24 @@ import (
 type CreateUserRequest struct {
 	Username  string        `json:"username" validate:"required,min=3"`
 	Password  string        `json:"password" validate:"required,min=8"`
+	Email     string        `json:"email"` // optional
 	FirstName string        `json:"first_name"`
 	LastName  string        `json:"last_name"`
 	Role      entities.Role `json:"role"`
 	IsActive  bool          `json:"is_active"`
+	ActorRole entities.Role `json:"-"` // role of the user performing the creation
 }
 
 // UpdateUserRequest represents user update request
 type UpdateUserRequest struct {
 	Username  *string        `json:"username"`
+	Email     *string        `json:"email"` // empty string removes the email
 	FirstName *string        `json:"first_name"`
 	LastName  *string        `json:"last_name"`
 	Role      *entities.Role `json:"role"`
 	IsActive  *bool          `json:"is_active"`
+	Version   *int           `json:"version"` // version the caller read, nil skips the check
+	ActorRole entities.Role  `json:"-"`       // role of the user performing the update
 }
 
 // ChangePasswordRequest represents password change request
@@ -49,14 +55,96 @@ type ListUsersRequest struct {
 	Role     entities.Role `query:"role"`
 	IsActive *bool         `query:"is_active"`
 	Search   string        `query:"search"`
+
+	SortBy    string `query:"sort"`  // one of repository.UserSortFields, created_at by default
+	SortOrder string `query:"order"` // asc or desc, desc by default
+
+	IncludeInactive *bool `query:"include_inactive"` // overrides the inactive users policy for manager listings
+
+	NeverChangedPassword *bool `query:"never_changed_password"` // users still on their initial password
+
+	CreatedFrom *time.Time `query:"created_from"` // users registered at or after
+	CreatedTo   *time.Time `query:"created_to"`   // users registered at or before
+
+	LastLoginBefore *time.Time `query:"last_login_before"` // users last logged in before, or never
+
+	CountOnly bool `query:"count_only"` // only count matches, the page has no items
+}
+
+// UserStats summarizes users by role and active status
+type UserStats struct {
+	Total    int64                   `json:"total"`
+	Active   int64                   `json:"active"`
+	Inactive int64                   `json:"inactive"`
+	ByRole   map[entities.Role]int64 `json:"by_role"`
+}
+
+// BulkRoleRequest represents request to assign a role to many users
+type BulkRoleRequest struct {
+	IDs       []uint        `json:"ids" validate:"required"`
+	Role      entities.Role `json:"role" validate:"required"`
+	ActorRole entities.Role `json:"-"`
+}
+
+// TempRoleRequest represents request to grant a role for a limited time
+type TempRoleRequest struct {
+	Role      entities.Role `json:"role" validate:"required"`
+	Until     time.Time     `json:"until" validate:"required"`
+	ActorRole entities.Role `json:"-"`
+}
+
+// UserAccess describes what a user may do with their effective role.
+// Route access mirrors the checks enforced by the auth middleware.
+type UserAccess struct {
+	UserID          uint            `json:"user_id"`
+	Role            entities.Role   `json:"role"`
+	TempRole        *entities.Role  `json:"temp_role"`
+	TempRoleUntil   *time.Time      `json:"temp_role_until"`
+	EffectiveRole   entities.Role   `json:"effective_role"`
+	ManagerAccess   bool            `json:"manager_access"`   // /manager routes
+	AdminAccess     bool            `json:"admin_access"`     // /admin routes
+	AssignableRoles []entities.Role `json:"assignable_roles"` // roles the user may grant and manage
+	Permissions     []string        `json:"permissions"`      // catalog permissions held by the effective role
+}
+
+// BulkResult represents the outcome of a bulk operation for a single user
+type BulkResult struct {
+	ID      uint   `json:"id"`
+	Success bool   `json:"success"`
+	Error   string `json:"error,omitempty"`
+}
+
+// ValidationResult represents the dry-run validation outcome of one proposed user
+type ValidationResult struct {
+	Row      int    `json:"row"` // 1-based position in the request
+	Username string `json:"username"`
+	Valid    bool   `json:"valid"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ImportResult represents the outcome of importing one user
+type ImportResult struct {
+	Row      int    `json:"row"` // 1-based position in the request
+	Username string `json:"username"`
+	ID       uint   `json:"id,omitempty"`
+	Success  bool   `json:"success"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ImportReport represents the outcome of a bulk user import
+type ImportReport struct {
+	Created    int            `json:"created"`
+	Failed     int            `json:"failed"`
+	RolledBack bool           `json:"rolled_back"` // atomic import with failed rows, nothing was created
+	Results    []ImportResult `json:"results"`
 }
 
-// ListUsersResponse represents paginated users response
-type ListUsersResponse struct {
-	Users  []*entities.User `json:"users"`
-	Total  int64            `json:"total"`
-	Limit  int              `json:"limit"`
-	Offset int              `json:"offset"`
+// UserDataExport represents all data stored about a user for data-subject access requests
+type UserDataExport struct {
+	ExportedAt   time.Time                `json:"exported_at"`
+	Profile      *entities.User           `json:"profile"`
+	LoginHistory []*entities.LoginAttempt `json:"login_history"`
+	AuditEntries []*entities.AuditEntry   `json:"audit_entries"` // entries where the user is the target
 }
 
 // UserService defines user management service interface
@@ -65,24 +153,66 @@ type UserService interface {
 	CreateUser(ctx context.Context, req *CreateUserRequest) (*entities.User, error)
 	// GetUser retrieves user by ID
 	GetUser(ctx context.Context, id uint) (*entities.User, error)
+	// GetManagedUser retrieves user by ID if the actor may manage them
+	GetManagedUser(ctx context.Context, actorRole entities.Role, id uint) (*entities.User, error)
 	// GetCurrentUser retrieves current authenticated user
 	GetCurrentUser(ctx context.Context, userID uint) (*entities.User, error)
+	// ExportUsers passes all users to fn in batches without loading the whole table
+	ExportUsers(ctx context.Context, fn func(users []*entities.User) error) error
+	// ExportUserData collects all data stored about the user
+	ExportUserData(ctx context.Context, userID uint) (*UserDataExport, error)
 	// UpdateUser updates user data
 	UpdateUser(ctx context.Context, id uint, req *UpdateUserRequest) (*entities.User, error)
 	// DeleteUser deletes user by ID (admin only)
 	DeleteUser(ctx context.Context, id uint) error
+	// RestoreUser brings back a deleted user (admin only)
+	RestoreUser(ctx context.Context, id uint) error
 	// ListUsers retrieves paginated list of users
-	ListUsers(ctx context.Context, req *ListUsersRequest) (*ListUsersResponse, error)
+	ListUsers(ctx context.Context, req *ListUsersRequest) (*Page[*entities.User], error)
 	// ListUsersForManager retrieves paginated list of users for manager (only user and guest roles)
-	ListUsersForManager(ctx context.Context, req *ListUsersRequest) (*ListUsersResponse, error)
+	ListUsersForManager(ctx context.Context, req *ListUsersRequest) (*Page[*entities.User], error)
+	// CountByRole counts users of every role, active and inactive
+	CountByRole(ctx context.Context) (map[entities.Role]int64, error)
+	// GetUserStats counts users the actor may manage by role and active status
+	GetUserStats(ctx context.Context, actorRole entities.Role) (*UserStats, error)
 	// ChangePassword allows user to change their password
 	ChangePassword(ctx context.Context, userID uint, req *ChangePasswordRequest) error
-	// ResetPassword initiates password reset process
-	ResetPassword(ctx context.Context, req *ResetPasswordRequest) error
+	// ResetPassword initiates password reset process and returns the plaintext reset token
+	ResetPassword(ctx context.Context, req *ResetPasswordRequest) (string, error)
 	// ConfirmPasswordReset confirms password reset with token
 	ConfirmPasswordReset(ctx context.Context, req *ConfirmPasswordResetRequest) error
-	// ActivateUser activates user account (admin only)
-	ActivateUser(ctx context.Context, id uint) error
+	// SendVerificationEmail issues a token verifying the user's email and returns it in plaintext
+	SendVerificationEmail(ctx context.Context, userID uint) (string, error)
+	// VerifyEmail marks the email a token was issued for verified
+	VerifyEmail(ctx context.Context, token string) error
+	// ActivateUser activates user account, optionally requiring a password change on next login (admin only)
+	ActivateUser(ctx context.Context, id uint, requirePasswordChange bool) error
 	// DeactivateUser deactivates user account (admin only)
 	DeactivateUser(ctx context.Context, id uint) error
+	// ScheduleDeactivation deactivates user account at a future time (admin only)
+	ScheduleDeactivation(ctx context.Context, id uint, at time.Time) error
+	// CancelScheduledDeactivation cancels a pending scheduled deactivation (admin only)
+	CancelScheduledDeactivation(ctx context.Context, id uint) error
+	// ApplyScheduledDeactivations deactivates users whose scheduled time has passed
+	ApplyScheduledDeactivations(ctx context.Context) (int64, error)
+	// GetAssignableRoles returns roles the actor may assign to the target user
+	GetAssignableRoles(ctx context.Context, actorRole entities.Role, targetID uint) ([]entities.Role, error)
+	// BulkAssignRole assigns a role to many users at once (admin only)
+	BulkAssignRole(ctx context.Context, req *BulkRoleRequest) ([]BulkResult, error)
+	// ValidateUsers checks proposed users against create policy without creating them (admin only)
+	ValidateUsers(ctx context.Context, reqs []*CreateUserRequest) ([]ValidationResult, error)
+	// BulkCreate creates many users in one transaction and reports the outcome per row (admin only)
+	BulkCreate(ctx context.Context, reqs []*CreateUserRequest, atomic bool) (*ImportReport, error)
+	// GetUserAccess returns effective access of the user including temporary roles (admin only)
+	GetUserAccess(ctx context.Context, id uint) (*UserAccess, error)
+	// ListPermissions returns the permission catalog with the roles holding each (admin only)
+	ListPermissions(ctx context.Context) ([]entities.Permission, error)
+	// GrantTempRole grants a role to the user until the given time (admin only)
+	GrantTempRole(ctx context.Context, id uint, req *TempRoleRequest) (*entities.User, error)
+	// ExpireTempRoles removes expired temporary roles and returns the affected users
+	ExpireTempRoles(ctx context.Context) ([]*entities.User, error)
+	// ApproveUser approves a pending user account and activates it (admin only)
+	ApproveUser(ctx context.Context, id uint) error
+	// RejectUser rejects a pending user account (admin only)
+	RejectUser(ctx context.Context, id uint) error
 }
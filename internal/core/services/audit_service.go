@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+	"github.com/ontair/admin-panel/internal/core/ports/service"
+	"go.uber.org/zap"
+)
+
+// AuditService implements AuditService interface
+type AuditService struct {
+	auditRepo repository.AuditRepository
+	logger    service.Logger
+}
+
+// NewAuditService creates new audit service
+func NewAuditService(auditRepo repository.AuditRepository, logger service.Logger) service.AuditService {
+	return &AuditService{
+		auditRepo: auditRepo,
+		logger:    logger,
+	}
+}
+
+// Record stores entry on behalf of the actor from ctx, failures are only logged
+func (s *AuditService) Record(ctx context.Context, entry *entities.AuditEntry) {
+	if actor, ok := entities.ActorFromContext(ctx); ok {
+		entry.ActorID = &actor.ID
+	}
+
+	if err := s.auditRepo.Record(ctx, entry); err != nil {
+		s.logger.Error("Failed to record audit entry",
+			zap.String("action", string(entry.Action)),
+			zap.String("error", err.Error()),
+		)
+	}
+}
+
+// ListAuditLogs retrieves paginated audit entries (admin only)
+func (s *AuditService) ListAuditLogs(ctx context.Context, req *service.ListAuditLogsRequest) (*service.Page[*entities.AuditEntry], error) {
+	// Set default pagination values
+	limit := req.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 20 // Default limit
+	}
+
+	offset := req.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	entries, total, err := s.auditRepo.List(ctx, repository.AuditFilter{
+		ActorID:  req.ActorID,
+		TargetID: req.TargetID,
+		Action:   req.Action,
+		Limit:    limit,
+		Offset:   offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return service.NewPage(entries, total, limit, offset), nil
+}
@@ -2,76 +2,259 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/ontair/admin-panel/internal/core/entities"
 	"github.com/ontair/admin-panel/internal/core/ports/repository"
 	"github.com/ontair/admin-panel/internal/core/ports/service"
+	"go.uber.org/zap"
 )
 
+// AuthServiceConfig holds configurable authentication behavior
+type AuthServiceConfig struct {
+	RequireApproval    bool // manager-registered users need admin approval
+	RejectNumericNames bool // disallow all-digit usernames
+
+	MaxLoginAttempts int           // failed logins per username or IP before lockout, 0 disables
+	LockoutWindow    time.Duration // period in which failed logins are counted
+
+	AccessExpiry  time.Duration // lifetime of issued access tokens, reported as ExpiresIn
+	RefreshExpiry time.Duration // lifetime of issued refresh tokens, kept with their records
+
+	UniqueNames  bool                   // reject users whose first and last name match another active user
+	MaxAdmins    int                    // active admins allowed, 0 means unlimited
+	RolePatterns []entities.RolePattern // roles for usernames matching a pattern when none is given
+
+	PasswordPolicy entities.PasswordPolicy // requirements for passwords chosen at registration
+
+	RequireVerifiedEmail bool // users with an unverified email can't log in
+}
+
 // AuthService implements AuthService interface
 type AuthService struct {
 	userRepo   repository.UserRepository
 	jwtService service.JWTService
+	blacklist  repository.TokenBlacklist
+	hasher     entities.PasswordHasher
+	notifier   service.Notifier
+	attempts   repository.LoginAttemptRepository
+	refresh    repository.RefreshTokenRepository
+	txManager  repository.TxManager
+	metrics    service.Metrics
+	logger     service.Logger
+	config     AuthServiceConfig
 }
 
 // NewAuthService creates new auth service
 func NewAuthService(
 	userRepo repository.UserRepository,
 	jwtService service.JWTService,
+	blacklist repository.TokenBlacklist,
+	hasher entities.PasswordHasher,
+	notifier service.Notifier,
+	attempts repository.LoginAttemptRepository,
+	refresh repository.RefreshTokenRepository,
+	txManager repository.TxManager,
+	metrics service.Metrics,
+	logger service.Logger,
+	config AuthServiceConfig,
 ) service.AuthService {
 	return &AuthService{
 		userRepo:   userRepo,
 		jwtService: jwtService,
+		blacklist:  blacklist,
+		hasher:     hasher,
+		notifier:   notifier,
+		attempts:   attempts,
+		refresh:    refresh,
+		txManager:  txManager,
+		metrics:    metrics,
+		logger:     logger,
+		config:     config,
 	}
 }
 
-// Login authenticates user and returns tokens
+// Login authenticates user, records the attempt and returns tokens
 func (s *AuthService) Login(ctx context.Context, req *service.LoginRequest) (*service.LoginResponse, error) {
+	response, err := s.authenticate(ctx, req)
+	s.metrics.LoginAttempt(err == nil)
+
+	// Server failures say nothing about the credentials and don't count towards lockout.
+	// Neither do attempts refused by the lockout, or retrying would keep extending it.
+	if err != nil && (!loginRejected(err) || err == entities.ErrAccountLocked) {
+		return nil, err
+	}
+
+	attempt := &entities.LoginAttempt{
+		Username:  req.Username,
+		Success:   err == nil,
+		IP:        req.IP,
+		UserAgent: req.UserAgent,
+	}
+	if err == nil {
+		attempt.UserID = &response.User.ID
+	}
+	// Attempt history must not block login
+	if recordErr := s.RecordLoginAttempt(ctx, attempt); recordErr != nil {
+		s.logger.With(ctx).Warn("Failed to record login attempt",
+			zap.String("username", req.Username),
+			zap.String("error", recordErr.Error()),
+		)
+	}
+
+	return response, err
+}
+
+// RecordLoginAttempt stores the outcome of a login attempt
+func (s *AuthService) RecordLoginAttempt(ctx context.Context, attempt *entities.LoginAttempt) error {
+	return s.attempts.Create(ctx, attempt)
+}
+
+// loginRejected tells refusals of the presented credentials or account apart from server failures
+func loginRejected(err error) bool {
+	switch err {
+	case entities.ErrInvalidCredentials, entities.ErrAccountLocked, entities.ErrPendingApproval,
+		entities.ErrApprovalRejected, entities.ErrUserDeactivated, entities.ErrEmailNotVerified:
+		return true
+	}
+	return false
+}
+
+// isLockedOut checks if username or client IP reached the failed login limit within the lockout window.
+// It fails closed: if failures can't be counted, the error is returned and login is refused.
+func (s *AuthService) isLockedOut(ctx context.Context, req *service.LoginRequest) (bool, error) {
+	if s.config.MaxLoginAttempts <= 0 {
+		return false, nil
+	}
+
+	limit := int64(s.config.MaxLoginAttempts)
+	since := time.Now().Add(-s.config.LockoutWindow)
+
+	failures, err := s.attempts.CountFailuresSince(ctx, req.Username, since)
+	if err != nil {
+		return false, err
+	}
+	if failures >= limit {
+		return true, nil
+	}
+	if req.IP == "" {
+		return false, nil
+	}
+
+	failures, err = s.attempts.CountIPFailuresSince(ctx, req.IP, since)
+	if err != nil {
+		return false, err
+	}
+	return failures >= limit, nil
+}
+
+// CountRecentFailures counts failed login attempts for username within window
+func (s *AuthService) CountRecentFailures(ctx context.Context, username string, window time.Duration) (int64, error) {
+	return s.attempts.CountFailuresSince(ctx, username, time.Now().Add(-window))
+}
+
+// authenticate verifies credentials and issues tokens
+func (s *AuthService) authenticate(ctx context.Context, req *service.LoginRequest) (*service.LoginResponse, error) {
 	// Validate input
 	if req.Username == "" || req.Password == "" {
 		return nil, entities.ErrInvalidCredentials
 	}
 
-	// Get user by username
-	user, err := s.getUserByUsername(ctx, req.Username)
+	// Checked before the user lookup, so lockout doesn't reveal which usernames exist
+	locked, err := s.isLockedOut(ctx, req)
 	if err != nil {
+		return nil, err
+	}
+	if locked {
+		return nil, entities.ErrAccountLocked
+	}
+
+	// Get user by username or email
+	user, err := s.getUserByLogin(ctx, req.Username)
+	if err == entities.ErrUserNotFound {
 		return nil, entities.ErrInvalidCredentials
 	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Check approval status before activity, pending users are inactive too
+	switch user.ApprovalStatus {
+	case entities.ApprovalPending:
+		return nil, entities.ErrPendingApproval
+	case entities.ApprovalRejected:
+		return nil, entities.ErrApprovalRejected
+	}
 
 	// Check if user is active
-	if !user.IsActive {
+	if !user.IsActive || user.IsDeactivationDue() {
 		return nil, entities.ErrUserDeactivated
 	}
 
 	// Verify password
-	if !user.VerifyPassword(req.Password) {
+	if !user.VerifyPassword(req.Password, s.hasher) {
 		return nil, entities.ErrInvalidCredentials
 	}
 
+	// Checked after the password so it doesn't reveal anything about the account
+	if s.config.RequireVerifiedEmail && user.Email != nil && !user.EmailVerified {
+		return nil, entities.ErrEmailNotVerified
+	}
+
+	// Migrate hashes made with a previous algorithm while the plaintext is at hand
+	if rehashed, err := user.RehashPassword(req.Password, s.hasher); err == nil && rehashed {
+		// Old hash still verifies, retry on next login
+		if err := s.userRepo.Update(ctx, user); err != nil {
+			s.logger.With(ctx).Warn("Failed to save rehashed password",
+				zap.Uint("userID", user.ID),
+				zap.String("error", err.Error()),
+			)
+		}
+	}
+
 	// Generate tokens
 	accessToken, err := s.jwtService.GenerateAccessToken(user)
 	if err != nil {
 		return nil, err
 	}
 
-	refreshToken, err := s.jwtService.GenerateRefreshToken(user)
+	// Every login starts a new refresh token family, a session
+	familyID, err := newRefreshFamilyID()
+	if err != nil {
+		return nil, err
+	}
+
+	refreshToken, err := s.issueRefreshToken(ctx, user, &entities.RefreshToken{
+		FamilyID:  familyID,
+		UserAgent: req.UserAgent,
+		IP:        req.IP,
+	})
 	if err != nil {
 		return nil, err
 	}
 
 	// Update last login
+	// Don't fail login if only the timestamp couldn't be saved
 	user.UpdateLastLogin()
 	if err := s.userRepo.UpdateLastLogin(ctx, user.ID); err != nil {
-		// Log error but don't fail login
-		// TODO: Add proper logging
+		s.logger.With(ctx).Warn("Failed to update last login",
+			zap.Uint("userID", user.ID),
+			zap.String("error", err.Error()),
+		)
 	}
 
 	return &service.LoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
 		User:         user,
-		ExpiresIn:    15, // 15 minutes
+		ExpiresIn:    int(s.config.AccessExpiry.Minutes()),
+
+		MustChangePassword: user.MustChangePass,
 	}, nil
 }
 
@@ -87,38 +270,68 @@ func (s *AuthService) Register(ctx context.Context, req *service.RegisterRequest
 		return nil, entities.ErrUserAlreadyExists
 	}
 
+	if s.config.UniqueNames {
+		if err := ensureUniqueName(ctx, s.userRepo, req.FirstName, req.LastName, 0); err != nil {
+			return nil, err
+		}
+	}
+
 	// Create new user
 	user := &entities.User{
 		Username:  req.Username,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
-		Role:      getDefaultRole(req.Role),
+		Role:      getDefaultRole(entities.ResolveRole(s.config.RolePatterns, req.Username, req.Role)),
 		IsActive:  true,
 	}
 
-	// Set password
-	if err := user.SetPassword(req.Password); err != nil {
+	if !req.ActorRole.CanManage(user.Role) {
+		return nil, entities.ErrRoleNotAssignable
+	}
+
+	// Manager-registered users wait for admin approval if configured
+	if s.config.RequireApproval && req.ActorRole == entities.RoleManager {
+		user.MarkPendingApproval()
+	}
+
+	// Set password, a manager or admin chose it so the user has to replace it on first login
+	if err := user.SetPassword(req.Password, s.hasher); err != nil {
 		return nil, err
 	}
+	user.MustChangePass = req.ActorRole != ""
 
 	// Validate user entity
 	if err := user.Validate(); err != nil {
 		return nil, err
 	}
 
-	// Save user to database
-	if err := s.userRepo.Create(ctx, user); err != nil {
+	// Save user to database, within the admin quota if it adds an active admin
+	err := withAdminQuota(ctx, s.txManager, s.userRepo, s.config.MaxAdmins, addsAdmin(s.config.MaxAdmins, nil, user.Role, user.IsActive), func(users repository.UserRepository) error {
+		return users.Create(ctx, user)
+	})
+	if err != nil {
 		return nil, err
 	}
 
+	if user.IsPendingApproval() {
+		s.notifier.NotifyPendingApproval(ctx, user)
+	}
+
 	return user, nil
 }
 
 // RefreshToken generates new access token using refresh token
 func (s *AuthService) RefreshToken(ctx context.Context, req *service.RefreshTokenRequest) (*service.LoginResponse, error) {
+	response, err := s.refreshToken(ctx, req)
+	s.metrics.TokenRefresh(err == nil)
+	return response, err
+}
+
+// refreshToken validates and rotates the presented refresh token, then issues new tokens
+func (s *AuthService) refreshToken(ctx context.Context, req *service.RefreshTokenRequest) (*service.LoginResponse, error) {
 	// Validate refresh token
 	token, err := s.jwtService.ParseRefreshToken(req.RefreshToken)
-	if err != nil {
+	if err != nil || s.IsTokenRevoked(ctx, token) {
 		return nil, entities.ErrInvalidToken
 	}
 
@@ -128,14 +341,20 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *service.RefreshToke
 		return nil, entities.ErrInvalidToken
 	}
 
+	// Rotate the presented token out, its successor continues the family
+	previous, err := s.rotateRefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get user from database
 	user, err := s.userRepo.GetByID(ctx, uint(userID))
 	if err != nil {
-		return nil, entities.ErrUserNotFound
+		return nil, err
 	}
 
 	// Check if user is active
-	if !user.IsActive {
+	if !user.IsActive || user.IsDeactivationDue() {
 		return nil, entities.ErrUserDeactivated
 	}
 
@@ -145,7 +364,7 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *service.RefreshToke
 		return nil, err
 	}
 
-	newRefreshToken, err := s.jwtService.GenerateRefreshToken(user)
+	newRefreshToken, err := s.issueRefreshToken(ctx, user, previous)
 	if err != nil {
 		return nil, err
 	}
@@ -154,33 +373,128 @@ func (s *AuthService) RefreshToken(ctx context.Context, req *service.RefreshToke
 		AccessToken:  accessToken,
 		RefreshToken: newRefreshToken,
 		User:         user,
-		ExpiresIn:    15, // 15 minutes
+		ExpiresIn:    int(s.config.AccessExpiry.Minutes()),
 	}, nil
 }
 
-// Logout invalidates user session
-func (s *AuthService) Logout(ctx context.Context, token string) error {
-	// Parse token to get user ID
+// GetRefreshSession returns the stored record of a refresh token without rotating it.
+// Fails with ErrInvalidToken unless the token can still be used for a refresh.
+func (s *AuthService) GetRefreshSession(ctx context.Context, refreshToken string) (*entities.RefreshToken, error) {
+	token, err := s.jwtService.ParseRefreshToken(refreshToken)
+	if err != nil || s.IsTokenRevoked(ctx, token) {
+		return nil, entities.ErrInvalidToken
+	}
+
+	record, err := s.refresh.GetByHash(ctx, hashRefreshToken(refreshToken))
+	if err != nil {
+		return nil, err
+	}
+	if !record.IsUsable() {
+		return nil, entities.ErrInvalidToken
+	}
+	return record, nil
+}
+
+// Logout invalidates user session: both tokens are blacklisted and the refresh
+// token's session is revoked, either token may be empty
+func (s *AuthService) Logout(ctx context.Context, token, refreshToken string) error {
+	if err := s.endRefreshSession(ctx, refreshToken); err != nil {
+		return err
+	}
+	if token == "" {
+		return nil
+	}
+
+	// Parse token to get its ID
 	parsedToken, err := s.jwtService.ParseAccessToken(token)
 	if err != nil {
 		return nil // Token already invalid
 	}
 
-	userID, ok := parsedToken.Claims.(jwt.MapClaims)["user_id"].(float64)
-	if !ok {
+	return s.blacklistToken(ctx, parsedToken)
+}
+
+// blacklistToken rejects the parsed token until it expires.
+// Tokens issued before jti was introduced can't be revoked, they just expire.
+func (s *AuthService) blacklistToken(ctx context.Context, parsedToken *jwt.Token) error {
+	jti, ok := parsedToken.Claims.(jwt.MapClaims)["jti"].(string)
+	if !ok || jti == "" {
 		return nil
 	}
 
-	// For now, just return success - in production you might want to blacklist tokens
-	_ = userID
-	return nil
+	exp, err := parsedToken.Claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return nil
+	}
+
+	// Keep the entry until the token would have expired anyway
+	return s.blacklist.Add(ctx, jti, exp.Time)
+}
+
+// endRefreshSession blacklists the refresh token and revokes its family, so it can't be refreshed again.
+// Invalid and unknown tokens have no session to end.
+func (s *AuthService) endRefreshSession(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return nil
+	}
+	parsedToken, err := s.jwtService.ParseRefreshToken(refreshToken)
+	if err != nil {
+		return nil
+	}
+	if err := s.blacklistToken(ctx, parsedToken); err != nil {
+		return err
+	}
+
+	record, err := s.refresh.GetByHash(ctx, hashRefreshToken(refreshToken))
+	if err == entities.ErrInvalidToken {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.refresh.RevokeFamily(ctx, record.FamilyID)
+}
+
+// IsTokenRevoked checks if a parsed token was blacklisted on logout
+// or issued before all of the user's tokens were revoked
+func (s *AuthService) IsTokenRevoked(ctx context.Context, token *jwt.Token) bool {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return true
+	}
+
+	if jti, ok := claims["jti"].(string); ok && jti != "" && s.blacklist.IsBlacklisted(ctx, jti) {
+		return true
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return true
+	}
+
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil {
+		return false
+	}
+
+	user, err := s.userRepo.GetByID(ctx, uint(userID))
+	if err != nil {
+		return false // revocation can't be decided without the user, user checks handle it
+	}
+
+	return user.IsTokenRevoked(issuedAt.Time)
+}
+
+// PurgeExpiredRevocations drops blacklist entries for tokens that have expired
+func (s *AuthService) PurgeExpiredRevocations(ctx context.Context) (int64, error) {
+	return s.blacklist.DeleteExpired(ctx)
 }
 
 // ValidateToken validates JWT token
 func (s *AuthService) ValidateToken(ctx context.Context, token string) (*entities.User, error) {
 	// Parse token
 	parsedToken, err := s.jwtService.ParseAccessToken(token)
-	if err != nil {
+	if err != nil || s.IsTokenRevoked(ctx, parsedToken) {
 		return nil, entities.ErrInvalidToken
 	}
 
@@ -193,35 +507,410 @@ func (s *AuthService) ValidateToken(ctx context.Context, token string) (*entitie
 	// Get user from database
 	user, err := s.userRepo.GetByID(ctx, uint(userID))
 	if err != nil {
-		return nil, entities.ErrUserNotFound
+		return nil, err
 	}
 
 	// Check if user is active
-	if !user.IsActive {
+	if !user.IsActive || user.IsDeactivationDue() {
 		return nil, entities.ErrUserDeactivated
 	}
 
 	return user, nil
 }
 
-// Helper methods
+// GetTokenUser loads the user a parsed token was issued to, so per-request checks share one lookup.
+// Fails with ErrInvalidToken if the token was blacklisted or all user's tokens were revoked since.
+func (s *AuthService) GetTokenUser(ctx context.Context, token *jwt.Token) (*entities.User, error) {
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, entities.ErrInvalidToken
+	}
 
-func (s *AuthService) getUserByUsername(ctx context.Context, username string) (*entities.User, error) {
-	// Get user by username
-	user, err := s.userRepo.GetByUsername(ctx, username)
+	if jti, ok := claims["jti"].(string); ok && jti != "" && s.blacklist.IsBlacklisted(ctx, jti) {
+		return nil, entities.ErrInvalidToken
+	}
+
+	userID, ok := claims["user_id"].(float64)
+	if !ok {
+		return nil, entities.ErrInvalidToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, uint(userID))
 	if err != nil {
-		return nil, entities.ErrUserNotFound
+		return nil, err
+	}
+
+	if issuedAt, err := claims.GetIssuedAt(); err == nil && issuedAt != nil && user.IsTokenRevoked(issuedAt.Time) {
+		return nil, entities.ErrInvalidToken
 	}
 	return user, nil
 }
 
+// SelfTest verifies token signing and parsing round-trips
+func (s *AuthService) SelfTest(ctx context.Context) []service.SelfTestCheck {
+	user := &entities.User{
+		ID:       selfTestUserID,
+		Username: "selftest",
+		Role:     entities.RoleGuest,
+	}
+
+	var checks []service.SelfTestCheck
+	record := func(name string, err error) bool {
+		check := service.SelfTestCheck{Name: name, Passed: err == nil}
+		if err != nil {
+			check.Error = err.Error()
+		}
+		checks = append(checks, check)
+		return err == nil
+	}
+
+	// Access token round-trip
+	accessToken, err := s.jwtService.GenerateAccessToken(user)
+	if record("access_token_sign", err) {
+		record("access_token_verify", s.verifySelfTestAccessToken(accessToken, user))
+		if _, err := s.jwtService.ParseRefreshToken(accessToken); err == nil {
+			record("access_token_not_refresh", errors.New("access token accepted as refresh token"))
+		} else {
+			record("access_token_not_refresh", nil)
+		}
+	}
+
+	// Refresh token round-trip
+	refreshToken, err := s.jwtService.GenerateRefreshToken(user)
+	if record("refresh_token_sign", err) {
+		record("refresh_token_verify", s.verifySelfTestRefreshToken(refreshToken, user))
+	}
+
+	return checks
+}
+
+// PreviewAccessToken decodes an access token generated for user without issuing it.
+// The signed token is discarded so it can't be used.
+func (s *AuthService) PreviewAccessToken(ctx context.Context, userID uint) (*service.TokenPreview, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := s.jwtService.GenerateAccessToken(user)
+	if err != nil {
+		return nil, err
+	}
+
+	parsedToken, err := s.jwtService.ParseAccessToken(accessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := parsedToken.Claims.(jwt.MapClaims)
+	if !ok {
+		return nil, errors.New("unexpected access token claims")
+	}
+
+	return &service.TokenPreview{
+		Header: parsedToken.Header,
+		Claims: claims,
+	}, nil
+}
+
+// ListSessions retrieves paginated sessions of a user, active ones unless Expired is set
+func (s *AuthService) ListSessions(ctx context.Context, req *service.ListSessionsRequest) (*service.Page[*entities.Session], error) {
+	if _, err := s.userRepo.GetByID(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+
+	// Set default pagination values
+	limit := req.Limit
+	if limit <= 0 || limit > 100 {
+		limit = 20 // Default limit
+	}
+
+	offset := req.Offset
+	if offset < 0 {
+		offset = 0
+	}
+
+	sessions, total, err := s.refresh.ListSessions(ctx, repository.SessionFilter{
+		UserID:  req.UserID,
+		Expired: req.Expired,
+		Limit:   limit,
+		Offset:  offset,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return service.NewPage(sessions, total, limit, offset), nil
+}
+
+// RevokeSession ends a session of the user, its refresh token stops working.
+// Access tokens already issued stay valid until they expire.
+func (s *AuthService) RevokeSession(ctx context.Context, userID uint, sessionID string) error {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return err
+	}
+	return s.refresh.RevokeSession(ctx, userID, sessionID)
+}
+
+// RevokeAllSessions ends every session of the user and returns how many were ended
+func (s *AuthService) RevokeAllSessions(ctx context.Context, userID uint) (int64, error) {
+	if _, err := s.userRepo.GetByID(ctx, userID); err != nil {
+		return 0, err
+	}
+	return s.refresh.RevokeAllSessions(ctx, userID)
+}
+
+// recentFailuresWindow is the period of failed logins reported in the security summary
+const recentFailuresWindow = 24 * time.Hour
+
+// GetSecuritySummary returns an overview of the user's account security.
+// Two-factor authentication isn't supported, so it's always reported disabled.
+func (s *AuthService) GetSecuritySummary(ctx context.Context, userID uint) (*service.SecuritySummary, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	activeSessions, err := s.refresh.CountActiveSessions(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	failures, err := s.CountRecentFailures(ctx, user.Username, recentFailuresWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	passwordSetAt := user.CreatedAt
+	if user.PassChangedAt != nil {
+		passwordSetAt = *user.PassChangedAt
+	}
+
+	return &service.SecuritySummary{
+		PasswordAgeDays:      int(time.Since(passwordSetAt).Hours() / 24),
+		TwoFactorEnabled:     false,
+		ActiveSessions:       activeSessions,
+		LastLogin:            user.LastLogin,
+		FailedAttemptsRecent: failures,
+	}, nil
+}
+
+// Stats range limits, hourly buckets are capped to keep responses small
+const (
+	maxStatsDays       = 366
+	maxHourlyStatsDays = 31
+)
+
+// GetLoginStats counts login attempts of the last req.Days days per interval.
+// Buckets are aligned in UTC like date_trunc, weeks start on Monday.
+func (s *AuthService) GetLoginStats(ctx context.Context, req *service.LoginStatsRequest) (*service.LoginStats, error) {
+	maxDays := maxStatsDays
+	switch req.Interval {
+	case service.StatsIntervalHour:
+		maxDays = maxHourlyStatsDays
+	case service.StatsIntervalDay, service.StatsIntervalWeek:
+	default:
+		return nil, entities.ErrInvalidStatsRange
+	}
+	if req.Days < 1 || req.Days > maxDays {
+		return nil, entities.ErrInvalidStatsRange
+	}
+
+	now := time.Now().UTC()
+	from := truncateStats(now.AddDate(0, 0, -req.Days), req.Interval)
+
+	counts, err := s.attempts.CountByInterval(ctx, req.Interval, from)
+	if err != nil {
+		return nil, err
+	}
+
+	byBucket := make(map[time.Time]repository.LoginCount, len(counts))
+	for _, count := range counts {
+		byBucket[count.Bucket.UTC()] = count
+	}
+
+	// Intervals without attempts are reported with zero counts
+	stats := &service.LoginStats{Interval: req.Interval, From: from, Buckets: []service.LoginStatsBucket{}}
+	last := truncateStats(now, req.Interval)
+	for start := from; !start.After(last); start = nextStats(start, req.Interval) {
+		count := byBucket[start]
+		stats.Buckets = append(stats.Buckets, service.LoginStatsBucket{
+			Start:      start,
+			Successful: count.Successful,
+			Failed:     count.Failed,
+		})
+	}
+
+	return stats, nil
+}
+
+// truncateStats returns the start of the stats interval containing t, t must be UTC
+func truncateStats(t time.Time, interval string) time.Time {
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch interval {
+	case service.StatsIntervalHour:
+		return t.Truncate(time.Hour)
+	case service.StatsIntervalWeek:
+		// Monday, as with date_trunc('week', ...)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	default:
+		return day
+	}
+}
+
+// nextStats returns the start of the stats interval following start
+func nextStats(start time.Time, interval string) time.Time {
+	switch interval {
+	case service.StatsIntervalHour:
+		return start.Add(time.Hour)
+	case service.StatsIntervalWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// Helper methods
+
+// selfTestUserID is the ID of the synthetic user used by SelfTest
+const selfTestUserID = 999999999
+
+func (s *AuthService) verifySelfTestAccessToken(token string, user *entities.User) error {
+	parsedToken, err := s.jwtService.ParseAccessToken(token)
+	if err != nil {
+		return err
+	}
+
+	userInfo, err := s.jwtService.ExtractUserFromToken(parsedToken)
+	if err != nil {
+		return err
+	}
+
+	if userInfo.UserID != user.ID || userInfo.Username != user.Username || userInfo.Role != user.Role {
+		return errors.New("access token claims do not match")
+	}
+
+	return nil
+}
+
+func (s *AuthService) verifySelfTestRefreshToken(token string, user *entities.User) error {
+	parsedToken, err := s.jwtService.ParseRefreshToken(token)
+	if err != nil {
+		return err
+	}
+
+	userID, ok := parsedToken.Claims.(jwt.MapClaims)["user_id"].(float64)
+	if !ok || uint(userID) != user.ID {
+		return errors.New("refresh token claims do not match")
+	}
+
+	return nil
+}
+
+// issueRefreshToken generates refresh token for user and stores its hash,
+// keeping family and login details of previous
+func (s *AuthService) issueRefreshToken(ctx context.Context, user *entities.User, previous *entities.RefreshToken) (string, error) {
+	token, err := s.jwtService.GenerateRefreshToken(user)
+	if err != nil {
+		return "", err
+	}
+
+	record := &entities.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  previous.FamilyID,
+		TokenHash: hashRefreshToken(token),
+		UserAgent: previous.UserAgent,
+		IP:        previous.IP,
+		ExpiresAt: time.Now().Add(s.config.RefreshExpiry),
+	}
+	if err := s.refresh.Create(ctx, record); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// refreshReuseGrace is how long after rotation a refresh token is still presented by
+// parallel requests of the same client rather than by someone it leaked to
+const refreshReuseGrace = 10 * time.Second
+
+// rotateRefreshToken marks the presented refresh token used and returns its record.
+// A token that was already used means it leaked, so the whole family is revoked
+// and ErrTokenReused returned. Within refreshReuseGrace of the rotation it is just
+// invalid, so concurrent refreshes of one client don't end its session. Tokens of
+// revoked sessions are invalid as well, as are unknown tokens, which can't be marked used.
+func (s *AuthService) rotateRefreshToken(ctx context.Context, token string) (*entities.RefreshToken, error) {
+	record, err := s.refresh.GetByHash(ctx, hashRefreshToken(token))
+	if err != nil {
+		return nil, err
+	}
+
+	if record.UsedAt != nil {
+		if time.Since(*record.UsedAt) < refreshReuseGrace {
+			return nil, entities.ErrInvalidToken
+		}
+		return nil, s.revokeRefreshFamily(ctx, record.FamilyID)
+	}
+	if record.RevokedAt != nil {
+		return nil, entities.ErrInvalidToken
+	}
+
+	if err := s.refresh.MarkUsed(ctx, record.ID); err != nil {
+		// A concurrent request rotated the same token just now, or the session was revoked meanwhile
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// revokeRefreshFamily revokes family after reuse was detected and returns ErrTokenReused
+func (s *AuthService) revokeRefreshFamily(ctx context.Context, familyID string) error {
+	if err := s.refresh.RevokeFamily(ctx, familyID); err != nil {
+		return err
+	}
+	return entities.ErrTokenReused
+}
+
+// newRefreshFamilyID generates a random id for a new refresh token family
+func newRefreshFamilyID() (string, error) {
+	raw := make([]byte, 16)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
+
+// hashRefreshToken hashes refresh token for storage and lookup
+func hashRefreshToken(token string) string {
+	return hashResetToken(token)
+}
+
+// getUserByLogin finds user by username, or by email if no username matches
+func (s *AuthService) getUserByLogin(ctx context.Context, login string) (*entities.User, error) {
+	// Get user by username
+	user, err := s.userRepo.GetByUsername(ctx, login)
+	if err == entities.ErrUserNotFound && strings.Contains(login, "@") {
+		return s.userRepo.GetByEmail(ctx, login)
+	}
+	return user, err
+}
+
 func (s *AuthService) validateRegistrationRequest(req *service.RegisterRequest) error {
 	if req.Username == "" || len(req.Username) < 3 {
 		return entities.ErrInvalidUsername
 	}
 
-	if req.Password == "" || len(req.Password) < 8 {
-		return entities.ErrPasswordTooShort
+	if s.config.RejectNumericNames && entities.IsNumericUsername(req.Username) {
+		return entities.ErrNumericUsername
+	}
+
+	if err := s.config.PasswordPolicy.Check(req.Password); err != nil {
+		return err
+	}
+
+	// Only the empty role defaults to user
+	if req.Role != "" && !req.Role.IsValid() {
+		return entities.ErrInvalidRole
 	}
 
 	return nil
@@ -233,10 +922,8 @@ func getDefaultRole(role entities.Role) entities.Role {
 	}
 
 	// Validate role
-	switch role {
-	case entities.RoleAdmin, entities.RoleManager, entities.RoleUser, entities.RoleGuest:
+	if role.IsValid() {
 		return role
-	default:
-		return entities.RoleUser
 	}
+	return entities.RoleUser
 }
@@ -0,0 +1,563 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	jwtlib "github.com/golang-jwt/jwt/v5"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/jwt"
+	"github.com/ontair/admin-panel/internal/adapters/secondary/metrics"
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/service"
+	"github.com/ontair/admin-panel/internal/infra/config"
+	"github.com/ontair/admin-panel/internal/testutil"
+)
+
+// testJWTConfig is the HS256 configuration auth service tests sign with
+func testJWTConfig() *config.Config {
+	return &config.Config{JWT: config.JWTConfig{
+		SecretKey:     "test-access-secret",
+		RefreshSecret: "test-refresh-secret",
+		AccessExpiry:  15,
+		RefreshExpiry: 60,
+		Issuer:        "test-issuer",
+		Audience:      "test-audience",
+		Algorithm:     "HS256",
+	}}
+}
+
+// authServiceFixture is an auth service over in-memory repositories and a real JWT service
+type authServiceFixture struct {
+	service *AuthService
+	store   *testutil.Store
+	logger  *testutil.Logger
+}
+
+func newAuthServiceFixture(t *testing.T, config AuthServiceConfig) *authServiceFixture {
+	t.Helper()
+	if config.PasswordPolicy.MinLength == 0 {
+		config.PasswordPolicy.MinLength = 8
+	}
+	if config.AccessExpiry == 0 {
+		config.AccessExpiry = 15 * time.Minute
+	}
+	if config.RefreshExpiry == 0 {
+		config.RefreshExpiry = time.Hour
+	}
+
+	jwtService, err := jwt.NewJWTService(testJWTConfig())
+	if err != nil {
+		t.Fatalf("failed to create JWT service: %v", err)
+	}
+
+	store := testutil.NewStore()
+	logger := testutil.NewLogger()
+	svc := NewAuthService(store.Users, jwtService, store.Blacklist, testutil.Hasher{}, &testutil.Notifier{},
+		store.LoginAttempts, store.RefreshTokens, store.TxManager(), metrics.NewRegistry(), logger, config)
+
+	return &authServiceFixture{
+		service: svc.(*AuthService),
+		store:   store,
+		logger:  logger,
+	}
+}
+
+// addUser stores an active approved user with testPassword
+func (f *authServiceFixture) addUser(t *testing.T, username string, role entities.Role) *entities.User {
+	t.Helper()
+	password, _ := testutil.Hasher{}.Hash(testPassword)
+	user := &entities.User{
+		Username:       username,
+		Password:       password,
+		Role:           role,
+		IsActive:       true,
+		ApprovalStatus: entities.ApprovalApproved,
+	}
+	if err := f.store.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("failed to add user %s: %v", username, err)
+	}
+	return user
+}
+
+// addSession stores a refresh token starting a new session of the user
+func (f *authServiceFixture) addSession(t *testing.T, userID uint, family string, expiresAt time.Time) {
+	t.Helper()
+	err := f.store.RefreshTokens.Create(context.Background(), &entities.RefreshToken{
+		UserID:    userID,
+		FamilyID:  family,
+		TokenHash: "hash-" + family,
+		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		t.Fatalf("failed to add session: %v", err)
+	}
+}
+
+func TestListSessionsPaginatesManySessions(t *testing.T) {
+	f := newAuthServiceFixture(t, AuthServiceConfig{})
+	user := f.addUser(t, "heavy", entities.RoleUser)
+	other := f.addUser(t, "other", entities.RoleUser)
+
+	for i := 0; i < 45; i++ {
+		f.addSession(t, user.ID, fmt.Sprintf("active-%d", i), time.Now().Add(time.Hour))
+	}
+	for i := 0; i < 3; i++ {
+		f.addSession(t, user.ID, fmt.Sprintf("expired-%d", i), time.Now().Add(-time.Minute))
+	}
+	f.addSession(t, other.ID, "foreign", time.Now().Add(time.Hour))
+
+	ctx := context.Background()
+	seen := make(map[string]bool)
+	for offset := 0; ; offset += 20 {
+		page, err := f.service.ListSessions(ctx, &service.ListSessionsRequest{UserID: user.ID, Limit: 20, Offset: offset})
+		if err != nil {
+			t.Fatalf("failed to list sessions: %v", err)
+		}
+		if page.Total != 45 || page.TotalPages != 3 || page.Page != offset/20+1 {
+			t.Fatalf("offset %d: unexpected metadata %+v", offset, page)
+		}
+		for _, session := range page.Items {
+			if session.UserID != user.ID || seen[session.ID] {
+				t.Fatalf("offset %d: unexpected session %+v", offset, session)
+			}
+			seen[session.ID] = true
+		}
+		if !page.HasNext {
+			if len(page.Items) != 5 {
+				t.Errorf("expected 5 sessions on the last page, got %d", len(page.Items))
+			}
+			break
+		}
+	}
+	if len(seen) != 45 {
+		t.Errorf("expected all 45 active sessions across pages, got %d", len(seen))
+	}
+
+	expired, err := f.service.ListSessions(ctx, &service.ListSessionsRequest{UserID: user.ID, Expired: true})
+	if err != nil {
+		t.Fatalf("failed to list expired sessions: %v", err)
+	}
+	if expired.Total != 3 || len(expired.Items) != 3 || expired.Limit != 20 {
+		t.Errorf("expected 3 expired sessions with default limit, got %+v", expired)
+	}
+}
+
+func TestListSessionsUnknownUser(t *testing.T) {
+	f := newAuthServiceFixture(t, AuthServiceConfig{})
+
+	_, err := f.service.ListSessions(context.Background(), &service.ListSessionsRequest{UserID: 42})
+	if err != entities.ErrUserNotFound {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestRegisterRejectsNumericUsername(t *testing.T) {
+	f := newAuthServiceFixture(t, AuthServiceConfig{RejectNumericNames: true})
+
+	_, err := f.service.Register(context.Background(), &service.RegisterRequest{Username: "12345", Password: testPassword, ActorRole: entities.RoleManager})
+	if err != entities.ErrNumericUsername {
+		t.Errorf("expected ErrNumericUsername, got %v", err)
+	}
+
+	if _, err := f.service.Register(context.Background(), &service.RegisterRequest{Username: "user123", Password: testPassword, ActorRole: entities.RoleManager}); err != nil {
+		t.Errorf("expected username with letters allowed, got %v", err)
+	}
+}
+
+func TestSelfTestPasses(t *testing.T) {
+	f := newAuthServiceFixture(t, AuthServiceConfig{})
+
+	checks := f.service.SelfTest(context.Background())
+	if len(checks) != 5 {
+		t.Fatalf("expected 5 checks, got %+v", checks)
+	}
+	for _, check := range checks {
+		if !check.Passed {
+			t.Errorf("check %s failed: %s", check.Name, check.Error)
+		}
+	}
+}
+
+// mismatchedJWTService signs with one configuration and verifies with another,
+// like instances deployed with different secrets
+type mismatchedJWTService struct {
+	service.JWTService
+	verifier service.JWTService
+}
+
+func (s mismatchedJWTService) ParseAccessToken(token string) (*jwtlib.Token, error) {
+	return s.verifier.ParseAccessToken(token)
+}
+
+func (s mismatchedJWTService) ParseRefreshToken(token string) (*jwtlib.Token, error) {
+	return s.verifier.ParseRefreshToken(token)
+}
+
+func TestSelfTestReportsMismatchedSecret(t *testing.T) {
+	signer, err := jwt.NewJWTService(testJWTConfig())
+	if err != nil {
+		t.Fatalf("failed to create JWT service: %v", err)
+	}
+	verifierConfig := testJWTConfig()
+	verifierConfig.JWT.SecretKey = "other-access-secret"
+	verifier, err := jwt.NewJWTService(verifierConfig)
+	if err != nil {
+		t.Fatalf("failed to create JWT service: %v", err)
+	}
+
+	store := testutil.NewStore()
+	svc := NewAuthService(store.Users, mismatchedJWTService{signer, verifier}, store.Blacklist, testutil.Hasher{}, &testutil.Notifier{},
+		store.LoginAttempts, store.RefreshTokens, store.TxManager(), metrics.NewRegistry(), testutil.NewLogger(), AuthServiceConfig{})
+
+	passed := make(map[string]bool)
+	for _, check := range svc.SelfTest(context.Background()) {
+		passed[check.Name] = check.Passed
+		if !check.Passed && strings.Contains(check.Error, "secret") {
+			t.Errorf("check %s exposes the secret: %s", check.Name, check.Error)
+		}
+	}
+	if passed["access_token_verify"] {
+		t.Errorf("expected access token verification to fail with mismatched secrets")
+	}
+	if !passed["access_token_sign"] || !passed["refresh_token_verify"] {
+		t.Errorf("expected signing and refresh checks to pass, got %v", passed)
+	}
+}
+
+func TestLoginExpiresInFollowsAccessExpiry(t *testing.T) {
+	f := newAuthServiceFixture(t, AuthServiceConfig{AccessExpiry: 30 * time.Minute})
+	f.addUser(t, "alice", entities.RoleUser)
+
+	response, err := f.service.Login(context.Background(), &service.LoginRequest{Username: "alice", Password: testPassword})
+	if err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+	if response.ExpiresIn != 30 {
+		t.Errorf("expected expires_in 30, got %d", response.ExpiresIn)
+	}
+}
+
+func TestSecuritySummaryReflectsRecentActivity(t *testing.T) {
+	f := newAuthServiceFixture(t, AuthServiceConfig{})
+	user := f.addUser(t, "alice", entities.RoleUser)
+	ctx := context.Background()
+
+	if _, err := f.service.Login(ctx, &service.LoginRequest{Username: "alice", Password: testPassword}); err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+	// Failures are counted since the last successful login
+	for i := 0; i < 2; i++ {
+		if _, err := f.service.Login(ctx, &service.LoginRequest{Username: "alice", Password: "wrong-password"}); err == nil {
+			t.Fatalf("expected login with a wrong password to fail")
+		}
+	}
+	changedAt := time.Now().Add(-10*24*time.Hour - time.Hour)
+	if err := f.store.Users.UpdatePasswordLocked(ctx, user.ID, func(u *entities.User) error {
+		u.PassChangedAt = &changedAt
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to set password date: %v", err)
+	}
+
+	summary, err := f.service.GetSecuritySummary(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("failed to get summary: %v", err)
+	}
+	if summary.FailedAttemptsRecent != 2 {
+		t.Errorf("expected 2 recent failures, got %d", summary.FailedAttemptsRecent)
+	}
+	if summary.ActiveSessions != 1 {
+		t.Errorf("expected 1 active session, got %d", summary.ActiveSessions)
+	}
+	if summary.LastLogin == nil || time.Since(*summary.LastLogin) > time.Minute {
+		t.Errorf("expected last login just now, got %v", summary.LastLogin)
+	}
+	if summary.PasswordAgeDays != 10 {
+		t.Errorf("expected password age 10 days, got %d", summary.PasswordAgeDays)
+	}
+	if summary.TwoFactorEnabled {
+		t.Errorf("expected two-factor reported disabled")
+	}
+}
+
+func TestRegisterRoleValidation(t *testing.T) {
+	tests := []struct {
+		role entities.Role
+		want entities.Role
+		err  error
+	}{
+		{"", entities.RoleUser, nil},
+		{entities.RoleGuest, entities.RoleGuest, nil},
+		{"amdin", "", entities.ErrInvalidRole},
+	}
+
+	for _, tt := range tests {
+		f := newAuthServiceFixture(t, AuthServiceConfig{})
+		user, err := f.service.Register(context.Background(), &service.RegisterRequest{
+			Username:  "newcomer",
+			Password:  testPassword,
+			Role:      tt.role,
+			ActorRole: entities.RoleAdmin,
+		})
+		if err != tt.err {
+			t.Errorf("role %q: expected error %v, got %v", tt.role, tt.err, err)
+			continue
+		}
+		if err == nil && user.Role != tt.want {
+			t.Errorf("role %q: expected %s, got %s", tt.role, tt.want, user.Role)
+		}
+	}
+}
+
+func TestLoginStatsZeroFillsDays(t *testing.T) {
+	f := newAuthServiceFixture(t, AuthServiceConfig{})
+	ctx := context.Background()
+	now := time.Now().UTC()
+	for _, attempt := range []*entities.LoginAttempt{
+		{Username: "alice", Success: true, CreatedAt: now.AddDate(0, 0, -1)},
+		{Username: "alice", Success: true, CreatedAt: now.AddDate(0, 0, -1)},
+		{Username: "bob", Success: false, CreatedAt: now.AddDate(0, 0, -3)},
+		{Username: "bob", Success: false, CreatedAt: now.AddDate(0, 0, -30)}, // out of range
+	} {
+		if err := f.store.LoginAttempts.Create(ctx, attempt); err != nil {
+			t.Fatalf("failed to add attempt: %v", err)
+		}
+	}
+
+	stats, err := f.service.GetLoginStats(ctx, &service.LoginStatsRequest{Interval: service.StatsIntervalDay, Days: 7})
+	if err != nil {
+		t.Fatalf("failed to get stats: %v", err)
+	}
+
+	// Seven days back through today, one bucket each
+	if len(stats.Buckets) != 8 {
+		t.Fatalf("expected 8 buckets, got %d", len(stats.Buckets))
+	}
+	var successful, failed, empty int64
+	for i, bucket := range stats.Buckets {
+		if i > 0 && !bucket.Start.Equal(stats.Buckets[i-1].Start.AddDate(0, 0, 1)) {
+			t.Errorf("expected consecutive days, got %v after %v", bucket.Start, stats.Buckets[i-1].Start)
+		}
+		successful += bucket.Successful
+		failed += bucket.Failed
+		if bucket.Successful == 0 && bucket.Failed == 0 {
+			empty++
+		}
+	}
+	if successful != 2 || failed != 1 || empty != 6 {
+		t.Errorf("expected 2 successful and 1 failed in 2 buckets, got %d, %d with %d empty", successful, failed, empty)
+	}
+}
+
+func TestLoginStatsRangeValidation(t *testing.T) {
+	f := newAuthServiceFixture(t, AuthServiceConfig{})
+
+	for _, req := range []service.LoginStatsRequest{
+		{Interval: "month", Days: 30},
+		{Interval: service.StatsIntervalDay, Days: 0},
+		{Interval: service.StatsIntervalDay, Days: maxStatsDays + 1},
+		{Interval: service.StatsIntervalHour, Days: maxHourlyStatsDays + 1},
+	} {
+		if _, err := f.service.GetLoginStats(context.Background(), &req); err != entities.ErrInvalidStatsRange {
+			t.Errorf("%+v: expected ErrInvalidStatsRange, got %v", req, err)
+		}
+	}
+}
+
+// failLogin attempts to log in as username with a wrong password
+func (f *authServiceFixture) failLogin(t *testing.T, username string) error {
+	t.Helper()
+	_, err := f.service.Login(context.Background(), &service.LoginRequest{Username: username, Password: "wrong-password", IP: "192.0.2.1"})
+	return err
+}
+
+func TestLoginLockedOutAfterMaxFailures(t *testing.T) {
+	f := newAuthServiceFixture(t, AuthServiceConfig{MaxLoginAttempts: 3, LockoutWindow: 10 * time.Minute})
+	f.addUser(t, "alice", entities.RoleUser)
+
+	for i := 0; i < 3; i++ {
+		if err := f.failLogin(t, "alice"); err != entities.ErrInvalidCredentials {
+			t.Fatalf("attempt %d: expected invalid credentials, got %v", i+1, err)
+		}
+	}
+
+	// Even the right password is refused while locked out
+	_, err := f.service.Login(context.Background(), &service.LoginRequest{Username: "alice", Password: testPassword, IP: "192.0.2.9"})
+	if err != entities.ErrAccountLocked {
+		t.Errorf("expected account locked, got %v", err)
+	}
+}
+
+func TestLoginSuccessResetsFailures(t *testing.T) {
+	f := newAuthServiceFixture(t, AuthServiceConfig{MaxLoginAttempts: 3, LockoutWindow: 10 * time.Minute})
+	f.addUser(t, "alice", entities.RoleUser)
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		f.failLogin(t, "alice")
+	}
+	if _, err := f.service.Login(ctx, &service.LoginRequest{Username: "alice", Password: testPassword, IP: "192.0.2.1"}); err != nil {
+		t.Fatalf("expected login below the limit, got %v", err)
+	}
+
+	// Only failures after the success count, so two more don't lock the account
+	for i := 0; i < 2; i++ {
+		f.failLogin(t, "alice")
+	}
+	if _, err := f.service.Login(ctx, &service.LoginRequest{Username: "alice", Password: testPassword, IP: "192.0.2.1"}); err != nil {
+		t.Errorf("expected failures reset by the successful login, got %v", err)
+	}
+}
+
+// Attempts refused by the lockout aren't recorded, so retrying doesn't push the window forward
+func TestLockedAttemptsDontExtendLockout(t *testing.T) {
+	f := newAuthServiceFixture(t, AuthServiceConfig{MaxLoginAttempts: 3, LockoutWindow: 10 * time.Minute})
+	f.addUser(t, "alice", entities.RoleUser)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		f.failLogin(t, "alice")
+	}
+	for i := 0; i < 5; i++ {
+		if err := f.failLogin(t, "alice"); err != entities.ErrAccountLocked {
+			t.Fatalf("expected account locked, got %v", err)
+		}
+	}
+
+	failures, err := f.service.CountRecentFailures(ctx, "alice", time.Hour)
+	if err != nil {
+		t.Fatalf("failed to count failures: %v", err)
+	}
+	if failures != 3 {
+		t.Errorf("expected only the 3 failures before the lockout recorded, got %d", failures)
+	}
+}
+
+// Parallel requests of one client refresh with the same token, the losers must not end the session
+func TestConcurrentRefreshKeepsSession(t *testing.T) {
+	f := newAuthServiceFixture(t, AuthServiceConfig{})
+	f.addUser(t, "alice", entities.RoleUser)
+	ctx := context.Background()
+
+	login, err := f.service.Login(ctx, &service.LoginRequest{Username: "alice", Password: testPassword})
+	if err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+
+	type result struct {
+		response *service.LoginResponse
+		err      error
+	}
+	results := make(chan result, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			response, err := f.service.RefreshToken(ctx, &service.RefreshTokenRequest{RefreshToken: login.RefreshToken})
+			results <- result{response, err}
+		}()
+	}
+
+	var successor string
+	for i := 0; i < 2; i++ {
+		r := <-results
+		switch {
+		case r.err == nil:
+			if successor != "" {
+				t.Fatalf("expected only one refresh to rotate the token")
+			}
+			successor = r.response.RefreshToken
+		case r.err != entities.ErrInvalidToken:
+			t.Fatalf("expected the concurrent refresh to be invalid, got %v", r.err)
+		}
+	}
+	if successor == "" {
+		t.Fatalf("expected one refresh to succeed")
+	}
+
+	if _, err := f.service.RefreshToken(ctx, &service.RefreshTokenRequest{RefreshToken: successor}); err != nil {
+		t.Errorf("expected the session to survive, got %v", err)
+	}
+}
+
+func TestLogoutEndsRefreshSession(t *testing.T) {
+	f := newAuthServiceFixture(t, AuthServiceConfig{})
+	user := f.addUser(t, "alice", entities.RoleUser)
+	ctx := context.Background()
+
+	login, err := f.service.Login(ctx, &service.LoginRequest{Username: "alice", Password: testPassword})
+	if err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+	if err := f.service.Logout(ctx, login.AccessToken, login.RefreshToken); err != nil {
+		t.Fatalf("failed to log out: %v", err)
+	}
+
+	if _, err := f.service.RefreshToken(ctx, &service.RefreshTokenRequest{RefreshToken: login.RefreshToken}); err != entities.ErrInvalidToken {
+		t.Errorf("expected the refresh after logout to be invalid, got %v", err)
+	}
+	page, err := f.service.ListSessions(ctx, &service.ListSessionsRequest{UserID: user.ID, Limit: 10})
+	if err != nil {
+		t.Fatalf("failed to list sessions: %v", err)
+	}
+	if page.Total != 0 {
+		t.Errorf("expected no active sessions after logout, got %d", page.Total)
+	}
+}
+
+func TestLogoutBlacklistsBothTokens(t *testing.T) {
+	f := newAuthServiceFixture(t, AuthServiceConfig{})
+	f.addUser(t, "alice", entities.RoleUser)
+	ctx := context.Background()
+
+	login, err := f.service.Login(ctx, &service.LoginRequest{Username: "alice", Password: testPassword})
+	if err != nil {
+		t.Fatalf("failed to log in: %v", err)
+	}
+	if err := f.service.Logout(ctx, login.AccessToken, login.RefreshToken); err != nil {
+		t.Fatalf("failed to log out: %v", err)
+	}
+
+	access, err := f.service.jwtService.ParseAccessToken(login.AccessToken)
+	if err != nil {
+		t.Fatalf("failed to parse access token: %v", err)
+	}
+	refresh, err := f.service.jwtService.ParseRefreshToken(login.RefreshToken)
+	if err != nil {
+		t.Fatalf("failed to parse refresh token: %v", err)
+	}
+	if !f.service.IsTokenRevoked(ctx, access) {
+		t.Errorf("expected the access token to be blacklisted")
+	}
+	if !f.service.IsTokenRevoked(ctx, refresh) {
+		t.Errorf("expected the refresh token to be blacklisted")
+	}
+}
+
+func TestRegisterAdminQuota(t *testing.T) {
+	f := newAuthServiceFixture(t, AuthServiceConfig{MaxAdmins: 1})
+	f.addUser(t, "root", entities.RoleAdmin)
+	ctx := context.Background()
+
+	_, err := f.service.Register(ctx, &service.RegisterRequest{
+		Username:  "second_admin",
+		Password:  testPassword,
+		Role:      entities.RoleAdmin,
+		ActorRole: entities.RoleAdmin,
+	})
+	if err != entities.ErrAdminQuotaExceeded {
+		t.Fatalf("expected ErrAdminQuotaExceeded, got %v", err)
+	}
+	if _, err := f.store.Users.GetByUsername(ctx, "second_admin"); err != entities.ErrUserNotFound {
+		t.Errorf("expected no user created past the quota, got %v", err)
+	}
+
+	if _, err := f.service.Register(ctx, &service.RegisterRequest{
+		Username:  "regular",
+		Password:  testPassword,
+		ActorRole: entities.RoleAdmin,
+	}); err != nil {
+		t.Errorf("expected non-admins to be registered at the quota, got %v", err)
+	}
+}
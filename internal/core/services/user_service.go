@@ -2,51 +2,98 @@ package services
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"slices"
 	"strings"
+	"time"
 
 	"github.com/ontair/admin-panel/internal/core/entities"
 	"github.com/ontair/admin-panel/internal/core/ports/repository"
 	"github.com/ontair/admin-panel/internal/core/ports/service"
 )
 
+// UserServiceConfig holds configurable user management behavior
+type UserServiceConfig struct {
+	RequireApproval     bool // manager-created users need admin approval
+	LockPasswordChanges bool // serialize concurrent password changes with a row lock
+	RejectNumericNames  bool // disallow all-digit usernames
+	SearchMaxLength     int  // longer search queries are rejected
+	SearchWildcards     bool // treat % and _ in search as wildcards
+	CustomRoles         bool // accept roles defined in the roles table besides built-in ones
+
+	LogoutOnPasswordChange  bool // revoke all tokens and sessions, the current one included, after the user changes their own password
+	RejectUnchangedPassword bool // new password in change and reset must differ from the current one
+
+	HideInactiveFromManagers bool // exclude inactive users from manager listings unless requested
+
+	RefetchAfterWrite bool // reload created and updated users so responses match the stored row
+
+	UniqueNames  bool                   // reject users whose first and last name match another active user
+	MaxAdmins    int                    // active admins allowed, 0 means unlimited
+	RolePatterns []entities.RolePattern // roles for usernames matching a pattern when none is given
+
+	PasswordPolicy  entities.PasswordPolicy // requirements for every password set through the service
+	PasswordHistory int                     // previous passwords that can't be reused on change and reset, 0 disables
+}
+
 // UserService implements UserService interface
 type UserService struct {
-	userRepo repository.UserRepository
+	userRepo    repository.UserRepository
+	roleRepo    repository.RoleRepository
+	resetRepo   repository.PasswordResetRepository
+	verifyRepo  repository.EmailVerificationRepository
+	historyRepo repository.PasswordHistoryRepository
+	attempts    repository.LoginAttemptRepository
+	txManager   repository.TxManager
+	hasher      entities.PasswordHasher
+	notifier    service.Notifier
+	audit       service.AuditService
+	config      UserServiceConfig
 }
 
 // NewUserService creates new user service
-func NewUserService(userRepo repository.UserRepository) service.UserService {
+func NewUserService(
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	resetRepo repository.PasswordResetRepository,
+	verifyRepo repository.EmailVerificationRepository,
+	historyRepo repository.PasswordHistoryRepository,
+	attempts repository.LoginAttemptRepository,
+	txManager repository.TxManager,
+	hasher entities.PasswordHasher,
+	notifier service.Notifier,
+	audit service.AuditService,
+	config UserServiceConfig,
+) service.UserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:    userRepo,
+		roleRepo:    roleRepo,
+		resetRepo:   resetRepo,
+		verifyRepo:  verifyRepo,
+		historyRepo: historyRepo,
+		attempts:    attempts,
+		txManager:   txManager,
+		hasher:      hasher,
+		notifier:    notifier,
+		audit:       audit,
+		config:      config,
 	}
 }
 
 // CreateUser creates a new user (admin only)
 func (s *UserService) CreateUser(ctx context.Context, req *service.CreateUserRequest) (*entities.User, error) {
-	// Validate input
-	if err := s.validateCreateUserRequest(req); err != nil {
+	user, err := s.newUser(ctx, req)
+	if err != nil {
 		return nil, err
 	}
 
-	// Check if user already exists
-	if _, err := s.userRepo.GetByUsername(ctx, req.Username); err == nil {
-		return nil, entities.ErrUserAlreadyExists
-	}
-
-	// Create new user
-	user := &entities.User{
-		Username:  req.Username,
-		Password:  "", // Will be set below
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Role:      s.getValidRole(req.Role),
-		IsActive:  req.IsActive,
-	}
-
-	// Set password
-	if err := user.SetPassword(req.Password); err != nil {
+	// Set password, chosen by the creator so the user has to replace it on first login
+	if err := user.SetPassword(req.Password, s.hasher); err != nil {
 		return nil, err
 	}
+	user.MustChangePass = true
 
 	// Validate user entity
 	if err := user.Validate(); err != nil {
@@ -54,18 +101,145 @@ func (s *UserService) CreateUser(ctx context.Context, req *service.CreateUserReq
 	}
 
 	// Save user to database
-	if err := s.userRepo.Create(ctx, user); err != nil {
+	err = withAdminQuota(ctx, s.txManager, s.userRepo, s.config.MaxAdmins, addsAdmin(s.config.MaxAdmins, nil, user.Role, user.IsActive), func(users repository.UserRepository) error {
+		return users.Create(ctx, user)
+	})
+	if err != nil {
 		return nil, err
 	}
 
-	return user, nil
+	if user.IsPendingApproval() {
+		s.notifier.NotifyPendingApproval(ctx, user)
+	}
+
+	s.audit.Record(ctx, createAuditEntry(user))
+
+	return s.reloadUser(ctx, user)
+}
+
+// ValidateUsers dry-runs CreateUser checks for each request without creating anything (admin only).
+// Rows are also checked against each other for duplicate usernames and emails.
+func (s *UserService) ValidateUsers(ctx context.Context, reqs []*service.CreateUserRequest) ([]service.ValidationResult, error) {
+	if len(reqs) == 0 || len(reqs) > maxBulkSize {
+		return nil, entities.ErrInvalidBulkSize
+	}
+
+	_, errs := s.prepareUsers(ctx, reqs)
+	results := make([]service.ValidationResult, 0, len(reqs))
+	for i, req := range reqs {
+		result := service.ValidationResult{Row: i + 1, Username: req.Username, Valid: errs[i] == nil}
+		if errs[i] != nil {
+			result.Error = errs[i].Error()
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// BulkCreate creates many users in one transaction and reports the outcome per row (admin only).
+// Rows failing validation are reported and skipped, with atomic nothing is created if any row fails.
+func (s *UserService) BulkCreate(ctx context.Context, reqs []*service.CreateUserRequest, atomic bool) (*service.ImportReport, error) {
+	if len(reqs) == 0 || len(reqs) > maxBulkSize {
+		return nil, entities.ErrInvalidBulkSize
+	}
+
+	users, errs := s.prepareUsers(ctx, reqs)
+	for i, user := range users {
+		if errs[i] != nil {
+			continue
+		}
+		if err := user.SetPassword(reqs[i].Password, s.hasher); err != nil {
+			errs[i] = err
+			continue
+		}
+		user.MustChangePass = true
+		errs[i] = user.Validate()
+	}
+
+	report := &service.ImportReport{Results: make([]service.ImportResult, 0, len(reqs))}
+	for i, req := range reqs {
+		result := service.ImportResult{Row: i + 1, Username: req.Username}
+		if errs[i] != nil {
+			result.Error = errs[i].Error()
+			report.Failed++
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	if atomic && report.Failed > 0 {
+		report.RolledBack = true
+		return report, nil
+	}
+
+	var admins int64
+	for i, user := range users {
+		if errs[i] == nil && addsAdmin(s.config.MaxAdmins, nil, user.Role, user.IsActive) {
+			admins++
+		}
+	}
+
+	// Users and their audit entries are committed together
+	err := s.txManager.WithTx(ctx, func(repos repository.Repositories) error {
+		// Rows were checked against the quota, this catches concurrent promotions since
+		if admins > 0 {
+			if err := checkAdminQuota(ctx, repos.Users, s.config.MaxAdmins, admins); err != nil {
+				return err
+			}
+		}
+
+		for i, user := range users {
+			if errs[i] != nil {
+				continue
+			}
+			if err := repos.Users.Create(ctx, user); err != nil {
+				return err
+			}
+			if err := repos.Audit.Record(ctx, withActor(ctx, createAuditEntry(user))); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, user := range users {
+		if errs[i] != nil {
+			continue
+		}
+		report.Results[i].ID = user.ID
+		report.Results[i].Success = true
+		report.Created++
+
+		if user.IsPendingApproval() {
+			s.notifier.NotifyPendingApproval(ctx, user)
+		}
+	}
+
+	return report, nil
 }
 
 // GetUser retrieves user by ID
 func (s *UserService) GetUser(ctx context.Context, id uint) (*entities.User, error) {
 	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
-		return nil, entities.ErrUserNotFound
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// GetManagedUser retrieves user by ID if the actor may manage them
+func (s *UserService) GetManagedUser(ctx context.Context, actorRole entities.Role, id uint) (*entities.User, error) {
+	user, err := s.GetUser(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !actorRole.CanManage(user.Role) {
+		return nil, entities.ErrForbidden
 	}
 
 	return user, nil
@@ -76,12 +250,68 @@ func (s *UserService) GetCurrentUser(ctx context.Context, userID uint) (*entitie
 	return s.GetUser(ctx, userID)
 }
 
+// exportBatchSize is the number of users loaded per query when exporting
+const exportBatchSize = 500
+
+// exportAuditPageSize is the number of audit entries loaded per query for a personal data export,
+// the largest page ListAuditLogs allows
+const exportAuditPageSize = 100
+
+// ExportUsers passes all users to fn in batches without loading the whole table
+func (s *UserService) ExportUsers(ctx context.Context, fn func(users []*entities.User) error) error {
+	return s.userRepo.Iterate(ctx, exportBatchSize, fn)
+}
+
+// ExportUserData collects all data stored about the user
+func (s *UserService) ExportUserData(ctx context.Context, userID uint) (*service.UserDataExport, error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Failed attempts are only known by the login that was typed in
+	logins := []string{user.Username}
+	if user.Email != nil {
+		logins = append(logins, *user.Email)
+	}
+	history, err := s.attempts.ListByUser(ctx, user.ID, logins)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := []*entities.AuditEntry{}
+	for offset := 0; ; offset += exportAuditPageSize {
+		page, err := s.audit.ListAuditLogs(ctx, &service.ListAuditLogsRequest{
+			TargetID: &user.ID,
+			Limit:    exportAuditPageSize,
+			Offset:   offset,
+		})
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, page.Items...)
+		if len(page.Items) < exportAuditPageSize {
+			break
+		}
+	}
+
+	return &service.UserDataExport{
+		ExportedAt:   time.Now(),
+		Profile:      user,
+		LoginHistory: history,
+		AuditEntries: entries,
+	}, nil
+}
+
 // UpdateUser updates user data
 func (s *UserService) UpdateUser(ctx context.Context, id uint, req *service.UpdateUserRequest) (*entities.User, error) {
-	// Get existing user
-	user, err := s.userRepo.GetByID(ctx, id)
+	// Get existing user the actor is allowed to manage
+	user, err := s.GetManagedUser(ctx, req.ActorRole, id)
 	if err != nil {
-		return nil, entities.ErrUserNotFound
+		return nil, err
+	}
+	if req.Version != nil && *req.Version != user.Version {
+		return nil, entities.ErrConcurrentModification
 	}
 
 	// Validate update request
@@ -89,6 +319,13 @@ func (s *UserService) UpdateUser(ctx context.Context, id uint, req *service.Upda
 		return nil, err
 	}
 
+	if req.Role != nil && !s.isKnownRole(ctx, *req.Role) {
+		return nil, entities.ErrInvalidRole
+	}
+	if req.Role != nil && !req.ActorRole.CanManage(*req.Role) {
+		return nil, entities.ErrRoleNotAssignable
+	}
+
 	// Update fields if provided
 	if req.Username != nil {
 		// Check if new username is available
@@ -98,6 +335,18 @@ func (s *UserService) UpdateUser(ctx context.Context, id uint, req *service.Upda
 		user.Username = *req.Username
 	}
 
+	if req.Email != nil {
+		email := entities.NormalizeEmail(*req.Email)
+		if err := s.ensureEmailAvailable(ctx, email, user.ID); err != nil {
+			return nil, err
+		}
+		// A new address has to be verified again
+		if email == nil || user.Email == nil || !strings.EqualFold(*email, *user.Email) {
+			user.EmailVerified = false
+		}
+		user.Email = email
+	}
+
 	if req.FirstName != nil {
 		user.FirstName = *req.FirstName
 	}
@@ -106,40 +355,80 @@ func (s *UserService) UpdateUser(ctx context.Context, id uint, req *service.Upda
 		user.LastName = *req.LastName
 	}
 
-	if req.Role != nil {
-		user.Role = s.getValidRole(*req.Role)
+	if s.config.UniqueNames && (req.FirstName != nil || req.LastName != nil) {
+		if err := ensureUniqueName(ctx, s.userRepo, user.FirstName, user.LastName, user.ID); err != nil {
+			return nil, err
+		}
 	}
 
+	role, isActive := user.Role, user.IsActive
+	if req.Role != nil {
+		role = *req.Role
+	}
 	if req.IsActive != nil {
-		user.IsActive = *req.IsActive
+		isActive = *req.IsActive
+	}
+	grantsAdmin := addsAdmin(s.config.MaxAdmins, user, role, isActive)
+
+	// Deactivating or demoting an admin is guarded like a removal
+	removesAccess := (user.IsActive && !isActive) || (user.Role == entities.RoleAdmin && role != entities.RoleAdmin)
+	user.Role, user.IsActive = role, isActive
+
+	// Role and status changes alone are written column by column,
+	// unless the caller wants the version checked on write
+	columnsOnly := req.Version == nil && req.Username == nil && req.Email == nil && req.FirstName == nil && req.LastName == nil
+	if !columnsOnly {
+		// Validate updated user
+		if err := user.Validate(); err != nil {
+			return nil, err
+		}
 	}
 
-	// Validate updated user
-	if err := user.Validate(); err != nil {
-		return nil, err
+	save := func(users repository.UserRepository) error {
+		if columnsOnly {
+			return updateRoleAndStatus(ctx, users, user, req)
+		}
+		return users.Update(ctx, user)
 	}
 
-	// Save updates
-	if err := s.userRepo.Update(ctx, user); err != nil {
+	if removesAccess {
+		err = s.removeUser(ctx, user.ID, save)
+	} else {
+		err = withAdminQuota(ctx, s.txManager, s.userRepo, s.config.MaxAdmins, grantsAdmin, save)
+	}
+	if err != nil {
 		return nil, err
 	}
 
-	return user, nil
+	s.recordUpdate(ctx, user.ID, req)
+	return s.reloadUser(ctx, user)
 }
 
 // DeleteUser deletes user by ID (admin only)
 func (s *UserService) DeleteUser(ctx context.Context, id uint) error {
-	// Check if user exists
-	if _, err := s.userRepo.GetByID(ctx, id); err != nil {
-		return entities.ErrUserNotFound
+	err := s.removeUser(ctx, id, func(users repository.UserRepository) error {
+		return users.Delete(ctx, id)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &entities.AuditEntry{Action: entities.AuditUserDelete, TargetID: &id})
+	return nil
+}
+
+// RestoreUser brings back a deleted user (admin only)
+func (s *UserService) RestoreUser(ctx context.Context, id uint) error {
+	if err := s.userRepo.Restore(ctx, id); err != nil {
+		return err
 	}
 
-	// Delete user
-	return s.userRepo.Delete(ctx, id)
+	s.audit.Record(ctx, &entities.AuditEntry{Action: entities.AuditUserRestore, TargetID: &id})
+	return nil
 }
 
 // ListUsers retrieves paginated list of users
-func (s *UserService) ListUsers(ctx context.Context, req *service.ListUsersRequest) (*service.ListUsersResponse, error) {
+func (s *UserService) ListUsers(ctx context.Context, req *service.ListUsersRequest) (*service.Page[*entities.User], error) {
 	// Set default pagination values
 	limit := req.Limit
 	if limit <= 0 || limit > 100 {
@@ -151,51 +440,28 @@ func (s *UserService) ListUsers(ctx context.Context, req *service.ListUsersReque
 		offset = 0
 	}
 
-	var users []*entities.User
-	var total int64
-	var err error
-
-	// Apply filters
-	if req.Role != "" && req.Search != "" {
-		// Search by role and text
-		users, total, err = s.searchUsersByRoleAndText(ctx, req.Role, req.Search, limit, offset)
-	} else if req.Role != "" {
-		// Filter by role only
-		users, err = s.userRepo.GetByRole(ctx, req.Role)
-		if err == nil {
-			total = int64(len(users))
-			users = s.paginateUsers(users, limit, offset)
-		}
-	} else if req.Search != "" {
-		// Search by text only
-		users, total, err = s.searchUsersByText(ctx, req.Search, limit, offset)
-	} else {
-		// Get all users
-		users, err = s.userRepo.List(ctx, limit, offset)
-		if err == nil {
-			total, err = s.userRepo.Count(ctx)
-		}
+	if err := s.validateSearch(req.Search); err != nil {
+		return nil, err
 	}
 
+	filter, err := s.newUserFilter(req, limit, offset)
 	if err != nil {
 		return nil, err
 	}
+	if req.Role != "" {
+		filter.Roles = []entities.Role{req.Role}
+	}
 
-	// Filter by IsActive if specified
-	if req.IsActive != nil {
-		users = s.filterUsersByActiveStatus(users, *req.IsActive)
+	users, total, err := s.userRepo.Search(ctx, filter)
+	if err != nil {
+		return nil, err
 	}
 
-	return &service.ListUsersResponse{
-		Users:  users,
-		Total:  total,
-		Limit:  limit,
-		Offset: offset,
-	}, nil
+	return service.NewPage(users, total, limit, offset), nil
 }
 
 // ListUsersForManager retrieves paginated list of users for manager (only user and guest roles)
-func (s *UserService) ListUsersForManager(ctx context.Context, req *service.ListUsersRequest) (*service.ListUsersResponse, error) {
+func (s *UserService) ListUsersForManager(ctx context.Context, req *service.ListUsersRequest) (*service.Page[*entities.User], error) {
 	// Set default pagination values
 	limit := req.Limit
 	if limit <= 0 || limit > 100 {
@@ -207,257 +473,1134 @@ func (s *UserService) ListUsersForManager(ctx context.Context, req *service.List
 		offset = 0
 	}
 
-	var users []*entities.User
-	var total int64
-	var err error
+	if err := s.validateSearch(req.Search); err != nil {
+		return nil, err
+	}
 
 	// Manager can only see user and guest roles
 	requestedRole := req.Role
-	if requestedRole != "" && requestedRole != entities.RoleUser && requestedRole != entities.RoleGuest {
+	if requestedRole != "" && !entities.RoleManager.CanManage(requestedRole) {
 		// Return empty result for invalid roles
-		return &service.ListUsersResponse{
-			Users:  []*entities.User{},
-			Total:  0,
-			Limit:  limit,
-			Offset: offset,
-		}, nil
+		return service.NewPage[*entities.User](nil, 0, limit, offset), nil
 	}
 
-	// If no specific role requested, get both user and guest roles
-	if requestedRole == "" {
-		// Get users with user and guest roles
-		users, err = s.userRepo.GetByRoles(ctx, []entities.Role{entities.RoleUser, entities.RoleGuest})
-		if err != nil {
-			return nil, err
-		}
+	filter, err := s.newUserFilter(req, limit, offset)
+	if err != nil {
+		return nil, err
+	}
 
-		total = int64(len(users))
+	// If no specific role requested, get both user and guest roles
+	filter.Roles = entities.RoleManager.AssignableRoles()
+	if requestedRole != "" {
+		filter.Roles = []entities.Role{requestedRole}
+	}
 
-		// Apply search filter if specified
-		if req.Search != "" {
-			users = s.filterUsersByText(users, req.Search)
-			total = int64(len(users))
-		}
+	// IsActive takes precedence over the inactive users policy
+	if filter.IsActive == nil && !s.includeInactive(req) {
+		active := true
+		filter.IsActive = &active
+	}
 
-		// Apply pagination
-		users = s.paginateUsers(users, limit, offset)
-	} else {
-		// Get specific role (only user or guest allowed)
-		users, err = s.userRepo.GetByRole(ctx, requestedRole)
-		if err != nil {
-			return nil, err
-		}
+	users, total, err := s.userRepo.Search(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
 
-		total = int64(len(users))
+	return service.NewPage(users, total, limit, offset), nil
+}
 
-		// Apply search filter if specified
-		if req.Search != "" {
-			users = s.filterUsersByText(users, req.Search)
-			total = int64(len(users))
-		}
+// CountByRole counts users of every role, active and inactive
+func (s *UserService) CountByRole(ctx context.Context) (map[entities.Role]int64, error) {
+	counts, err := s.userRepo.CountByRoleAndStatus(ctx)
+	if err != nil {
+		return nil, err
+	}
 
-		// Apply pagination
-		users = s.paginateUsers(users, limit, offset)
+	byRole := make(map[entities.Role]int64)
+	for _, count := range counts {
+		byRole[count.Role] += count.Count
 	}
+	return byRole, nil
+}
 
+// GetUserStats counts users the actor may manage by role and active status
+func (s *UserService) GetUserStats(ctx context.Context, actorRole entities.Role) (*service.UserStats, error) {
+	counts, err := s.userRepo.CountByRoleAndStatus(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	// Filter by IsActive if specified
-	if req.IsActive != nil {
-		users = s.filterUsersByActiveStatus(users, *req.IsActive)
+	// Roles without users are reported as zero
+	stats := &service.UserStats{ByRole: make(map[entities.Role]int64)}
+	for _, role := range actorRole.AssignableRoles() {
+		stats.ByRole[role] = 0
 	}
 
-	return &service.ListUsersResponse{
-		Users:  users,
-		Total:  total,
-		Limit:  limit,
-		Offset: offset,
-	}, nil
+	// Managers only see the roles they manage, same as in listings
+	for _, count := range counts {
+		if !actorRole.CanManage(count.Role) {
+			continue
+		}
+		stats.ByRole[count.Role] += count.Count
+		stats.Total += count.Count
+		if count.IsActive {
+			stats.Active += count.Count
+		} else {
+			stats.Inactive += count.Count
+		}
+	}
+
+	return stats, nil
 }
 
 // ChangePassword allows user to change their password
 func (s *UserService) ChangePassword(ctx context.Context, userID uint, req *service.ChangePasswordRequest) error {
-	// Get user
-	user, err := s.userRepo.GetByID(ctx, userID)
-	if err != nil {
-		return entities.ErrUserNotFound
-	}
+	change := func(user *entities.User) error {
+		// Verify current password
+		if !user.VerifyPassword(req.CurrentPassword, s.hasher) {
+			return entities.ErrInvalidCredentials
+		}
 
-	// Verify current password
-	if !user.VerifyPassword(req.CurrentPassword) {
-		return entities.ErrInvalidCredentials
-	}
+		// Validate new password
+		if err := s.config.PasswordPolicy.Check(req.NewPassword); err != nil {
+			return err
+		}
+		if err := s.checkPasswordChanged(user, req.NewPassword); err != nil {
+			return err
+		}
 
-	// Validate new password
-	if len(req.NewPassword) < 8 {
-		return entities.ErrPasswordTooShort
+		// Set new password
+		return user.SetPassword(req.NewPassword, s.hasher)
 	}
 
-	// Set new password
-	if err := user.SetPassword(req.NewPassword); err != nil {
+	if err := s.updatePassword(ctx, userID, req.NewPassword, change); err != nil {
 		return err
 	}
 
-	// Save updated user
-	return s.userRepo.Update(ctx, user)
-}
-
-// ResetPassword initiates password reset process
-func (s *UserService) ResetPassword(ctx context.Context, req *service.ResetPasswordRequest) error {
-	// Get user by username
-	user, err := s.userRepo.GetByUsername(ctx, req.Username)
-	if err != nil {
-		// Don't reveal if user exists or not for security
-		return nil
+	// Log out all sessions if configured, the one changing the password included
+	if s.config.LogoutOnPasswordChange {
+		return s.logoutEverywhere(ctx, userID)
 	}
 
-	// TODO: Implement password reset token generation and email sending
-	// For now, just validate that user exists
-	_ = user
-	// This would typically involve:
-	// 1. Generate reset token
-	// 2. Store token with expiration
-	// 3. Send email with reset link
-
 	return nil
 }
 
-// ConfirmPasswordReset confirms password reset with token
-func (s *UserService) ConfirmPasswordReset(ctx context.Context, req *service.ConfirmPasswordResetRequest) error {
-	// TODO: Implement password reset confirmation
-	// For now, just validate input
-	if req.Token == "" || len(req.NewPassword) < 8 {
-		return entities.ErrPasswordTooShort
+// checkPasswordChanged rejects a new password equal to the current one if configured
+func (s *UserService) checkPasswordChanged(user *entities.User, newPassword string) error {
+	if s.config.RejectUnchangedPassword && user.VerifyPassword(newPassword, s.hasher) {
+		return entities.ErrPasswordUnchanged
 	}
-
-	// This would typically involve:
-	// 1. Validate reset token and get user ID
-	// 2. Set new password
-	// 3. Invalidate token
-
 	return nil
 }
 
-// ActivateUser activates user account (admin only)
-func (s *UserService) ActivateUser(ctx context.Context, id uint) error {
-	return s.toggleUserActiveStatus(ctx, id, true)
-}
-
-// DeactivateUser deactivates user account (admin only)
-func (s *UserService) DeactivateUser(ctx context.Context, id uint) error {
-	return s.toggleUserActiveStatus(ctx, id, false)
-}
-
-// Private helper methods
-
-func (s *UserService) validateCreateUserRequest(req *service.CreateUserRequest) error {
-	if req.Username == "" || len(req.Username) < 3 {
-		return entities.ErrInvalidUsername
+// checkPasswordReused rejects a new password matching one of the user's recent passwords if configured
+func (s *UserService) checkPasswordReused(ctx context.Context, historyRepo repository.PasswordHistoryRepository, userID uint, newPassword string) error {
+	if s.config.PasswordHistory <= 0 {
+		return nil
 	}
 
-	if req.Password == "" || len(req.Password) < 8 {
-		return entities.ErrPasswordTooShort
+	hashes, err := historyRepo.Recent(ctx, userID, s.config.PasswordHistory)
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		if s.hasher.Verify(newPassword, hash) {
+			return entities.ErrPasswordReused
+		}
 	}
-
 	return nil
 }
 
-func (s *UserService) validateUpdateUserRequest(req *service.UpdateUserRequest) error {
-	if req.Username != nil && (*req.Username == "" || len(*req.Username) < 3) {
-		return entities.ErrInvalidUsername
+// updatePassword applies change to the user and saves the new password.
+// The replaced hash goes to the password history in the same transaction.
+func (s *UserService) updatePassword(ctx context.Context, userID uint, newPassword string, apply func(user *entities.User) error) error {
+	if s.config.PasswordHistory <= 0 {
+		return s.savePassword(ctx, s.userRepo, userID, apply)
 	}
 
-	return nil
+	return s.txManager.WithTx(ctx, func(repos repository.Repositories) error {
+		var previous string
+		err := s.savePassword(ctx, repos.Users, userID, func(user *entities.User) error {
+			previous = user.Password
+			if err := apply(user); err != nil {
+				return err
+			}
+			return s.checkPasswordReused(ctx, repos.PasswordHistory, userID, newPassword)
+		})
+		if err != nil {
+			return err
+		}
+
+		return repos.PasswordHistory.Add(ctx, userID, previous, s.config.PasswordHistory)
+	})
 }
 
-func (s *UserService) getValidRole(role entities.Role) entities.Role {
-	switch role {
-	case entities.RoleAdmin, entities.RoleManager, entities.RoleUser, entities.RoleGuest:
-		return role
-	default:
-		return entities.RoleUser
+// savePassword applies change to the user loaded from userRepo and saves the new password
+func (s *UserService) savePassword(ctx context.Context, userRepo repository.UserRepository, userID uint, apply func(user *entities.User) error) error {
+	change := func(user *entities.User) error {
+		if err := apply(user); err != nil {
+			return err
+		}
+		now := time.Now()
+		user.PassChangedAt = &now
+		return nil
 	}
-}
 
-func (s *UserService) searchUsersByRoleAndText(ctx context.Context, role entities.Role, search string, limit, offset int) ([]*entities.User, int64, error) {
-	// TODO: Implement database-specific search
-	// For now, get by role and filter in memory
-	users, err := s.userRepo.GetByRole(ctx, role)
+	// Serialize concurrent changes on the user row if configured
+	if s.config.LockPasswordChanges {
+		return userRepo.UpdatePasswordLocked(ctx, userID, change)
+	}
+
+	// Get user
+	user, err := userRepo.GetByID(ctx, userID)
 	if err != nil {
-		return nil, 0, err
+		return err
 	}
 
-	// Filter by search term
-	filteredUsers := s.filterUsersByText(users, search)
-	total := int64(len(filteredUsers))
+	if err := change(user); err != nil {
+		return err
+	}
 
-	return s.paginateUsers(filteredUsers, limit, offset), total, nil
+	// Save updated user
+	return userRepo.Update(ctx, user)
 }
 
-func (s *UserService) searchUsersByText(ctx context.Context, search string, limit, offset int) ([]*entities.User, int64, error) {
-	// TODO: Implement database-specific search
-	// For now, get all users and filter in memory
-	allUsers, err := s.userRepo.List(ctx, 10000, 0) // Get large batch for search
+// ResetPassword initiates password reset process and returns the plaintext reset token.
+// Unknown users get an empty token and no error so existence isn't revealed.
+func (s *UserService) ResetPassword(ctx context.Context, req *service.ResetPasswordRequest) (string, error) {
+	// Get user by username
+	user, err := s.userRepo.GetByUsername(ctx, req.Username)
 	if err != nil {
-		return nil, 0, err
+		// Don't reveal if user exists or not for security
+		return "", nil
 	}
 
-	filteredUsers := s.filterUsersByText(allUsers, search)
-	total := int64(len(filteredUsers))
-
-	return s.paginateUsers(filteredUsers, limit, offset), total, nil
-}
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
 
-func (s *UserService) filterUsersByText(users []*entities.User, search string) []*entities.User {
-	if search == "" {
-		return users
+	resetToken := &entities.PasswordResetToken{
+		UserID:    user.ID,
+		TokenHash: hashResetToken(token),
+		ExpiresAt: time.Now().Add(resetTokenTTL),
+	}
+	if err := s.resetRepo.Create(ctx, resetToken); err != nil {
+		return "", err
 	}
 
-	searchLower := strings.ToLower(search)
-	var filtered []*entities.User
+	return token, nil
+}
 
-	for _, user := range users {
-		if strings.Contains(strings.ToLower(user.Username), searchLower) ||
-			strings.Contains(strings.ToLower(user.FirstName), searchLower) ||
-			strings.Contains(strings.ToLower(user.LastName), searchLower) {
-			filtered = append(filtered, user)
-		}
+// ConfirmPasswordReset confirms password reset with token
+func (s *UserService) ConfirmPasswordReset(ctx context.Context, req *service.ConfirmPasswordResetRequest) error {
+	if req.Token == "" {
+		return entities.ErrInvalidToken
+	}
+	if err := s.config.PasswordPolicy.Check(req.NewPassword); err != nil {
+		return err
 	}
 
-	return filtered
-}
+	resetToken, err := s.resetRepo.GetByHash(ctx, hashResetToken(req.Token))
+	if err != nil {
+		return err
+	}
+	if !resetToken.IsUsable() {
+		return entities.ErrInvalidToken
+	}
 
-func (s *UserService) filterUsersByActiveStatus(users []*entities.User, isActive bool) []*entities.User {
-	var filtered []*entities.User
-	for _, user := range users {
-		if user.IsActive == isActive {
-			filtered = append(filtered, user)
+	// Check before consuming so the user can retry with the same token
+	if s.config.RejectUnchangedPassword {
+		user, err := s.userRepo.GetByID(ctx, resetToken.UserID)
+		if err != nil {
+			return err
+		}
+		if err := s.checkPasswordChanged(user, req.NewPassword); err != nil {
+			return err
 		}
 	}
-	return filtered
-}
+	if err := s.checkPasswordReused(ctx, s.historyRepo, resetToken.UserID, req.NewPassword); err != nil {
+		return err
+	}
 
-func (s *UserService) paginateUsers(users []*entities.User, limit, offset int) []*entities.User {
-	if offset >= len(users) {
-		return []*entities.User{}
+	// Consume first so concurrent requests can't use the same token twice
+	if err := s.resetRepo.MarkUsed(ctx, resetToken.ID); err != nil {
+		return err
 	}
 
-	end := offset + limit
-	if end > len(users) {
-		end = len(users)
+	err = s.updatePassword(ctx, resetToken.UserID, req.NewPassword, func(user *entities.User) error {
+		return user.SetPassword(req.NewPassword, s.hasher)
+	})
+	if err != nil {
+		return err
 	}
 
-	return users[offset:end]
+	// A reset means the old password may be compromised, always log out everywhere
+	return s.logoutEverywhere(ctx, resetToken.UserID)
 }
 
-func (s *UserService) toggleUserActiveStatus(ctx context.Context, id uint, isActive bool) error {
-	user, err := s.userRepo.GetByID(ctx, id)
-	if err != nil {
-		return entities.ErrUserNotFound
-	}
+// logoutEverywhere revokes all tokens issued to the user and ends their stored sessions,
+// so they are also gone from the session lists
+func (s *UserService) logoutEverywhere(ctx context.Context, userID uint) error {
+	return s.txManager.WithTx(ctx, func(repos repository.Repositories) error {
+		if err := repos.Users.RevokeTokens(ctx, userID); err != nil {
+			return err
+		}
+		_, err := repos.RefreshTokens.RevokeAllSessions(ctx, userID)
+		return err
+	})
+}
+
+// SendVerificationEmail issues a token verifying the user's current email and returns it in plaintext
+func (s *UserService) SendVerificationEmail(ctx context.Context, userID uint) (string, error) {
+	user, err := s.GetUser(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+	if user.Email == nil {
+		return "", entities.ErrEmailMissing
+	}
+	if user.EmailVerified {
+		return "", entities.ErrEmailVerified
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(raw)
+
+	verifyToken := &entities.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     *user.Email,
+		TokenHash: hashResetToken(token),
+		ExpiresAt: time.Now().Add(verificationTokenTTL),
+	}
+	if err := s.verifyRepo.Create(ctx, verifyToken); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// VerifyEmail marks email verified with a token, tokens issued for a previous email are rejected
+func (s *UserService) VerifyEmail(ctx context.Context, token string) error {
+	if token == "" {
+		return entities.ErrInvalidToken
+	}
+
+	verifyToken, err := s.verifyRepo.GetByHash(ctx, hashResetToken(token))
+	if err != nil {
+		return err
+	}
+	if !verifyToken.IsUsable() {
+		return entities.ErrInvalidToken
+	}
+
+	// Consume first so concurrent requests can't use the same token twice
+	if err := s.verifyRepo.MarkUsed(ctx, verifyToken.ID); err != nil {
+		return err
+	}
+
+	return s.userRepo.SetEmailVerified(ctx, verifyToken.UserID, verifyToken.Email)
+}
+
+// ActivateUser activates user account, optionally requiring a password change on next login (admin only)
+func (s *UserService) ActivateUser(ctx context.Context, id uint, requirePasswordChange bool) error {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	// Flag, status and their audit entry are committed together
+	return s.txManager.WithTx(ctx, func(repos repository.Repositories) error {
+		if addsAdmin(s.config.MaxAdmins, user, user.Role, true) {
+			if err := checkAdminQuota(ctx, repos.Users, s.config.MaxAdmins, 1); err != nil {
+				return err
+			}
+		}
+
+		if requirePasswordChange {
+			if err := repos.Users.SetMustChangePassword(ctx, id, true); err != nil {
+				return err
+			}
+		}
+		if err := repos.Users.SetActive(ctx, id, true); err != nil {
+			return err
+		}
+
+		return repos.Audit.Record(ctx, withActor(ctx, &entities.AuditEntry{
+			Action:   entities.AuditUserActivate,
+			TargetID: &id,
+			Details:  map[string]interface{}{"require_password_change": requirePasswordChange},
+		}))
+	})
+}
+
+// DeactivateUser deactivates user account (admin only)
+func (s *UserService) DeactivateUser(ctx context.Context, id uint) error {
+	err := s.removeUser(ctx, id, func(users repository.UserRepository) error {
+		return users.SetActive(ctx, id, false)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &entities.AuditEntry{Action: entities.AuditUserDeactivate, TargetID: &id})
+	return nil
+}
+
+// ScheduleDeactivation deactivates user account at a future time (admin only)
+func (s *UserService) ScheduleDeactivation(ctx context.Context, id uint, at time.Time) error {
+	if !at.After(time.Now()) {
+		return entities.ErrInvalidSchedule
+	}
+
+	// Checked again when the deactivation is applied
+	err := s.removeUser(ctx, id, func(users repository.UserRepository) error {
+		return users.SetDeactivateAt(ctx, id, &at)
+	})
+	if err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &entities.AuditEntry{
+		Action:   entities.AuditUserScheduleDeactivate,
+		TargetID: &id,
+		Details:  map[string]interface{}{"deactivate_at": at},
+	})
+	return nil
+}
+
+// CancelScheduledDeactivation cancels a pending scheduled deactivation (admin only)
+func (s *UserService) CancelScheduledDeactivation(ctx context.Context, id uint) error {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if user.DeactivateAt == nil {
+		return entities.ErrNotScheduled
+	}
+
+	if err := s.userRepo.SetDeactivateAt(ctx, id, nil); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &entities.AuditEntry{Action: entities.AuditUserCancelDeactivate, TargetID: &id})
+	return nil
+}
+
+// ApplyScheduledDeactivations deactivates users whose scheduled time has passed.
+// The schedule of the last active admin is cancelled instead.
+func (s *UserService) ApplyScheduledDeactivations(ctx context.Context) (int64, error) {
+	var count int64
+	err := s.txManager.WithTx(ctx, func(repos repository.Repositories) error {
+		adminCount, err := repos.Users.LockActiveAdmins(ctx)
+		if err != nil {
+			return err
+		}
+
+		users, err := repos.Users.LockDueDeactivations(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, user := range users {
+			if err := repos.Users.SetDeactivateAt(ctx, user.ID, nil); err != nil {
+				return err
+			}
+			if !user.IsActive {
+				continue
+			}
+
+			id := user.ID
+			if user.Role == entities.RoleAdmin && adminCount <= 1 {
+				if err := repos.Audit.Record(ctx, &entities.AuditEntry{
+					Action:   entities.AuditUserCancelDeactivate,
+					TargetID: &id,
+					Details:  map[string]interface{}{"reason": "last_admin"},
+				}); err != nil {
+					return err
+				}
+				continue
+			}
+
+			if err := repos.Users.SetActive(ctx, id, false); err != nil {
+				return err
+			}
+			if err := repos.Audit.Record(ctx, &entities.AuditEntry{
+				Action:   entities.AuditUserDeactivate,
+				TargetID: &id,
+				Details:  map[string]interface{}{"scheduled": true},
+			}); err != nil {
+				return err
+			}
+
+			if user.Role == entities.RoleAdmin {
+				adminCount--
+			}
+			count++
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GrantTempRole grants a role to the user until the given time (admin only)
+func (s *UserService) GrantTempRole(ctx context.Context, id uint, req *service.TempRoleRequest) (*entities.User, error) {
+	if !s.isKnownRole(ctx, req.Role) {
+		return nil, entities.ErrInvalidRole
+	}
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !req.ActorRole.CanManage(user.Role) || !req.ActorRole.CanManage(req.Role) {
+		return nil, entities.ErrForbidden
+	}
+
+	if req.Role == user.Role || !req.Until.After(time.Now()) {
+		return nil, entities.ErrInvalidTempRole
+	}
+
+	err = withAdminQuota(ctx, s.txManager, s.userRepo, s.config.MaxAdmins, addsAdmin(s.config.MaxAdmins, user, req.Role, user.IsActive), func(users repository.UserRepository) error {
+		return users.SetTempRole(ctx, id, &req.Role, &req.Until)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	user.TempRole = &req.Role
+	user.TempRoleUntil = &req.Until
+
+	s.audit.Record(ctx, &entities.AuditEntry{
+		Action:   entities.AuditUserGrantTempRole,
+		TargetID: &id,
+		Details:  map[string]interface{}{"role": req.Role, "until": req.Until},
+	})
+	return user, nil
+}
+
+// ExpireTempRoles removes expired temporary roles and returns the affected users
+func (s *UserService) ExpireTempRoles(ctx context.Context) ([]*entities.User, error) {
+	return s.userRepo.ClearExpiredTempRoles(ctx)
+}
+
+// GetAssignableRoles returns roles the actor may assign to the target user
+func (s *UserService) GetAssignableRoles(ctx context.Context, actorRole entities.Role, targetID uint) ([]entities.Role, error) {
+	user, err := s.userRepo.GetByID(ctx, targetID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Actor can't change the role of a user above their own reach
+	if !actorRole.CanManage(user.Role) {
+		return []entities.Role{}, nil
+	}
+
+	// Admins may also assign custom roles
+	if actorRole == entities.RoleAdmin && s.config.CustomRoles {
+		return s.roleRepo.List(ctx)
+	}
+
+	return actorRole.AssignableRoles(), nil
+}
+
+// GetUserAccess returns effective access of the user including temporary roles (admin only)
+func (s *UserService) GetUserAccess(ctx context.Context, id uint) (*service.UserAccess, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	access := &service.UserAccess{
+		UserID:          user.ID,
+		Role:            user.Role,
+		EffectiveRole:   user.EffectiveRole(),
+		AssignableRoles: user.EffectiveRole().AssignableRoles(),
+	}
+	if user.HasActiveTempRole() {
+		access.TempRole = user.TempRole
+		access.TempRoleUntil = user.TempRoleUntil
+	}
+	access.ManagerAccess = access.EffectiveRole.AtLeast(entities.RoleManager)
+	access.AdminAccess = access.EffectiveRole == entities.RoleAdmin
+
+	// Permissions of the effective role, so a temporary role's permissions are included
+	permissions, err := s.roleRepo.ListPermissions(ctx)
+	if err != nil {
+		return nil, err
+	}
+	access.Permissions = []string{}
+	for _, p := range permissions {
+		if p.HeldBy(access.EffectiveRole) {
+			access.Permissions = append(access.Permissions, p.Name)
+		}
+	}
+
+	// Admins may also assign custom roles
+	if access.AdminAccess && s.config.CustomRoles {
+		if access.AssignableRoles, err = s.roleRepo.List(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	return access, nil
+}
+
+// ListPermissions returns the permission catalog with the roles holding each (admin only)
+func (s *UserService) ListPermissions(ctx context.Context) ([]entities.Permission, error) {
+	return s.roleRepo.ListPermissions(ctx)
+}
+
+// BulkAssignRole assigns a role to many users at once (admin only)
+func (s *UserService) BulkAssignRole(ctx context.Context, req *service.BulkRoleRequest) ([]service.BulkResult, error) {
+	if len(req.IDs) == 0 || len(req.IDs) > maxBulkSize {
+		return nil, entities.ErrInvalidBulkSize
+	}
+
+	if !s.isKnownRole(ctx, req.Role) || !req.ActorRole.CanManage(req.Role) {
+		return nil, entities.ErrInvalidRole
+	}
+
+	actor, hasActor := entities.ActorFromContext(ctx)
+	check := func(user *entities.User, adminCount int64) error {
+		if !req.ActorRole.CanManage(user.Role) {
+			return entities.ErrForbidden
+		}
+		wasActiveAdmin := user.Role == entities.RoleAdmin && user.IsActive
+		if wasActiveAdmin && req.Role != entities.RoleAdmin {
+			if hasActor && actor.ID == user.ID {
+				return entities.ErrCannotRemoveSelf
+			}
+			// Demoting the only remaining admin would lock everyone out
+			if adminCount <= 1 {
+				return entities.ErrCannotRemoveLastAdmin
+			}
+		}
+		if !wasActiveAdmin && req.Role == entities.RoleAdmin && user.IsActive &&
+			s.config.MaxAdmins > 0 && adminCount >= int64(s.config.MaxAdmins) {
+			return entities.ErrAdminQuotaExceeded
+		}
+		return nil
+	}
+
+	// Role changes and their audit entries are committed together.
+	// Admins are locked first like in removeUser, so the count stays accurate.
+	var results []service.BulkResult
+	err := s.txManager.WithTx(ctx, func(repos repository.Repositories) error {
+		adminCount, err := repos.Users.LockActiveAdmins(ctx)
+		if err != nil {
+			return err
+		}
+
+		ids := uniqueIDs(req.IDs)
+		results = make([]service.BulkResult, 0, len(ids))
+		for _, id := range ids {
+			user, err := repos.Users.GetByID(ctx, id)
+			if err != nil && err != entities.ErrUserNotFound {
+				return err
+			}
+			if err == nil {
+				err = check(user, adminCount)
+			}
+			if err != nil {
+				results = append(results, service.BulkResult{ID: id, Error: err.Error()})
+				continue
+			}
+
+			results = append(results, service.BulkResult{ID: id, Success: true})
+			// Users already having the role are left untouched and not audited
+			if user.Role == req.Role {
+				continue
+			}
+
+			if err := repos.Users.UpdateRole(ctx, id, req.Role); err != nil {
+				return err
+			}
+			if err := repos.Audit.Record(ctx, withActor(ctx, &entities.AuditEntry{
+				Action:   entities.AuditUserBulkRole,
+				TargetID: &id,
+				Details:  map[string]interface{}{"role": req.Role, "previous_role": user.Role},
+			})); err != nil {
+				return err
+			}
+
+			if user.IsActive && user.Role == entities.RoleAdmin && req.Role != entities.RoleAdmin {
+				adminCount--
+			} else if user.IsActive && user.Role != entities.RoleAdmin && req.Role == entities.RoleAdmin {
+				adminCount++
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// ApproveUser approves a pending user account and activates it (admin only)
+func (s *UserService) ApproveUser(ctx context.Context, id uint) error {
+	user, err := s.getPendingUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	user.Approve()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &entities.AuditEntry{Action: entities.AuditUserApprove, TargetID: &id})
+	return nil
+}
+
+// RejectUser rejects a pending user account (admin only)
+func (s *UserService) RejectUser(ctx context.Context, id uint) error {
+	user, err := s.getPendingUser(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	user.Reject()
+	if err := s.userRepo.Update(ctx, user); err != nil {
+		return err
+	}
+
+	s.audit.Record(ctx, &entities.AuditEntry{Action: entities.AuditUserReject, TargetID: &id})
+	return nil
+}
+
+// Private helper methods
+
+// newUser validates req against policy and existing users and builds the user to create.
+// The password is not set.
+func (s *UserService) newUser(ctx context.Context, req *service.CreateUserRequest) (*entities.User, error) {
+	// Validate input
+	if err := s.validateCreateUserRequest(req); err != nil {
+		return nil, err
+	}
+
+	// Empty role is resolved below, anything else must exist
+	if req.Role != "" && !s.isKnownRole(ctx, req.Role) {
+		return nil, entities.ErrInvalidRole
+	}
+
+	// Check if user already exists
+	if _, err := s.userRepo.GetByUsername(ctx, req.Username); err == nil {
+		return nil, entities.ErrUserAlreadyExists
+	}
+
+	email := entities.NormalizeEmail(req.Email)
+	if err := s.ensureEmailAvailable(ctx, email, 0); err != nil {
+		return nil, err
+	}
+
+	if s.config.UniqueNames {
+		if err := ensureUniqueName(ctx, s.userRepo, req.FirstName, req.LastName, 0); err != nil {
+			return nil, err
+		}
+	}
+
+	user := &entities.User{
+		Username:  req.Username,
+		Email:     email,
+		FirstName: req.FirstName,
+		LastName:  req.LastName,
+		Role:      s.getValidRole(ctx, entities.ResolveRole(s.config.RolePatterns, req.Username, req.Role)),
+		IsActive:  req.IsActive,
+	}
+
+	// Managers create only roles below their own, pattern-assigned roles included
+	if !req.ActorRole.CanManage(user.Role) {
+		return nil, entities.ErrRoleNotAssignable
+	}
+
+	// Manager-created users wait for admin approval if configured
+	if s.config.RequireApproval && req.ActorRole == entities.RoleManager {
+		user.MarkPendingApproval()
+	}
+
+	return user, nil
+}
+
+// prepareUsers runs newUser for each request and also rejects usernames and emails
+// repeated within reqs and admins beyond MaxAdmins, earlier rows included.
+// For each row either the user or the error is set.
+func (s *UserService) prepareUsers(ctx context.Context, reqs []*service.CreateUserRequest) ([]*entities.User, []error) {
+	users := make([]*entities.User, len(reqs))
+	errs := make([]error, len(reqs))
+	usernames := make(map[string]bool, len(reqs))
+	emails := make(map[string]bool, len(reqs))
+	admins := int64(-1) // counted on the first admin row
+	for i, req := range reqs {
+		user, err := s.newUser(ctx, req)
+		if err == nil && addsAdmin(s.config.MaxAdmins, nil, user.Role, user.IsActive) {
+			if admins < 0 {
+				admins, err = s.userRepo.CountByRole(ctx, entities.RoleAdmin)
+			}
+			if err == nil && admins >= int64(s.config.MaxAdmins) {
+				err = entities.ErrAdminQuotaExceeded
+			}
+		}
+
+		switch {
+		case err != nil:
+		case usernames[user.Username]:
+			err = entities.ErrUserAlreadyExists
+		case user.Email != nil && emails[*user.Email]:
+			err = entities.ErrEmailAlreadyExists
+		default:
+			usernames[user.Username] = true
+			if user.Email != nil {
+				emails[*user.Email] = true
+			}
+			if addsAdmin(s.config.MaxAdmins, nil, user.Role, user.IsActive) {
+				admins++
+			}
+		}
+
+		if err != nil {
+			errs[i] = err
+			continue
+		}
+		users[i] = user
+	}
+	return users, errs
+}
+
+// createAuditEntry describes creation of user for the audit log
+func createAuditEntry(user *entities.User) *entities.AuditEntry {
+	return &entities.AuditEntry{
+		Action:   entities.AuditUserCreate,
+		TargetID: &user.ID,
+		Details: map[string]interface{}{
+			"username":        user.Username,
+			"role":            user.Role,
+			"is_active":       user.IsActive,
+			"approval_status": user.ApprovalStatus,
+		},
+	}
+}
+
+// withActor attributes entry to the actor from ctx, as AuditService.Record does.
+// Needed when entries are written through a transaction-scoped repository.
+func withActor(ctx context.Context, entry *entities.AuditEntry) *entities.AuditEntry {
+	if actor, ok := entities.ActorFromContext(ctx); ok {
+		entry.ActorID = &actor.ID
+	}
+	return entry
+}
+
+func (s *UserService) validateCreateUserRequest(req *service.CreateUserRequest) error {
+	if req.Username == "" || len(req.Username) < 3 {
+		return entities.ErrInvalidUsername
+	}
+
+	if s.config.RejectNumericNames && entities.IsNumericUsername(req.Username) {
+		return entities.ErrNumericUsername
+	}
+
+	if err := s.config.PasswordPolicy.Check(req.Password); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (s *UserService) validateUpdateUserRequest(req *service.UpdateUserRequest) error {
+	if req.Username != nil && (*req.Username == "" || len(*req.Username) < 3) {
+		return entities.ErrInvalidUsername
+	}
+
+	if req.Username != nil && s.config.RejectNumericNames && entities.IsNumericUsername(*req.Username) {
+		return entities.ErrNumericUsername
+	}
+
+	return nil
+}
+
+// includeInactive resolves whether inactive users appear in manager results
+func (s *UserService) includeInactive(req *service.ListUsersRequest) bool {
+	if req.IncludeInactive != nil {
+		return *req.IncludeInactive
+	}
+	return !s.config.HideInactiveFromManagers
+}
+
+// resetTokenTTL is how long a password reset token stays valid
+const resetTokenTTL = 30 * time.Minute
+
+// verificationTokenTTL is how long an email verification token stays valid
+const verificationTokenTTL = 24 * time.Hour
+
+// hashResetToken hashes reset and verification tokens for storage and lookup
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// maxBulkSize limits the number of users affected by one bulk request
+const maxBulkSize = 100
+
+func uniqueIDs(ids []uint) []uint {
+	seen := make(map[uint]bool, len(ids))
+	unique := make([]uint, 0, len(ids))
+	for _, id := range ids {
+		if !seen[id] {
+			seen[id] = true
+			unique = append(unique, id)
+		}
+	}
+	return unique
+}
+
+// getValidRole returns role if known and user otherwise, for roles not given by the client
+func (s *UserService) getValidRole(ctx context.Context, role entities.Role) entities.Role {
+	if s.isKnownRole(ctx, role) {
+		return role
+	}
+	return entities.RoleUser
+}
+
+// isKnownRole checks built-in roles and, if enabled, custom roles from the roles table
+func (s *UserService) isKnownRole(ctx context.Context, role entities.Role) bool {
+	if role.IsValid() {
+		return true
+	}
+
+	if !s.config.CustomRoles || role == "" {
+		return false
+	}
+
+	exists, err := s.roleRepo.Exists(ctx, role)
+	return err == nil && exists
+}
+
+// newUserFilter maps listing request onto repository filter, roles are left to the caller
+func (s *UserService) newUserFilter(req *service.ListUsersRequest, limit, offset int) (repository.UserFilter, error) {
+	filter := repository.UserFilter{
+		IsActive:  req.IsActive,
+		Search:    req.Search,
+		Wildcards: s.config.SearchWildcards,
+		Limit:     limit,
+		Offset:    offset,
+
+		NeverChangedPassword: req.NeverChangedPassword,
+
+		CreatedFrom: req.CreatedFrom,
+		CreatedTo:   req.CreatedTo,
+
+		LastLoginBefore: req.LastLoginBefore,
+
+		CountOnly: req.CountOnly,
+	}
+
+	if req.CreatedFrom != nil && req.CreatedTo != nil && req.CreatedFrom.After(*req.CreatedTo) {
+		return filter, entities.ErrInvalidDateRange
+	}
+
+	if req.SortBy != "" && !slices.Contains(repository.UserSortFields, req.SortBy) {
+		return filter, entities.ErrInvalidSort
+	}
+	filter.SortBy = req.SortBy
+
+	switch strings.ToLower(req.SortOrder) {
+	case "", "desc":
+		filter.SortDesc = true
+	case "asc":
+		filter.SortDesc = false
+	default:
+		return filter, entities.ErrInvalidSort
+	}
+
+	return filter, nil
+}
+
+// addsAdmin tells whether giving user the role and status adds an active admin
+// counted against maxAdmins, 0 meaning unlimited. User is nil for users being created.
+func addsAdmin(maxAdmins int, user *entities.User, role entities.Role, isActive bool) bool {
+	if maxAdmins <= 0 || role != entities.RoleAdmin || !isActive {
+		return false
+	}
+	// Already counted, temporary admins included
+	return user == nil || !user.IsActive || (user.Role != entities.RoleAdmin && user.EffectiveRole() != entities.RoleAdmin)
+}
+
+// withAdminQuota runs write on userRepo, or on the transaction's users if it adds an active admin.
+// Such writes run after checkAdminQuota, so concurrent promotions can't both fit under maxAdmins.
+func withAdminQuota(ctx context.Context, txManager repository.TxManager, userRepo repository.UserRepository, maxAdmins int, addsAdmin bool, write func(users repository.UserRepository) error) error {
+	if !addsAdmin {
+		return write(userRepo)
+	}
+
+	return txManager.WithTx(ctx, func(repos repository.Repositories) error {
+		if err := checkAdminQuota(ctx, repos.Users, maxAdmins, 1); err != nil {
+			return err
+		}
+		return write(repos.Users)
+	})
+}
+
+// checkAdminQuota locks active admins like removeUser and fails with ErrAdminQuotaExceeded
+// if that many more active admins would exceed maxAdmins. Must run in a transaction.
+func checkAdminQuota(ctx context.Context, users repository.UserRepository, maxAdmins int, admins int64) error {
+	if _, err := users.LockActiveAdmins(ctx); err != nil {
+		return err
+	}
+
+	// Counted after the lock, temporary admins included
+	count, err := users.CountByRole(ctx, entities.RoleAdmin)
+	if err != nil {
+		return err
+	}
+	if count+admins > int64(maxAdmins) {
+		return entities.ErrAdminQuotaExceeded
+	}
+	return nil
+}
+
+// ensureEmailAvailable validates email and fails with ErrEmailAlreadyExists if another user has it.
+// A nil email is always available.
+func (s *UserService) ensureEmailAvailable(ctx context.Context, email *string, excludeID uint) error {
+	if email == nil {
+		return nil
+	}
+	if !entities.IsValidEmail(*email) {
+		return entities.ErrInvalidEmail
+	}
+
+	if existingUser, err := s.userRepo.GetByEmail(ctx, *email); err == nil && existingUser.ID != excludeID {
+		return entities.ErrEmailAlreadyExists
+	}
+	return nil
+}
+
+// ensureUniqueName fails with ErrDuplicateName if another active user has the same name.
+// Users without any name are not compared.
+func ensureUniqueName(ctx context.Context, userRepo repository.UserRepository, firstName, lastName string, excludeID uint) error {
+	if strings.TrimSpace(firstName) == "" && strings.TrimSpace(lastName) == "" {
+		return nil
+	}
+
+	count, err := userRepo.CountActiveByName(ctx, firstName, lastName, excludeID)
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return entities.ErrDuplicateName
+	}
+	return nil
+}
+
+func (s *UserService) validateSearch(search string) error {
+	if s.config.SearchMaxLength > 0 && len([]rune(search)) > s.config.SearchMaxLength {
+		return entities.ErrSearchTooLong
+	}
+	return nil
+}
+
+func (s *UserService) getPendingUser(ctx context.Context, id uint) (*entities.User, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.IsPendingApproval() {
+		return nil, entities.ErrNotPendingApproval
+	}
+
+	return user, nil
+}
+
+// updateRoleAndStatus writes only the role and status columns set by req
+func updateRoleAndStatus(ctx context.Context, users repository.UserRepository, user *entities.User, req *service.UpdateUserRequest) error {
+	if req.Role != nil {
+		if err := users.UpdateRole(ctx, user.ID, user.Role); err != nil {
+			return err
+		}
+	}
+
+	if req.IsActive != nil {
+		if err := users.SetActive(ctx, user.ID, user.IsActive); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordUpdate audits the fields set by an update request
+func (s *UserService) recordUpdate(ctx context.Context, id uint, req *service.UpdateUserRequest) {
+	details := map[string]interface{}{}
+	if req.Username != nil {
+		details["username"] = *req.Username
+	}
+	if req.Email != nil {
+		details["email"] = *req.Email
+	}
+	if req.FirstName != nil {
+		details["first_name"] = *req.FirstName
+	}
+	if req.LastName != nil {
+		details["last_name"] = *req.LastName
+	}
+	if req.Role != nil {
+		details["role"] = *req.Role
+	}
+	if req.IsActive != nil {
+		details["is_active"] = *req.IsActive
+	}
+
+	s.audit.Record(ctx, &entities.AuditEntry{Action: entities.AuditUserUpdate, TargetID: &id, Details: details})
+}
+
+// reloadUser returns the stored copy of a just written user if re-fetching is enabled.
+// Columns filled by defaults or triggers may differ from the entity that was saved.
+func (s *UserService) reloadUser(ctx context.Context, user *entities.User) (*entities.User, error) {
+	if !s.config.RefetchAfterWrite {
+		return user, nil
+	}
+
+	stored, err := s.userRepo.GetByID(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+// removeUser runs remove, which deletes, deactivates or demotes user id, unless it is
+// the actor or the last active admin. Admins are locked first, so concurrent removals
+// can't both see another admin left.
+func (s *UserService) removeUser(ctx context.Context, id uint, remove func(users repository.UserRepository) error) error {
+	if actor, ok := entities.ActorFromContext(ctx); ok && actor.ID == id {
+		return entities.ErrCannotRemoveSelf
+	}
+
+	return s.txManager.WithTx(ctx, func(repos repository.Repositories) error {
+		adminCount, err := repos.Users.LockActiveAdmins(ctx)
+		if err != nil {
+			return err
+		}
+
+		user, err := repos.Users.GetByID(ctx, id)
+		if err != nil {
+			return err
+		}
+		if user.Role == entities.RoleAdmin && user.IsActive && adminCount <= 1 {
+			return entities.ErrCannotRemoveLastAdmin
+		}
 
-	user.IsActive = isActive
-	return s.userRepo.Update(ctx, user)
+		return remove(repos.Users)
+	})
 }
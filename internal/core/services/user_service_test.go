@@ -0,0 +1,1239 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/service"
+	"github.com/ontair/admin-panel/internal/testutil"
+)
+
+const testPassword = "Secret123!"
+
+// userServiceFixture is a user service over in-memory repositories
+type userServiceFixture struct {
+	service  *UserService
+	store    *testutil.Store
+	notifier *testutil.Notifier
+}
+
+func newUserServiceFixture(t *testing.T, config UserServiceConfig) *userServiceFixture {
+	t.Helper()
+	if config.PasswordPolicy.MinLength == 0 {
+		config.PasswordPolicy.MinLength = 8
+	}
+
+	store := testutil.NewStore()
+	notifier := &testutil.Notifier{}
+	logger := testutil.NewLogger()
+	audit := NewAuditService(store.Audit, logger)
+
+	svc := NewUserService(store.Users, store.Roles, store.PasswordResets, store.EmailVerifications, store.PasswordHistory,
+		store.LoginAttempts, store.TxManager(), testutil.Hasher{}, notifier, audit, config)
+
+	return &userServiceFixture{
+		service:  svc.(*UserService),
+		store:    store,
+		notifier: notifier,
+	}
+}
+
+// addUser stores an active approved user with testPassword
+func (f *userServiceFixture) addUser(t *testing.T, username string, role entities.Role) *entities.User {
+	t.Helper()
+	password, _ := testutil.Hasher{}.Hash(testPassword)
+	user := &entities.User{
+		Username:       username,
+		Password:       password,
+		FirstName:      "First " + username,
+		LastName:       "Last " + username,
+		Role:           role,
+		IsActive:       true,
+		ApprovalStatus: entities.ApprovalApproved,
+	}
+	if err := f.store.Users.Create(context.Background(), user); err != nil {
+		t.Fatalf("failed to add user %s: %v", username, err)
+	}
+	return user
+}
+
+// getUser reads the stored state of the user
+func (f *userServiceFixture) getUser(t *testing.T, id uint) *entities.User {
+	t.Helper()
+	user, err := f.store.Users.GetByID(context.Background(), id)
+	if err != nil {
+		t.Fatalf("failed to get user %d: %v", id, err)
+	}
+	return user
+}
+
+// asActor returns context carrying user as the authenticated actor
+func asActor(user *entities.User) context.Context {
+	return entities.ContextWithActor(context.Background(), entities.Actor{ID: user.ID, Username: user.Username, Role: user.Role})
+}
+
+// auditActions lists recorded audit actions in order
+func (f *userServiceFixture) auditActions() []entities.AuditAction {
+	var actions []entities.AuditAction
+	for _, entry := range f.store.Audit.Entries() {
+		actions = append(actions, entry.Action)
+	}
+	return actions
+}
+
+func createPendingUser(t *testing.T, f *userServiceFixture) *entities.User {
+	t.Helper()
+	manager := f.addUser(t, "manager", entities.RoleManager)
+	user, err := f.service.CreateUser(asActor(manager), &service.CreateUserRequest{
+		Username:  "newcomer",
+		Password:  testPassword,
+		Role:      entities.RoleUser,
+		IsActive:  true,
+		ActorRole: entities.RoleManager,
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	return user
+}
+
+func TestCreateUserByManagerAwaitsApproval(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{RequireApproval: true})
+	user := createPendingUser(t, f)
+
+	stored := f.getUser(t, user.ID)
+	if stored.ApprovalStatus != entities.ApprovalPending || stored.IsActive {
+		t.Errorf("expected inactive pending user, got status %q active %v", stored.ApprovalStatus, stored.IsActive)
+	}
+	if pending := f.notifier.Pending(); len(pending) != 1 || pending[0] != user.ID {
+		t.Errorf("expected admins notified about user %d, got %v", user.ID, pending)
+	}
+}
+
+func TestCreateUserByAdminSkipsApproval(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{RequireApproval: true})
+	admin := f.addUser(t, "admin", entities.RoleAdmin)
+
+	user, err := f.service.CreateUser(asActor(admin), &service.CreateUserRequest{
+		Username:  "newcomer",
+		Password:  testPassword,
+		Role:      entities.RoleUser,
+		IsActive:  true,
+		ActorRole: entities.RoleAdmin,
+	})
+	if err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+
+	if stored := f.getUser(t, user.ID); stored.IsPendingApproval() || !stored.IsActive {
+		t.Errorf("expected active user, got status %q active %v", stored.ApprovalStatus, stored.IsActive)
+	}
+	if pending := f.notifier.Pending(); len(pending) != 0 {
+		t.Errorf("expected no notifications, got %v", pending)
+	}
+}
+
+func TestApproveUserActivatesPendingUser(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{RequireApproval: true})
+	user := createPendingUser(t, f)
+
+	if err := f.service.ApproveUser(context.Background(), user.ID); err != nil {
+		t.Fatalf("failed to approve: %v", err)
+	}
+
+	stored := f.getUser(t, user.ID)
+	if stored.ApprovalStatus != entities.ApprovalApproved || !stored.IsActive {
+		t.Errorf("expected active approved user, got status %q active %v", stored.ApprovalStatus, stored.IsActive)
+	}
+	if actions := f.auditActions(); actions[len(actions)-1] != entities.AuditUserApprove {
+		t.Errorf("expected approval in audit log, got %v", actions)
+	}
+
+	// Approved users are no longer pending
+	if err := f.service.ApproveUser(context.Background(), user.ID); err != entities.ErrNotPendingApproval {
+		t.Errorf("expected ErrNotPendingApproval on second approval, got %v", err)
+	}
+}
+
+func TestRejectUserKeepsUserInactive(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{RequireApproval: true})
+	user := createPendingUser(t, f)
+
+	if err := f.service.RejectUser(context.Background(), user.ID); err != nil {
+		t.Fatalf("failed to reject: %v", err)
+	}
+
+	stored := f.getUser(t, user.ID)
+	if stored.ApprovalStatus != entities.ApprovalRejected || stored.IsActive {
+		t.Errorf("expected inactive rejected user, got status %q active %v", stored.ApprovalStatus, stored.IsActive)
+	}
+	if actions := f.auditActions(); actions[len(actions)-1] != entities.AuditUserReject {
+		t.Errorf("expected rejection in audit log, got %v", actions)
+	}
+
+	if err := f.service.ApproveUser(context.Background(), user.ID); err != entities.ErrNotPendingApproval {
+		t.Errorf("expected rejected user not to be approvable, got %v", err)
+	}
+}
+
+func TestChangePasswordConcurrentChangesSerialize(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	user := f.addUser(t, "alice", entities.RoleUser)
+
+	const attempts = 8
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make([]error, attempts)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			<-start
+			errs[i] = f.service.ChangePassword(context.Background(), user.ID, &service.ChangePasswordRequest{
+				CurrentPassword: testPassword,
+				NewPassword:     fmt.Sprintf("Changed%d!pass", i),
+			})
+		}(i)
+	}
+	close(start)
+	wg.Wait()
+
+	// The first change wins, the rest find the row locked or the current password already replaced
+	winner := -1
+	for i, err := range errs {
+		switch err {
+		case nil:
+			if winner >= 0 {
+				t.Fatalf("changes %d and %d both succeeded", winner, i)
+			}
+			winner = i
+		case entities.ErrUserBusy, entities.ErrInvalidCredentials:
+		default:
+			t.Errorf("change %d: unexpected error %v", i, err)
+		}
+	}
+	if winner < 0 {
+		t.Fatalf("expected one change to succeed, got %v", errs)
+	}
+
+	stored := f.getUser(t, user.ID)
+	if !stored.VerifyPassword(fmt.Sprintf("Changed%d!pass", winner), testutil.Hasher{}) {
+		t.Errorf("expected the winning password to be stored")
+	}
+}
+
+func TestGetAssignableRolesByActor(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	user := f.addUser(t, "alice", entities.RoleUser)
+	manager := f.addUser(t, "bob", entities.RoleManager)
+
+	tests := []struct {
+		name   string
+		actor  entities.Role
+		target uint
+		want   []entities.Role
+	}{
+		{"admin on user", entities.RoleAdmin, user.ID, entities.AllRoles},
+		{"admin on manager", entities.RoleAdmin, manager.ID, entities.AllRoles},
+		{"manager on user", entities.RoleManager, user.ID, []entities.Role{entities.RoleUser, entities.RoleGuest}},
+		{"manager on manager", entities.RoleManager, manager.ID, []entities.Role{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roles, err := f.service.GetAssignableRoles(context.Background(), tt.actor, tt.target)
+			if err != nil {
+				t.Fatalf("failed to get assignable roles: %v", err)
+			}
+			if fmt.Sprint(roles) != fmt.Sprint(tt.want) {
+				t.Errorf("expected %v, got %v", tt.want, roles)
+			}
+		})
+	}
+
+	if _, err := f.service.GetAssignableRoles(context.Background(), entities.RoleAdmin, 999); err != entities.ErrUserNotFound {
+		t.Errorf("expected ErrUserNotFound for unknown target, got %v", err)
+	}
+}
+
+func TestCreateUserRejectsNumericUsername(t *testing.T) {
+	for _, reject := range []bool{true, false} {
+		f := newUserServiceFixture(t, UserServiceConfig{RejectNumericNames: reject})
+		admin := f.addUser(t, "admin", entities.RoleAdmin)
+
+		_, err := f.service.CreateUser(asActor(admin), &service.CreateUserRequest{
+			Username:  " 12345 ",
+			Password:  testPassword,
+			Role:      entities.RoleUser,
+			IsActive:  true,
+			ActorRole: entities.RoleAdmin,
+		})
+		if reject && err != entities.ErrNumericUsername {
+			t.Errorf("expected ErrNumericUsername with the rule on, got %v", err)
+		}
+		if !reject && err != nil {
+			t.Errorf("expected numeric username allowed with the rule off, got %v", err)
+		}
+	}
+}
+
+func TestListUsersRejectsLongSearch(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{SearchMaxLength: 10})
+
+	_, err := f.service.ListUsers(context.Background(), &service.ListUsersRequest{Search: "%%%%%%%%%%%"})
+	if err != entities.ErrSearchTooLong {
+		t.Errorf("expected ErrSearchTooLong, got %v", err)
+	}
+
+	if _, err := f.service.ListUsers(context.Background(), &service.ListUsersRequest{Search: "50%"}); err != nil {
+		t.Errorf("expected short search allowed, got %v", err)
+	}
+}
+
+func TestBulkAssignRoleKeepsLastAdmin(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	first := f.addUser(t, "admin1", entities.RoleAdmin)
+	second := f.addUser(t, "admin2", entities.RoleAdmin)
+	user := f.addUser(t, "alice", entities.RoleUser)
+
+	results, err := f.service.BulkAssignRole(context.Background(), &service.BulkRoleRequest{
+		IDs:       []uint{user.ID, first.ID, second.ID, 999},
+		Role:      entities.RoleManager,
+		ActorRole: entities.RoleAdmin,
+	})
+	if err != nil {
+		t.Fatalf("failed to assign roles: %v", err)
+	}
+
+	want := []service.BulkResult{
+		{ID: user.ID, Success: true},
+		{ID: first.ID, Success: true},
+		{ID: second.ID, Error: entities.ErrCannotRemoveLastAdmin.Error()},
+		{ID: 999, Error: entities.ErrUserNotFound.Error()},
+	}
+	if fmt.Sprint(results) != fmt.Sprint(want) {
+		t.Errorf("expected %v, got %v", want, results)
+	}
+
+	if role := f.getUser(t, second.ID).Role; role != entities.RoleAdmin {
+		t.Errorf("expected the last admin to stay admin, got %s", role)
+	}
+	if role := f.getUser(t, first.ID).Role; role != entities.RoleManager {
+		t.Errorf("expected the other admin demoted, got %s", role)
+	}
+	if actions := f.auditActions(); len(actions) != 2 || actions[0] != entities.AuditUserBulkRole || actions[1] != entities.AuditUserBulkRole {
+		t.Errorf("expected an audit entry per change, got %v", actions)
+	}
+}
+
+func TestBulkAssignRoleRejectsSelfDemotion(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	admin := f.addUser(t, "admin1", entities.RoleAdmin)
+	f.addUser(t, "admin2", entities.RoleAdmin)
+
+	results, err := f.service.BulkAssignRole(asActor(admin), &service.BulkRoleRequest{
+		IDs:       []uint{admin.ID},
+		Role:      entities.RoleUser,
+		ActorRole: entities.RoleAdmin,
+	})
+	if err != nil {
+		t.Fatalf("failed to assign roles: %v", err)
+	}
+	if len(results) != 1 || results[0].Error != entities.ErrCannotRemoveSelf.Error() {
+		t.Errorf("expected self demotion rejected, got %v", results)
+	}
+}
+
+func TestExportUserDataContainsOnlyOwnData(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	alice := f.addUser(t, "alice", entities.RoleUser)
+	bob := f.addUser(t, "bob", entities.RoleUser)
+	ctx := context.Background()
+
+	for _, attempt := range []*entities.LoginAttempt{
+		{Username: "alice", UserID: &alice.ID, Success: true, IP: "10.0.0.1"},
+		{Username: "alice", Success: false, IP: "10.0.0.2"},
+		{Username: "bob", UserID: &bob.ID, Success: true, IP: "10.0.0.3"},
+	} {
+		if err := f.store.LoginAttempts.Create(ctx, attempt); err != nil {
+			t.Fatalf("failed to record attempt: %v", err)
+		}
+	}
+	for _, target := range []uint{alice.ID, bob.ID} {
+		if err := f.store.Audit.Record(ctx, &entities.AuditEntry{Action: entities.AuditUserUpdate, TargetID: &target}); err != nil {
+			t.Fatalf("failed to record audit entry: %v", err)
+		}
+	}
+
+	export, err := f.service.ExportUserData(ctx, alice.ID)
+	if err != nil {
+		t.Fatalf("failed to export: %v", err)
+	}
+
+	if export.Profile.ID != alice.ID {
+		t.Errorf("expected alice's profile, got %d", export.Profile.ID)
+	}
+	if len(export.LoginHistory) != 2 {
+		t.Errorf("expected alice's 2 login attempts, got %d", len(export.LoginHistory))
+	}
+	for _, attempt := range export.LoginHistory {
+		if attempt.Username != "alice" {
+			t.Errorf("unexpected attempt of %s in export", attempt.Username)
+		}
+	}
+	if len(export.AuditEntries) != 1 || *export.AuditEntries[0].TargetID != alice.ID {
+		t.Errorf("expected the one audit entry about alice, got %v", export.AuditEntries)
+	}
+
+	body, err := json.Marshal(export)
+	if err != nil {
+		t.Fatalf("failed to encode export: %v", err)
+	}
+	if strings.Contains(string(body), alice.Password) {
+		t.Errorf("expected the password hash excluded from the export")
+	}
+}
+
+func TestScheduledDeactivationAppliesWhenDue(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	user := f.addUser(t, "alice", entities.RoleUser)
+	ctx := context.Background()
+
+	if err := f.service.ScheduleDeactivation(ctx, user.ID, time.Now().Add(-time.Minute)); err != entities.ErrInvalidSchedule {
+		t.Errorf("expected ErrInvalidSchedule for a past time, got %v", err)
+	}
+
+	at := time.Now().Add(time.Hour)
+	if err := f.service.ScheduleDeactivation(ctx, user.ID, at); err != nil {
+		t.Fatalf("failed to schedule deactivation: %v", err)
+	}
+	if stored := f.getUser(t, user.ID); !stored.IsActive || stored.DeactivateAt == nil || !stored.DeactivateAt.Equal(at) {
+		t.Fatalf("expected active user scheduled for %v, got active %v at %v", at, stored.IsActive, stored.DeactivateAt)
+	}
+
+	// Nothing is due yet
+	if count, err := f.service.ApplyScheduledDeactivations(ctx); err != nil || count != 0 {
+		t.Fatalf("expected nothing applied before the time, got %d, %v", count, err)
+	}
+
+	past := time.Now().Add(-time.Second)
+	if err := f.store.Users.SetDeactivateAt(ctx, user.ID, &past); err != nil {
+		t.Fatalf("failed to move schedule: %v", err)
+	}
+	if count, err := f.service.ApplyScheduledDeactivations(ctx); err != nil || count != 1 {
+		t.Fatalf("expected one deactivation applied, got %d, %v", count, err)
+	}
+	if stored := f.getUser(t, user.ID); stored.IsActive || stored.DeactivateAt != nil {
+		t.Errorf("expected inactive user without schedule, got active %v at %v", stored.IsActive, stored.DeactivateAt)
+	}
+}
+
+func TestCancelScheduledDeactivation(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	user := f.addUser(t, "alice", entities.RoleUser)
+	ctx := context.Background()
+
+	if err := f.service.ScheduleDeactivation(ctx, user.ID, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("failed to schedule deactivation: %v", err)
+	}
+	if err := f.service.CancelScheduledDeactivation(ctx, user.ID); err != nil {
+		t.Fatalf("failed to cancel: %v", err)
+	}
+	if stored := f.getUser(t, user.ID); !stored.IsActive || stored.DeactivateAt != nil {
+		t.Errorf("expected active user without schedule, got active %v at %v", stored.IsActive, stored.DeactivateAt)
+	}
+	if err := f.service.CancelScheduledDeactivation(ctx, user.ID); err != entities.ErrNotScheduled {
+		t.Errorf("expected ErrNotScheduled, got %v", err)
+	}
+}
+
+func TestCreateUserWithCustomRole(t *testing.T) {
+	const auditor entities.Role = "auditor"
+
+	for _, custom := range []bool{true, false} {
+		f := newUserServiceFixture(t, UserServiceConfig{CustomRoles: custom})
+		f.store.Roles.Add(auditor)
+		admin := f.addUser(t, "admin", entities.RoleAdmin)
+
+		user, err := f.service.CreateUser(asActor(admin), &service.CreateUserRequest{
+			Username:  "alice",
+			Password:  testPassword,
+			Role:      auditor,
+			IsActive:  true,
+			ActorRole: entities.RoleAdmin,
+		})
+		if !custom {
+			if err != entities.ErrInvalidRole {
+				t.Errorf("expected ErrInvalidRole with custom roles off, got %v", err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("failed to create user with custom role: %v", err)
+		}
+		if stored := f.getUser(t, user.ID); stored.Role != auditor {
+			t.Errorf("expected role %s, got %s", auditor, stored.Role)
+		}
+
+		roles, err := f.service.GetAssignableRoles(context.Background(), entities.RoleAdmin, user.ID)
+		if err != nil || !slices.Contains(roles, auditor) {
+			t.Errorf("expected the custom role assignable by admins, got %v, %v", roles, err)
+		}
+	}
+}
+
+// addSession stores a refresh token starting a new session of the user
+func (f *userServiceFixture) addSession(t *testing.T, userID uint, family string) {
+	t.Helper()
+	err := f.store.RefreshTokens.Create(context.Background(), &entities.RefreshToken{
+		UserID:    userID,
+		FamilyID:  family,
+		TokenHash: "hash-" + family,
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("failed to add session: %v", err)
+	}
+}
+
+// activeSessions counts usable sessions of the user
+func (f *userServiceFixture) activeSessions(t *testing.T, userID uint) int64 {
+	t.Helper()
+	count, err := f.store.RefreshTokens.CountActiveSessions(context.Background(), userID)
+	if err != nil {
+		t.Fatalf("failed to count sessions: %v", err)
+	}
+	return count
+}
+
+func TestConfirmPasswordResetEndsAllSessions(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	user := f.addUser(t, "alice", entities.RoleUser)
+	other := f.addUser(t, "bob", entities.RoleUser)
+	f.addSession(t, user.ID, "laptop")
+	f.addSession(t, user.ID, "phone")
+	f.addSession(t, other.ID, "desktop")
+	ctx := context.Background()
+
+	token, err := f.service.ResetPassword(ctx, &service.ResetPasswordRequest{Username: "alice"})
+	if err != nil || token == "" {
+		t.Fatalf("failed to request reset: %q, %v", token, err)
+	}
+	if err := f.service.ConfirmPasswordReset(ctx, &service.ConfirmPasswordResetRequest{Token: token, NewPassword: "Changed123!"}); err != nil {
+		t.Fatalf("failed to confirm reset: %v", err)
+	}
+
+	if count := f.activeSessions(t, user.ID); count != 0 {
+		t.Errorf("expected no sessions after reset, got %d", count)
+	}
+	if f.getUser(t, user.ID).TokensRevoked == nil {
+		t.Errorf("expected issued access tokens revoked")
+	}
+	if count := f.activeSessions(t, other.ID); count != 1 {
+		t.Errorf("expected other user's session kept, got %d", count)
+	}
+}
+
+func TestChangePasswordLogoutIsConfigurable(t *testing.T) {
+	for _, logout := range []bool{true, false} {
+		f := newUserServiceFixture(t, UserServiceConfig{LogoutOnPasswordChange: logout})
+		user := f.addUser(t, "alice", entities.RoleUser)
+		f.addSession(t, user.ID, "laptop")
+
+		err := f.service.ChangePassword(context.Background(), user.ID, &service.ChangePasswordRequest{
+			CurrentPassword: testPassword,
+			NewPassword:     "Changed123!",
+		})
+		if err != nil {
+			t.Fatalf("failed to change password: %v", err)
+		}
+
+		want := int64(1)
+		if logout {
+			want = 0
+		}
+		if count := f.activeSessions(t, user.ID); count != want {
+			t.Errorf("logout %v: expected %d sessions, got %d", logout, want, count)
+		}
+	}
+}
+
+func TestChangePasswordToSamePassword(t *testing.T) {
+	for _, reject := range []bool{true, false} {
+		f := newUserServiceFixture(t, UserServiceConfig{RejectUnchangedPassword: reject})
+		user := f.addUser(t, "alice", entities.RoleUser)
+
+		err := f.service.ChangePassword(context.Background(), user.ID, &service.ChangePasswordRequest{
+			CurrentPassword: testPassword,
+			NewPassword:     testPassword,
+		})
+		if reject && err != entities.ErrPasswordUnchanged {
+			t.Errorf("expected ErrPasswordUnchanged, got %v", err)
+		}
+		if !reject && err != nil {
+			t.Errorf("expected the same password accepted when not enforced, got %v", err)
+		}
+	}
+}
+
+func TestConfirmPasswordResetToSamePassword(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{RejectUnchangedPassword: true})
+	user := f.addUser(t, "alice", entities.RoleUser)
+	ctx := context.Background()
+
+	token, err := f.service.ResetPassword(ctx, &service.ResetPasswordRequest{Username: "alice"})
+	if err != nil {
+		t.Fatalf("failed to request reset: %v", err)
+	}
+
+	err = f.service.ConfirmPasswordReset(ctx, &service.ConfirmPasswordResetRequest{Token: token, NewPassword: testPassword})
+	if err != entities.ErrPasswordUnchanged {
+		t.Fatalf("expected ErrPasswordUnchanged, got %v", err)
+	}
+
+	// The rejected attempt leaves the token usable
+	if err := f.service.ConfirmPasswordReset(ctx, &service.ConfirmPasswordResetRequest{Token: token, NewPassword: "Changed123!"}); err != nil {
+		t.Fatalf("failed to retry reset: %v", err)
+	}
+	if !f.getUser(t, user.ID).VerifyPassword("Changed123!", testutil.Hasher{}) {
+		t.Errorf("expected the new password stored")
+	}
+}
+
+func TestActivateUserRequiringPasswordReset(t *testing.T) {
+	for _, requireReset := range []bool{true, false} {
+		f := newUserServiceFixture(t, UserServiceConfig{})
+		user := f.addUser(t, "alice", entities.RoleUser)
+		if err := f.store.Users.SetActive(context.Background(), user.ID, false); err != nil {
+			t.Fatalf("failed to deactivate: %v", err)
+		}
+
+		if err := f.service.ActivateUser(context.Background(), user.ID, requireReset); err != nil {
+			t.Fatalf("failed to activate: %v", err)
+		}
+
+		stored := f.getUser(t, user.ID)
+		if !stored.IsActive || stored.MustChangePass != requireReset {
+			t.Errorf("require reset %v: got active %v, must change password %v", requireReset, stored.IsActive, stored.MustChangePass)
+		}
+
+		entries := f.store.Audit.Entries()
+		last := entries[len(entries)-1]
+		if last.Action != entities.AuditUserActivate || last.Details["require_password_change"] != requireReset {
+			t.Errorf("expected activation audited with the requirement, got %v %v", last.Action, last.Details)
+		}
+	}
+}
+
+// addUsers stores count users of role with the given active status
+func (f *userServiceFixture) addUsers(t *testing.T, prefix string, count int, role entities.Role, active bool) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		user := f.addUser(t, fmt.Sprintf("%s%d", prefix, i), role)
+		if !active {
+			if err := f.store.Users.SetActive(context.Background(), user.ID, false); err != nil {
+				t.Fatalf("failed to deactivate: %v", err)
+			}
+		}
+	}
+}
+
+func TestListUsersForManagerInactivePolicy(t *testing.T) {
+	include, exclude := true, false
+	tests := []struct {
+		name     string
+		hide     bool
+		override *bool
+		total    int64
+	}{
+		{"shown by default", false, nil, 5},
+		{"hidden by policy", true, nil, 3},
+		{"policy overridden to show", true, &include, 5},
+		{"default overridden to hide", false, &exclude, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newUserServiceFixture(t, UserServiceConfig{HideInactiveFromManagers: tt.hide})
+			f.addUsers(t, "active", 3, entities.RoleUser, true)
+			f.addUsers(t, "inactive", 2, entities.RoleGuest, false)
+			f.addUser(t, "admin", entities.RoleAdmin)
+
+			page, err := f.service.ListUsersForManager(context.Background(), &service.ListUsersRequest{Limit: 2, IncludeInactive: tt.override})
+			if err != nil {
+				t.Fatalf("failed to list users: %v", err)
+			}
+			if page.Total != tt.total || len(page.Items) != 2 {
+				t.Errorf("expected total %d with a page of 2, got %d with %d", tt.total, page.Total, len(page.Items))
+			}
+			for _, user := range page.Items {
+				if tt.total == 3 && !user.IsActive {
+					t.Errorf("unexpected inactive user %s", user.Username)
+				}
+			}
+		})
+	}
+}
+
+func TestListUsersPastTheLastPage(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	f.addUsers(t, "user", 5, entities.RoleUser, true)
+
+	page, err := f.service.ListUsers(context.Background(), &service.ListUsersRequest{Limit: 2, Offset: 10})
+	if err != nil {
+		t.Fatalf("expected no error past the end, got %v", err)
+	}
+	if len(page.Items) != 0 || page.Total != 5 || page.HasNext || page.Page <= page.TotalPages {
+		t.Errorf("expected an empty page past the end with total 5, got %+v", page)
+	}
+}
+
+// triggerUserRepository changes users right after they are written, like a database trigger
+type triggerUserRepository struct {
+	*testutil.UserRepository
+}
+
+func (r triggerUserRepository) Create(ctx context.Context, user *entities.User) error {
+	if err := r.UserRepository.Create(ctx, user); err != nil {
+		return err
+	}
+	return r.UserRepository.SetMustChangePassword(ctx, user.ID, true)
+}
+
+func (r triggerUserRepository) Update(ctx context.Context, user *entities.User) error {
+	if err := r.UserRepository.Update(ctx, user); err != nil {
+		return err
+	}
+	return r.UserRepository.SetMustChangePassword(ctx, user.ID, true)
+}
+
+func TestRefetchAfterWriteReturnsStoredUser(t *testing.T) {
+	for _, refetch := range []bool{true, false} {
+		f := newUserServiceFixture(t, UserServiceConfig{RefetchAfterWrite: refetch})
+		f.service.userRepo = triggerUserRepository{f.store.Users}
+		admin := f.addUser(t, "admin", entities.RoleAdmin)
+
+		created, err := f.service.CreateUser(asActor(admin), &service.CreateUserRequest{
+			Username:  "alice",
+			Password:  testPassword,
+			Role:      entities.RoleUser,
+			IsActive:  true,
+			ActorRole: entities.RoleAdmin,
+		})
+		if err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+		stored := f.getUser(t, created.ID)
+		if matches := created.MustChangePass == stored.MustChangePass && created.Version == stored.Version; matches != refetch {
+			t.Errorf("refetch %v: created %+v, stored %+v", refetch, created, stored)
+		}
+
+		firstName := "Alicia"
+		updated, err := f.service.UpdateUser(asActor(admin), created.ID, &service.UpdateUserRequest{
+			FirstName: &firstName,
+			ActorRole: entities.RoleAdmin,
+		})
+		if err != nil {
+			t.Fatalf("failed to update user: %v", err)
+		}
+		stored = f.getUser(t, created.ID)
+		if matches := updated.Version == stored.Version; matches != refetch {
+			t.Errorf("refetch %v: updated version %d, stored %d", refetch, updated.Version, stored.Version)
+		}
+	}
+}
+
+func TestListUsersActiveFilterTotal(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	f.addUsers(t, "active", 3, entities.RoleUser, true)
+	f.addUsers(t, "inactive", 4, entities.RoleUser, false)
+	active := true
+
+	for offset := 0; offset < 4; offset += 2 {
+		page, err := f.service.ListUsers(context.Background(), &service.ListUsersRequest{IsActive: &active, Limit: 2, Offset: offset})
+		if err != nil {
+			t.Fatalf("failed to list users: %v", err)
+		}
+		if page.Total != 3 || page.TotalPages != 2 {
+			t.Errorf("offset %d: expected total 3 over 2 pages, got %d over %d", offset, page.Total, page.TotalPages)
+		}
+		if want := min(2, 3-offset); len(page.Items) != want {
+			t.Errorf("offset %d: expected %d users, got %d", offset, want, len(page.Items))
+		}
+	}
+}
+
+func TestGetUserAccessIncludesTempRole(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	f.addUser(t, "admin", entities.RoleAdmin)
+	user := f.addUser(t, "alice", entities.RoleUser)
+
+	until := time.Now().Add(time.Hour)
+	_, err := f.service.GrantTempRole(context.Background(), user.ID, &service.TempRoleRequest{
+		Role:      entities.RoleManager,
+		Until:     until,
+		ActorRole: entities.RoleAdmin,
+	})
+	if err != nil {
+		t.Fatalf("failed to grant temp role: %v", err)
+	}
+
+	access, err := f.service.GetUserAccess(context.Background(), user.ID)
+	if err != nil {
+		t.Fatalf("failed to get access: %v", err)
+	}
+	if access.Role != entities.RoleUser || access.EffectiveRole != entities.RoleManager {
+		t.Errorf("expected user elevated to manager, got role %s effective %s", access.Role, access.EffectiveRole)
+	}
+	if access.TempRole == nil || *access.TempRole != entities.RoleManager || access.TempRoleUntil == nil || !access.TempRoleUntil.Equal(until) {
+		t.Errorf("expected the grant in the result, got %v until %v", access.TempRole, access.TempRoleUntil)
+	}
+	if !access.ManagerAccess || access.AdminAccess {
+		t.Errorf("expected manager access only, got manager %v admin %v", access.ManagerAccess, access.AdminAccess)
+	}
+	if fmt.Sprint(access.AssignableRoles) != fmt.Sprint([]entities.Role{entities.RoleUser, entities.RoleGuest}) {
+		t.Errorf("expected roles assignable by a manager, got %v", access.AssignableRoles)
+	}
+	// The user's own permissions plus those the temporary manager role adds, none of an admin's
+	for _, name := range []string{"profile.read", "users.read", "users.update"} {
+		if !slices.Contains(access.Permissions, name) {
+			t.Errorf("expected permission %s, got %v", name, access.Permissions)
+		}
+	}
+	if slices.Contains(access.Permissions, "users.manage") {
+		t.Errorf("expected no admin permissions, got %v", access.Permissions)
+	}
+
+	if _, err := f.service.GetUserAccess(context.Background(), 999); err != entities.ErrUserNotFound {
+		t.Errorf("expected ErrUserNotFound, got %v", err)
+	}
+}
+
+func TestListUsersNeverChangedPassword(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	ctx := context.Background()
+	f.addUser(t, "admin", entities.RoleAdmin)
+	changed := f.addUser(t, "changed", entities.RoleUser)
+	seeded := f.addUser(t, "seeded", entities.RoleUser)
+	f.addUsers(t, "inactive", 1, entities.RoleUser, false)
+
+	if err := f.service.ChangePassword(ctx, changed.ID, &service.ChangePasswordRequest{
+		CurrentPassword: testPassword,
+		NewPassword:     "Changed123!",
+	}); err != nil {
+		t.Fatalf("failed to change password: %v", err)
+	}
+	// Set on creation counts as never changed
+	if err := f.store.Users.UpdatePasswordLocked(ctx, seeded.ID, func(user *entities.User) error {
+		user.PassChangedAt = &user.CreatedAt
+		return nil
+	}); err != nil {
+		t.Fatalf("failed to set password date: %v", err)
+	}
+
+	yes, no, active := true, false, true
+	tests := []struct {
+		name string
+		req  service.ListUsersRequest
+		want []string
+	}{
+		{"never changed", service.ListUsersRequest{NeverChangedPassword: &yes}, []string{"admin", "inactive0", "seeded"}},
+		{"changed", service.ListUsersRequest{NeverChangedPassword: &no}, []string{"changed"}},
+		{"never changed and active", service.ListUsersRequest{NeverChangedPassword: &yes, IsActive: &active}, []string{"admin", "seeded"}},
+		{"never changed matching search", service.ListUsersRequest{NeverChangedPassword: &yes, Search: "adm"}, []string{"admin"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := f.service.ListUsers(ctx, &tt.req)
+			if err != nil {
+				t.Fatalf("failed to list users: %v", err)
+			}
+			var usernames []string
+			for _, user := range page.Items {
+				usernames = append(usernames, user.Username)
+			}
+			slices.Sort(usernames)
+			if !slices.Equal(usernames, tt.want) || page.Total != int64(len(tt.want)) {
+				t.Errorf("expected %v, got %v (total %d)", tt.want, usernames, page.Total)
+			}
+		})
+	}
+}
+
+func TestCreateUserDuplicateName(t *testing.T) {
+	for _, unique := range []bool{true, false} {
+		f := newUserServiceFixture(t, UserServiceConfig{UniqueNames: unique})
+		admin := f.addUser(t, "admin", entities.RoleAdmin)
+		f.addUser(t, "alice", entities.RoleUser)
+
+		// Names are compared ignoring case and surrounding spaces
+		_, err := f.service.CreateUser(asActor(admin), &service.CreateUserRequest{
+			Username:  "alice2",
+			Password:  testPassword,
+			FirstName: " first ALICE",
+			LastName:  "Last alice ",
+			Role:      entities.RoleUser,
+			IsActive:  true,
+			ActorRole: entities.RoleAdmin,
+		})
+		if unique && err != entities.ErrDuplicateName {
+			t.Errorf("expected ErrDuplicateName, got %v", err)
+		}
+		if !unique && err != nil {
+			t.Errorf("expected duplicate name allowed by default, got %v", err)
+		}
+	}
+}
+
+func TestUpdateUserDuplicateName(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{UniqueNames: true})
+	admin := f.addUser(t, "admin", entities.RoleAdmin)
+	alice := f.addUser(t, "alice", entities.RoleUser)
+	bob := f.addUser(t, "bob", entities.RoleUser)
+	first, last := alice.FirstName, alice.LastName
+
+	_, err := f.service.UpdateUser(asActor(admin), bob.ID, &service.UpdateUserRequest{FirstName: &first, LastName: &last, ActorRole: entities.RoleAdmin})
+	if err != entities.ErrDuplicateName {
+		t.Errorf("expected ErrDuplicateName renaming to another user's name, got %v", err)
+	}
+
+	// The user's own name is not a duplicate
+	if _, err := f.service.UpdateUser(asActor(admin), alice.ID, &service.UpdateUserRequest{FirstName: &first, LastName: &last, ActorRole: entities.RoleAdmin}); err != nil {
+		t.Errorf("expected own name kept, got %v", err)
+	}
+
+	// Inactive users don't hold their name
+	if err := f.store.Users.SetActive(context.Background(), alice.ID, false); err != nil {
+		t.Fatalf("failed to deactivate: %v", err)
+	}
+	if _, err := f.service.UpdateUser(asActor(admin), bob.ID, &service.UpdateUserRequest{FirstName: &first, LastName: &last, ActorRole: entities.RoleAdmin}); err != nil {
+		t.Errorf("expected name of an inactive user available, got %v", err)
+	}
+}
+
+func TestAdminQuotaCeiling(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{MaxAdmins: 2})
+	admin := f.addUser(t, "admin", entities.RoleAdmin)
+	f.addUser(t, "second", entities.RoleAdmin)
+	user := f.addUser(t, "alice", entities.RoleUser)
+	ctx := asActor(admin)
+	adminRole := entities.RoleAdmin
+
+	_, err := f.service.CreateUser(ctx, &service.CreateUserRequest{
+		Username:  "third",
+		Password:  testPassword,
+		Role:      entities.RoleAdmin,
+		IsActive:  true,
+		ActorRole: entities.RoleAdmin,
+	})
+	if err != entities.ErrAdminQuotaExceeded {
+		t.Errorf("create: expected ErrAdminQuotaExceeded, got %v", err)
+	}
+
+	_, err = f.service.UpdateUser(ctx, user.ID, &service.UpdateUserRequest{Role: &adminRole, ActorRole: entities.RoleAdmin})
+	if err != entities.ErrAdminQuotaExceeded {
+		t.Errorf("promote: expected ErrAdminQuotaExceeded, got %v", err)
+	}
+	if f.getUser(t, user.ID).Role != entities.RoleUser {
+		t.Errorf("expected the rejected promotion not stored")
+	}
+
+	_, err = f.service.GrantTempRole(ctx, user.ID, &service.TempRoleRequest{Role: entities.RoleAdmin, Until: time.Now().Add(time.Hour), ActorRole: entities.RoleAdmin})
+	if err != entities.ErrAdminQuotaExceeded {
+		t.Errorf("temp role: expected ErrAdminQuotaExceeded, got %v", err)
+	}
+
+	// Admins already counted can still be updated
+	if _, err := f.service.UpdateUser(ctx, admin.ID, &service.UpdateUserRequest{Role: &adminRole, ActorRole: entities.RoleAdmin}); err != nil {
+		t.Errorf("expected an existing admin updated, got %v", err)
+	}
+}
+
+func TestAdminQuotaFreedByDeactivation(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{MaxAdmins: 1})
+	admin := f.addUser(t, "admin", entities.RoleAdmin)
+	f.addUsers(t, "former", 1, entities.RoleAdmin, false)
+	user := f.addUser(t, "alice", entities.RoleUser)
+	ctx := asActor(admin)
+
+	// Inactive admins don't count, but activating one would exceed the quota
+	former, err := f.store.Users.GetByUsername(context.Background(), "former0")
+	if err != nil {
+		t.Fatalf("failed to get user: %v", err)
+	}
+	if err := f.service.ActivateUser(ctx, former.ID, false); err != entities.ErrAdminQuotaExceeded {
+		t.Errorf("activate: expected ErrAdminQuotaExceeded, got %v", err)
+	}
+
+	if err := f.store.Users.SetActive(context.Background(), admin.ID, false); err != nil {
+		t.Fatalf("failed to deactivate: %v", err)
+	}
+	adminRole := entities.RoleAdmin
+	if _, err := f.service.UpdateUser(ctx, user.ID, &service.UpdateUserRequest{Role: &adminRole, ActorRole: entities.RoleAdmin}); err != nil {
+		t.Errorf("expected promotion within the quota, got %v", err)
+	}
+}
+
+// Admin rows of one import count against the quota together, not each against the stored count
+func TestAdminQuotaAcrossImportRows(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{MaxAdmins: 2})
+	admin := f.addUser(t, "admin", entities.RoleAdmin)
+
+	row := func(username string) *service.CreateUserRequest {
+		return &service.CreateUserRequest{Username: username, Password: testPassword, Role: entities.RoleAdmin, IsActive: true, ActorRole: entities.RoleAdmin}
+	}
+	report, err := f.service.BulkCreate(asActor(admin), []*service.CreateUserRequest{row("second"), row("third")}, false)
+	if err != nil {
+		t.Fatalf("failed to import: %v", err)
+	}
+	if report.Created != 1 || report.Results[1].Error != entities.ErrAdminQuotaExceeded.Error() {
+		t.Errorf("expected only the first admin created, got %+v", report.Results)
+	}
+	if count, _ := f.store.Users.CountByRole(context.Background(), entities.RoleAdmin); count != 2 {
+		t.Errorf("expected 2 admins, got %d", count)
+	}
+}
+
+func TestValidateUsersReportsEachRow(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	admin := f.addUser(t, "admin", entities.RoleAdmin)
+	f.addUser(t, "alice", entities.RoleUser)
+
+	row := func(username, password string, role entities.Role) *service.CreateUserRequest {
+		return &service.CreateUserRequest{Username: username, Password: password, Role: role, IsActive: true, ActorRole: entities.RoleAdmin}
+	}
+	results, err := f.service.ValidateUsers(asActor(admin), []*service.CreateUserRequest{
+		row("bob", testPassword, entities.RoleUser),
+		row("alice", testPassword, entities.RoleUser),
+		row("carol", testPassword, entities.RoleUser),
+		row("carol", testPassword, entities.RoleUser),
+		row("dave", "short", entities.RoleUser),
+		row("erin", testPassword, "superuser"),
+	})
+	if err != nil {
+		t.Fatalf("failed to validate: %v", err)
+	}
+
+	want := []bool{true, false, true, false, false, false}
+	if len(results) != len(want) {
+		t.Fatalf("expected %d results, got %v", len(want), results)
+	}
+	for i, result := range results {
+		if result.Row != i+1 || result.Valid != want[i] || (result.Error == "") != want[i] {
+			t.Errorf("row %d: expected valid %v, got %+v", i+1, want[i], result)
+		}
+	}
+	if results[1].Error != entities.ErrUserAlreadyExists.Error() || results[3].Error != entities.ErrUserAlreadyExists.Error() {
+		t.Errorf("expected existing and repeated usernames reported as duplicates, got %+v and %+v", results[1], results[3])
+	}
+	if results[5].Error != entities.ErrInvalidRole.Error() {
+		t.Errorf("expected unknown role reported, got %+v", results[5])
+	}
+
+	// Nothing is created by a dry run
+	if _, err := f.store.Users.GetByUsername(context.Background(), "bob"); err == nil {
+		t.Errorf("expected no user created")
+	}
+}
+
+func TestCreateUserRoleFromUsernamePattern(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{RolePatterns: []entities.RolePattern{
+		{Pattern: regexp.MustCompile(`^svc-`), Role: entities.RoleGuest},
+	}})
+	admin := f.addUser(t, "admin", entities.RoleAdmin)
+
+	tests := []struct {
+		username string
+		role     entities.Role
+		want     entities.Role
+	}{
+		{"svc-backup", "", entities.RoleGuest},
+		{"svc-report", entities.RoleUser, entities.RoleUser},
+		{"alice", "", entities.RoleUser},
+	}
+
+	for _, tt := range tests {
+		user, err := f.service.CreateUser(asActor(admin), &service.CreateUserRequest{
+			Username:  tt.username,
+			Password:  testPassword,
+			Role:      tt.role,
+			IsActive:  true,
+			ActorRole: entities.RoleAdmin,
+		})
+		if err != nil {
+			t.Fatalf("%s: failed to create user: %v", tt.username, err)
+		}
+		if user.Role != tt.want {
+			t.Errorf("%s: expected role %s, got %s", tt.username, tt.want, user.Role)
+		}
+	}
+}
+
+func TestCreateUserRoleValidation(t *testing.T) {
+	tests := []struct {
+		role entities.Role
+		want entities.Role
+		err  error
+	}{
+		{"", entities.RoleUser, nil},
+		{entities.RoleGuest, entities.RoleGuest, nil},
+		{entities.RoleManager, entities.RoleManager, nil},
+		{entities.RoleAdmin, entities.RoleAdmin, nil},
+		{"amdin", "", entities.ErrInvalidRole},
+		{"ADMIN", "", entities.ErrInvalidRole},
+	}
+
+	for _, tt := range tests {
+		f := newUserServiceFixture(t, UserServiceConfig{})
+		admin := f.addUser(t, "admin", entities.RoleAdmin)
+
+		user, err := f.service.CreateUser(asActor(admin), &service.CreateUserRequest{
+			Username:  "newcomer",
+			Password:  testPassword,
+			Role:      tt.role,
+			IsActive:  true,
+			ActorRole: entities.RoleAdmin,
+		})
+		if err != tt.err {
+			t.Errorf("role %q: expected error %v, got %v", tt.role, tt.err, err)
+			continue
+		}
+		if err == nil && user.Role != tt.want {
+			t.Errorf("role %q: expected %s, got %s", tt.role, tt.want, user.Role)
+		}
+	}
+}
+
+func TestUpdateUserUnknownRole(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	admin := f.addUser(t, "admin", entities.RoleAdmin)
+	user := f.addUser(t, "alice", entities.RoleGuest)
+
+	for _, role := range []entities.Role{"amdin", ""} {
+		_, err := f.service.UpdateUser(asActor(admin), user.ID, &service.UpdateUserRequest{Role: &role, ActorRole: entities.RoleAdmin})
+		if err != entities.ErrInvalidRole {
+			t.Errorf("role %q: expected ErrInvalidRole, got %v", role, err)
+		}
+	}
+	if role := f.getUser(t, user.ID).Role; role != entities.RoleGuest {
+		t.Errorf("expected role unchanged, got %s", role)
+	}
+}
+
+func TestManagerCannotEscalateRoles(t *testing.T) {
+	tests := []struct {
+		role entities.Role
+		err  error
+	}{
+		{entities.RoleGuest, nil},
+		{entities.RoleUser, nil},
+		{entities.RoleManager, entities.ErrRoleNotAssignable},
+		{entities.RoleAdmin, entities.ErrRoleNotAssignable},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.role), func(t *testing.T) {
+			f := newUserServiceFixture(t, UserServiceConfig{})
+			manager := f.addUser(t, "manager", entities.RoleManager)
+			target := f.addUser(t, "alice", entities.RoleGuest)
+			ctx := asActor(manager)
+
+			_, err := f.service.CreateUser(ctx, &service.CreateUserRequest{
+				Username:  "newcomer",
+				Password:  testPassword,
+				Role:      tt.role,
+				IsActive:  true,
+				ActorRole: entities.RoleManager,
+			})
+			if err != tt.err {
+				t.Errorf("create: expected %v, got %v", tt.err, err)
+			}
+
+			role := tt.role
+			_, err = f.service.UpdateUser(ctx, target.ID, &service.UpdateUserRequest{Role: &role, ActorRole: entities.RoleManager})
+			if err != tt.err {
+				t.Errorf("update: expected %v, got %v", tt.err, err)
+			}
+			if tt.err != nil && f.getUser(t, target.ID).Role != entities.RoleGuest {
+				t.Errorf("expected the rejected role not stored")
+			}
+		})
+	}
+}
+
+func TestManagerCannotUpdatePrivilegedUsers(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	manager := f.addUser(t, "manager", entities.RoleManager)
+	admin := f.addUser(t, "admin", entities.RoleAdmin)
+	guest := entities.RoleGuest
+
+	_, err := f.service.UpdateUser(asActor(manager), admin.ID, &service.UpdateUserRequest{Role: &guest, ActorRole: entities.RoleManager})
+	if err != entities.ErrForbidden {
+		t.Errorf("expected ErrForbidden demoting an admin, got %v", err)
+	}
+
+	// Admins assign any role
+	target := f.addUser(t, "alice", entities.RoleUser)
+	promoted := entities.RoleManager
+	if _, err := f.service.UpdateUser(asActor(admin), target.ID, &service.UpdateUserRequest{Role: &promoted, ActorRole: entities.RoleAdmin}); err != nil {
+		t.Errorf("expected admin to assign manager, got %v", err)
+	}
+}
+
+func TestCreateUserRequiresPasswordChange(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	user := createPendingUser(t, f)
+
+	if !f.getUser(t, user.ID).MustChangePass {
+		t.Errorf("expected a created user to change the password on first login")
+	}
+}
+
+// timeoutUserRepository answers user lookups the way the database adapter does when a query runs past its deadline
+type timeoutUserRepository struct {
+	*testutil.UserRepository
+}
+
+func (r timeoutUserRepository) GetByID(ctx context.Context, id uint) (*entities.User, error) {
+	return nil, fmt.Errorf("%w: %w", entities.ErrDatabaseTimeout, context.DeadlineExceeded)
+}
+
+func TestUserLookupTimeoutIsNotNotFound(t *testing.T) {
+	f := newUserServiceFixture(t, UserServiceConfig{})
+	user := f.addUser(t, "alice", entities.RoleUser)
+	f.service.userRepo = timeoutUserRepository{f.store.Users}
+	ctx := context.Background()
+
+	lookups := map[string]func() error{
+		"GetUser": func() error {
+			_, err := f.service.GetUser(ctx, user.ID)
+			return err
+		},
+		"GetUserAccess": func() error {
+			_, err := f.service.GetUserAccess(ctx, user.ID)
+			return err
+		},
+		"GetAssignableRoles": func() error {
+			_, err := f.service.GetAssignableRoles(ctx, entities.RoleAdmin, user.ID)
+			return err
+		},
+		"CancelScheduledDeactivation": func() error {
+			return f.service.CancelScheduledDeactivation(ctx, user.ID)
+		},
+	}
+	for name, lookup := range lookups {
+		if err := lookup(); !errors.Is(err, entities.ErrDatabaseTimeout) {
+			t.Errorf("%s: expected ErrDatabaseTimeout, got %v", name, err)
+		}
+	}
+}
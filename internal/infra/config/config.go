@@ -3,17 +3,31 @@ package config
 import (
 	"fmt"
 	"log"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
 
 	"github.com/spf13/viper"
+	"golang.org/x/crypto/bcrypt"
 )
 
 // Config represents application configuration
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	Cookie   CookieConfig   `mapstructure:"cookie"`
-	Logging  LoggingConfig  `mapstructure:"logging"`
+	Server    ServerConfig    `mapstructure:"server"`
+	Database  DatabaseConfig  `mapstructure:"database"`
+	JWT       JWTConfig       `mapstructure:"jwt"`
+	Cookie    CookieConfig    `mapstructure:"cookie"`
+	Logging   LoggingConfig   `mapstructure:"logging"`
+	Users     UsersConfig     `mapstructure:"users"`
+	Auth      AuthConfig      `mapstructure:"auth"`
+	Security  SecurityConfig  `mapstructure:"security"`
+	RateLimit RateLimitConfig `mapstructure:"rate_limit"`
+	Cache     CacheConfig     `mapstructure:"cache"`
+	Metrics   MetricsConfig   `mapstructure:"metrics"`
+	Seed      SeedConfig      `mapstructure:"seed"`
 }
 
 // ServerConfig represents server configuration
@@ -23,6 +37,25 @@ type ServerConfig struct {
 	Environment  string `mapstructure:"environment"`
 	ReadTimeout  int    `mapstructure:"read_timeout"`
 	WriteTimeout int    `mapstructure:"write_timeout"`
+
+	StrictQueryParams bool `mapstructure:"strict_query_params"` // reject invalid query parameter values with 400
+	MaxURILength      int  `mapstructure:"max_uri_length"`      // bytes of path and query, longer requests get 414, 0 disables
+	ShutdownTimeout   int  `mapstructure:"shutdown_timeout"`    // seconds to drain in-flight requests before connections are closed
+
+	TrustedProxies []string `mapstructure:"trusted_proxies"` // IPs or CIDRs whose X-Forwarded-For is used as client IP, empty trusts none
+
+	CORS CORSConfig `mapstructure:"cors"`
+}
+
+// CORSConfig represents cross-origin settings for deployments without a proxy handling CORS
+type CORSConfig struct {
+	Enabled          bool     `mapstructure:"enabled"`
+	AllowedOrigins   []string `mapstructure:"allowed_origins"` // exact origins, "*" allows any
+	AllowedMethods   []string `mapstructure:"allowed_methods"`
+	AllowedHeaders   []string `mapstructure:"allowed_headers"`
+	ExposedHeaders   []string `mapstructure:"exposed_headers"`   // response headers readable by the browser
+	AllowCredentials bool     `mapstructure:"allow_credentials"` // needed for cookie auth, not allowed with "*"
+	MaxAge           int      `mapstructure:"max_age"`           // seconds browsers cache a preflight response
 }
 
 // DatabaseConfig represents database configuration
@@ -33,6 +66,20 @@ type DatabaseConfig struct {
 	Password string `mapstructure:"password"`
 	Name     string `mapstructure:"name"`
 	SSLMode  string `mapstructure:"sslmode"`
+
+	PasswordFile  string `mapstructure:"password_file"`  // file holding the password (Docker secrets), wins over password
+	RunMigrations bool   `mapstructure:"run_migrations"` // apply schema migrations on startup, disable on replicas
+	QueryTimeout  int    `mapstructure:"query_timeout"`  // seconds a single repository query may take, 0 disables
+
+	Pool DatabasePoolConfig `mapstructure:"pool"`
+}
+
+// DatabasePoolConfig represents pgx connection pool sizing
+type DatabasePoolConfig struct {
+	MaxConns        int `mapstructure:"max_conns"`          // upper bound of open connections
+	MinConns        int `mapstructure:"min_conns"`          // connections kept open when idle
+	MaxConnLifetime int `mapstructure:"max_conn_lifetime"`  // minutes before a connection is replaced
+	MaxConnIdleTime int `mapstructure:"max_conn_idle_time"` // minutes an idle connection is kept above min_conns
 }
 
 // JWTConfig represents JWT configuration
@@ -41,6 +88,22 @@ type JWTConfig struct {
 	RefreshSecret string `mapstructure:"refresh_secret"`
 	AccessExpiry  int    `mapstructure:"access_expiry"`  // minutes
 	RefreshExpiry int    `mapstructure:"refresh_expiry"` // minutes
+
+	Issuer   string `mapstructure:"issuer"`   // iss claim set on issued tokens and required on parsed ones
+	Audience string `mapstructure:"audience"` // aud claim set on issued tokens and required on parsed ones
+
+	SlidingExpiration bool `mapstructure:"sliding_expiration"` // re-issue access token on activity
+	SlidingWindow     int  `mapstructure:"sliding_window"`     // minutes before expiry when re-issue kicks in
+
+	BlacklistCleanupInterval int `mapstructure:"blacklist_cleanup_interval"` // seconds between removals of expired revoked tokens
+
+	Algorithm      string `mapstructure:"algorithm"`        // "HS256" with secrets or "RS256" with a key pair
+	PrivateKeyFile string `mapstructure:"private_key_file"` // PEM RSA private key for RS256 signing
+	PublicKeyFile  string `mapstructure:"public_key_file"`  // PEM RSA public key for RS256, derived from the private key if empty
+
+	PreviousPublicKeyFiles []string `mapstructure:"previous_public_key_files"` // PEM public keys of rotated out pairs, still verify and published in JWKS
+	PreviousSecretKeys     []string `mapstructure:"previous_secret_keys"`      // rotated out HS256 access secrets, still verify
+	PreviousRefreshSecrets []string `mapstructure:"previous_refresh_secrets"`  // rotated out HS256 refresh secrets, still verify
 }
 
 // CookieConfig represents cookie configuration
@@ -51,6 +114,8 @@ type CookieConfig struct {
 	Path          string `mapstructure:"path"`
 	AccessExpiry  int    `mapstructure:"access_expiry"`  // minutes
 	RefreshExpiry int    `mapstructure:"refresh_expiry"` // minutes
+
+	TokenPrecedence string `mapstructure:"token_precedence"` // "header" or "cookie" when both carry a token
 }
 
 // LoggingConfig represents logging configuration
@@ -60,6 +125,95 @@ type LoggingConfig struct {
 	File   string `mapstructure:"file"`
 }
 
+// UsersConfig represents user management configuration
+type UsersConfig struct {
+	RequireApproval     bool `mapstructure:"require_approval"`      // manager-created users need admin approval
+	LockPasswordChanges bool `mapstructure:"lock_password_changes"` // serialize concurrent password changes
+	RejectNumericNames  bool `mapstructure:"reject_numeric_names"`  // disallow all-digit usernames
+	SearchMaxLength     int  `mapstructure:"search_max_length"`     // characters, longer queries are rejected
+	SearchWildcards     bool `mapstructure:"search_wildcards"`      // treat % and _ in search as wildcards
+	CustomRoles         bool `mapstructure:"custom_roles"`          // accept roles added to the roles table
+
+	HideInactiveFromManagers bool `mapstructure:"hide_inactive_from_managers"` // exclude inactive users from manager listings by default
+	RefetchAfterWrite        bool `mapstructure:"refetch_after_write"`         // reload users after create/update for responses
+	UniqueNames              bool `mapstructure:"unique_names"`                // first + last name must differ from other active users
+	MaxAdmins                int  `mapstructure:"max_admins"`                  // active admins allowed, 0 means unlimited
+
+	DeactivationInterval int `mapstructure:"deactivation_interval"` // seconds between scheduled deactivation runs
+
+	RolePatterns           []RolePatternConfig `mapstructure:"role_patterns"`            // default roles by username regex, first match wins
+	PrivilegedRolePatterns bool                `mapstructure:"privileged_role_patterns"` // allow role_patterns to assign admin or manager
+}
+
+// RolePatternConfig maps usernames matching Pattern to Role when no role is given
+type RolePatternConfig struct {
+	Pattern string `mapstructure:"pattern"`
+	Role    string `mapstructure:"role"`
+}
+
+// AuthConfig represents authentication configuration
+type AuthConfig struct {
+	LogoutOnPasswordChange  bool `mapstructure:"logout_on_password_change"` // revoke all tokens and sessions, the current one included, after the user changes their own password
+	RejectUnchangedPassword bool `mapstructure:"reject_unchanged_password"` // new password must differ from the current one
+
+	TokenProfile bool `mapstructure:"token_profile"` // serve deprecated token-only /auth/profile, 410 Gone otherwise
+
+	RequireVerifiedEmail bool `mapstructure:"require_verified_email"` // users with an unverified email can't log in
+}
+
+// SecurityConfig represents security configuration
+type SecurityConfig struct {
+	HashAlgo   string `mapstructure:"hash_algo"`   // "bcrypt" or "argon2id" for new password hashes
+	BcryptCost int    `mapstructure:"bcrypt_cost"` // cost for newly hashed bcrypt passwords
+
+	MaxLoginAttempts int `mapstructure:"max_login_attempts"` // failed logins per username or IP before lockout, 0 disables
+	LockoutWindow    int `mapstructure:"lockout_window"`     // minutes in which failed logins are counted
+
+	Password PasswordPolicyConfig `mapstructure:"password"`
+}
+
+// PasswordPolicyConfig represents requirements for new passwords
+type PasswordPolicyConfig struct {
+	MinLength     int  `mapstructure:"min_length"`     // characters
+	RequireUpper  bool `mapstructure:"require_upper"`  // at least one upper case letter
+	RequireLower  bool `mapstructure:"require_lower"`  // at least one lower case letter
+	RequireDigit  bool `mapstructure:"require_digit"`  // at least one digit
+	RequireSymbol bool `mapstructure:"require_symbol"` // at least one non-alphanumeric character
+	RejectCommon  bool `mapstructure:"reject_common"`  // refuse well-known leaked passwords
+	History       int  `mapstructure:"history"`        // previous passwords that can't be reused, 0 disables
+}
+
+// RateLimitConfig represents per client IP request limits
+type RateLimitConfig struct {
+	APIRPS    float64 `mapstructure:"api_rps"`    // requests per second for all API routes, 0 disables
+	APIBurst  int     `mapstructure:"api_burst"`  // requests allowed at once above the rate
+	AuthRPS   float64 `mapstructure:"auth_rps"`   // requests per second for public auth routes, 0 disables
+	AuthBurst int     `mapstructure:"auth_burst"` // requests allowed at once above the rate
+
+	Headers bool `mapstructure:"headers"` // send X-RateLimit-Limit, -Remaining and -Reset on limited routes
+}
+
+// CacheConfig represents storage for state shared between instances
+type CacheConfig struct {
+	Driver   string `mapstructure:"driver"`   // "memory" (single instance) or "redis"
+	Addr     string `mapstructure:"addr"`     // host:port of Redis
+	Password string `mapstructure:"password"` // Redis AUTH password
+	DB       int    `mapstructure:"db"`       // Redis database number
+}
+
+// MetricsConfig represents the Prometheus metrics endpoint
+type MetricsConfig struct {
+	Enabled bool   `mapstructure:"enabled"` // serve /metrics
+	Addr    string `mapstructure:"addr"`    // host:port of a separate internal listener, empty serves on the main port
+}
+
+// SeedConfig represents the admin account created when no admin exists
+type SeedConfig struct {
+	Enabled       *bool  `mapstructure:"enabled"`        // unset means on outside production, see SeedEnabled
+	AdminUsername string `mapstructure:"admin_username"` // username of the seeded admin
+	AdminPassword string `mapstructure:"admin_password"` // empty generates a random password, logged once
+}
+
 // Load reads configuration from files and environment variables
 func Load() (*Config, error) {
 	viper.SetConfigName("config")
@@ -78,8 +232,22 @@ func Load() (*Config, error) {
 	viper.BindEnv("database.port", "DATABASE_PORT")
 	viper.BindEnv("database.username", "DATABASE_USERNAME")
 	viper.BindEnv("database.password", "DATABASE_PASSWORD")
+	viper.BindEnv("database.password_file", "DATABASE_PASSWORD_FILE")
 	viper.BindEnv("database.name", "DATABASE_NAME")
 	viper.BindEnv("database.sslmode", "DATABASE_SSLMODE")
+	viper.BindEnv("database.run_migrations", "DATABASE_RUN_MIGRATIONS")
+	viper.BindEnv("database.query_timeout", "DATABASE_QUERY_TIMEOUT")
+	viper.BindEnv("cache.driver", "CACHE_DRIVER")
+	viper.BindEnv("cache.addr", "CACHE_ADDR")
+	viper.BindEnv("cache.password", "CACHE_PASSWORD")
+	viper.BindEnv("cache.db", "CACHE_DB")
+	viper.BindEnv("metrics.addr", "METRICS_ADDR")
+	viper.BindEnv("seed.enabled", "SEED_ENABLED")
+	viper.BindEnv("seed.admin_username", "ADMIN_USERNAME")
+	viper.BindEnv("seed.admin_password", "ADMIN_PASSWORD")
+	viper.BindEnv("server.cors.enabled", "CORS_ENABLED")
+	viper.BindEnv("server.cors.allowed_origins", "CORS_ALLOWED_ORIGINS")
+	viper.BindEnv("server.trusted_proxies", "TRUSTED_PROXIES")
 
 	// Read config file
 	if err := viper.ReadInConfig(); err != nil {
@@ -91,6 +259,14 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("unable to decode config into struct: %w", err)
 	}
 
+	if err := config.loadSecrets(); err != nil {
+		return nil, err
+	}
+
+	if err := config.validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
 	return &config, nil
 }
 
@@ -102,20 +278,49 @@ func setDefaults() {
 	viper.SetDefault("server.environment", "development")
 	viper.SetDefault("server.read_timeout", 30)
 	viper.SetDefault("server.write_timeout", 30)
+	viper.SetDefault("server.strict_query_params", false)
+	viper.SetDefault("server.max_uri_length", 4096)
+	viper.SetDefault("server.shutdown_timeout", 30)
+	viper.SetDefault("server.trusted_proxies", []string{})
+	viper.SetDefault("server.cors.enabled", false)
+	viper.SetDefault("server.cors.allowed_origins", []string{})
+	viper.SetDefault("server.cors.allowed_methods", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"})
+	viper.SetDefault("server.cors.allowed_headers", []string{"Authorization", "Content-Type", "X-Request-ID"})
+	viper.SetDefault("server.cors.exposed_headers", []string{"X-Request-ID"})
+	viper.SetDefault("server.cors.allow_credentials", true)
+	viper.SetDefault("server.cors.max_age", 600)
 
 	// Database defaults
 	viper.SetDefault("database.host", "localhost")
 	viper.SetDefault("database.port", 5432)
 	viper.SetDefault("database.username", "postgres")
 	viper.SetDefault("database.password", "password")
+	viper.SetDefault("database.password_file", "")
 	viper.SetDefault("database.name", "admin_panel")
 	viper.SetDefault("database.sslmode", "disable")
+	viper.SetDefault("database.run_migrations", true)
+	viper.SetDefault("database.query_timeout", 5)
+	viper.SetDefault("database.pool.max_conns", 100)
+	viper.SetDefault("database.pool.min_conns", 5)
+	viper.SetDefault("database.pool.max_conn_lifetime", 60)
+	viper.SetDefault("database.pool.max_conn_idle_time", 30)
 
 	// JWT defaults
 	viper.SetDefault("jwt.secret_key", "your-secret-key")
 	viper.SetDefault("jwt.refresh_secret", "your-refresh-secret")
 	viper.SetDefault("jwt.access_expiry", 15)    // 15 minutes
 	viper.SetDefault("jwt.refresh_expiry", 1440) // 24 hours
+	viper.SetDefault("jwt.issuer", "github.com/ontair/admin-panel")
+	viper.SetDefault("jwt.audience", "admin-panel-users")
+	viper.SetDefault("jwt.sliding_expiration", false)
+	viper.SetDefault("jwt.sliding_window", 5) // 5 minutes
+	viper.SetDefault("jwt.blacklist_cleanup_interval", 3600)
+	viper.SetDefault("jwt.algorithm", "HS256")
+	viper.SetDefault("jwt.private_key_file", "")
+	viper.SetDefault("jwt.public_key_file", "")
+	viper.SetDefault("jwt.previous_public_key_files", []string{})
+	viper.SetDefault("jwt.previous_secret_keys", []string{})
+	viper.SetDefault("jwt.previous_refresh_secrets", []string{})
 
 	// Cookie defaults
 	viper.SetDefault("cookie.domain", "")
@@ -124,11 +329,185 @@ func setDefaults() {
 	viper.SetDefault("cookie.path", "/")
 	viper.SetDefault("cookie.access_expiry", 15)    // 15 minutes
 	viper.SetDefault("cookie.refresh_expiry", 1440) // 24 hours
+	viper.SetDefault("cookie.token_precedence", "header")
 
 	// Logging defaults
 	viper.SetDefault("logging.level", "info")
 	viper.SetDefault("logging.format", "json")
 	viper.SetDefault("logging.file", "")
+
+	// Users defaults
+	viper.SetDefault("users.require_approval", false)
+	viper.SetDefault("users.lock_password_changes", false)
+	viper.SetDefault("users.reject_numeric_names", false)
+	viper.SetDefault("users.search_max_length", 100)
+	viper.SetDefault("users.search_wildcards", false)
+	viper.SetDefault("users.custom_roles", false)
+	viper.SetDefault("users.hide_inactive_from_managers", false)
+	viper.SetDefault("users.refetch_after_write", false)
+	viper.SetDefault("users.unique_names", false)
+	viper.SetDefault("users.max_admins", 0)
+	viper.SetDefault("users.deactivation_interval", 60) // 1 minute
+	viper.SetDefault("users.role_patterns", []RolePatternConfig{})
+	viper.SetDefault("users.privileged_role_patterns", false)
+
+	// Auth defaults
+	viper.SetDefault("auth.logout_on_password_change", false)
+	viper.SetDefault("auth.reject_unchanged_password", true)
+	viper.SetDefault("auth.token_profile", true)
+	viper.SetDefault("auth.require_verified_email", false)
+
+	// Security defaults
+	viper.SetDefault("security.hash_algo", "bcrypt")
+	viper.SetDefault("security.bcrypt_cost", bcrypt.DefaultCost)
+	viper.SetDefault("security.max_login_attempts", 5)
+	viper.SetDefault("security.lockout_window", 15) // 15 minutes
+	viper.SetDefault("security.password.min_length", 8)
+	viper.SetDefault("security.password.require_upper", false)
+	viper.SetDefault("security.password.require_lower", false)
+	viper.SetDefault("security.password.require_digit", false)
+	viper.SetDefault("security.password.require_symbol", false)
+	viper.SetDefault("security.password.reject_common", true)
+	viper.SetDefault("security.password.history", 5)
+
+	// Rate limit defaults
+	viper.SetDefault("rate_limit.api_rps", 20)
+	viper.SetDefault("rate_limit.api_burst", 40)
+	viper.SetDefault("rate_limit.auth_rps", 1)
+	viper.SetDefault("rate_limit.auth_burst", 5)
+	viper.SetDefault("rate_limit.headers", true)
+
+	// Cache defaults
+	viper.SetDefault("cache.driver", "memory")
+	viper.SetDefault("cache.addr", "localhost:6379")
+	viper.SetDefault("cache.password", "")
+	viper.SetDefault("cache.db", 0)
+
+	// Metrics defaults
+	viper.SetDefault("metrics.enabled", true)
+	viper.SetDefault("metrics.addr", "")
+
+	// Seed defaults, seed.enabled has none so production only seeds when it is set explicitly
+	viper.SetDefault("seed.admin_username", "admin")
+	viper.SetDefault("seed.admin_password", "")
+}
+
+// loadSecrets reads secrets referenced by file paths
+func (c *Config) loadSecrets() error {
+	if c.Database.PasswordFile == "" {
+		return nil
+	}
+
+	password, err := os.ReadFile(c.Database.PasswordFile)
+	if err != nil {
+		return fmt.Errorf("unable to read database.password_file: %w", err)
+	}
+
+	// Secret files usually end with a newline
+	c.Database.Password = strings.TrimRight(string(password), "\r\n")
+	return nil
+}
+
+// validate checks configuration values that would otherwise fail at runtime
+func (c *Config) validate() error {
+	if c.Server.ShutdownTimeout < 1 {
+		return fmt.Errorf("server.shutdown_timeout must be at least 1 second")
+	}
+	if c.Server.CORS.Enabled {
+		if len(c.Server.CORS.AllowedOrigins) == 0 {
+			return fmt.Errorf("server.cors.allowed_origins must not be empty when CORS is enabled")
+		}
+		if c.Server.CORS.AllowCredentials && slices.Contains(c.Server.CORS.AllowedOrigins, "*") {
+			return fmt.Errorf("server.cors.allowed_origins must list origins explicitly when allow_credentials is set")
+		}
+	}
+	if c.Security.BcryptCost < bcrypt.MinCost || c.Security.BcryptCost > bcrypt.MaxCost {
+		return fmt.Errorf("security.bcrypt_cost must be between %d and %d, got %d",
+			bcrypt.MinCost, bcrypt.MaxCost, c.Security.BcryptCost)
+	}
+	if c.Security.HashAlgo != "bcrypt" && c.Security.HashAlgo != "argon2id" {
+		return fmt.Errorf("security.hash_algo must be bcrypt or argon2id, got %q", c.Security.HashAlgo)
+	}
+	if c.Security.Password.MinLength < 8 {
+		return fmt.Errorf("security.password.min_length must be at least 8, got %d", c.Security.Password.MinLength)
+	}
+	if c.Security.Password.History < 0 {
+		return fmt.Errorf("security.password.history must not be negative, got %d", c.Security.Password.History)
+	}
+	if c.Database.Pool.MaxConns < 1 {
+		return fmt.Errorf("database.pool.max_conns must be at least 1, got %d", c.Database.Pool.MaxConns)
+	}
+	if c.Database.Pool.MinConns < 0 || c.Database.Pool.MinConns > c.Database.Pool.MaxConns {
+		return fmt.Errorf("database.pool.min_conns must be between 0 and max_conns (%d), got %d",
+			c.Database.Pool.MaxConns, c.Database.Pool.MinConns)
+	}
+	if c.Database.Pool.MaxConnLifetime < 1 || c.Database.Pool.MaxConnIdleTime < 1 {
+		return fmt.Errorf("database.pool.max_conn_lifetime and max_conn_idle_time must be at least 1 minute")
+	}
+	if c.Database.QueryTimeout < 0 {
+		return fmt.Errorf("database.query_timeout must not be negative, got %d", c.Database.QueryTimeout)
+	}
+	switch c.Cookie.SameSite {
+	case "Lax", "Strict":
+	case "None":
+		// Browsers drop SameSite=None cookies that are not Secure
+		if !c.Cookie.Secure {
+			return fmt.Errorf("cookie.same_site None requires cookie.secure true")
+		}
+	default:
+		return fmt.Errorf("cookie.same_site must be Lax, Strict or None, got %q", c.Cookie.SameSite)
+	}
+	if c.JWT.Algorithm != "HS256" && c.JWT.Algorithm != "RS256" {
+		return fmt.Errorf("jwt.algorithm must be HS256 or RS256, got %q", c.JWT.Algorithm)
+	}
+	if c.JWT.Algorithm == "RS256" && c.JWT.PrivateKeyFile == "" {
+		return fmt.Errorf("jwt.private_key_file is required for RS256")
+	}
+	if c.JWT.Issuer == "" || c.JWT.Audience == "" {
+		return fmt.Errorf("jwt.issuer and jwt.audience must not be empty")
+	}
+	if c.Cache.Driver != "memory" && c.Cache.Driver != "redis" {
+		return fmt.Errorf("cache.driver must be memory or redis, got %q", c.Cache.Driver)
+	}
+	if c.Seed.AdminUsername == "" {
+		return fmt.Errorf("seed.admin_username must not be empty")
+	}
+	if c.Seed.AdminPassword != "" && len(c.Seed.AdminPassword) < 8 {
+		return fmt.Errorf("seed.admin_password must be at least 8 characters")
+	}
+	if c.Metrics.Addr != "" {
+		if _, _, err := net.SplitHostPort(c.Metrics.Addr); err != nil {
+			return fmt.Errorf("metrics.addr must be host:port, got %q", c.Metrics.Addr)
+		}
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return fmt.Errorf("server.trusted_proxies must be IPs or CIDRs, got %q", proxy)
+			}
+		}
+	}
+	if c.RateLimit.APIRPS > 0 && c.RateLimit.APIBurst < 1 {
+		return fmt.Errorf("rate_limit.api_burst must be at least 1, got %d", c.RateLimit.APIBurst)
+	}
+	if c.RateLimit.AuthRPS > 0 && c.RateLimit.AuthBurst < 1 {
+		return fmt.Errorf("rate_limit.auth_burst must be at least 1, got %d", c.RateLimit.AuthBurst)
+	}
+	if c.Users.MaxAdmins < 0 {
+		return fmt.Errorf("users.max_admins must not be negative, got %d", c.Users.MaxAdmins)
+	}
+	for i, p := range c.Users.RolePatterns {
+		if _, err := regexp.Compile(p.Pattern); err != nil || p.Pattern == "" {
+			return fmt.Errorf("users.role_patterns[%d].pattern must be a valid regular expression, got %q", i, p.Pattern)
+		}
+		if p.Role == "" {
+			return fmt.Errorf("users.role_patterns[%d].role must not be empty", i)
+		}
+		if (p.Role == "admin" || p.Role == "manager") && !c.Users.PrivilegedRolePatterns {
+			return fmt.Errorf("users.role_patterns[%d] assigns %s, set users.privileged_role_patterns to allow it", i, p.Role)
+		}
+	}
+	return nil
 }
 
 // GetDSN returns database connection string
@@ -148,19 +527,29 @@ func (c *Config) IsProduction() bool {
 	return c.Server.Environment == "production"
 }
 
+// SeedEnabled reports whether the default admin is seeded. Unless seed.enabled is
+// set explicitly, seeding is on outside production and off in production.
+func (c *Config) SeedEnabled() bool {
+	if c.Seed.Enabled != nil {
+		return *c.Seed.Enabled
+	}
+	return !c.IsProduction()
+}
+
 // GetPort returns server port
 func (c *Config) GetPort() string {
 	return ":" + c.Server.Port
 }
 
-// GetPostgresURL returns PostgreSQL connection URL
+// GetPostgresURL returns PostgreSQL connection URL.
+// It contains the password and must never be logged.
 func (c *Config) GetPostgresURL() string {
-	return fmt.Sprintf("postgres://%s:%s@%s:%d/%s?sslmode=%s",
-		c.Database.Username,
-		c.Database.Password,
-		c.Database.Host,
-		c.Database.Port,
-		c.Database.Name,
-		c.Database.SSLMode,
-	)
+	dbURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.Database.Username, c.Database.Password),
+		Host:     fmt.Sprintf("%s:%d", c.Database.Host, c.Database.Port),
+		Path:     "/" + c.Database.Name,
+		RawQuery: url.Values{"sslmode": {c.Database.SSLMode}}.Encode(),
+	}
+	return dbURL.String()
 }
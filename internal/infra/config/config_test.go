@@ -0,0 +1,126 @@
+package config
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// loadDefaults loads configuration without a config file, i.e. the defaults
+func loadDefaults(t *testing.T) *Config {
+	t.Helper()
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("failed to load config: %v", err)
+	}
+	return cfg
+}
+
+func TestBcryptCostValidation(t *testing.T) {
+	if cost := loadDefaults(t).Security.BcryptCost; cost != bcrypt.DefaultCost {
+		t.Errorf("expected default cost %d, got %d", bcrypt.DefaultCost, cost)
+	}
+
+	tests := []struct {
+		cost  int
+		valid bool
+	}{
+		{bcrypt.MinCost, true},
+		{12, true},
+		{bcrypt.MaxCost, true},
+		{bcrypt.MinCost - 1, false},
+		{bcrypt.MaxCost + 1, false},
+	}
+
+	for _, tt := range tests {
+		cfg := loadDefaults(t)
+		cfg.Security.BcryptCost = tt.cost
+
+		err := cfg.validate()
+		if tt.valid && err != nil {
+			t.Errorf("cost %d: expected valid, got %v", tt.cost, err)
+		}
+		if !tt.valid && (err == nil || !strings.Contains(err.Error(), "security.bcrypt_cost")) {
+			t.Errorf("cost %d: expected bcrypt_cost error, got %v", tt.cost, err)
+		}
+	}
+}
+
+func TestDatabasePasswordFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db_password")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0o600); err != nil {
+		t.Fatalf("failed to write secret: %v", err)
+	}
+	t.Setenv("DATABASE_PASSWORD", "inline")
+	t.Setenv("DATABASE_PASSWORD_FILE", path)
+
+	// The file wins over the inline password, its trailing newline is dropped
+	if password := loadDefaults(t).Database.Password; password != "from-file" {
+		t.Errorf("expected password from file, got %q", password)
+	}
+}
+
+func TestDatabasePasswordFileMissing(t *testing.T) {
+	t.Setenv("DATABASE_PASSWORD_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "database.password_file") {
+		t.Errorf("expected password_file error, got %v", err)
+	}
+}
+
+func TestGetPostgresURLEscapesPassword(t *testing.T) {
+	cfg := loadDefaults(t)
+	cfg.Database.Password = "p@ss/word:1"
+
+	parsed, err := url.Parse(cfg.GetPostgresURL())
+	if err != nil {
+		t.Fatalf("failed to parse URL: %v", err)
+	}
+	if password, _ := parsed.User.Password(); password != "p@ss/word:1" {
+		t.Errorf("expected password to round-trip, got %q", password)
+	}
+}
+
+func TestRunMigrations(t *testing.T) {
+	if !loadDefaults(t).Database.RunMigrations {
+		t.Errorf("expected migrations to run by default")
+	}
+
+	t.Setenv("DATABASE_RUN_MIGRATIONS", "false")
+	if loadDefaults(t).Database.RunMigrations {
+		t.Errorf("expected DATABASE_RUN_MIGRATIONS=false to disable migrations")
+	}
+}
+
+func TestRolePatternValidation(t *testing.T) {
+	tests := []struct {
+		name       string
+		pattern    RolePatternConfig
+		privileged bool
+		valid      bool
+	}{
+		{"guest pattern", RolePatternConfig{Pattern: "^svc-", Role: "guest"}, false, true},
+		{"invalid regexp", RolePatternConfig{Pattern: "(", Role: "guest"}, false, false},
+		{"missing role", RolePatternConfig{Pattern: "^svc-"}, false, false},
+		{"privileged role", RolePatternConfig{Pattern: "^ops-", Role: "admin"}, false, false},
+		{"privileged role allowed", RolePatternConfig{Pattern: "^ops-", Role: "admin"}, true, true},
+	}
+
+	for _, tt := range tests {
+		cfg := loadDefaults(t)
+		cfg.Users.RolePatterns = []RolePatternConfig{tt.pattern}
+		cfg.Users.PrivilegedRolePatterns = tt.privileged
+
+		err := cfg.validate()
+		if tt.valid && err != nil {
+			t.Errorf("%s: expected valid, got %v", tt.name, err)
+		}
+		if !tt.valid && (err == nil || !strings.Contains(err.Error(), "users.role_patterns")) {
+			t.Errorf("%s: expected role_patterns error, got %v", tt.name, err)
+		}
+	}
+}
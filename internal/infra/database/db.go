@@ -2,27 +2,36 @@ package database
 
 import (
 	"context"
+	"crypto/rand"
+	"encoding/base64"
 	"fmt"
 	"log"
 	"time"
 
 	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/service"
 	"github.com/ontair/admin-panel/internal/infra/config"
+	"github.com/ontair/admin-panel/internal/infra/database/migrations"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
 )
 
 // DatabaseService handles database operations with pgx
 type DatabaseService struct {
 	db     *pgxpool.Pool
 	config *config.Config
+	hasher entities.PasswordHasher
+	logger service.Logger
 }
 
 // NewDatabaseService creates new database service
-func NewDatabaseService(cfg *config.Config) (*DatabaseService, error) {
+func NewDatabaseService(cfg *config.Config, hasher entities.PasswordHasher, logger service.Logger) (*DatabaseService, error) {
 	// Parse configuration
 	dbURL := cfg.GetPostgresURL()
-	log.Printf("Connecting to database: %s", dbURL)
+	log.Printf("Connecting to database %s at %s:%d", cfg.Database.Name, cfg.Database.Host, cfg.Database.Port)
 
 	// Configure connection pool
 	config, err := pgxpool.ParseConfig(dbURL)
@@ -30,11 +39,11 @@ func NewDatabaseService(cfg *config.Config) (*DatabaseService, error) {
 		return nil, fmt.Errorf("failed to parse database config: %w", err)
 	}
 
-	// Configure pool settings
-	config.MaxConns = 100
-	config.MinConns = 5
-	config.MaxConnLifetime = time.Hour
-	config.MaxConnIdleTime = time.Minute * 30
+	// Configure pool settings, bounds are validated on config load
+	config.MaxConns = int32(cfg.Database.Pool.MaxConns)
+	config.MinConns = int32(cfg.Database.Pool.MinConns)
+	config.MaxConnLifetime = time.Duration(cfg.Database.Pool.MaxConnLifetime) * time.Minute
+	config.MaxConnIdleTime = time.Duration(cfg.Database.Pool.MaxConnIdleTime) * time.Minute
 
 	// Connect to database with retry logic
 	var db *pgxpool.Pool
@@ -61,6 +70,8 @@ func NewDatabaseService(cfg *config.Config) (*DatabaseService, error) {
 	service := &DatabaseService{
 		db:     db,
 		config: cfg,
+		hasher: hasher,
+		logger: logger,
 	}
 
 	// Test connection
@@ -69,8 +80,7 @@ func NewDatabaseService(cfg *config.Config) (*DatabaseService, error) {
 		return nil, fmt.Errorf("database health check failed: %w", err)
 	}
 
-	// Initialize database migrations
-	if err := service.migrate(); err != nil {
+	if err := service.migrateOnStartup(); err != nil {
 		db.Close()
 		return nil, fmt.Errorf("failed to migrate database: %w", err)
 	}
@@ -78,11 +88,84 @@ func NewDatabaseService(cfg *config.Config) (*DatabaseService, error) {
 	return service, nil
 }
 
+// migrateOnStartup initializes database migrations unless another process owns them (read replicas, sidecars)
+func (s *DatabaseService) migrateOnStartup() error {
+	if !s.config.Database.RunMigrations {
+		log.Println("Skipping database migrations, database.run_migrations is disabled")
+		return nil
+	}
+	return s.MigrateUp()
+}
+
+// migrationLockID is the advisory lock key held while migrating, "admin" in ASCII
+const migrationLockID int64 = 0x61646d696e
+
+// MigrateUp applies pending migrations under an advisory lock so instances starting together don't race
+func (s *DatabaseService) MigrateUp() error {
+	return s.withMigrationLock(s.migrate)
+}
+
+// MigrateDown rolls back the latest steps applied migrations under the migration lock
+func (s *DatabaseService) MigrateDown(steps int) error {
+	return s.withMigrationLock(func(ctx context.Context, conn *pgxpool.Conn) error {
+		count, err := migrations.Down(ctx, conn, steps)
+		log.Printf("Rolled back %d migration(s)", count)
+		return err
+	})
+}
+
+// MigrationStatus lists known migrations and when they were applied
+func (s *DatabaseService) MigrationStatus() ([]migrations.Status, error) {
+	return migrations.List(context.Background(), s.db)
+}
+
+// withMigrationLock runs fn on a connection holding the migration advisory lock
+func (s *DatabaseService) withMigrationLock(fn func(ctx context.Context, conn *pgxpool.Conn) error) error {
+	ctx := context.Background()
+
+	// Session level lock, it must be taken and released on the same connection
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection for migration lock: %w", err)
+	}
+	defer conn.Release()
+
+	log.Println("Waiting for migration lock...")
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		return fmt.Errorf("failed to take migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.Exec(ctx, "SELECT pg_advisory_unlock($1)", migrationLockID); err != nil {
+			log.Printf("Failed to release migration lock: %v", err)
+		}
+	}()
+
+	return fn(ctx, conn)
+}
+
 // GetPool returns database connection pool
 func (s *DatabaseService) GetPool() *pgxpool.Pool {
 	return s.db
 }
 
+// WithTx runs fn in a transaction, committing if fn returns nil and rolling back otherwise
+func (s *DatabaseService) WithTx(ctx context.Context, fn func(tx pgx.Tx) error) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
 // Close closes database connection
 func (s *DatabaseService) Close() error {
 	s.db.Close()
@@ -102,26 +185,18 @@ func (s *DatabaseService) Health() error {
 	return nil
 }
 
-// migrate runs database migrations
-func (s *DatabaseService) migrate() error {
+// migrate applies pending schema migrations, then seeds initial data
+func (s *DatabaseService) migrate(ctx context.Context, conn *pgxpool.Conn) error {
 	log.Println("Running database migrations...")
 
-	ctx := context.Background()
-
-	// Create users table
-	if err := s.createUsersTable(ctx); err != nil {
-		return fmt.Errorf("failed to create users table: %w", err)
-	}
-	log.Println("Users table created successfully")
-
-	// Create indexes
-	if err := s.createIndexes(ctx); err != nil {
-		return fmt.Errorf("failed to create indexes: %w", err)
+	count, err := migrations.Up(ctx, conn)
+	if err != nil {
+		return err
 	}
-	log.Println("Database indexes created successfully")
+	log.Printf("Applied %d migration(s)", count)
 
 	// Seed data if needed
-	if err := s.seedData(ctx); err != nil {
+	if err := s.seedData(ctx, s.db); err != nil {
 		return fmt.Errorf("failed to seed data: %w", err)
 	}
 	log.Println("Initial data seeded successfully")
@@ -130,115 +205,96 @@ func (s *DatabaseService) migrate() error {
 	return nil
 }
 
-// createUsersTable creates users table
-func (s *DatabaseService) createUsersTable(ctx context.Context) error {
-	query := `
-		CREATE TABLE IF NOT EXISTS users (
-			id SERIAL PRIMARY KEY,
-			username VARCHAR(50) UNIQUE NOT NULL,
-			password VARCHAR(255) NOT NULL,
-			first_name VARCHAR(50),
-			last_name VARCHAR(50),
-			role VARCHAR(20) DEFAULT 'user' NOT NULL CHECK (role IN ('admin', 'manager', 'user', 'guest')),
-			is_active BOOLEAN DEFAULT true NOT NULL,
-			last_login TIMESTAMP WITH TIME ZONE,
-			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
-			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
-		)`
-
-	_, err := s.db.Exec(ctx, query)
-	if err != nil {
-		return fmt.Errorf("failed to create users table: %w", err)
+// seedQuerier is the part of the pool seeding uses
+type seedQuerier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// seedData creates the configured admin when seeding is enabled and no admin exists
+func (s *DatabaseService) seedData(ctx context.Context, db seedQuerier) error {
+	if !s.config.SeedEnabled() {
+		log.Println("Skipping default admin seeding, seed.enabled is off")
+		return nil
 	}
 
-	// Verify table was created
-	var tableExists bool
-	err = s.db.QueryRow(ctx, `
-		SELECT EXISTS (
-			SELECT FROM information_schema.tables 
-			WHERE table_schema = 'public' 
-			AND table_name = 'users'
-		)`).Scan(&tableExists)
+	// Check if admin user exists
+	var adminCount int
+	err := db.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE role = 'admin' AND deleted_at IS NULL").Scan(&adminCount)
 	if err != nil {
-		return fmt.Errorf("failed to verify users table: %w", err)
+		return fmt.Errorf("failed to count admin users: %w", err)
 	}
-
-	if !tableExists {
-		return fmt.Errorf("users table was not created")
+	if adminCount > 0 {
+		return nil
 	}
 
-	log.Println("Users table verified successfully")
-	return nil
-}
-
-// createIndexes creates database indexes
-func (s *DatabaseService) createIndexes(ctx context.Context) error {
-	indexes := []string{
-		"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users(username)",
-		"CREATE INDEX IF NOT EXISTS idx_users_role ON users(role)",
-		"CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at)",
-		"CREATE INDEX IF NOT EXISTS idx_users_is_active ON users(is_active)",
-	}
+	log.Println("Creating default admin user...")
 
-	for _, idx := range indexes {
-		if _, err := s.db.Exec(ctx, idx); err != nil {
-			log.Printf("Warning: Index creation failed: %v", err)
+	password := s.config.Seed.AdminPassword
+	generated := password == ""
+	if generated {
+		if password, err = generateAdminPassword(); err != nil {
+			return fmt.Errorf("failed to generate admin password: %w", err)
 		}
 	}
 
-	return nil
-}
+	admin := &entities.User{
+		Username:  s.config.Seed.AdminUsername,
+		FirstName: "Admin",
+		LastName:  "User",
+		Role:      entities.RoleAdmin,
+		IsActive:  true,
+	}
+	if err := admin.SetPassword(password, s.hasher); err != nil {
+		return fmt.Errorf("failed to set admin password: %w", err)
+	}
+	// Seeded credentials are shared or logged, so they have to be replaced on first login
+	admin.MustChangePass = true
 
-// seedData seeds initial data if needed
-func (s *DatabaseService) seedData(ctx context.Context) error {
-	// Check if admin user exists
-	var adminCount int
-	err := s.db.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE role = 'admin'").Scan(&adminCount)
+	query := `
+		INSERT INTO users (username, password, first_name, last_name, role, is_active, must_change_password, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NOW(), NOW())`
+
+	_, err = db.Exec(ctx, query,
+		admin.Username,
+		admin.Password,
+		admin.FirstName,
+		admin.LastName,
+		admin.Role,
+		admin.IsActive,
+		admin.MustChangePass,
+	)
 	if err != nil {
-		return fmt.Errorf("failed to count admin users: %w", err)
+		log.Printf("Warning: Failed to create default admin user: %v", err)
+		return nil
 	}
 
-	// Create default admin if no admin exists
-	if adminCount == 0 {
-		log.Println("Creating default admin user...")
-
-		// Create admin user with hashed password
-		admin := &entities.User{
-			Username:  "admin",
-			FirstName: "Admin",
-			LastName:  "User",
-			Role:      entities.RoleAdmin,
-			IsActive:  true,
-		}
+	s.logDefaultAdminCreated(admin.Username, password, generated)
+	return nil
+}
 
-		// Set default password
-		if err := admin.SetPassword("admin123"); err != nil {
-			return fmt.Errorf("failed to set admin password: %w", err)
-		}
+// generateAdminPassword returns a random password for the seeded admin
+func generateAdminPassword() (string, error) {
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
 
-		// Insert admin user
-		query := `
-			INSERT INTO users (username, password, first_name, last_name, role, is_active, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW())`
-
-		_, err := s.db.Exec(ctx, query,
-			admin.Username,
-			admin.Password,
-			admin.FirstName,
-			admin.LastName,
-			admin.Role,
-			admin.IsActive,
+// logDefaultAdminCreated reports the seeded admin account. A generated password is
+// logged this one time at Warn level, since nobody could log in otherwise; a configured
+// password is never logged.
+func (s *DatabaseService) logDefaultAdminCreated(username, password string, generated bool) {
+	if !generated {
+		s.logger.Info("Default admin user created with the configured password, it must be changed on first login",
+			zap.String("username", username),
 		)
-
-		if err != nil {
-			log.Printf("Warning: Failed to create default admin user: %v", err)
-		} else {
-			log.Println("Default admin user created successfully")
-			log.Println("Login credentials:")
-			log.Println("  Username: admin")
-			log.Println("  Password: admin123")
-		}
+		return
 	}
 
-	return nil
+	s.logger.Warn("Default admin user created with a generated password, change this password: it is shown only once and must be changed on first login",
+		zap.String("username", username),
+		zap.String("password", password),
+	)
 }
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/ontair/admin-panel/internal/infra/config"
+	"github.com/ontair/admin-panel/internal/testutil"
+)
+
+// seedLog reports the seeded admin in environment and returns what was logged
+func seedLog(environment string, generated bool) []testutil.LogEntry {
+	cfg := &config.Config{}
+	cfg.Server.Environment = environment
+	logger := testutil.NewLogger()
+	s := &DatabaseService{config: cfg, logger: logger}
+
+	s.logDefaultAdminCreated("admin", "Seeded-Pass-123", generated)
+	return logger.Entries()
+}
+
+func TestSeededAdminConfiguredPasswordNeverLogged(t *testing.T) {
+	for _, environment := range []string{"production", "development"} {
+		for _, entry := range seedLog(environment, false) {
+			if strings.Contains(entry.Message+fmt.Sprint(entry.Fields), "Seeded-Pass-123") {
+				t.Errorf("%s: configured password logged: %s %v", environment, entry.Message, entry.Fields)
+			}
+		}
+	}
+}
+
+// A generated password is the only way in, so it is logged once, but never at Info where shared logs keep it
+func TestSeededAdminGeneratedPasswordLoggedAtWarn(t *testing.T) {
+	entries := seedLog("production", true)
+	if len(entries) != 1 {
+		t.Fatalf("expected one entry, got %v", entries)
+	}
+	if entries[0].Level != zapcore.WarnLevel || entries[0].Fields["password"] != "Seeded-Pass-123" {
+		t.Errorf("expected the generated password at warn level, got %v %v", entries[0].Level, entries[0].Fields)
+	}
+}
+
+// Without a pool any migration attempt would panic, so returning cleanly means nothing touched the database
+func TestMigrateOnStartupSkippedWhenDisabled(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Database.RunMigrations = false
+	s := &DatabaseService{config: cfg, logger: testutil.NewLogger()}
+
+	if err := s.migrateOnStartup(); err != nil {
+		t.Errorf("expected migrations skipped, got %v", err)
+	}
+}
+
+// seedDB answers the admin count and records the insert
+type seedDB struct {
+	adminCount int
+	inserted   []any
+}
+
+func (d *seedDB) Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	d.inserted = args
+	return pgconn.NewCommandTag("INSERT 0 1"), nil
+}
+
+func (d *seedDB) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return countRow(d.adminCount)
+}
+
+type countRow int
+
+func (r countRow) Scan(dest ...any) error {
+	*dest[0].(*int) = int(r)
+	return nil
+}
+
+// SetPassword clears the flag, so the seeded admin must still have it set when inserted
+func TestSeededAdminMustChangePassword(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.Server.Environment = "development"
+	cfg.Seed.AdminUsername = "admin"
+	cfg.Seed.AdminPassword = "Seeded-Pass-123"
+	s := &DatabaseService{config: cfg, logger: testutil.NewLogger(), hasher: testutil.Hasher{}}
+	db := &seedDB{}
+
+	if err := s.seedData(context.Background(), db); err != nil {
+		t.Fatalf("seed failed: %v", err)
+	}
+	if len(db.inserted) != 7 || db.inserted[6] != true {
+		t.Errorf("expected must_change_password set on the seeded admin, got %v", db.inserted)
+	}
+}
@@ -0,0 +1,179 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+// initialSchema is the schema as built by the ad-hoc migrations that preceded
+// versioning. Every statement is idempotent, so databases created by those
+// releases adopt it without changes and later migrations can build on it.
+var initialSchema = Migration{
+	Version: 1,
+	Name:    "initial_schema",
+	Up: func(ctx context.Context, tx pgx.Tx) error {
+		if err := execAll(ctx, tx, initialTables); err != nil {
+			return err
+		}
+
+		for _, role := range entities.AllRoles {
+			_, err := tx.Exec(ctx, `
+				INSERT INTO roles (name, is_builtin) VALUES ($1, true)
+				ON CONFLICT (name) DO UPDATE SET is_builtin = true`, string(role))
+			if err != nil {
+				return fmt.Errorf("failed to seed role %s: %w", role, err)
+			}
+		}
+
+		if err := execAll(ctx, tx, initialAlterations); err != nil {
+			return err
+		}
+		return execAll(ctx, tx, initialIndexes)
+	},
+	Down: func(ctx context.Context, tx pgx.Tx) error {
+		return execAll(ctx, tx, []string{
+			"DROP TABLE IF EXISTS login_attempts",
+			"DROP TABLE IF EXISTS audit_logs",
+			"DROP TABLE IF EXISTS refresh_tokens",
+			"DROP TABLE IF EXISTS email_verification_tokens",
+			"DROP TABLE IF EXISTS password_reset_tokens",
+			"DROP TABLE IF EXISTS token_blacklist",
+			"DROP TABLE IF EXISTS users",
+			"DROP TABLE IF EXISTS roles",
+		})
+	},
+}
+
+var initialTables = []string{
+	// Roles are referenced by users.role
+	`CREATE TABLE IF NOT EXISTS roles (
+		name VARCHAR(20) PRIMARY KEY,
+		description TEXT,
+		is_builtin BOOLEAN DEFAULT false NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS users (
+		id SERIAL PRIMARY KEY,
+		username VARCHAR(50) UNIQUE NOT NULL,
+		password VARCHAR(255) NOT NULL,
+		first_name VARCHAR(50),
+		last_name VARCHAR(50),
+		role VARCHAR(20) DEFAULT 'user' NOT NULL CONSTRAINT fk_users_role REFERENCES roles(name),
+		is_active BOOLEAN DEFAULT true NOT NULL,
+		approval_status VARCHAR(20) DEFAULT 'approved' NOT NULL CHECK (approval_status IN ('pending', 'approved', 'rejected')),
+		last_login TIMESTAMP WITH TIME ZONE,
+		deactivate_at TIMESTAMP WITH TIME ZONE,
+		temp_role VARCHAR(20) CONSTRAINT fk_users_temp_role REFERENCES roles(name),
+		temp_role_until TIMESTAMP WITH TIME ZONE,
+		tokens_revoked_at TIMESTAMP WITH TIME ZONE,
+		must_change_password BOOLEAN DEFAULT false NOT NULL,
+		deleted_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	)`,
+	// Tokens revoked before expiry
+	`CREATE TABLE IF NOT EXISTS token_blacklist (
+		jti VARCHAR(64) PRIMARY KEY,
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS password_reset_tokens (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		token_hash VARCHAR(64) UNIQUE NOT NULL,
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		used_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS email_verification_tokens (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		email VARCHAR(255) NOT NULL,
+		token_hash VARCHAR(64) UNIQUE NOT NULL,
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		used_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	)`,
+	// Hashed refresh tokens grouped in rotation families
+	`CREATE TABLE IF NOT EXISTS refresh_tokens (
+		id SERIAL PRIMARY KEY,
+		user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+		family_id VARCHAR(32) NOT NULL,
+		token_hash VARCHAR(64) UNIQUE NOT NULL,
+		expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+		used_at TIMESTAMP WITH TIME ZONE,
+		revoked_at TIMESTAMP WITH TIME ZONE,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	)`,
+	// Entries reference users without foreign keys, so they outlive deleted accounts
+	`CREATE TABLE IF NOT EXISTS audit_logs (
+		id SERIAL PRIMARY KEY,
+		actor_id INTEGER,
+		action VARCHAR(50) NOT NULL,
+		target_id INTEGER,
+		details JSONB DEFAULT '{}' NOT NULL,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	)`,
+	`CREATE TABLE IF NOT EXISTS login_attempts (
+		id SERIAL PRIMARY KEY,
+		username TEXT NOT NULL,
+		user_id INTEGER,
+		success BOOLEAN NOT NULL,
+		ip VARCHAR(45),
+		user_agent TEXT,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+	)`,
+}
+
+// initialAlterations bring tables created by older releases up to the schema above
+var initialAlterations = []string{
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS approval_status VARCHAR(20) DEFAULT 'approved' NOT NULL
+		CHECK (approval_status IN ('pending', 'approved', 'rejected'))`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivate_at TIMESTAMP WITH TIME ZONE`,
+	// Replace the built-in roles CHECK with a foreign key, keeping any role already in use
+	`INSERT INTO roles (name) SELECT DISTINCT role FROM users ON CONFLICT (name) DO NOTHING`,
+	`ALTER TABLE users DROP CONSTRAINT IF EXISTS users_role_check`,
+	`DO $$
+	BEGIN
+		IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'fk_users_role') THEN
+			ALTER TABLE users ADD CONSTRAINT fk_users_role FOREIGN KEY (role) REFERENCES roles(name);
+		END IF;
+	END $$`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS temp_role VARCHAR(20) CONSTRAINT fk_users_temp_role REFERENCES roles(name)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS temp_role_until TIMESTAMP WITH TIME ZONE`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS tokens_revoked_at TIMESTAMP WITH TIME ZONE`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN DEFAULT false NOT NULL`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP WITH TIME ZONE`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS email VARCHAR(255)`,
+	`ALTER TABLE users ADD COLUMN IF NOT EXISTS email_verified BOOLEAN DEFAULT false NOT NULL`,
+	// Login details shown in the session list
+	`ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS user_agent TEXT`,
+	`ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS ip VARCHAR(45)`,
+}
+
+var initialIndexes = []string{
+	"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username ON users(username)",
+	"CREATE INDEX IF NOT EXISTS idx_users_role ON users(role)",
+	"CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_users_is_active ON users(is_active)",
+	"CREATE INDEX IF NOT EXISTS idx_users_approval_status ON users(approval_status)",
+	"CREATE INDEX IF NOT EXISTS idx_users_deactivate_at ON users(deactivate_at) WHERE deactivate_at IS NOT NULL",
+	"CREATE INDEX IF NOT EXISTS idx_users_temp_role_until ON users(temp_role_until) WHERE temp_role_until IS NOT NULL",
+	"CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users(deleted_at) WHERE deleted_at IS NOT NULL",
+	// NULLs never collide, so users without email are not constrained
+	"CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users(LOWER(email))",
+	"CREATE INDEX IF NOT EXISTS idx_token_blacklist_expires_at ON token_blacklist(expires_at)",
+	"CREATE INDEX IF NOT EXISTS idx_password_reset_tokens_user_id ON password_reset_tokens(user_id)",
+	"CREATE INDEX IF NOT EXISTS idx_email_verification_tokens_user_id ON email_verification_tokens(user_id)",
+	"CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens(family_id)",
+	"CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens(user_id)",
+	"CREATE INDEX IF NOT EXISTS idx_audit_logs_actor_id ON audit_logs(actor_id)",
+	"CREATE INDEX IF NOT EXISTS idx_audit_logs_action ON audit_logs(action)",
+	"CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs(created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_login_attempts_username_created_at ON login_attempts(username, created_at)",
+	"CREATE INDEX IF NOT EXISTS idx_login_attempts_ip_created_at ON login_attempts(ip, created_at)",
+}
@@ -0,0 +1,29 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// passwordHistory keeps hashes of replaced passwords so they can't be reused
+var passwordHistory = Migration{
+	Version: 2,
+	Name:    "password_history",
+	Up: func(ctx context.Context, tx pgx.Tx) error {
+		return execAll(ctx, tx, []string{
+			`CREATE TABLE password_history (
+				id SERIAL PRIMARY KEY,
+				user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+				password_hash VARCHAR(255) NOT NULL,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			)`,
+			"CREATE INDEX idx_password_history_user_id_created_at ON password_history(user_id, created_at)",
+		})
+	},
+	Down: func(ctx context.Context, tx pgx.Tx) error {
+		return execAll(ctx, tx, []string{
+			"DROP TABLE IF EXISTS password_history",
+		})
+	},
+}
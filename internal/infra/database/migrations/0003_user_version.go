@@ -0,0 +1,23 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// userVersion adds the row version checked by user updates to detect lost writes
+var userVersion = Migration{
+	Version: 3,
+	Name:    "user_version",
+	Up: func(ctx context.Context, tx pgx.Tx) error {
+		return execAll(ctx, tx, []string{
+			"ALTER TABLE users ADD COLUMN version INTEGER DEFAULT 1 NOT NULL",
+		})
+	},
+	Down: func(ctx context.Context, tx pgx.Tx) error {
+		return execAll(ctx, tx, []string{
+			"ALTER TABLE users DROP COLUMN IF EXISTS version",
+		})
+	},
+}
@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+// permissions adds the permission catalog and the roles granted each permission,
+// seeded with the built-in permissions
+var permissions = Migration{
+	Version: 4,
+	Name:    "permissions",
+	Up: func(ctx context.Context, tx pgx.Tx) error {
+		err := execAll(ctx, tx, []string{
+			`CREATE TABLE permissions (
+				name VARCHAR(50) PRIMARY KEY,
+				description TEXT NOT NULL,
+				created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+			)`,
+			`CREATE TABLE role_permissions (
+				role VARCHAR(20) NOT NULL REFERENCES roles(name) ON DELETE CASCADE,
+				permission VARCHAR(50) NOT NULL REFERENCES permissions(name) ON DELETE CASCADE,
+				PRIMARY KEY (role, permission)
+			)`,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, p := range entities.BuiltInPermissions {
+			if _, err := tx.Exec(ctx, `INSERT INTO permissions (name, description) VALUES ($1, $2)`, p.Name, p.Description); err != nil {
+				return fmt.Errorf("failed to seed permission %s: %w", p.Name, err)
+			}
+			for _, role := range p.Roles {
+				_, err := tx.Exec(ctx, `INSERT INTO role_permissions (role, permission) VALUES ($1, $2)`, string(role), p.Name)
+				if err != nil {
+					return fmt.Errorf("failed to grant permission %s to %s: %w", p.Name, role, err)
+				}
+			}
+		}
+		return nil
+	},
+	Down: func(ctx context.Context, tx pgx.Tx) error {
+		return execAll(ctx, tx, []string{
+			"DROP TABLE IF EXISTS role_permissions",
+			"DROP TABLE IF EXISTS permissions",
+		})
+	},
+}
@@ -1,9 +1,11 @@
 package logger
 
 import (
+	"context"
 	"os"
 	"path/filepath"
 
+	"github.com/ontair/admin-panel/internal/core/entities"
 	"github.com/ontair/admin-panel/internal/core/ports/service"
 	"github.com/ontair/admin-panel/internal/infra/config"
 
@@ -94,6 +96,15 @@ func (l *AppLogger) Fatal(msg string, fields ...zap.Field) {
 	l.logger.Fatal(msg, fields...)
 }
 
+// With returns logger tagged with the request ID from ctx, or l itself when there is none
+func (l *AppLogger) With(ctx context.Context) service.Logger {
+	requestID := entities.RequestIDFromContext(ctx)
+	if requestID == "" {
+		return l
+	}
+	return &AppLogger{logger: l.logger.With(zap.String("request_id", requestID))}
+}
+
 // Close closes the logger
 func (l *AppLogger) Close() error {
 	return l.logger.Sync()
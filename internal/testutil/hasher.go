@@ -0,0 +1,30 @@
+package testutil
+
+import (
+	"strings"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+)
+
+const hashPrefix = "plain$"
+
+// Hasher implements PasswordHasher interface without hashing, so tests don't pay for bcrypt.
+// Hashes without the prefix count as another algorithm and need a rehash.
+type Hasher struct{}
+
+var _ entities.PasswordHasher = Hasher{}
+
+// Hash prefixes password
+func (Hasher) Hash(password string) (string, error) {
+	return hashPrefix + password, nil
+}
+
+// Verify checks password against a prefixed hash
+func (Hasher) Verify(password, encoded string) bool {
+	return encoded == hashPrefix+password
+}
+
+// NeedsRehash checks if encoded hash was produced by a different hasher
+func (Hasher) NeedsRehash(encoded string) bool {
+	return !strings.HasPrefix(encoded, hashPrefix)
+}
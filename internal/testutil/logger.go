@@ -0,0 +1,72 @@
+package testutil
+
+import (
+	"context"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/ontair/admin-panel/internal/core/ports/service"
+)
+
+// LogEntry is one recorded log call
+type LogEntry struct {
+	Level   zapcore.Level
+	Message string
+	Fields  map[string]interface{}
+}
+
+// Logger implements Logger interface and keeps entries so tests can check what was logged
+type Logger struct {
+	mu      sync.Mutex
+	entries []LogEntry
+}
+
+// NewLogger creates logger recording entries of all levels
+func NewLogger() *Logger {
+	return &Logger{}
+}
+
+var _ service.Logger = (*Logger)(nil)
+
+func (l *Logger) Debug(msg string, fields ...zap.Field) { l.record(zapcore.DebugLevel, msg, fields) }
+func (l *Logger) Info(msg string, fields ...zap.Field)  { l.record(zapcore.InfoLevel, msg, fields) }
+func (l *Logger) Warn(msg string, fields ...zap.Field)  { l.record(zapcore.WarnLevel, msg, fields) }
+func (l *Logger) Error(msg string, fields ...zap.Field) { l.record(zapcore.ErrorLevel, msg, fields) }
+
+// Fatal records msg without exiting, so tests notice it instead of stopping
+func (l *Logger) Fatal(msg string, fields ...zap.Field) { l.record(zapcore.FatalLevel, msg, fields) }
+
+// With returns the same logger, request IDs are not recorded
+func (l *Logger) With(ctx context.Context) service.Logger { return l }
+
+// Close does nothing
+func (l *Logger) Close() error { return nil }
+
+// Messages returns all logged messages in order
+func (l *Logger) Messages() []string {
+	var messages []string
+	for _, entry := range l.Entries() {
+		messages = append(messages, entry.Message)
+	}
+	return messages
+}
+
+// Entries returns all log calls in order
+func (l *Logger) Entries() []LogEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]LogEntry{}, l.entries...)
+}
+
+func (l *Logger) record(level zapcore.Level, msg string, fields []zap.Field) {
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries = append(l.entries, LogEntry{Level: level, Message: msg, Fields: encoder.Fields})
+}
@@ -0,0 +1,31 @@
+package testutil
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/service"
+)
+
+// Notifier implements Notifier interface and keeps IDs of users admins were notified about
+type Notifier struct {
+	mu      sync.Mutex
+	pending []uint
+}
+
+var _ service.Notifier = (*Notifier)(nil)
+
+// NotifyPendingApproval records user
+func (n *Notifier) NotifyPendingApproval(ctx context.Context, user *entities.User) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.pending = append(n.pending, user.ID)
+}
+
+// Pending returns IDs of users notified about, in order
+func (n *Notifier) Pending() []uint {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return append([]uint{}, n.pending...)
+}
@@ -0,0 +1,630 @@
+package testutil
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+)
+
+// Store groups in-memory implementations of all repositories
+type Store struct {
+	Users              *UserRepository
+	Roles              *RoleRepository
+	Blacklist          *TokenBlacklist
+	RefreshTokens      *RefreshTokenRepository
+	LoginAttempts      *LoginAttemptRepository
+	PasswordResets     *PasswordResetRepository
+	EmailVerifications *EmailVerificationRepository
+	Audit              *AuditRepository
+	PasswordHistory    *PasswordHistoryRepository
+}
+
+// NewStore creates empty repositories, the role list holds the built-in roles
+func NewStore() *Store {
+	return &Store{
+		Users:              NewUserRepository(),
+		Roles:              NewRoleRepository(),
+		Blacklist:          NewTokenBlacklist(),
+		RefreshTokens:      NewRefreshTokenRepository(),
+		LoginAttempts:      NewLoginAttemptRepository(),
+		PasswordResets:     NewPasswordResetRepository(),
+		EmailVerifications: NewEmailVerificationRepository(),
+		Audit:              NewAuditRepository(),
+		PasswordHistory:    NewPasswordHistoryRepository(),
+	}
+}
+
+// TxManager returns transaction manager running work against the store's repositories
+func (s *Store) TxManager() *TxManager {
+	return &TxManager{Repos: repository.Repositories{
+		Users:           s.Users,
+		RefreshTokens:   s.RefreshTokens,
+		Audit:           s.Audit,
+		PasswordHistory: s.PasswordHistory,
+	}}
+}
+
+// TxManager implements TxManager interface by calling fn directly.
+// Nothing is rolled back when fn fails, tests check the error instead.
+type TxManager struct {
+	Repos repository.Repositories
+	Calls int // number of WithTx calls
+}
+
+var _ repository.TxManager = (*TxManager)(nil)
+
+// WithTx runs fn with the manager's repositories
+func (m *TxManager) WithTx(ctx context.Context, fn func(repos repository.Repositories) error) error {
+	m.Calls++
+	return fn(m.Repos)
+}
+
+// RoleRepository implements RoleRepository interface in memory
+type RoleRepository struct {
+	mu          sync.Mutex
+	roles       []entities.Role
+	permissions []entities.Permission
+}
+
+// NewRoleRepository creates role repository holding the built-in roles and custom ones,
+// with the built-in permissions granted as the permissions migration seeds them
+func NewRoleRepository(custom ...entities.Role) *RoleRepository {
+	return &RoleRepository{
+		roles:       append(append([]entities.Role{}, entities.AllRoles...), custom...),
+		permissions: append([]entities.Permission{}, entities.BuiltInPermissions...),
+	}
+}
+
+var _ repository.RoleRepository = (*RoleRepository)(nil)
+
+// List retrieves all roles, built-in and custom
+func (r *RoleRepository) List(ctx context.Context) ([]entities.Role, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]entities.Role{}, r.roles...), nil
+}
+
+// Exists checks if role is defined
+func (r *RoleRepository) Exists(ctx context.Context, role entities.Role) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return containsRole(r.roles, role), nil
+}
+
+// ListPermissions retrieves the permission catalog with the roles granted each permission
+func (r *RoleRepository) ListPermissions(ctx context.Context) ([]entities.Permission, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	permissions := make([]entities.Permission, len(r.permissions))
+	for i, p := range r.permissions {
+		p.Roles = append([]entities.Role{}, p.Roles...)
+		permissions[i] = p
+	}
+	return permissions, nil
+}
+
+// Add defines a custom role, like inserting it into the roles table
+func (r *RoleRepository) Add(role entities.Role) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.roles = append(r.roles, role)
+}
+
+// TokenBlacklist implements TokenBlacklist interface in memory
+type TokenBlacklist struct {
+	mu      sync.Mutex
+	entries map[string]time.Time // expiry by jti
+}
+
+// NewTokenBlacklist creates empty token blacklist
+func NewTokenBlacklist() *TokenBlacklist {
+	return &TokenBlacklist{entries: make(map[string]time.Time)}
+}
+
+var _ repository.TokenBlacklist = (*TokenBlacklist)(nil)
+
+// Add revokes token with given jti until its expiry
+func (b *TokenBlacklist) Add(ctx context.Context, jti string, exp time.Time) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.entries[jti] = exp
+	return nil
+}
+
+// IsBlacklisted checks if token with given jti was revoked
+func (b *TokenBlacklist) IsBlacklisted(ctx context.Context, jti string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	_, ok := b.entries[jti]
+	return ok
+}
+
+// DeleteExpired drops entries for tokens that have expired anyway
+func (b *TokenBlacklist) DeleteExpired(ctx context.Context) (int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var deleted int64
+	for jti, exp := range b.entries {
+		if !exp.After(time.Now()) {
+			delete(b.entries, jti)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// RefreshTokenRepository implements RefreshTokenRepository interface in memory
+type RefreshTokenRepository struct {
+	mu     sync.Mutex
+	tokens []*entities.RefreshToken
+}
+
+// NewRefreshTokenRepository creates empty refresh token repository
+func NewRefreshTokenRepository() *RefreshTokenRepository {
+	return &RefreshTokenRepository{}
+}
+
+var _ repository.RefreshTokenRepository = (*RefreshTokenRepository)(nil)
+
+// Create stores a newly issued refresh token
+func (r *RefreshTokenRepository) Create(ctx context.Context, token *entities.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token.ID = uint(len(r.tokens) + 1)
+	token.CreatedAt = time.Now()
+	stored := *token
+	r.tokens = append(r.tokens, &stored)
+	return nil
+}
+
+// GetByHash retrieves refresh token by its hash, fails with ErrInvalidToken if unknown
+func (r *RefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*entities.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, entities.ErrInvalidToken
+}
+
+// MarkUsed rotates refresh token out, fails with ErrInvalidToken if it was already used or revoked
+func (r *RefreshTokenRepository) MarkUsed(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.ID == id && token.UsedAt == nil && token.RevokedAt == nil {
+			now := time.Now()
+			token.UsedAt = &now
+			return nil
+		}
+	}
+	return entities.ErrInvalidToken
+}
+
+// RevokeFamily revokes all tokens of the family
+func (r *RefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	r.revoke(func(t *entities.RefreshToken) bool { return t.FamilyID == familyID })
+	return nil
+}
+
+// ListSessions retrieves not revoked sessions of a user and their total count
+func (r *RefreshTokenRepository) ListSessions(ctx context.Context, filter repository.SessionFilter) ([]*entities.Session, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	sessions := []*entities.Session{}
+	for i := len(r.tokens) - 1; i >= 0; i-- {
+		token := r.tokens[i]
+		if token.UserID != filter.UserID || token.UsedAt != nil || token.RevokedAt != nil {
+			continue
+		}
+		if token.ExpiresAt.After(now) == filter.Expired {
+			continue
+		}
+
+		loginAt := token.CreatedAt
+		for _, t := range r.tokens {
+			if t.FamilyID == token.FamilyID && t.CreatedAt.Before(loginAt) {
+				loginAt = t.CreatedAt
+			}
+		}
+		sessions = append(sessions, &entities.Session{
+			ID:         token.FamilyID,
+			UserID:     token.UserID,
+			UserAgent:  token.UserAgent,
+			IP:         token.IP,
+			CreatedAt:  loginAt,
+			LastUsedAt: token.CreatedAt,
+			ExpiresAt:  token.ExpiresAt,
+		})
+	}
+
+	return page(sessions, filter.Limit, filter.Offset), int64(len(sessions)), nil
+}
+
+// CountActiveSessions counts sessions of a user whose refresh token is still usable
+func (r *RefreshTokenRepository) CountActiveSessions(ctx context.Context, userID uint) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.IsUsable() {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// RevokeSession revokes family of the user, fails with ErrSessionNotFound if it has no tokens left to revoke
+func (r *RefreshTokenRepository) RevokeSession(ctx context.Context, userID uint, familyID string) error {
+	revoked := r.revoke(func(t *entities.RefreshToken) bool { return t.UserID == userID && t.FamilyID == familyID })
+	if len(revoked) == 0 {
+		return entities.ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeAllSessions revokes every token of the user and returns the number of sessions ended
+func (r *RefreshTokenRepository) RevokeAllSessions(ctx context.Context, userID uint) (int64, error) {
+	var ended int64
+	for _, token := range r.revoke(func(t *entities.RefreshToken) bool { return t.UserID == userID }) {
+		if token.UsedAt == nil {
+			ended++
+		}
+	}
+	return ended, nil
+}
+
+// revoke revokes not yet revoked tokens matching fn and returns them
+func (r *RefreshTokenRepository) revoke(fn func(t *entities.RefreshToken) bool) []*entities.RefreshToken {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var revoked []*entities.RefreshToken
+	for _, token := range r.tokens {
+		if token.RevokedAt == nil && fn(token) {
+			token.RevokedAt = &now
+			revoked = append(revoked, token)
+		}
+	}
+	return revoked
+}
+
+// LoginAttemptRepository implements LoginAttemptRepository interface in memory
+type LoginAttemptRepository struct {
+	mu       sync.Mutex
+	attempts []*entities.LoginAttempt
+}
+
+// NewLoginAttemptRepository creates empty login attempt repository
+func NewLoginAttemptRepository() *LoginAttemptRepository {
+	return &LoginAttemptRepository{}
+}
+
+var _ repository.LoginAttemptRepository = (*LoginAttemptRepository)(nil)
+
+// Create stores a login attempt, a preset CreatedAt is kept so tests can backdate attempts
+func (r *LoginAttemptRepository) Create(ctx context.Context, attempt *entities.LoginAttempt) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt.ID = uint(len(r.attempts) + 1)
+	if attempt.CreatedAt.IsZero() {
+		attempt.CreatedAt = time.Now()
+	}
+	stored := *attempt
+	r.attempts = append(r.attempts, &stored)
+	return nil
+}
+
+// CountFailuresSince counts failed attempts for username made after since and after its last success
+func (r *LoginAttemptRepository) CountFailuresSince(ctx context.Context, username string, since time.Time) (int64, error) {
+	return r.countFailures(func(a *entities.LoginAttempt) bool { return a.Username == username }, since), nil
+}
+
+// CountIPFailuresSince counts failed attempts from ip made after since and after its last success
+func (r *LoginAttemptRepository) CountIPFailuresSince(ctx context.Context, ip string, since time.Time) (int64, error) {
+	return r.countFailures(func(a *entities.LoginAttempt) bool { return a.IP == ip }, since), nil
+}
+
+// ListByUser retrieves attempts of the user, newest first: successful ones by user ID
+// and failed ones by any of the logins the user can be identified with
+func (r *LoginAttemptRepository) ListByUser(ctx context.Context, userID uint, logins []string) ([]*entities.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempts := []*entities.LoginAttempt{}
+	for i := len(r.attempts) - 1; i >= 0; i-- {
+		attempt := r.attempts[i]
+		matched := attempt.UserID != nil && *attempt.UserID == userID
+		if attempt.UserID == nil {
+			for _, login := range logins {
+				if attempt.Username == login {
+					matched = true
+				}
+			}
+		}
+		if matched {
+			found := *attempt
+			attempts = append(attempts, &found)
+		}
+	}
+	sort.SliceStable(attempts, func(i, j int) bool { return attempts[i].CreatedAt.After(attempts[j].CreatedAt) })
+	return attempts, nil
+}
+
+// CountByInterval counts attempts made since since, grouped by hour, day or week in UTC
+func (r *LoginAttemptRepository) CountByInterval(ctx context.Context, interval string, since time.Time) ([]repository.LoginCount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	buckets := make(map[time.Time]*repository.LoginCount)
+	for _, attempt := range r.attempts {
+		if attempt.CreatedAt.Before(since) {
+			continue
+		}
+		start := truncateUTC(attempt.CreatedAt, interval)
+		count, ok := buckets[start]
+		if !ok {
+			count = &repository.LoginCount{Bucket: start}
+			buckets[start] = count
+		}
+		if attempt.Success {
+			count.Successful++
+		} else {
+			count.Failed++
+		}
+	}
+
+	counts := []repository.LoginCount{}
+	for _, count := range buckets {
+		counts = append(counts, *count)
+	}
+	sort.Slice(counts, func(i, j int) bool { return counts[i].Bucket.Before(counts[j].Bucket) })
+	return counts, nil
+}
+
+func (r *LoginAttemptRepository) countFailures(match func(a *entities.LoginAttempt) bool, since time.Time) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, attempt := range r.attempts {
+		if attempt.Success && match(attempt) && attempt.CreatedAt.After(since) {
+			since = attempt.CreatedAt
+		}
+	}
+
+	var count int64
+	for _, attempt := range r.attempts {
+		if !attempt.Success && match(attempt) && attempt.CreatedAt.After(since) {
+			count++
+		}
+	}
+	return count
+}
+
+// truncateUTC works like date_trunc, weeks start on Monday
+func truncateUTC(t time.Time, interval string) time.Time {
+	t = t.UTC()
+	switch interval {
+	case "hour":
+		return t.Truncate(time.Hour)
+	case "week":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -(int(day.Weekday())+6)%7)
+	default:
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+// PasswordResetRepository implements PasswordResetRepository interface in memory
+type PasswordResetRepository struct {
+	mu     sync.Mutex
+	tokens []*entities.PasswordResetToken
+}
+
+// NewPasswordResetRepository creates empty password reset repository
+func NewPasswordResetRepository() *PasswordResetRepository {
+	return &PasswordResetRepository{}
+}
+
+var _ repository.PasswordResetRepository = (*PasswordResetRepository)(nil)
+
+// Create stores a new reset token
+func (r *PasswordResetRepository) Create(ctx context.Context, token *entities.PasswordResetToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token.ID = uint(len(r.tokens) + 1)
+	token.CreatedAt = time.Now()
+	stored := *token
+	r.tokens = append(r.tokens, &stored)
+	return nil
+}
+
+// GetByHash retrieves reset token by its hash
+func (r *PasswordResetRepository) GetByHash(ctx context.Context, tokenHash string) (*entities.PasswordResetToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, entities.ErrInvalidToken
+}
+
+// MarkUsed consumes reset token, fails with ErrInvalidToken if it was already used
+func (r *PasswordResetRepository) MarkUsed(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.ID == id && token.UsedAt == nil {
+			now := time.Now()
+			token.UsedAt = &now
+			return nil
+		}
+	}
+	return entities.ErrInvalidToken
+}
+
+// EmailVerificationRepository implements EmailVerificationRepository interface in memory
+type EmailVerificationRepository struct {
+	mu     sync.Mutex
+	tokens []*entities.EmailVerificationToken
+}
+
+// NewEmailVerificationRepository creates empty email verification repository
+func NewEmailVerificationRepository() *EmailVerificationRepository {
+	return &EmailVerificationRepository{}
+}
+
+var _ repository.EmailVerificationRepository = (*EmailVerificationRepository)(nil)
+
+// Create stores a new verification token
+func (r *EmailVerificationRepository) Create(ctx context.Context, token *entities.EmailVerificationToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token.ID = uint(len(r.tokens) + 1)
+	token.CreatedAt = time.Now()
+	stored := *token
+	r.tokens = append(r.tokens, &stored)
+	return nil
+}
+
+// GetByHash retrieves verification token by its hash
+func (r *EmailVerificationRepository) GetByHash(ctx context.Context, tokenHash string) (*entities.EmailVerificationToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			found := *token
+			return &found, nil
+		}
+	}
+	return nil, entities.ErrInvalidToken
+}
+
+// MarkUsed consumes verification token, fails with ErrInvalidToken if it was already used
+func (r *EmailVerificationRepository) MarkUsed(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.ID == id && token.UsedAt == nil {
+			now := time.Now()
+			token.UsedAt = &now
+			return nil
+		}
+	}
+	return entities.ErrInvalidToken
+}
+
+// AuditRepository implements AuditRepository interface in memory
+type AuditRepository struct {
+	mu      sync.Mutex
+	entries []*entities.AuditEntry
+}
+
+// NewAuditRepository creates empty audit repository
+func NewAuditRepository() *AuditRepository {
+	return &AuditRepository{}
+}
+
+var _ repository.AuditRepository = (*AuditRepository)(nil)
+
+// Record stores a new audit entry
+func (r *AuditRepository) Record(ctx context.Context, entry *entities.AuditEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entry.ID = uint(len(r.entries) + 1)
+	entry.CreatedAt = time.Now()
+	stored := *entry
+	r.entries = append(r.entries, &stored)
+	return nil
+}
+
+// List retrieves a page of entries matching filter, newest first, and the total number of matches
+func (r *AuditRepository) List(ctx context.Context, filter repository.AuditFilter) ([]*entities.AuditEntry, int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries := []*entities.AuditEntry{}
+	for i := len(r.entries) - 1; i >= 0; i-- {
+		entry := r.entries[i]
+		if filter.ActorID != nil && (entry.ActorID == nil || *entry.ActorID != *filter.ActorID) {
+			continue
+		}
+		if filter.TargetID != nil && (entry.TargetID == nil || *entry.TargetID != *filter.TargetID) {
+			continue
+		}
+		if filter.Action != "" && entry.Action != filter.Action {
+			continue
+		}
+		found := *entry
+		entries = append(entries, &found)
+	}
+
+	return page(entries, filter.Limit, filter.Offset), int64(len(entries)), nil
+}
+
+// Entries returns all recorded entries, oldest first
+func (r *AuditRepository) Entries() []*entities.AuditEntry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]*entities.AuditEntry{}, r.entries...)
+}
+
+// PasswordHistoryRepository implements PasswordHistoryRepository interface in memory
+type PasswordHistoryRepository struct {
+	mu     sync.Mutex
+	hashes map[uint][]string // newest first
+}
+
+// NewPasswordHistoryRepository creates empty password history repository
+func NewPasswordHistoryRepository() *PasswordHistoryRepository {
+	return &PasswordHistoryRepository{hashes: make(map[uint][]string)}
+}
+
+var _ repository.PasswordHistoryRepository = (*PasswordHistoryRepository)(nil)
+
+// Recent returns up to limit hashes of the user's previous passwords, newest first
+func (r *PasswordHistoryRepository) Recent(ctx context.Context, userID uint, limit int) ([]string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, page(r.hashes[userID], limit, 0)...), nil
+}
+
+// Add stores a replaced password hash and drops all but the newest keep entries
+func (r *PasswordHistoryRepository) Add(ctx context.Context, userID uint, passwordHash string, keep int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	hashes := append([]string{passwordHash}, r.hashes[userID]...)
+	if len(hashes) > keep {
+		hashes = hashes[:keep]
+	}
+	r.hashes[userID] = hashes
+	return nil
+}
@@ -0,0 +1,506 @@
+package testutil
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ontair/admin-panel/internal/core/entities"
+	"github.com/ontair/admin-panel/internal/core/ports/repository"
+)
+
+// UserRepository implements UserRepository interface in memory.
+// Users are copied in and out, so callers can't change stored state by accident.
+type UserRepository struct {
+	mu     sync.Mutex
+	users  map[uint]*entities.User
+	locked map[uint]bool // rows held by UpdatePasswordLocked
+	nextID uint
+}
+
+// NewUserRepository creates empty in-memory user repository
+func NewUserRepository() *UserRepository {
+	return &UserRepository{
+		users:  make(map[uint]*entities.User),
+		locked: make(map[uint]bool),
+		nextID: 1,
+	}
+}
+
+var _ repository.UserRepository = (*UserRepository)(nil)
+
+// Create creates a new user
+func (r *UserRepository) Create(ctx context.Context, user *entities.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Username == user.Username {
+			return entities.ErrUserAlreadyExists
+		}
+		if user.Email != nil && existing.Email != nil && strings.EqualFold(*existing.Email, *user.Email) {
+			return entities.ErrEmailAlreadyExists
+		}
+	}
+
+	now := time.Now()
+	user.ID = r.nextID
+	user.Version = 1
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	r.nextID++
+
+	r.users[user.ID] = cloneUser(user)
+	return nil
+}
+
+// GetByID retrieves user by ID
+func (r *UserRepository) GetByID(ctx context.Context, id uint) (*entities.User, error) {
+	return r.find(func(u *entities.User) bool { return u.ID == id })
+}
+
+// GetByUsername retrieves user by username
+func (r *UserRepository) GetByUsername(ctx context.Context, username string) (*entities.User, error) {
+	return r.find(func(u *entities.User) bool { return u.Username == username })
+}
+
+// GetByEmail retrieves user by email, case-insensitively
+func (r *UserRepository) GetByEmail(ctx context.Context, email string) (*entities.User, error) {
+	return r.find(func(u *entities.User) bool { return u.Email != nil && strings.EqualFold(*u.Email, email) })
+}
+
+// SetEmailVerified marks email of the user verified, fails with ErrInvalidToken if the user's email changed
+func (r *UserRepository) SetEmailVerified(ctx context.Context, id uint, email string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt != nil || user.Email == nil || !strings.EqualFold(*user.Email, email) {
+		return entities.ErrInvalidToken
+	}
+	user.EmailVerified = true
+	touch(user)
+	return nil
+}
+
+// CountByRole counts active users with the role, unexpired temporary grants of it included
+func (r *UserRepository) CountByRole(ctx context.Context, role entities.Role) (int64, error) {
+	now := time.Now()
+	return r.count(func(u *entities.User) bool {
+		temp := u.TempRole != nil && *u.TempRole == role && u.TempRoleUntil != nil && u.TempRoleUntil.After(now)
+		return u.IsActive && (u.Role == role || temp)
+	}), nil
+}
+
+// CountByRoleAndStatus counts users grouped by role and active status
+func (r *UserRepository) CountByRoleAndStatus(ctx context.Context) ([]repository.UserCount, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	type key struct {
+		role   entities.Role
+		active bool
+	}
+	grouped := make(map[key]int64)
+	for _, user := range r.users {
+		if user.DeletedAt == nil {
+			grouped[key{user.Role, user.IsActive}]++
+		}
+	}
+
+	counts := []repository.UserCount{}
+	for k, count := range grouped {
+		counts = append(counts, repository.UserCount{Role: k.role, IsActive: k.active, Count: count})
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Role != counts[j].Role {
+			return counts[i].Role < counts[j].Role
+		}
+		return !counts[i].IsActive && counts[j].IsActive
+	})
+	return counts, nil
+}
+
+// LockActiveAdmins counts active admins, there is nothing to lock in memory
+func (r *UserRepository) LockActiveAdmins(ctx context.Context) (int64, error) {
+	return r.count(func(u *entities.User) bool { return u.Role == entities.RoleAdmin && u.IsActive }), nil
+}
+
+// Update updates user data if user.Version still matches the stored row and increments it
+func (r *UserRepository) Update(ctx context.Context, user *entities.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[user.ID]
+	if !ok {
+		return entities.ErrUserNotFound
+	}
+	if stored.Version != user.Version {
+		return entities.ErrConcurrentModification
+	}
+	for _, existing := range r.users {
+		if existing.ID == user.ID {
+			continue
+		}
+		if existing.Username == user.Username {
+			return entities.ErrUserAlreadyExists
+		}
+		if user.Email != nil && existing.Email != nil && strings.EqualFold(*existing.Email, *user.Email) {
+			return entities.ErrEmailAlreadyExists
+		}
+	}
+
+	stored.Username = user.Username
+	stored.Password = user.Password
+	stored.FirstName = user.FirstName
+	stored.LastName = user.LastName
+	stored.Role = user.Role
+	stored.IsActive = user.IsActive
+	stored.LastLogin = user.LastLogin
+	stored.ApprovalStatus = user.ApprovalStatus
+	stored.MustChangePass = user.MustChangePass
+	stored.PassChangedAt = user.PassChangedAt
+	stored.Email = user.Email
+	stored.EmailVerified = user.EmailVerified
+	touch(stored)
+
+	user.Version = stored.Version
+	return nil
+}
+
+// UpdateRole updates only user's role
+func (r *UserRepository) UpdateRole(ctx context.Context, id uint, role entities.Role) error {
+	return r.modify(id, func(u *entities.User) { u.Role = role })
+}
+
+// SetActive updates only user's active status
+func (r *UserRepository) SetActive(ctx context.Context, id uint, active bool) error {
+	return r.modify(id, func(u *entities.User) { u.IsActive = active })
+}
+
+// SetMustChangePassword sets whether user has to change password on next login
+func (r *UserRepository) SetMustChangePassword(ctx context.Context, id uint, required bool) error {
+	return r.modify(id, func(u *entities.User) { u.MustChangePass = required })
+}
+
+// SetDeactivateAt schedules user deactivation, nil cancels it
+func (r *UserRepository) SetDeactivateAt(ctx context.Context, id uint, at *time.Time) error {
+	return r.modify(id, func(u *entities.User) { u.DeactivateAt = at })
+}
+
+// SetTempRole grants a temporary role until the given time, nil role revokes it
+func (r *UserRepository) SetTempRole(ctx context.Context, id uint, role *entities.Role, until *time.Time) error {
+	return r.modify(id, func(u *entities.User) {
+		u.TempRole = role
+		u.TempRoleUntil = until
+	})
+}
+
+// RevokeTokens invalidates all tokens issued to the user so far
+func (r *UserRepository) RevokeTokens(ctx context.Context, id uint) error {
+	return r.modify(id, func(u *entities.User) {
+		now := time.Now()
+		u.TokensRevoked = &now
+	})
+}
+
+// ClearExpiredTempRoles removes temporary roles that have expired and returns the affected users
+func (r *UserRepository) ClearExpiredTempRoles(ctx context.Context) ([]*entities.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var users []*entities.User
+	for _, id := range r.sortedIDs() {
+		user := r.users[id]
+		if user.TempRoleUntil != nil && !user.TempRoleUntil.After(now) {
+			user.TempRole = nil
+			user.TempRoleUntil = nil
+			touch(user)
+			users = append(users, cloneUser(user))
+		}
+	}
+	return users, nil
+}
+
+// LockDueDeactivations returns users whose scheduled deactivation time has passed
+func (r *UserRepository) LockDueDeactivations(ctx context.Context) ([]*entities.User, error) {
+	now := time.Now()
+	return r.filter(func(u *entities.User) bool {
+		return u.DeactivateAt != nil && !u.DeactivateAt.After(now)
+	}), nil
+}
+
+// UpdatePasswordLocked applies change and saves the new password.
+// Like the NOWAIT row lock, a second call for a user still being changed fails with ErrUserBusy.
+func (r *UserRepository) UpdatePasswordLocked(ctx context.Context, id uint, change func(user *entities.User) error) error {
+	r.mu.Lock()
+	if r.locked[id] {
+		r.mu.Unlock()
+		return entities.ErrUserBusy
+	}
+	r.locked[id] = true
+	r.mu.Unlock()
+
+	defer func() {
+		r.mu.Lock()
+		delete(r.locked, id)
+		r.mu.Unlock()
+	}()
+
+	user, err := r.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := change(user); err != nil {
+		return err
+	}
+	return r.modify(id, func(u *entities.User) {
+		u.Password = user.Password
+		u.MustChangePass = user.MustChangePass
+		u.PassChangedAt = user.PassChangedAt
+	})
+}
+
+// Delete soft-deletes user by ID
+func (r *UserRepository) Delete(ctx context.Context, id uint) error {
+	return r.modify(id, func(u *entities.User) {
+		now := time.Now()
+		u.DeletedAt = &now
+	})
+}
+
+// HardDelete removes user permanently, including soft-deleted users
+func (r *UserRepository) HardDelete(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.users[id]; !ok {
+		return entities.ErrUserNotFound
+	}
+	delete(r.users, id)
+	return nil
+}
+
+// Restore brings back a soft-deleted user, fails with ErrUserNotFound if user is not deleted
+func (r *UserRepository) Restore(ctx context.Context, id uint) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt == nil {
+		return entities.ErrUserNotFound
+	}
+	user.DeletedAt = nil
+	touch(user)
+	return nil
+}
+
+// List retrieves list of users with pagination, newest first
+func (r *UserRepository) List(ctx context.Context, limit, offset int) ([]*entities.User, error) {
+	users := r.filter(func(*entities.User) bool { return true })
+	sort.SliceStable(users, func(i, j int) bool { return users[i].CreatedAt.After(users[j].CreatedAt) })
+	return page(users, limit, offset), nil
+}
+
+// Iterate calls fn with batches of all users ordered by ID, stopping at the first error
+func (r *UserRepository) Iterate(ctx context.Context, batchSize int, fn func(users []*entities.User) error) error {
+	users := r.filter(func(*entities.User) bool { return true })
+	for start := 0; start < len(users); start += batchSize {
+		end := min(start+batchSize, len(users))
+		if err := fn(users[start:end]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CountActiveByName counts active users with the given first and last name, ignoring case and excludeID
+func (r *UserRepository) CountActiveByName(ctx context.Context, firstName, lastName string, excludeID uint) (int64, error) {
+	return r.count(func(u *entities.User) bool {
+		return u.IsActive && u.ID != excludeID &&
+			strings.EqualFold(strings.TrimSpace(u.FirstName), strings.TrimSpace(firstName)) &&
+			strings.EqualFold(strings.TrimSpace(u.LastName), strings.TrimSpace(lastName))
+	}), nil
+}
+
+// Count returns total number of users
+func (r *UserRepository) Count(ctx context.Context) (int64, error) {
+	return r.count(func(*entities.User) bool { return true }), nil
+}
+
+// Search retrieves a page of users matching filter and the total number of matches.
+// Search text is matched as a plain substring, wildcards are not supported.
+func (r *UserRepository) Search(ctx context.Context, filter repository.UserFilter) ([]*entities.User, int64, error) {
+	search := strings.ToLower(filter.Search)
+	users := r.filter(func(u *entities.User) bool {
+		if len(filter.Roles) > 0 && !containsRole(filter.Roles, u.Role) {
+			return false
+		}
+		if filter.IsActive != nil && u.IsActive != *filter.IsActive {
+			return false
+		}
+		if filter.NeverChangedPassword != nil {
+			neverChanged := u.PassChangedAt == nil || u.PassChangedAt.Equal(u.CreatedAt)
+			if neverChanged != *filter.NeverChangedPassword {
+				return false
+			}
+		}
+		if filter.CreatedFrom != nil && u.CreatedAt.Before(*filter.CreatedFrom) {
+			return false
+		}
+		if filter.CreatedTo != nil && u.CreatedAt.After(*filter.CreatedTo) {
+			return false
+		}
+		if filter.LastLoginBefore != nil && u.LastLogin != nil && !u.LastLogin.Before(*filter.LastLoginBefore) {
+			return false
+		}
+		if search != "" {
+			email := ""
+			if u.Email != nil {
+				email = *u.Email
+			}
+			fields := []string{u.Username, email, u.FirstName, u.LastName}
+			matched := false
+			for _, field := range fields {
+				if strings.Contains(strings.ToLower(field), search) {
+					matched = true
+				}
+			}
+			if !matched {
+				return false
+			}
+		}
+		return true
+	})
+
+	total := int64(len(users))
+	if filter.CountOnly {
+		return []*entities.User{}, total, nil
+	}
+
+	switch filter.SortBy {
+	case "":
+		sort.SliceStable(users, func(i, j int) bool { return users[i].CreatedAt.After(users[j].CreatedAt) })
+	case "username":
+		sort.SliceStable(users, func(i, j int) bool { return (users[i].Username < users[j].Username) != filter.SortDesc })
+	case "created_at":
+		sort.SliceStable(users, func(i, j int) bool { return users[i].CreatedAt.Before(users[j].CreatedAt) != filter.SortDesc })
+	case "role":
+		sort.SliceStable(users, func(i, j int) bool { return (users[i].Role < users[j].Role) != filter.SortDesc })
+	case "last_login":
+		sort.SliceStable(users, func(i, j int) bool {
+			a, b := users[i].LastLogin, users[j].LastLogin
+			before := a == nil && b != nil || a != nil && b != nil && a.Before(*b)
+			return before != filter.SortDesc
+		})
+	default:
+		return nil, 0, entities.ErrInvalidSort
+	}
+
+	return page(users, filter.Limit, filter.Offset), total, nil
+}
+
+// GetByRole retrieves users by role
+func (r *UserRepository) GetByRole(ctx context.Context, role entities.Role) ([]*entities.User, error) {
+	return r.GetByRoles(ctx, []entities.Role{role})
+}
+
+// GetByRoles retrieves users by multiple roles
+func (r *UserRepository) GetByRoles(ctx context.Context, roles []entities.Role) ([]*entities.User, error) {
+	users := r.filter(func(u *entities.User) bool { return containsRole(roles, u.Role) })
+	sort.SliceStable(users, func(i, j int) bool { return users[i].CreatedAt.After(users[j].CreatedAt) })
+	return users, nil
+}
+
+// UpdateLastLogin updates user's last login timestamp
+func (r *UserRepository) UpdateLastLogin(ctx context.Context, userID uint) error {
+	return r.modify(userID, func(u *entities.User) {
+		now := time.Now()
+		u.LastLogin = &now
+	})
+}
+
+// find returns a copy of the first not deleted user matching fn
+func (r *UserRepository) find(fn func(u *entities.User) bool) (*entities.User, error) {
+	users := r.filter(fn)
+	if len(users) == 0 {
+		return nil, entities.ErrUserNotFound
+	}
+	return users[0], nil
+}
+
+// filter returns copies of not deleted users matching fn, ordered by ID
+func (r *UserRepository) filter(fn func(u *entities.User) bool) []*entities.User {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	users := []*entities.User{}
+	for _, id := range r.sortedIDs() {
+		user := r.users[id]
+		if user.DeletedAt == nil && fn(user) {
+			users = append(users, cloneUser(user))
+		}
+	}
+	return users
+}
+
+func (r *UserRepository) count(fn func(u *entities.User) bool) int64 {
+	return int64(len(r.filter(fn)))
+}
+
+// modify applies fn to the stored user and bumps its version
+func (r *UserRepository) modify(id uint, fn func(u *entities.User)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return entities.ErrUserNotFound
+	}
+	fn(user)
+	touch(user)
+	return nil
+}
+
+func (r *UserRepository) sortedIDs() []uint {
+	ids := make([]uint, 0, len(r.users))
+	for id := range r.users {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+func touch(user *entities.User) {
+	user.Version++
+	user.UpdatedAt = time.Now()
+}
+
+func cloneUser(user *entities.User) *entities.User {
+	clone := *user
+	return &clone
+}
+
+func containsRole(roles []entities.Role, role entities.Role) bool {
+	for _, r := range roles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// page cuts one page out of items, limit 0 means no limit
+func page[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return []T{}
+	}
+	items = items[offset:]
+	if limit > 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}